@@ -0,0 +1,10 @@
+// Package configs embeds the default configuration files, so they are available to
+// binaries run without the repository around.
+package configs
+
+import _ "embed"
+
+// Policy is policy.yml, the access policy used unless another policy file is loaded.
+//
+//go:embed policy.yml
+var Policy []byte
@@ -1,9 +1,15 @@
 package app
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"test/internal/config"
 	v1 "test/internal/delivery/http/v1"
 	"test/internal/repository"
@@ -12,6 +18,29 @@ import (
 	"test/pkg/api/auth"
 	"test/pkg/client/mongodb"
 	"test/pkg/hash"
+	"test/pkg/lockout"
+	"test/pkg/notify"
+	"test/pkg/oauth"
+	"time"
+)
+
+const (
+	memoryStorage     = "memory"
+	argon2idAlgorithm = "argon2id"
+	bcryptAlgorithm   = "bcrypt"
+	sha1Algorithm     = "sha1"
+	// oauthClientTimeout bounds requests to OAuth providers made while the user waits.
+	oauthClientTimeout = 10 * time.Second
+	// loginAction in email_verification.required_for blocks sign in with unverified email.
+	loginAction = "login"
+
+	smtpTransport   = "smtp"
+	fileTransport   = "file"
+	stdoutTransport = "stdout"
+	// mailQueueCloseTimeout is how long queued mail is delivered after the server stops.
+	mailQueueCloseTimeout = 30 * time.Second
+	// defaultSecretKey is the publicly known secret of the example config.
+	defaultSecretKey = "secret"
 )
 
 func Run() {
@@ -26,12 +55,68 @@ func Run() {
 	db := mongoClient.Database(cfg.MongodbConfig.Database)
 	repository := repository.NewRepository(db)
 
-	tokenManager, err := auth.NewManager(cfg.AuthConfig.JWT.SecretKey)
+	denylist := repository.Denylist
+	if cfg.AuthConfig.JWT.DenylistStorage == memoryStorage {
+		denylist = auth.NewMemoryDenylist()
+	}
+
+	keys, err := newKeySet(cfg.AuthConfig.JWT)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	policy, err := auth.LoadPolicy(cfg.AuthConfig.PolicyFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	go reloadPolicyOnSignal(policy)
+
+	tokenManager, err := auth.NewManager(keys, denylist, policy, auth.ManagerConfig{
+		Issuer:             cfg.AuthConfig.JWT.Issuer,
+		Audiences:          cfg.AuthConfig.JWT.Audiences,
+		Leeway:             cfg.AuthConfig.JWT.Leeway,
+		RefreshTokenFormat: cfg.AuthConfig.JWT.RefreshTokenFormat,
+		Cookies:            cfg.AuthConfig.Cookies.Enabled,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	hasher, err := newPasswordHasher(cfg.AuthConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	oauthProviders, err := newOAuthRegistry(cfg.Oauth2Config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	lockoutConfig, err := newLockout(cfg.AuthConfig.Lockout, repository.Attempts)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	magicLinkConfig, err := newMagicLink(cfg.AuthConfig.MagicLink, cfg.AuthConfig.Lockout, repository.Attempts)
 	if err != nil {
-		return
+		log.Fatal(err)
+	}
+
+	notifier, mailQueue, err := newMailNotifier(cfg.MailConfig)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	hasher := hash.NewSHA1Hasher(cfg.AuthConfig.PasswordSalt)
+	verificationConfig := cfg.AuthConfig.EmailVerification
+	var requiredForLogin bool
+	var verifiedEmailPermissions []string
+	for _, action := range verificationConfig.RequiredFor {
+		if action == loginAction {
+			requiredForLogin = true
+			continue
+		}
+		verifiedEmailPermissions = append(verifiedEmailPermissions, action)
+	}
 
 	services := service.NewServices(service.Deps{
 		Repos:           repository,
@@ -39,15 +124,271 @@ func Run() {
 		Hasher:          hasher,
 		AccessTokenTTL:  cfg.AuthConfig.JWT.AccessTokenTTL,
 		RefreshTokenTTL: cfg.AuthConfig.JWT.RefreshTokenTTL,
+		OAuthProviders:  oauthProviders,
+		Notifier:        notifier,
+
+		PasswordResetTTL: cfg.AuthConfig.PasswordResetTTL,
+		EmailVerification: service.EmailVerification{
+			LinkURL:          verificationConfig.LinkURL,
+			TokenTTL:         verificationConfig.TokenTTL,
+			RequiredForLogin: requiredForLogin,
+		},
+		TOTP: service.TOTP{
+			Issuer:        cfg.AuthConfig.MFA.Issuer,
+			Skew:          cfg.AuthConfig.MFA.Skew,
+			RecoveryCodes: cfg.AuthConfig.MFA.RecoveryCodes,
+		},
+		Lockout:               lockoutConfig,
+		ClientTokenTTL:        cfg.AuthConfig.JWT.ClientTokenTTL,
+		ImpersonationTokenTTL: cfg.AuthConfig.JWT.ImpersonationTokenTTL,
+		MagicLink:             magicLinkConfig,
 	})
 
+	if adminConfig := cfg.AuthConfig.Admin; adminConfig.Email != "" {
+		if err := services.Users.BootstrapAdmin(context.Background(), adminConfig.Email, adminConfig.Password); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	handlers := v1.NewHandler(services, tokenManager)
+	handlers.RequireVerifiedEmail(verifiedEmailPermissions...)
+	if cookiesConfig := cfg.AuthConfig.Cookies; cookiesConfig.Enabled {
+		sameSite, err := parseSameSite(cookiesConfig.SameSite)
+		if err != nil {
+			log.Fatal(err)
+		}
+		handlers.UseCookies(v1.Cookies{
+			Domain:          cookiesConfig.Domain,
+			SameSite:        sameSite,
+			AccessTokenTTL:  cfg.AuthConfig.JWT.AccessTokenTTL,
+			RefreshTokenTTL: cfg.AuthConfig.JWT.RefreshTokenTTL,
+		})
+	}
 
 	router := handlers.Init()
 
 	srv := server.NewServer(router, cfg)
-	if err := srv.Run(); !errors.Is(err, http.ErrServerClosed) {
+	err = srv.Run()
+
+	ctx, cancel := context.WithTimeout(context.Background(), mailQueueCloseTimeout)
+	defer cancel()
+	if err := mailQueue.Close(ctx); err != nil {
+		log.Printf("failed to deliver queued mail due to error: %v", err)
+	}
+
+	if !errors.Is(err, http.ErrServerClosed) {
 		panic(err)
 	}
+}
+
+// newKeySet loads signing keys. HS256 key built from the secret key has empty id,
+// it signs tokens when no signing key id is configured and verifies tokens without kid.
+// Once a signing key id is set, the secret key is trusted only with AcceptSecretKey.
+func newKeySet(cfg config.JWTConfig) (*auth.KeySet, error) {
+	if cfg.SecretKey == defaultSecretKey {
+		return nil, fmt.Errorf("jwt secret key is the default one, set JWT_SECRET_KEY")
+	}
+	var keys []*auth.Key
+	if cfg.SecretKey != "" && (cfg.SigningKeyId == "" || cfg.AcceptSecretKey) {
+		key, err := auth.NewHMACKey("", cfg.SecretKey)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
 
+	for _, keyConfig := range cfg.Keys {
+		key, err := auth.LoadKey(keyConfig.Id, keyConfig.Algorithm, keyConfig.PrivateKeyFile, keyConfig.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return auth.NewKeySet(cfg.SigningKeyId, keys...)
+}
+
+// newOAuthRegistry configures sign in providers, nothing is requested from them
+// until the first sign in.
+func newOAuthRegistry(cfg config.Oauth2Config) (*oauth.Registry, error) {
+	client := &http.Client{Timeout: oauthClientTimeout}
+
+	var providers []*oauth.Provider
+	for _, providerConfig := range cfg.Providers {
+		provider, err := oauth.NewProvider(oauth.Config{
+			Name:         providerConfig.Name,
+			Issuer:       providerConfig.Issuer,
+			ClientId:     providerConfig.ClientID,
+			ClientSecret: providerConfig.ClientSecret,
+			RedirectURL:  providerConfig.RedirectURL,
+			Scopes:       providerConfig.Scopes,
+			AuthURL:      providerConfig.AuthURL,
+			TokenURL:     providerConfig.TokenURL,
+			UserInfoURL:  providerConfig.UserInfoURL,
+			PKCE:         providerConfig.PKCE,
+			Claims: oauth.ClaimMapping{
+				Subject:       providerConfig.Claims.Subject,
+				Email:         providerConfig.Claims.Email,
+				EmailVerified: providerConfig.Claims.EmailVerified,
+			},
+		}, client)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+
+	return oauth.NewRegistry(providers...)
+}
+
+// newLockout builds limiters of failed attempts, a rule with zero window limits nothing.
+func newLockout(cfg config.LockoutConfig, store lockout.Store) (service.Lockout, error) {
+	if cfg.Storage == memoryStorage {
+		store = lockout.NewMemoryStore()
+	}
+
+	newLimiter := func(ruleConfig config.LockoutRuleConfig) (*lockout.Limiter, error) {
+		if ruleConfig.Window == 0 {
+			return nil, nil
+		}
+		return lockout.NewLimiter(store, lockout.Rule{
+			FreeAttempts: ruleConfig.FreeAttempts,
+			BaseDelay:    ruleConfig.BaseDelay,
+			MaxDelay:     ruleConfig.MaxDelay,
+			LockAfter:    ruleConfig.LockAfter,
+			LockDuration: ruleConfig.LockDuration,
+			Window:       ruleConfig.Window,
+		})
+	}
+
+	accounts, err := newLimiter(cfg.Account)
+	if err != nil {
+		return service.Lockout{}, err
+	}
+	ips, err := newLimiter(cfg.IP)
+	if err != nil {
+		return service.Lockout{}, err
+	}
+	return service.Lockout{Accounts: accounts, IPs: ips}, nil
+}
+
+// newMagicLink limits magic links sent to an address, they are counted where failed
+// attempts are.
+func newMagicLink(cfg config.MagicLinkConfig, lockoutCfg config.LockoutConfig, store lockout.Store) (service.MagicLink, error) {
+	magicLink := service.MagicLink{
+		Enabled:  cfg.Enabled,
+		LinkURL:  cfg.LinkURL,
+		TokenTTL: cfg.TokenTTL,
+	}
+	if !cfg.Enabled || cfg.MaxRequests <= 0 {
+		return magicLink, nil
+	}
+	if lockoutCfg.Storage == memoryStorage {
+		store = lockout.NewMemoryStore()
+	}
+
+	limiter, err := lockout.NewLimiter(store, lockout.Rule{
+		LockAfter:    cfg.MaxRequests,
+		LockDuration: cfg.RequestWindow,
+		Window:       cfg.RequestWindow,
+	})
+	if err != nil {
+		return service.MagicLink{}, err
+	}
+	magicLink.Limiter = limiter
+	return magicLink, nil
+}
+
+func parseSameSite(sameSite string) (http.SameSite, error) {
+	switch strings.ToLower(sameSite) {
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	default:
+		return 0, fmt.Errorf("unknown cookie same site %q", sameSite)
+	}
+}
+
+// newMailNotifier emails notifications through a queue, so requests don't wait for
+// the transport. The queue is closed when the server stops.
+func newMailNotifier(cfg config.MailConfig) (*service.MailNotifier, *notify.Queue, error) {
+	templates, err := notify.LoadTemplates(cfg.TemplatesDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var mailer notify.Mailer
+	switch cfg.Transport {
+	case smtpTransport:
+		mailer, err = notify.NewSMTPMailer(notify.SMTPConfig{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+			TLS:      cfg.SMTP.TLS,
+			Timeout:  cfg.SMTP.Timeout,
+		})
+	case fileTransport:
+		mailer, err = notify.NewFileMailer(cfg.FileDir)
+	case stdoutTransport:
+		mailer = notify.NewWriterMailer(os.Stdout)
+	default:
+		return nil, nil, fmt.Errorf("unknown mail transport %q", cfg.Transport)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	queue := notify.NewQueue(mailer, notify.QueueConfig{
+		Size:         cfg.Queue.Size,
+		Workers:      cfg.Queue.Workers,
+		MaxAttempts:  cfg.Queue.MaxAttempts,
+		RetryBackoff: cfg.Queue.RetryBackoff,
+	})
+	return service.NewMailNotifier(queue, templates, cfg.From), queue, nil
+}
+
+func reloadPolicyOnSignal(policy *auth.Policy) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		if err := policy.Reload(); err != nil {
+			log.Printf("failed to reload policy, keeping the previous one: %v", err)
+			continue
+		}
+		log.Print("policy reloaded")
+	}
+}
+
+// newPasswordHasher returns the configured hasher, SHA1 hashes made before it was
+// configurable are still verified and upgraded on sign in.
+func newPasswordHasher(cfg config.AuthConfig) (hash.PasswordHasher, error) {
+	legacy := hash.NewSHA1Hasher(cfg.PasswordSalt)
+
+	var primary hash.PasswordHasher
+	var err error
+	switch cfg.PasswordHashing.Algorithm {
+	case argon2idAlgorithm:
+		argon2Config := cfg.PasswordHashing.Argon2id
+		primary, err = hash.NewArgon2idHasher(hash.Argon2idParams{
+			Memory:      argon2Config.Memory,
+			Iterations:  argon2Config.Iterations,
+			Parallelism: argon2Config.Parallelism,
+			SaltLength:  argon2Config.SaltLength,
+			KeyLength:   argon2Config.KeyLength,
+		})
+	case bcryptAlgorithm:
+		primary, err = hash.NewBcryptHasher(cfg.PasswordHashing.BcryptCost)
+	case sha1Algorithm:
+		return legacy, nil
+	default:
+		return nil, fmt.Errorf("unknown password hashing algorithm %q", cfg.PasswordHashing.Algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return hash.NewMigratingHasher(primary, legacy), nil
 }
@@ -15,6 +15,7 @@ type Config struct {
 	PostgresdbConfig `yaml:"postgresdb"`
 	AuthConfig       `yaml:"auth"`
 	Oauth2Config     `yaml:"oauth2"`
+	MailConfig       `yaml:"mail"`
 }
 
 type ListenConfig struct {
@@ -38,21 +39,199 @@ type PostgresdbConfig struct {
 }
 
 type AuthConfig struct {
-	JWT          JWTConfig `yaml:"jwt"`
-	PasswordSalt string    `yaml:"password_salt"`
+	JWT          JWTConfig   `yaml:"jwt"`
+	PasswordSalt string      `yaml:"password_salt"`
+	Admin        AdminConfig `yaml:"admin"`
+	// PolicyFile maps roles to permissions, it is reloaded on SIGHUP.
+	PolicyFile      string                `yaml:"policy_file" env-default:"configs/policy.yml"`
+	PasswordHashing PasswordHashingConfig `yaml:"password_hashing"`
+	// PasswordResetTTL is how long a password reset token can be used.
+	PasswordResetTTL  time.Duration           `yaml:"password_reset_ttl" env-default:"30m"`
+	EmailVerification EmailVerificationConfig `yaml:"email_verification"`
+	MFA               MFAConfig               `yaml:"mfa"`
+	Lockout           LockoutConfig           `yaml:"lockout"`
+	MagicLink         MagicLinkConfig         `yaml:"magic_link"`
+	Cookies           CookiesConfig           `yaml:"cookies"`
+}
+
+// CookiesConfig sends tokens to browsers in HttpOnly cookies instead of the Access-Token
+// and Refresh-Token headers, requests authenticated with them need the CSRF token.
+// SameSite is "strict", "lax" or "none".
+type CookiesConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Domain   string `yaml:"domain"`
+	SameSite string `yaml:"same_site" env-default:"strict"`
+}
+
+// MagicLinkConfig is passwordless sign in with links sent to the email. At most
+// MaxRequests links are sent to an address within RequestWindow, zero doesn't limit them.
+// Links are opened in browsers, so they are meant for cookie mode, without cookies the
+// tokens are returned in the response body.
+type MagicLinkConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	LinkURL       string        `yaml:"link_url" env-default:"http://localhost:4000/api/v1/auth/magic-link/consume"`
+	TokenTTL      time.Duration `yaml:"token_ttl" env-default:"15m"`
+	MaxRequests   int           `yaml:"max_requests" env-default:"3"`
+	RequestWindow time.Duration `yaml:"request_window" env-default:"1h"`
+}
+
+// LockoutConfig limits failed attempts to sign in, refresh tokens and verify codes per
+// account and per IP. Storage is where attempts are counted: "mongo" or "memory".
+type LockoutConfig struct {
+	Storage string            `yaml:"storage" env-default:"mongo"`
+	Account LockoutRuleConfig `yaml:"account"`
+	IP      LockoutRuleConfig `yaml:"ip"`
+}
+
+// LockoutRuleConfig lets FreeAttempts failures go, then delays the next attempt by
+// BaseDelay doubled on every failure up to MaxDelay. LockAfter failures lock out for
+// LockDuration, zero never locks out. Failures are forgotten after Window without one,
+// zero window turns the rule off.
+type LockoutRuleConfig struct {
+	FreeAttempts int           `yaml:"free_attempts"`
+	BaseDelay    time.Duration `yaml:"base_delay"`
+	MaxDelay     time.Duration `yaml:"max_delay"`
+	LockAfter    int           `yaml:"lock_after"`
+	LockDuration time.Duration `yaml:"lock_duration"`
+	Window       time.Duration `yaml:"window"`
+}
+
+// MFAConfig is the TOTP second factor. Skew is how many 30 second steps a code is
+// accepted before and after the current one, it tolerates clock drift of the phone.
+type MFAConfig struct {
+	Issuer        string `yaml:"issuer" env-default:"Users"`
+	Skew          int    `yaml:"skew" env-default:"1"`
+	RecoveryCodes int    `yaml:"recovery_codes" env-default:"10"`
+}
+
+// EmailVerificationConfig tells where verification links point to and what users can't
+// do until they verify the email: "login" or permissions, like "users:update".
+type EmailVerificationConfig struct {
+	LinkURL     string        `yaml:"link_url" env-default:"http://localhost:4000/api/v1/auth/verify-email"`
+	TokenTTL    time.Duration `yaml:"token_ttl" env-default:"24h"`
+	RequiredFor []string      `yaml:"required_for"`
+}
+
+// PasswordHashingConfig chooses how new passwords are hashed: "argon2id", "bcrypt" or
+// the legacy "sha1". Hashes of other algorithms or with other parameters are upgraded
+// when users sign in.
+type PasswordHashingConfig struct {
+	Algorithm  string         `yaml:"algorithm" env-default:"argon2id"`
+	Argon2id   Argon2idConfig `yaml:"argon2id"`
+	BcryptCost int            `yaml:"bcrypt_cost" env-default:"12"`
+}
+
+type Argon2idConfig struct {
+	// Memory is in KiB.
+	Memory      uint32 `yaml:"memory" env-default:"65536"`
+	Iterations  uint32 `yaml:"iterations" env-default:"3"`
+	Parallelism uint8  `yaml:"parallelism" env-default:"2"`
+	SaltLength  uint32 `yaml:"salt_length" env-default:"16"`
+	KeyLength   uint32 `yaml:"key_length" env-default:"32"`
+}
+
+// AdminConfig is the first admin created on start, nothing is done when the email is empty.
+// An existing user with the email is granted the admin role and keeps the password.
+type AdminConfig struct {
+	Email    string `yaml:"email" env:"ADMIN_EMAIL"`
+	Password string `yaml:"password" env:"ADMIN_PASSWORD"`
 }
 
 type JWTConfig struct {
 	AccessTokenTTL  time.Duration `yaml:"access_token_ttl"`
 	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl"`
-	SecretKey       string        `yaml:"secret_key"`
+	SecretKey       string        `yaml:"secret_key" env:"JWT_SECRET_KEY"`
+	// ClientTokenTTL is the lifetime of tokens of OAuth clients, they get no refresh token.
+	ClientTokenTTL time.Duration `yaml:"client_token_ttl" env-default:"15m"`
+	// ImpersonationTokenTTL is the lifetime of tokens admins act as users with.
+	ImpersonationTokenTTL time.Duration `yaml:"impersonation_token_ttl" env-default:"15m"`
+	// DenylistStorage is where revoked access tokens are kept: "mongo" or "memory".
+	DenylistStorage string `yaml:"denylist_storage" env-default:"mongo"`
+	// SigningKeyId is the id of the key from Keys new tokens are signed with.
+	// Empty id keeps signing with HS256 and SecretKey.
+	SigningKeyId string         `yaml:"signing_key_id"`
+	Keys         []JWTKeyConfig `yaml:"keys"`
+	// AcceptSecretKey keeps verifying tokens signed with SecretKey after SigningKeyId is
+	// set, while tokens issued before the migration expire.
+	AcceptSecretKey bool `yaml:"accept_secret_key"`
+	// Issuer is the iss claim of issued tokens, tokens of other issuers are refused.
+	Issuer string `yaml:"issuer"`
+	// Audiences are accepted in the aud claim, tokens are issued for the first one.
+	Audiences []string `yaml:"audiences"`
+	// Leeway allows clocks of services to differ when token times are checked.
+	Leeway time.Duration `yaml:"leeway" env-default:"30s"`
+	// RefreshTokenFormat is "opaque" for random strings or "jwt" for signed tokens
+	// with the session id.
+	RefreshTokenFormat string `yaml:"refresh_token_format" env-default:"opaque"`
+}
+
+// JWTKeyConfig is an asymmetric key in PEM files. Retired keys are kept with the
+// public key only, so tokens signed with them stay valid until they expire.
+type JWTKeyConfig struct {
+	Id             string `yaml:"id"`
+	Algorithm      string `yaml:"algorithm"`
+	PrivateKeyFile string `yaml:"private_key_file"`
+	PublicKeyFile  string `yaml:"public_key_file"`
 }
 
+// Oauth2Config lists the providers users can sign in with, the provider name is
+// the one in /auth/{provider}/login and /auth/{provider}/callback routes.
 type Oauth2Config struct {
-	RedirectURL  string   `yaml:"redirect_url"`
+	Providers []OAuth2ProviderConfig `yaml:"providers"`
+}
+
+// OAuth2ProviderConfig is an OpenID Connect provider when the issuer is set, its
+// endpoints are discovered. Plain OAuth2 providers set the urls instead.
+type OAuth2ProviderConfig struct {
+	Name         string   `yaml:"name"`
+	Issuer       string   `yaml:"issuer"`
 	ClientID     string   `yaml:"client_id"`
 	ClientSecret string   `yaml:"client_secret"`
+	RedirectURL  string   `yaml:"redirect_url"`
 	Scopes       []string `yaml:"scopes"`
+	AuthURL      string   `yaml:"auth_url"`
+	TokenURL     string   `yaml:"token_url"`
+	UserInfoURL  string   `yaml:"userinfo_url"`
+	PKCE         bool     `yaml:"pkce"`
+	// Claims maps the user to the claims of the ID token or userinfo response,
+	// empty names mean the standard sub, email and email_verified claims.
+	Claims OAuth2ClaimsConfig `yaml:"claims"`
+}
+
+type OAuth2ClaimsConfig struct {
+	Subject       string `yaml:"subject"`
+	Email         string `yaml:"email"`
+	EmailVerified string `yaml:"email_verified"`
+}
+
+// MailConfig chooses how mail is sent: "smtp", "file" drops .eml files into FileDir
+// and "stdout" prints them. Messages are rendered from templates in TemplatesDir.
+type MailConfig struct {
+	Transport    string          `yaml:"transport" env-default:"stdout"`
+	From         string          `yaml:"from" env-default:"no-reply@localhost"`
+	TemplatesDir string          `yaml:"templates_dir" env-default:"configs/mail"`
+	FileDir      string          `yaml:"file_dir" env-default:"mail"`
+	SMTP         SMTPConfig      `yaml:"smtp"`
+	Queue        MailQueueConfig `yaml:"queue"`
+}
+
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port" env-default:"587"`
+	Username string `yaml:"username" env:"SMTP_USERNAME"`
+	Password string `yaml:"password" env:"SMTP_PASSWORD"`
+	// TLS is "starttls", "tls" or "none".
+	TLS     string        `yaml:"tls" env-default:"starttls"`
+	Timeout time.Duration `yaml:"timeout" env-default:"30s"`
+}
+
+// MailQueueConfig is the background delivery, failed messages are retried after
+// RetryBackoff that doubles on every attempt.
+type MailQueueConfig struct {
+	Size         int           `yaml:"size" env-default:"100"`
+	Workers      int           `yaml:"workers" env-default:"2"`
+	MaxAttempts  int           `yaml:"max_attempts" env-default:"5"`
+	RetryBackoff time.Duration `yaml:"retry_backoff" env-default:"5s"`
 }
 
 var instance *Config
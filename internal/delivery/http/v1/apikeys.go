@@ -0,0 +1,103 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"test/internal/domain"
+	"test/internal/service/dto"
+	apierrors "test/pkg/api/api_errors"
+	"test/pkg/api/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	apiKeyIdNameURL = "keyId"
+	apiKeysURL      = "/:id/api-keys"
+	apiKeyURL       = "/:id/api-keys/:keyId"
+)
+
+// @Summary Create API key
+// @Tags user/:id/api-keys
+// @Description Create an API key of the user, the key is returned once. Send it in the X-API-Key header or as the bearer token. Keys are created with access tokens only
+// @ID create-api-key
+// @Accept json
+// @Produce json
+// @Param createDTO body dto.CreateAPIKeyDTO true "name, scopes and expiry of the key"
+// @Success 201 {object} dto.APIKeyDTO
+// @Router /users/:id/api-keys [post]
+
+func (h *Handler) CreateAPIKey(ctx *gin.Context) {
+	userId := ctx.Param(idNameURL)
+	if !isAccountOwner(ctx, userId) {
+		newResponse(ctx, http.StatusForbidden, "forbidden")
+		return
+	}
+	// A key could otherwise mint a key without the limits of its own scopes.
+	if claims, ok := auth.GetClaims(ctx); ok && claims.APIKeyId != "" {
+		newResponse(ctx, http.StatusForbidden, "api keys can't create api keys")
+		return
+	}
+
+	var createDTO dto.CreateAPIKeyDTO
+	if err := ctx.BindJSON(&createDTO); err != nil {
+		newResponse(ctx, http.StatusBadRequest, "failed to bind api key and json")
+		return
+	}
+
+	apiKeyDTO, err := h.services.APIKeys.Create(ctx.Request.Context(), userId, createDTO)
+	if err != nil {
+		newAPIKeyErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, apiKeyDTO)
+}
+
+// @Summary Find API keys
+// @Tags user/:id/api-keys
+// @Description Find API keys of the user, keys themselves aren't returned
+// @ID find-api-keys
+// @Produce json
+// @Success 200 {array} domain.APIKey
+// @Router /users/:id/api-keys [get]
+
+func (h *Handler) FindAPIKeys(ctx *gin.Context) {
+	apiKeys, err := h.services.APIKeys.FindAll(ctx.Request.Context(), ctx.Param(idNameURL))
+	if err != nil {
+		newAPIKeyErrorResponse(ctx, err)
+		return
+	}
+	if apiKeys == nil {
+		apiKeys = []domain.APIKey{}
+	}
+	ctx.JSON(http.StatusOK, apiKeys)
+}
+
+// @Summary Revoke API key
+// @Tags user/:id/api-keys
+// @Description Revoke the API key of the user
+// @ID revoke-api-key
+// @Seccess 200 {integer} integer 1
+// @Router /users/:id/api-keys/:keyId [delete]
+
+func (h *Handler) RevokeAPIKey(ctx *gin.Context) {
+	err := h.services.APIKeys.Revoke(ctx.Request.Context(), ctx.Param(idNameURL), ctx.Param(apiKeyIdNameURL))
+	if err != nil {
+		newAPIKeyErrorResponse(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
+
+func newAPIKeyErrorResponse(ctx *gin.Context, err error) {
+	var apiErr *apierrors.ApiError
+	switch {
+	case errors.Is(err, domain.ErrUserNotFound), errors.Is(err, domain.ErrAPIKeyNotFound):
+		newResponse(ctx, http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrInvalidAPIKeyName), errors.Is(err, domain.ErrInvalidScope),
+		errors.Is(err, domain.ErrInvalidExpiry), errors.As(err, &apiErr):
+		newResponse(ctx, http.StatusBadRequest, err.Error())
+	default:
+		newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+}
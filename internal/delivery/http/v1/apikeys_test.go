@@ -0,0 +1,320 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"test/internal/domain"
+	"test/internal/service"
+	"test/internal/service/dto"
+	"test/internal/service/mocks"
+	"test/pkg/api/auth"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestHandler_CreateAPIKey(t *testing.T) {
+	type mockBehavior func(s *mocks.MockAPIKeys, id string)
+
+	claims := auth.Claims{Roles: []string{auth.UserRole}}
+	claims.Subject = "000000000001"
+	keyId, _ := primitive.ObjectIDFromHex("000000000000000000000001")
+	createdAt := time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                string
+		id                  string
+		apiKeyId            string
+		inputBody           string
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name:      "OK",
+			id:        "000000000001",
+			inputBody: `{"name":"ci","scopes":["users:read"]}`,
+			mockBehavior: func(s *mocks.MockAPIKeys, id string) {
+				s.EXPECT().Create(context.Background(), id, dto.CreateAPIKeyDTO{Name: "ci", Scopes: []string{"users:read"}}).
+					Return(dto.APIKeyDTO{Key: "uak_key", APIKey: domain.APIKey{
+						Id:        keyId,
+						Name:      "ci",
+						Prefix:    "uak_key",
+						Scopes:    []string{"users:read"},
+						CreatedAt: createdAt,
+					}}, nil)
+			},
+			expectedStatusCode: 201,
+			expectedRequestBody: `{"key":"uak_key","id":"000000000000000000000001","name":"ci","prefix":"uak_key",` +
+				`"scopes":["users:read"],"created_at":"2022-11-01T00:00:00Z","last_used_at":"0001-01-01T00:00:00Z"}`,
+		},
+		{
+			name:                "Account of another user",
+			id:                  "000000000002",
+			inputBody:           `{"name":"ci"}`,
+			mockBehavior:        func(s *mocks.MockAPIKeys, id string) {},
+			expectedStatusCode:  403,
+			expectedRequestBody: `{"message":"forbidden"}`,
+		},
+		{
+			name:                "API key",
+			id:                  "000000000001",
+			apiKeyId:            "000000000000000000000002",
+			inputBody:           `{"name":"ci"}`,
+			mockBehavior:        func(s *mocks.MockAPIKeys, id string) {},
+			expectedStatusCode:  403,
+			expectedRequestBody: `{"message":"api keys can't create api keys"}`,
+		},
+		{
+			name:      "Invalid scope",
+			id:        "000000000001",
+			inputBody: `{"name":"ci","scopes":["users"]}`,
+			mockBehavior: func(s *mocks.MockAPIKeys, id string) {
+				s.EXPECT().Create(context.Background(), id, dto.CreateAPIKeyDTO{Name: "ci", Scopes: []string{"users"}}).
+					Return(dto.APIKeyDTO{}, domain.ErrInvalidScope)
+			},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"scope must be resource:action"}`,
+		},
+		{
+			name:                "Empty body",
+			id:                  "000000000001",
+			mockBehavior:        func(s *mocks.MockAPIKeys, id string) {},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"failed to bind api key and json"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			apiKeysMockService := mocks.NewMockAPIKeys(c)
+			testCase.mockBehavior(apiKeysMockService, testCase.id)
+
+			services := &service.Services{APIKeys: apiKeysMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			callerClaims := claims
+			callerClaims.APIKeyId = testCase.apiKeyId
+			r.POST(apiKeysURL, func(ctx *gin.Context) {
+				ctx.Set(auth.ClaimsContextKey, &callerClaims)
+			}, handler.CreateAPIKey)
+			req := httptest.NewRequest("POST", fmt.Sprintf("/%s/api-keys", testCase.id), bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_FindAPIKeys(t *testing.T) {
+	type mockBehavior func(s *mocks.MockAPIKeys, id string)
+
+	testTable := []struct {
+		name                string
+		id                  string
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name: "No keys",
+			id:   "000000000001",
+			mockBehavior: func(s *mocks.MockAPIKeys, id string) {
+				s.EXPECT().FindAll(context.Background(), id).Return(nil, nil)
+			},
+			expectedStatusCode:  200,
+			expectedRequestBody: `[]`,
+		},
+		{
+			name: "Service Failure",
+			id:   "000000000001",
+			mockBehavior: func(s *mocks.MockAPIKeys, id string) {
+				s.EXPECT().FindAll(context.Background(), id).Return(nil, errors.New("service failure"))
+			},
+			expectedStatusCode:  500,
+			expectedRequestBody: `{"message":"service failure"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			apiKeysMockService := mocks.NewMockAPIKeys(c)
+			testCase.mockBehavior(apiKeysMockService, testCase.id)
+
+			services := &service.Services{APIKeys: apiKeysMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.GET(apiKeysURL, handler.FindAPIKeys)
+			req := httptest.NewRequest("GET", fmt.Sprintf("/%s/api-keys", testCase.id), nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_RevokeAPIKey(t *testing.T) {
+	type mockBehavior func(s *mocks.MockAPIKeys, id, keyId string)
+
+	testTable := []struct {
+		name                string
+		id                  string
+		keyId               string
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name:  "OK",
+			id:    "000000000001",
+			keyId: "000000000002",
+			mockBehavior: func(s *mocks.MockAPIKeys, id, keyId string) {
+				s.EXPECT().Revoke(context.Background(), id, keyId).Return(nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:  "Key not found",
+			id:    "000000000001",
+			keyId: "000000000002",
+			mockBehavior: func(s *mocks.MockAPIKeys, id, keyId string) {
+				s.EXPECT().Revoke(context.Background(), id, keyId).Return(domain.ErrAPIKeyNotFound)
+			},
+			expectedStatusCode:  404,
+			expectedRequestBody: `{"message":"api key doesn't exists"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			apiKeysMockService := mocks.NewMockAPIKeys(c)
+			testCase.mockBehavior(apiKeysMockService, testCase.id, testCase.keyId)
+
+			services := &service.Services{APIKeys: apiKeysMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.DELETE(apiKeyURL, handler.RevokeAPIKey)
+			req := httptest.NewRequest("DELETE", fmt.Sprintf("/%s/api-keys/%s", testCase.id, testCase.keyId), nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_AuthenticateAPIKey(t *testing.T) {
+	type mockBehavior func(s *mocks.MockAPIKeys)
+
+	claims := &auth.Claims{Roles: []string{auth.UserRole}, APIKeyId: "000000000002"}
+	claims.Subject = "000000000001"
+
+	testTable := []struct {
+		name                string
+		headers             map[string]string
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name:    "Key header",
+			headers: map[string]string{"X-API-Key": "uak_key"},
+			mockBehavior: func(s *mocks.MockAPIKeys) {
+				s.EXPECT().Authenticate(context.Background(), "uak_key").Return(claims, nil)
+			},
+			expectedStatusCode:  200,
+			expectedRequestBody: "000000000001",
+		},
+		{
+			name:    "Bearer key",
+			headers: map[string]string{"Authorization": "Bearer uak_key"},
+			mockBehavior: func(s *mocks.MockAPIKeys) {
+				s.EXPECT().Authenticate(context.Background(), "uak_key").Return(claims, nil)
+			},
+			expectedStatusCode:  200,
+			expectedRequestBody: "000000000001",
+		},
+		{
+			name:    "Invalid key",
+			headers: map[string]string{"X-API-Key": "uak_revoked"},
+			mockBehavior: func(s *mocks.MockAPIKeys) {
+				s.EXPECT().Authenticate(context.Background(), "uak_revoked").Return(nil, domain.ErrInvalidAPIKey)
+			},
+			expectedStatusCode:  401,
+			expectedRequestBody: `{"message":"api key is invalid, expired or revoked"}`,
+		},
+		{
+			name:    "Service Failure",
+			headers: map[string]string{"X-API-Key": "uak_key"},
+			mockBehavior: func(s *mocks.MockAPIKeys) {
+				s.EXPECT().Authenticate(context.Background(), "uak_key").Return(nil, errors.New("service failure"))
+			},
+			expectedStatusCode:  500,
+			expectedRequestBody: `{"message":"service failure"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			apiKeysMockService := mocks.NewMockAPIKeys(c)
+			testCase.mockBehavior(apiKeysMockService)
+
+			services := &service.Services{APIKeys: apiKeysMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.GET("/", handler.authenticate(), func(ctx *gin.Context) {
+				claims, _ := auth.GetClaims(ctx)
+				ctx.String(200, claims.Subject)
+			})
+			req := httptest.NewRequest("GET", "/", nil)
+			for key, value := range testCase.headers {
+				req.Header.Set(key, value)
+			}
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
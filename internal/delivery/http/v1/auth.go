@@ -0,0 +1,228 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"test/internal/domain"
+	"test/internal/service/dto"
+	"test/pkg/api/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	authGroup    = "/auth"
+	loginURL     = "/login"
+	refreshURL   = "/refresh"
+	logoutURL    = "/logout"
+	logoutAllURL = "/logout-all"
+	forgotURL    = "/password/forgot"
+	resetURL     = "/password/reset"
+	verifyURL    = "/verify-email"
+	resendURL    = "/verify-email/resend"
+	mfaVerifyURL = "/mfa/verify"
+	jwksURL      = "/.well-known/jwks.json"
+
+	refreshTokenPath = auth.BasicURL + auth.Version + authGroup + refreshURL
+
+	// sessionsLogout ends sessions of the caller, logout routes have no /:id, so the
+	// permission is granted with scope "any".
+	sessionsLogout = "sessions:logout"
+)
+
+func (h *Handler) initAuthRoutes(api *gin.RouterGroup) {
+
+	authRoutes := api.Group(authGroup)
+	{
+		authRoutes.POST(loginURL, h.SignIn)
+		authRoutes.POST(refreshURL, h.RefreshToken)
+		authRoutes.POST(forgotURL, h.ForgotPassword)
+		authRoutes.POST(resetURL, h.ResetPassword)
+		authRoutes.GET(verifyURL, h.VerifyEmail)
+		authRoutes.POST(resendURL, h.ResendVerification)
+		authRoutes.POST(mfaVerifyURL, h.VerifyMFA)
+		authRoutes.POST(magicLinkURL, h.SendMagicLink)
+		authRoutes.GET(magicLinkConsumeURL, h.ConsumeMagicLink)
+
+		authenticated := authRoutes.Group("/").Use(h.authenticate(), h.auditImpersonation())
+		{
+			authenticated.POST(logoutURL, h.requirePermission(sessionsLogout), h.Logout)
+			authenticated.POST(logoutAllURL, h.requirePermission(sessionsLogout), h.LogoutAll)
+		}
+	}
+}
+
+// @Summary Sign in
+// @Tags auth
+// @Description Sign in with email and password
+// @ID sign-in
+// @Accept json
+// @Produce json
+// @Param signInDTO body dto.SignInDTO true "credentials"
+// @Seccess 200 {integer} integer 1
+// @Success 202 {object} dto.MFAChallengeDTO
+// @Router /auth/login [post]
+
+func (h *Handler) SignIn(ctx *gin.Context) {
+
+	var signInDTO dto.SignInDTO
+	err := ctx.BindJSON(&signInDTO)
+	if err != nil {
+		newResponse(ctx, http.StatusBadRequest, "failed to bind credentials and json")
+		return
+	}
+
+	tokenDTO, err := h.services.Users.SignIn(ctx.Request.Context(), signInDTO, newDeviceDTO(ctx))
+	if err != nil {
+		if newBlockedResponse(ctx, err) {
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidCredentials) {
+			newResponse(ctx, http.StatusUnauthorized, domain.ErrInvalidCredentials.Error())
+			return
+		}
+		if errors.Is(err, domain.ErrEmailNotVerified) {
+			newResponse(ctx, http.StatusForbidden, err.Error())
+			return
+		}
+		newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.newTokenResponse(ctx, tokenDTO)
+}
+
+// newTokenResponse sends the tokens, see setTokens. Users with the second factor get the
+// challenge token in the body instead, it is exchanged for the tokens by /auth/mfa/verify.
+func (h *Handler) newTokenResponse(ctx *gin.Context, tokenDTO dto.TokenDTO) {
+	if tokenDTO.MFAToken != "" {
+		ctx.JSON(http.StatusAccepted, dto.MFAChallengeDTO{MFAToken: tokenDTO.MFAToken})
+		return
+	}
+	if err := h.setTokens(ctx, tokenDTO); err != nil {
+		newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
+
+// @Summary Refresh tokens
+// @Tags auth
+// @Description Exchange refresh token for a new token pair. In cookie mode the refresh token cookie is used when set, the request must then carry the CSRF token
+// @ID refresh-token
+// @Accept json
+// @Produce json
+// @Param refreshTokenDTO body dto.RefreshTokenDTO true "refresh token"
+// @Seccess 200 {integer} integer 1
+// @Router /auth/refresh [post]
+
+func (h *Handler) RefreshToken(ctx *gin.Context) {
+
+	var refreshTokenDTO dto.RefreshTokenDTO
+	if refreshToken, ok := h.refreshTokenFromCookie(ctx); ok {
+		if !auth.ValidCSRF(ctx) {
+			newResponse(ctx, http.StatusForbidden, "invalid csrf token")
+			return
+		}
+		refreshTokenDTO.RefreshToken = refreshToken
+	} else if err := ctx.BindJSON(&refreshTokenDTO); err != nil {
+		newResponse(ctx, http.StatusBadRequest, "failed to bind refresh token and json")
+		return
+	}
+
+	tokenDTO, err := h.services.Users.RefreshUserToken(ctx.Request.Context(), refreshTokenDTO.RefreshToken, newDeviceDTO(ctx))
+	if err != nil {
+		if newBlockedResponse(ctx, err) {
+			return
+		}
+		if errors.Is(err, domain.ErrSessionNotFound) || errors.Is(err, domain.ErrRefreshTokenReused) {
+			newResponse(ctx, http.StatusUnauthorized, err.Error())
+			return
+		}
+		newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := h.setTokens(ctx, tokenDTO); err != nil {
+		newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
+
+// @Summary Logout
+// @Tags auth
+// @Description End the current session
+// @ID logout
+// @Seccess 200 {integer} integer 1
+// @Router /auth/logout [post]
+
+func (h *Handler) Logout(ctx *gin.Context) {
+	claims, ok := auth.GetClaims(ctx)
+	if !ok {
+		newResponse(ctx, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if claims.IsImpersonated() {
+		h.endImpersonation(ctx, *claims)
+		return
+	}
+
+	err := h.services.Users.Logout(ctx.Request.Context(), *claims)
+	if err != nil && !errors.Is(err, domain.ErrSessionNotFound) {
+		newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.clearTokens(ctx)
+	ctx.Status(http.StatusOK)
+}
+
+// @Summary Logout from all devices
+// @Tags auth
+// @Description End all sessions of the user
+// @ID logout-all
+// @Seccess 200 {integer} integer 1
+// @Router /auth/logout-all [post]
+
+func (h *Handler) LogoutAll(ctx *gin.Context) {
+	claims, ok := auth.GetClaims(ctx)
+	if !ok {
+		newResponse(ctx, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if claims.IsImpersonated() {
+		h.endImpersonation(ctx, *claims)
+		return
+	}
+
+	if err := h.services.Users.LogoutAll(ctx.Request.Context(), *claims); err != nil {
+		newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.clearTokens(ctx)
+	ctx.Status(http.StatusOK)
+}
+
+// endImpersonation signs the admin out of the impersonation, sessions of the user
+// stay alive.
+func (h *Handler) endImpersonation(ctx *gin.Context, claims auth.Claims) {
+	err := h.services.Impersonations.End(ctx.Request.Context(), claims, newDeviceDTO(ctx))
+	if err != nil && !errors.Is(err, domain.ErrImpersonationNotFound) {
+		newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
+
+// @Summary JSON Web Key Set
+// @Tags auth
+// @Description Public keys access tokens can be verified with
+// @ID jwks
+// @Produce json
+// @Success 200 {object} auth.JWKS
+// @Router /.well-known/jwks.json [get]
+
+func (h *Handler) JWKS(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, h.tokenManager.JWKS())
+}
@@ -0,0 +1,413 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"test/internal/domain"
+	"test/internal/service"
+	"test/internal/service/dto"
+	"test/internal/service/mocks"
+	"test/pkg/api/auth"
+	"test/pkg/lockout"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_SignIn(t *testing.T) {
+	type mockBehavior func(s *mocks.MockUsers, signInDTO dto.SignInDTO)
+
+	testTable := []struct {
+		name                string
+		inputBody           string
+		inputCredentials    dto.SignInDTO
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name:      "OK",
+			inputBody: `{"email":"test@test.ru","password":"qwerty123"}`,
+			inputCredentials: dto.SignInDTO{
+				Email:    "test@test.ru",
+				Password: "qwerty123",
+			},
+			mockBehavior: func(s *mocks.MockUsers, signInDTO dto.SignInDTO) {
+				s.EXPECT().SignIn(context.Background(), signInDTO, testDevice).Return(dto.TokenDTO{
+					AccessToken:  "Rand string",
+					RefreshToken: "Rand string",
+				}, nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:      "Second factor required",
+			inputBody: `{"email":"test@test.ru","password":"qwerty123"}`,
+			inputCredentials: dto.SignInDTO{
+				Email:    "test@test.ru",
+				Password: "qwerty123",
+			},
+			mockBehavior: func(s *mocks.MockUsers, signInDTO dto.SignInDTO) {
+				s.EXPECT().SignIn(context.Background(), signInDTO, testDevice).Return(dto.TokenDTO{MFAToken: "mfa token"}, nil)
+			},
+			expectedStatusCode:  202,
+			expectedRequestBody: `{"mfa_token":"mfa token"}`,
+		},
+		{
+			name:      "Unknown email",
+			inputBody: `{"email":"test@test.ru","password":"qwerty123"}`,
+			inputCredentials: dto.SignInDTO{
+				Email:    "test@test.ru",
+				Password: "qwerty123",
+			},
+			mockBehavior: func(s *mocks.MockUsers, signInDTO dto.SignInDTO) {
+				s.EXPECT().SignIn(context.Background(), signInDTO, testDevice).Return(dto.TokenDTO{}, domain.ErrUnknownEmail)
+			},
+			expectedStatusCode:  401,
+			expectedRequestBody: `{"message":"invalid email or password"}`,
+		},
+		{
+			name:      "Email not verified",
+			inputBody: `{"email":"test@test.ru","password":"qwerty123"}`,
+			inputCredentials: dto.SignInDTO{
+				Email:    "test@test.ru",
+				Password: "qwerty123",
+			},
+			mockBehavior: func(s *mocks.MockUsers, signInDTO dto.SignInDTO) {
+				s.EXPECT().SignIn(context.Background(), signInDTO, testDevice).Return(dto.TokenDTO{}, domain.ErrEmailNotVerified)
+			},
+			expectedStatusCode:  403,
+			expectedRequestBody: `{"message":"email isn't verified"}`,
+		},
+		{
+			name:      "Wrong password",
+			inputBody: `{"email":"test@test.ru","password":"qwerty123"}`,
+			inputCredentials: dto.SignInDTO{
+				Email:    "test@test.ru",
+				Password: "qwerty123",
+			},
+			mockBehavior: func(s *mocks.MockUsers, signInDTO dto.SignInDTO) {
+				s.EXPECT().SignIn(context.Background(), signInDTO, testDevice).Return(dto.TokenDTO{}, domain.ErrWrongPassword)
+			},
+			expectedStatusCode:  401,
+			expectedRequestBody: `{"message":"invalid email or password"}`,
+		},
+		{
+			name:      "Too many attempts",
+			inputBody: `{"email":"test@test.ru","password":"qwerty123"}`,
+			inputCredentials: dto.SignInDTO{
+				Email:    "test@test.ru",
+				Password: "qwerty123",
+			},
+			mockBehavior: func(s *mocks.MockUsers, signInDTO dto.SignInDTO) {
+				s.EXPECT().SignIn(context.Background(), signInDTO, testDevice).Return(dto.TokenDTO{},
+					&lockout.BlockedError{Err: lockout.ErrTooManyAttempts, RetryAfter: time.Second})
+			},
+			expectedStatusCode:  429,
+			expectedRequestBody: `{"message":"too many failed attempts, try again later"}`,
+		},
+		{
+			name:      "Account locked",
+			inputBody: `{"email":"test@test.ru","password":"qwerty123"}`,
+			inputCredentials: dto.SignInDTO{
+				Email:    "test@test.ru",
+				Password: "qwerty123",
+			},
+			mockBehavior: func(s *mocks.MockUsers, signInDTO dto.SignInDTO) {
+				s.EXPECT().SignIn(context.Background(), signInDTO, testDevice).Return(dto.TokenDTO{},
+					&lockout.BlockedError{Err: lockout.ErrLocked, RetryAfter: time.Minute})
+			},
+			expectedStatusCode:  423,
+			expectedRequestBody: `{"message":"locked after too many failed attempts"}`,
+		},
+		{
+			name:                "Empty body",
+			mockBehavior:        func(s *mocks.MockUsers, signInDTO dto.SignInDTO) {},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"failed to bind credentials and json"}`,
+		},
+		{
+			name:      "Service Failure",
+			inputBody: `{"email":"test@test.ru","password":"qwerty123"}`,
+			inputCredentials: dto.SignInDTO{
+				Email:    "test@test.ru",
+				Password: "qwerty123",
+			},
+			mockBehavior: func(s *mocks.MockUsers, signInDTO dto.SignInDTO) {
+				s.EXPECT().SignIn(context.Background(), signInDTO, testDevice).Return(dto.TokenDTO{}, errors.New("service failure"))
+			},
+			expectedStatusCode:  500,
+			expectedRequestBody: `{"message":"service failure"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			userMockService := mocks.NewMockUsers(c)
+			testCase.mockBehavior(userMockService, testCase.inputCredentials)
+
+			services := &service.Services{Users: userMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.POST("/auth/login", handler.SignIn)
+			req := httptest.NewRequest("POST", "/auth/login", bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_RefreshToken(t *testing.T) {
+	type mockBehavior func(s *mocks.MockUsers, refreshToken string)
+
+	testTable := []struct {
+		name                string
+		inputBody           string
+		refreshToken        string
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name:         "OK",
+			inputBody:    `{"refresh_token":"Refresh token"}`,
+			refreshToken: "Refresh token",
+			mockBehavior: func(s *mocks.MockUsers, refreshToken string) {
+				s.EXPECT().RefreshUserToken(context.Background(), refreshToken, testDevice).Return(dto.TokenDTO{
+					AccessToken:  "Rand string",
+					RefreshToken: "Rand string",
+				}, nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:         "Session not found",
+			inputBody:    `{"refresh_token":"Refresh token"}`,
+			refreshToken: "Refresh token",
+			mockBehavior: func(s *mocks.MockUsers, refreshToken string) {
+				s.EXPECT().RefreshUserToken(context.Background(), refreshToken, testDevice).Return(dto.TokenDTO{}, domain.ErrSessionNotFound)
+			},
+			expectedStatusCode:  401,
+			expectedRequestBody: `{"message":"session doesn't exists or expired"}`,
+		},
+		{
+			name:         "Refresh token reused",
+			inputBody:    `{"refresh_token":"Refresh token"}`,
+			refreshToken: "Refresh token",
+			mockBehavior: func(s *mocks.MockUsers, refreshToken string) {
+				s.EXPECT().RefreshUserToken(context.Background(), refreshToken, testDevice).Return(dto.TokenDTO{}, domain.ErrRefreshTokenReused)
+			},
+			expectedStatusCode:  401,
+			expectedRequestBody: `{"message":"refresh token has already been used, session revoked"}`,
+		},
+		{
+			name:                "Empty body",
+			mockBehavior:        func(s *mocks.MockUsers, refreshToken string) {},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"failed to bind refresh token and json"}`,
+		},
+		{
+			name:         "Service Failure",
+			inputBody:    `{"refresh_token":"Refresh token"}`,
+			refreshToken: "Refresh token",
+			mockBehavior: func(s *mocks.MockUsers, refreshToken string) {
+				s.EXPECT().RefreshUserToken(context.Background(), refreshToken, testDevice).Return(dto.TokenDTO{}, errors.New("service failure"))
+			},
+			expectedStatusCode:  500,
+			expectedRequestBody: `{"message":"service failure"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			userMockService := mocks.NewMockUsers(c)
+			testCase.mockBehavior(userMockService, testCase.refreshToken)
+
+			services := &service.Services{Users: userMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.POST("/auth/refresh", handler.RefreshToken)
+			req := httptest.NewRequest("POST", "/auth/refresh", bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_Logout(t *testing.T) {
+	type mockBehavior func(s *mocks.MockUsers, claims auth.Claims)
+
+	claims := auth.Claims{Roles: []string{auth.UserRole}, SessionId: "000000000001"}
+	claims.Subject = "000000000000"
+
+	testTable := []struct {
+		name                string
+		claims              *auth.Claims
+		logoutURL           string
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name:      "OK",
+			claims:    &claims,
+			logoutURL: "/auth/logout",
+			mockBehavior: func(s *mocks.MockUsers, claims auth.Claims) {
+				s.EXPECT().Logout(context.Background(), claims).Return(nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:      "Session already revoked",
+			claims:    &claims,
+			logoutURL: "/auth/logout",
+			mockBehavior: func(s *mocks.MockUsers, claims auth.Claims) {
+				s.EXPECT().Logout(context.Background(), claims).Return(domain.ErrSessionNotFound)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:                "Unauthorized",
+			logoutURL:           "/auth/logout",
+			mockBehavior:        func(s *mocks.MockUsers, claims auth.Claims) {},
+			expectedStatusCode:  401,
+			expectedRequestBody: `{"message":"unauthorized"}`,
+		},
+		{
+			name:      "Service Failure",
+			claims:    &claims,
+			logoutURL: "/auth/logout",
+			mockBehavior: func(s *mocks.MockUsers, claims auth.Claims) {
+				s.EXPECT().Logout(context.Background(), claims).Return(errors.New("service failure"))
+			},
+			expectedStatusCode:  500,
+			expectedRequestBody: `{"message":"service failure"}`,
+		},
+		{
+			name:      "OK. All sessions",
+			claims:    &claims,
+			logoutURL: "/auth/logout-all",
+			mockBehavior: func(s *mocks.MockUsers, claims auth.Claims) {
+				s.EXPECT().LogoutAll(context.Background(), claims).Return(nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:      "Service Failure. All sessions",
+			claims:    &claims,
+			logoutURL: "/auth/logout-all",
+			mockBehavior: func(s *mocks.MockUsers, claims auth.Claims) {
+				s.EXPECT().LogoutAll(context.Background(), claims).Return(errors.New("service failure"))
+			},
+			expectedStatusCode:  500,
+			expectedRequestBody: `{"message":"service failure"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			userMockService := mocks.NewMockUsers(c)
+			testCase.mockBehavior(userMockService, claims)
+
+			services := &service.Services{Users: userMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			setClaims := func(ctx *gin.Context) {
+				if testCase.claims != nil {
+					ctx.Set(auth.ClaimsContextKey, testCase.claims)
+				}
+			}
+			r.POST("/auth/logout", setClaims, handler.Logout)
+			r.POST("/auth/logout-all", setClaims, handler.LogoutAll)
+			req := httptest.NewRequest("POST", testCase.logoutURL, &bytes.Reader{})
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_LogoutPermission(t *testing.T) {
+	keys, err := auth.NewHMACKeySet("secret")
+	require.NoError(t, err)
+	tokenManager, err := auth.NewManager(keys, nil, auth.DefaultPolicy(), auth.ManagerConfig{})
+	require.NoError(t, err)
+
+	user := &auth.Claims{Roles: []string{auth.UserRole}}
+	user.Subject = "000000000001"
+	admin := &auth.Claims{Roles: []string{auth.AdminRole}}
+	admin.Subject = "000000000002"
+	impersonated := &auth.Claims{Roles: []string{auth.UserRole}, Actor: &auth.Actor{Subject: admin.Subject}}
+	impersonated.Subject = user.Subject
+	apiKey := &auth.Claims{Roles: []string{auth.UserRole}, Scopes: []string{"users:read"}}
+	apiKey.Subject = user.Subject
+
+	testTable := []struct {
+		name               string
+		claims             *auth.Claims
+		expectedStatusCode int
+	}{
+		{name: "User", claims: user, expectedStatusCode: 200},
+		{name: "Admin", claims: admin, expectedStatusCode: 200},
+		{name: "Impersonation", claims: impersonated, expectedStatusCode: 200},
+		{name: "API key without the scope", claims: apiKey, expectedStatusCode: 403},
+		{name: "Service without the scope", claims: &auth.Claims{ClientId: "client", Scopes: []string{"users:read"}}, expectedStatusCode: 403},
+		{name: "Service with the scope", claims: &auth.Claims{ClientId: "client", Scopes: []string{sessionsLogout}}, expectedStatusCode: 200},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			handler := NewHandler(&service.Services{}, tokenManager)
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.POST("/auth/logout", func(ctx *gin.Context) {
+				auth.SetClaims(ctx, testCase.claims)
+			}, handler.requirePermission(sessionsLogout), func(ctx *gin.Context) {
+				ctx.Status(200)
+			})
+			req := httptest.NewRequest("POST", "/auth/logout", nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"test/internal/domain"
+	"test/internal/service/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	clientsGroup    = "/clients"
+	clientIdNameURL = "clientId"
+	clientURL       = "/:clientId"
+	oauthTokenURL   = "/oauth/token"
+
+	clientsRead   = "clients:read"
+	clientsUpdate = "clients:update"
+	clientsDelete = "clients:delete"
+
+	grantTypeClientCredentials = "client_credentials"
+)
+
+// Error codes of the token endpoint, RFC 6749 section 5.2.
+const (
+	oauthInvalidRequest       = "invalid_request"
+	oauthInvalidClient        = "invalid_client"
+	oauthUnsupportedGrantType = "unsupported_grant_type"
+	oauthInvalidScope         = "invalid_scope"
+	oauthUnauthorizedClient   = "unauthorized_client"
+	oauthServerError          = "server_error"
+)
+
+// oauthClientChallenge is sent with invalid_client, clients may retry with HTTP Basic.
+const oauthClientChallenge = `Basic realm="oauth"`
+
+type tokenErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+func (h *Handler) initClientsRoutes(api *gin.RouterGroup) {
+
+	clients := api.Group(clientsGroup).Use(h.authenticate(), h.auditImpersonation())
+	{
+		clients.POST("/", h.requirePermission(clientsUpdate), h.CreateClient)
+		clients.GET("/", h.requirePermission(clientsRead), h.FindClients)
+		clients.DELETE(clientURL, h.requirePermission(clientsDelete), h.DeleteClient)
+	}
+}
+
+// @Summary Token
+// @Tags oauth
+// @Description Issue an access token to a client with the client credentials grant. The client authenticates with HTTP Basic or client_id and client_secret form fields
+// @ID oauth-token
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "client_credentials"
+// @Param scope formData string false "space separated scopes, all scopes of the client by default"
+// @Success 200 {object} dto.ClientTokenDTO
+// @Router /oauth/token [post]
+
+func (h *Handler) Token(ctx *gin.Context) {
+	// Responses carry credentials and mustn't be cached.
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+
+	if grantType := ctx.PostForm("grant_type"); grantType != grantTypeClientCredentials {
+		if grantType == "" {
+			newTokenErrorResponse(ctx, http.StatusBadRequest, oauthInvalidRequest, "grant_type is required")
+			return
+		}
+		newTokenErrorResponse(ctx, http.StatusBadRequest, oauthUnsupportedGrantType, "")
+		return
+	}
+
+	credentials, ok := clientCredentials(ctx)
+	if !ok {
+		return
+	}
+	credentials.Scope = ctx.PostForm("scope")
+
+	tokenDTO, err := h.services.Clients.Token(ctx.Request.Context(), credentials)
+	if err != nil {
+		newClientAuthErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, tokenDTO)
+}
+
+// authenticateClient authenticates the client of the token endpoints and answers with
+// invalid_client when it fails, or with unauthorized_client when the client wasn't
+// granted the scope.
+func (h *Handler) authenticateClient(ctx *gin.Context, scope string) bool {
+	credentials, ok := clientCredentials(ctx)
+	if !ok {
+		return false
+	}
+	client, err := h.services.Clients.Authenticate(ctx.Request.Context(), credentials.ClientId, credentials.ClientSecret)
+	if err != nil {
+		newClientAuthErrorResponse(ctx, err)
+		return false
+	}
+	for _, clientScope := range client.Scopes {
+		if clientScope == scope {
+			return true
+		}
+	}
+	newTokenErrorResponse(ctx, http.StatusForbidden, oauthUnauthorizedClient, "client isn't allowed "+scope)
+	return false
+}
+
+// clientCredentials reads the client credentials from HTTP Basic or the form, a client
+// may use only one of them.
+func clientCredentials(ctx *gin.Context) (dto.ClientCredentialsDTO, bool) {
+	id, secret, basic := ctx.Request.BasicAuth()
+	formId, formSecret := ctx.PostForm("client_id"), ctx.PostForm("client_secret")
+	if basic && (formId != "" || formSecret != "") {
+		newTokenErrorResponse(ctx, http.StatusBadRequest, oauthInvalidRequest, "only one client authentication method may be used")
+		return dto.ClientCredentialsDTO{}, false
+	}
+	if !basic {
+		id, secret = formId, formSecret
+	}
+	return dto.ClientCredentialsDTO{ClientId: id, ClientSecret: secret}, true
+}
+
+// @Summary Create client
+// @Tags clients
+// @Description Register an OAuth client, the secret is returned once
+// @ID create-client
+// @Accept json
+// @Produce json
+// @Param createDTO body dto.CreateClientDTO true "name and allowed scopes of the client"
+// @Success 201 {object} dto.ClientDTO
+// @Router /clients [post]
+
+func (h *Handler) CreateClient(ctx *gin.Context) {
+	var createDTO dto.CreateClientDTO
+	if err := ctx.BindJSON(&createDTO); err != nil {
+		newResponse(ctx, http.StatusBadRequest, "failed to bind client and json")
+		return
+	}
+
+	clientDTO, err := h.services.Clients.Create(ctx.Request.Context(), createDTO)
+	if err != nil {
+		newClientErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, clientDTO)
+}
+
+// @Summary Find clients
+// @Tags clients
+// @Description Find registered OAuth clients, secrets aren't returned
+// @ID find-clients
+// @Produce json
+// @Success 200 {array} domain.Client
+// @Router /clients [get]
+
+func (h *Handler) FindClients(ctx *gin.Context) {
+	clients, err := h.services.Clients.FindAll(ctx.Request.Context())
+	if err != nil {
+		newClientErrorResponse(ctx, err)
+		return
+	}
+	if clients == nil {
+		clients = []domain.Client{}
+	}
+	ctx.JSON(http.StatusOK, clients)
+}
+
+// @Summary Delete client
+// @Tags clients
+// @Description Delete the OAuth client, issued tokens stay valid until they expire
+// @ID delete-client
+// @Seccess 200 {integer} integer 1
+// @Router /clients/:clientId [delete]
+
+func (h *Handler) DeleteClient(ctx *gin.Context) {
+	if err := h.services.Clients.Delete(ctx.Request.Context(), ctx.Param(clientIdNameURL)); err != nil {
+		newClientErrorResponse(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
+
+func newClientErrorResponse(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrClientNotFound):
+		newResponse(ctx, http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrInvalidClientName), errors.Is(err, domain.ErrInvalidScope):
+		newResponse(ctx, http.StatusBadRequest, err.Error())
+	default:
+		newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func newClientAuthErrorResponse(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidClient):
+		ctx.Header("WWW-Authenticate", oauthClientChallenge)
+		newTokenErrorResponse(ctx, http.StatusUnauthorized, oauthInvalidClient, err.Error())
+	case errors.Is(err, domain.ErrScopeNotAllowed):
+		newTokenErrorResponse(ctx, http.StatusBadRequest, oauthInvalidScope, err.Error())
+	default:
+		newTokenErrorResponse(ctx, http.StatusInternalServerError, oauthServerError, err.Error())
+	}
+}
+
+// newTokenErrorResponse answers in the error format of RFC 6749 the token endpoint uses
+// instead of the message of the rest of the API.
+func newTokenErrorResponse(ctx *gin.Context, statusCode int, code, description string) {
+	ctx.AbortWithStatusJSON(statusCode, tokenErrorResponse{Error: code, ErrorDescription: description})
+}
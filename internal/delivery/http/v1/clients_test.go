@@ -0,0 +1,272 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"test/internal/domain"
+	"test/internal/service"
+	"test/internal/service/dto"
+	"test/internal/service/mocks"
+	"test/pkg/api/auth"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_Token(t *testing.T) {
+	type mockBehavior func(s *mocks.MockClients)
+
+	tokenDTO := dto.ClientTokenDTO{AccessToken: "token", TokenType: "Bearer", ExpiresIn: 900, Scope: "users:read"}
+
+	testTable := []struct {
+		name                string
+		form                url.Values
+		basicAuth           []string
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+		expectedChallenge   string
+	}{
+		{
+			name:      "Basic auth",
+			form:      url.Values{"grant_type": {"client_credentials"}, "scope": {"users:read"}},
+			basicAuth: []string{"reports", "ucs_secret"},
+			mockBehavior: func(s *mocks.MockClients) {
+				s.EXPECT().Token(context.Background(), dto.ClientCredentialsDTO{
+					ClientId: "reports", ClientSecret: "ucs_secret", Scope: "users:read",
+				}).Return(tokenDTO, nil)
+			},
+			expectedStatusCode:  200,
+			expectedRequestBody: `{"access_token":"token","token_type":"Bearer","expires_in":900,"scope":"users:read"}`,
+		},
+		{
+			name: "Form credentials",
+			form: url.Values{"grant_type": {"client_credentials"}, "client_id": {"reports"}, "client_secret": {"ucs_secret"}},
+			mockBehavior: func(s *mocks.MockClients) {
+				s.EXPECT().Token(context.Background(), dto.ClientCredentialsDTO{ClientId: "reports", ClientSecret: "ucs_secret"}).
+					Return(tokenDTO, nil)
+			},
+			expectedStatusCode:  200,
+			expectedRequestBody: `{"access_token":"token","token_type":"Bearer","expires_in":900,"scope":"users:read"}`,
+		},
+		{
+			name:                "Both authentication methods",
+			form:                url.Values{"grant_type": {"client_credentials"}, "client_id": {"reports"}},
+			basicAuth:           []string{"reports", "ucs_secret"},
+			mockBehavior:        func(s *mocks.MockClients) {},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"error":"invalid_request","error_description":"only one client authentication method may be used"}`,
+		},
+		{
+			name:                "Unsupported grant type",
+			form:                url.Values{"grant_type": {"password"}},
+			mockBehavior:        func(s *mocks.MockClients) {},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"error":"unsupported_grant_type"}`,
+		},
+		{
+			name:                "Missing grant type",
+			form:                url.Values{},
+			mockBehavior:        func(s *mocks.MockClients) {},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"error":"invalid_request","error_description":"grant_type is required"}`,
+		},
+		{
+			name:      "Invalid client",
+			form:      url.Values{"grant_type": {"client_credentials"}},
+			basicAuth: []string{"reports", "wrong"},
+			mockBehavior: func(s *mocks.MockClients) {
+				s.EXPECT().Token(context.Background(), dto.ClientCredentialsDTO{ClientId: "reports", ClientSecret: "wrong"}).
+					Return(dto.ClientTokenDTO{}, domain.ErrInvalidClient)
+			},
+			expectedStatusCode:  401,
+			expectedRequestBody: `{"error":"invalid_client","error_description":"client authentication failed"}`,
+			expectedChallenge:   `Basic realm="oauth"`,
+		},
+		{
+			name:      "Scope not allowed",
+			form:      url.Values{"grant_type": {"client_credentials"}, "scope": {"users:delete"}},
+			basicAuth: []string{"reports", "ucs_secret"},
+			mockBehavior: func(s *mocks.MockClients) {
+				s.EXPECT().Token(context.Background(), dto.ClientCredentialsDTO{
+					ClientId: "reports", ClientSecret: "ucs_secret", Scope: "users:delete",
+				}).Return(dto.ClientTokenDTO{}, domain.ErrScopeNotAllowed)
+			},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"error":"invalid_scope","error_description":"requested scope isn't allowed for the client"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			clientsMockService := mocks.NewMockClients(c)
+			testCase.mockBehavior(clientsMockService)
+
+			services := &service.Services{Clients: clientsMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.POST(oauthTokenURL, handler.Token)
+			req := httptest.NewRequest("POST", oauthTokenURL, strings.NewReader(testCase.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if testCase.basicAuth != nil {
+				req.SetBasicAuth(testCase.basicAuth[0], testCase.basicAuth[1])
+			}
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+			assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+			assert.Equal(t, testCase.expectedChallenge, w.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
+
+func TestHandler_CreateClient(t *testing.T) {
+	type mockBehavior func(s *mocks.MockClients)
+
+	createdAt := time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                string
+		inputBody           string
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name:      "OK",
+			inputBody: `{"name":"reports","scopes":["users:read"]}`,
+			mockBehavior: func(s *mocks.MockClients) {
+				s.EXPECT().Create(context.Background(), dto.CreateClientDTO{Name: "reports", Scopes: []string{"users:read"}}).
+					Return(dto.ClientDTO{ClientSecret: "ucs_secret", Client: domain.Client{
+						Id:        "reports",
+						Name:      "reports",
+						Scopes:    []string{"users:read"},
+						CreatedAt: createdAt,
+					}}, nil)
+			},
+			expectedStatusCode: 201,
+			expectedRequestBody: `{"client_secret":"ucs_secret","client_id":"reports","name":"reports",` +
+				`"scopes":["users:read"],"created_at":"2022-11-01T00:00:00Z"}`,
+		},
+		{
+			name:      "Invalid name",
+			inputBody: `{"name":""}`,
+			mockBehavior: func(s *mocks.MockClients) {
+				s.EXPECT().Create(context.Background(), dto.CreateClientDTO{}).Return(dto.ClientDTO{}, domain.ErrInvalidClientName)
+			},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"client name must be 1 to 100 characters"}`,
+		},
+		{
+			name:                "Empty body",
+			mockBehavior:        func(s *mocks.MockClients) {},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"failed to bind client and json"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			clientsMockService := mocks.NewMockClients(c)
+			testCase.mockBehavior(clientsMockService)
+
+			services := &service.Services{Clients: clientsMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.POST(clientsGroup, handler.CreateClient)
+			req := httptest.NewRequest("POST", clientsGroup, bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_DeleteClient(t *testing.T) {
+	type mockBehavior func(s *mocks.MockClients, id string)
+
+	testTable := []struct {
+		name                string
+		id                  string
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name: "OK",
+			id:   "reports",
+			mockBehavior: func(s *mocks.MockClients, id string) {
+				s.EXPECT().Delete(context.Background(), id).Return(nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name: "Client not found",
+			id:   "reports",
+			mockBehavior: func(s *mocks.MockClients, id string) {
+				s.EXPECT().Delete(context.Background(), id).Return(domain.ErrClientNotFound)
+			},
+			expectedStatusCode:  404,
+			expectedRequestBody: `{"message":"client doesn't exists"}`,
+		},
+		{
+			name: "Service Failure",
+			id:   "reports",
+			mockBehavior: func(s *mocks.MockClients, id string) {
+				s.EXPECT().Delete(context.Background(), id).Return(errors.New("service failure"))
+			},
+			expectedStatusCode:  500,
+			expectedRequestBody: `{"message":"service failure"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			clientsMockService := mocks.NewMockClients(c)
+			testCase.mockBehavior(clientsMockService, testCase.id)
+
+			services := &service.Services{Clients: clientsMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.DELETE(clientURL, handler.DeleteClient)
+			req := httptest.NewRequest("DELETE", "/"+testCase.id, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package v1
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"test/internal/service/dto"
+	"test/pkg/api/auth"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const csrfTokenLength = 32
+
+// Cookies sends tokens to browsers in HttpOnly cookies instead of headers, so scripts
+// can't read them. The refresh token is sent only to the refresh route. TTLs are how
+// long browsers keep the cookies, the tokens expire by themselves.
+type Cookies struct {
+	Domain          string
+	SameSite        http.SameSite
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
+// UseCookies switches from headers to cookies, it is called before Init.
+func (h *Handler) UseCookies(cookies Cookies) {
+	h.cookies = &cookies
+}
+
+// setTokens sends the tokens in headers or, with UseCookies, in cookies next to a new
+// CSRF token.
+func (h *Handler) setTokens(ctx *gin.Context, tokenDTO dto.TokenDTO) error {
+	if h.cookies == nil {
+		ctx.Header("Access-Token", tokenDTO.AccessToken)
+		ctx.Header("Refresh-Token", tokenDTO.RefreshToken)
+		return nil
+	}
+
+	csrfToken, err := generateCSRFToken()
+	if err != nil {
+		return err
+	}
+	accessTokenAge := int(h.cookies.AccessTokenTTL.Seconds())
+	refreshTokenAge := int(h.cookies.RefreshTokenTTL.Seconds())
+	h.setCookie(ctx, auth.AccessTokenCookie, strings.TrimPrefix(tokenDTO.AccessToken, auth.PrefixToken), "/", accessTokenAge, true)
+	h.setCookie(ctx, auth.RefreshTokenCookie, tokenDTO.RefreshToken, refreshTokenPath, refreshTokenAge, true)
+	h.setCookie(ctx, auth.CSRFCookie, csrfToken, "/", refreshTokenAge, false)
+	return nil
+}
+
+// clearTokens removes the cookies of the tokens on logout.
+func (h *Handler) clearTokens(ctx *gin.Context) {
+	if h.cookies == nil {
+		return
+	}
+	h.setCookie(ctx, auth.AccessTokenCookie, "", "/", -1, true)
+	h.setCookie(ctx, auth.RefreshTokenCookie, "", refreshTokenPath, -1, true)
+	h.setCookie(ctx, auth.CSRFCookie, "", "/", -1, false)
+}
+
+// refreshTokenFromCookie returns the refresh token of the cookie, with UseCookies only.
+func (h *Handler) refreshTokenFromCookie(ctx *gin.Context) (string, bool) {
+	if h.cookies == nil {
+		return "", false
+	}
+	refreshToken, err := ctx.Cookie(auth.RefreshTokenCookie)
+	return refreshToken, err == nil && refreshToken != ""
+}
+
+func (h *Handler) setCookie(ctx *gin.Context, name, value, path string, maxAge int, httpOnly bool) {
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   h.cookies.Domain,
+		MaxAge:   maxAge,
+		Secure:   true,
+		HttpOnly: httpOnly,
+		SameSite: h.cookies.SameSite,
+	})
+}
+
+func generateCSRFToken() (string, error) {
+	b := make([]byte, csrfTokenLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate csrf token due to error: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
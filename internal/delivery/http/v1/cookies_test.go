@@ -0,0 +1,143 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"test/internal/service"
+	"test/internal/service/dto"
+	"test/internal/service/mocks"
+	"test/pkg/api/auth"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCookies = Cookies{
+	SameSite:        http.SameSiteStrictMode,
+	AccessTokenTTL:  15 * time.Minute,
+	RefreshTokenTTL: time.Hour,
+}
+
+func TestHandler_SignInCookies(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	signInDTO := dto.SignInDTO{Email: "test@test.com", Password: "password"}
+	usersMockService := mocks.NewMockUsers(c)
+	usersMockService.EXPECT().SignIn(context.Background(), signInDTO, testDevice).
+		Return(dto.TokenDTO{AccessToken: auth.PrefixToken + "access", RefreshToken: "refresh"}, nil)
+
+	handler := NewHandler(&service.Services{Users: usersMockService}, &auth.Manager{})
+	handler.UseCookies(testCookies)
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	w := httptest.NewRecorder()
+
+	r.POST("/auth/login", handler.SignIn)
+	req := httptest.NewRequest("POST", "/auth/login", bytes.NewBufferString(`{"email":"test@test.com","password":"password"}`))
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Token"), "tokens aren't sent in headers")
+	assert.Empty(t, w.Header().Get("Refresh-Token"))
+
+	cookies := make(map[string]*http.Cookie)
+	for _, cookie := range w.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+		assert.True(t, cookie.Secure)
+		assert.Equal(t, http.SameSiteStrictMode, cookie.SameSite)
+	}
+	require.Len(t, cookies, 3)
+
+	accessCookie := cookies[auth.AccessTokenCookie]
+	assert.Equal(t, "access", accessCookie.Value)
+	assert.Equal(t, "/", accessCookie.Path)
+	assert.Equal(t, 900, accessCookie.MaxAge)
+	assert.True(t, accessCookie.HttpOnly)
+
+	refreshCookie := cookies[auth.RefreshTokenCookie]
+	assert.Equal(t, "refresh", refreshCookie.Value)
+	assert.Equal(t, "/api/v1/auth/refresh", refreshCookie.Path)
+	assert.Equal(t, 3600, refreshCookie.MaxAge)
+	assert.True(t, refreshCookie.HttpOnly)
+
+	csrfCookie := cookies[auth.CSRFCookie]
+	assert.NotEmpty(t, csrfCookie.Value)
+	assert.False(t, csrfCookie.HttpOnly, "scripts read the csrf token")
+}
+
+func TestHandler_RefreshTokenCookies(t *testing.T) {
+	type mockBehavior func(s *mocks.MockUsers)
+
+	testTable := []struct {
+		name                string
+		csrfHeader          string
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name:       "OK",
+			csrfHeader: "csrf",
+			mockBehavior: func(s *mocks.MockUsers) {
+				s.EXPECT().RefreshUserToken(context.Background(), "refresh", testDevice).
+					Return(dto.TokenDTO{AccessToken: auth.PrefixToken + "access", RefreshToken: "new refresh"}, nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:                "No CSRF token",
+			mockBehavior:        func(s *mocks.MockUsers) {},
+			expectedStatusCode:  403,
+			expectedRequestBody: `{"message":"invalid csrf token"}`,
+		},
+		{
+			name:                "Wrong CSRF token",
+			csrfHeader:          "wrong",
+			mockBehavior:        func(s *mocks.MockUsers) {},
+			expectedStatusCode:  403,
+			expectedRequestBody: `{"message":"invalid csrf token"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			usersMockService := mocks.NewMockUsers(c)
+			testCase.mockBehavior(usersMockService)
+
+			handler := NewHandler(&service.Services{Users: usersMockService}, &auth.Manager{})
+			handler.UseCookies(testCookies)
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.POST("/auth/refresh", handler.RefreshToken)
+			req := httptest.NewRequest("POST", "/auth/refresh", nil)
+			req.AddCookie(&http.Cookie{Name: auth.RefreshTokenCookie, Value: "refresh"})
+			req.AddCookie(&http.Cookie{Name: auth.CSRFCookie, Value: "csrf"})
+			if testCase.csrfHeader != "" {
+				req.Header.Set(auth.CSRFHeader, testCase.csrfHeader)
+			}
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+			if testCase.expectedStatusCode == 200 {
+				assert.Len(t, w.Result().Cookies(), 3, "tokens and csrf token are replaced")
+			}
+		})
+	}
+}
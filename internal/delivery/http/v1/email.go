@@ -0,0 +1,57 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"test/internal/domain"
+	"test/internal/service/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Verify email
+// @Tags auth
+// @Description Verify the email with the link sent to it, a pending email replaces the current one
+// @ID verify-email
+// @Param token query string true "verification token"
+// @Seccess 200 {integer} integer 1
+// @Router /auth/verify-email [get]
+
+func (h *Handler) VerifyEmail(ctx *gin.Context) {
+	if err := h.services.Emails.VerifyEmail(ctx.Request.Context(), ctx.Query("token")); err != nil {
+		if errors.Is(err, domain.ErrInvalidVerificationLink) || errors.Is(err, domain.ErrUserAlreadyExists) {
+			newResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
+
+// @Summary Resend email verification
+// @Tags auth
+// @Description Send the verification link again, the response is the same for unknown and verified emails
+// @ID resend-verification
+// @Accept json
+// @Param emailDTO body dto.EmailDTO true "email"
+// @Seccess 202 {integer} integer 1
+// @Router /auth/verify-email/resend [post]
+
+func (h *Handler) ResendVerification(ctx *gin.Context) {
+	var emailDTO dto.EmailDTO
+	if err := ctx.BindJSON(&emailDTO); err != nil {
+		newResponse(ctx, http.StatusBadRequest, "failed to bind email and json")
+		return
+	}
+
+	if err := h.services.Emails.ResendVerification(ctx.Request.Context(), emailDTO); err != nil {
+		if errors.Is(err, domain.ErrInvalidEmail) {
+			newResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.Status(http.StatusAccepted)
+}
@@ -0,0 +1,200 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"test/internal/domain"
+	"test/internal/service"
+	"test/internal/service/dto"
+	"test/internal/service/mocks"
+	"test/pkg/api/auth"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_VerifyEmail(t *testing.T) {
+	type mockBehavior func(s *mocks.MockEmails, token string)
+
+	testTable := []struct {
+		name                string
+		token               string
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name:  "OK",
+			token: "token",
+			mockBehavior: func(s *mocks.MockEmails, token string) {
+				s.EXPECT().VerifyEmail(context.Background(), token).Return(nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:  "Invalid link",
+			token: "token",
+			mockBehavior: func(s *mocks.MockEmails, token string) {
+				s.EXPECT().VerifyEmail(context.Background(), token).Return(domain.ErrInvalidVerificationLink)
+			},
+			expectedStatusCode:  400,
+			expectedRequestBody: fmt.Sprintf(`{"message":%q}`, domain.ErrInvalidVerificationLink.Error()),
+		},
+		{
+			name:  "Email taken",
+			token: "token",
+			mockBehavior: func(s *mocks.MockEmails, token string) {
+				s.EXPECT().VerifyEmail(context.Background(), token).Return(domain.ErrUserAlreadyExists)
+			},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"user with such email already exists"}`,
+		},
+		{
+			name:  "Service Failure",
+			token: "token",
+			mockBehavior: func(s *mocks.MockEmails, token string) {
+				s.EXPECT().VerifyEmail(context.Background(), token).Return(errors.New("service failure"))
+			},
+			expectedStatusCode:  500,
+			expectedRequestBody: `{"message":"service failure"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			emailsMockService := mocks.NewMockEmails(c)
+			testCase.mockBehavior(emailsMockService, testCase.token)
+
+			services := &service.Services{Emails: emailsMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.GET(verifyURL, handler.VerifyEmail)
+			req := httptest.NewRequest("GET", verifyURL+"?token="+testCase.token, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_ResendVerification(t *testing.T) {
+	type mockBehavior func(s *mocks.MockEmails, emailDTO dto.EmailDTO)
+
+	testTable := []struct {
+		name                string
+		inputBody           string
+		emailDTO            dto.EmailDTO
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name:      "OK",
+			inputBody: `{"email":"test@test.ru"}`,
+			emailDTO:  dto.EmailDTO{Email: "test@test.ru"},
+			mockBehavior: func(s *mocks.MockEmails, emailDTO dto.EmailDTO) {
+				s.EXPECT().ResendVerification(context.Background(), emailDTO).Return(nil)
+			},
+			expectedStatusCode: 202,
+		},
+		{
+			name:      "Invalid email",
+			inputBody: `{"email":"test"}`,
+			emailDTO:  dto.EmailDTO{Email: "test"},
+			mockBehavior: func(s *mocks.MockEmails, emailDTO dto.EmailDTO) {
+				s.EXPECT().ResendVerification(context.Background(), emailDTO).Return(domain.ErrInvalidEmail)
+			},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"invalid email"}`,
+		},
+		{
+			name:                "Empty body",
+			mockBehavior:        func(s *mocks.MockEmails, emailDTO dto.EmailDTO) {},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"failed to bind email and json"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			emailsMockService := mocks.NewMockEmails(c)
+			testCase.mockBehavior(emailsMockService, testCase.emailDTO)
+
+			services := &service.Services{Emails: emailsMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.POST(resendURL, handler.ResendVerification)
+			req := httptest.NewRequest("POST", resendURL, bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_RequireVerifiedEmail(t *testing.T) {
+	keys, err := auth.NewHMACKeySet("secret")
+	require.NoError(t, err)
+	tokenManager, err := auth.NewManager(keys, nil, auth.DefaultPolicy(), auth.ManagerConfig{})
+	require.NoError(t, err)
+
+	testTable := []struct {
+		name               string
+		permission         string
+		emailVerified      bool
+		expectedStatusCode int
+	}{
+		{name: "Verified email", permission: usersUpdate, emailVerified: true, expectedStatusCode: 200},
+		{name: "Unverified email", permission: usersUpdate, emailVerified: false, expectedStatusCode: 403},
+		{name: "Verification not required", permission: usersRead, emailVerified: false, expectedStatusCode: 200},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			handler := NewHandler(&service.Services{}, tokenManager)
+			handler.RequireVerifiedEmail(usersUpdate)
+
+			claims := &auth.Claims{Roles: []string{auth.UserRole}, EmailVerified: testCase.emailVerified}
+			claims.Subject = "000000000001"
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.GET("/:id", func(ctx *gin.Context) {
+				ctx.Set(auth.ClaimsContextKey, claims)
+			}, handler.requirePermission(testCase.permission), func(ctx *gin.Context) {
+				ctx.Status(200)
+			})
+			req := httptest.NewRequest("GET", "/000000000001", nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+		})
+	}
+}
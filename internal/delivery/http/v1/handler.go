@@ -1,7 +1,12 @@
 package v1
 
 import (
+	"errors"
+	"net/http"
+	"strings"
+	"test/internal/domain"
 	"test/internal/service"
+	"test/internal/service/dto"
 	"test/pkg/api/auth"
 
 	swaggerfiles "github.com/swaggo/files"
@@ -10,9 +15,18 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	authorizationHeader = "Authorization"
+	apiKeyHeader        = "X-API-Key"
+)
+
 type Handler struct {
 	services     *service.Services
 	tokenManager auth.TokenManager
+	// verifiedEmailPermissions are permissions only users with a verified email are given.
+	verifiedEmailPermissions map[string]bool
+	// cookies are set instead of token headers when not nil, see UseCookies.
+	cookies *Cookies
 }
 
 func NewHandler(services *service.Services, tokenManager auth.TokenManager) *Handler {
@@ -22,12 +36,74 @@ func NewHandler(services *service.Services, tokenManager auth.TokenManager) *Han
 	}
 }
 
+// RequireVerifiedEmail denies the permissions, like "users:update", to users whose email
+// isn't verified. It is called before Init.
+func (h *Handler) RequireVerifiedEmail(permissions ...string) {
+	if h.verifiedEmailPermissions == nil {
+		h.verifiedEmailPermissions = make(map[string]bool, len(permissions))
+	}
+	for _, permission := range permissions {
+		h.verifiedEmailPermissions[permission] = true
+	}
+}
+
+// requirePermission is RequirePermission of the token manager that also requires
+// a verified email for permissions listed in RequireVerifiedEmail.
+func (h *Handler) requirePermission(permission string) gin.HandlerFunc {
+	requirePermission := h.tokenManager.RequirePermission(permission)
+	if !h.verifiedEmailPermissions[permission] {
+		return requirePermission
+	}
+	return func(ctx *gin.Context) {
+		claims, ok := auth.GetClaims(ctx)
+		if ok && !claims.IsService() && !claims.EmailVerified {
+			newResponse(ctx, http.StatusForbidden, domain.ErrEmailNotVerified.Error())
+			return
+		}
+		requirePermission(ctx)
+	}
+}
+
+// authenticate is VerifyJWTMiddleware of the token manager that also accepts API keys
+// in the X-API-Key header or as the bearer token. Both set the same claims.
+func (h *Handler) authenticate() gin.HandlerFunc {
+	verifyJWT := h.tokenManager.VerifyJWTMiddleware()
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(apiKeyHeader)
+		if key == "" {
+			bearer := strings.TrimPrefix(ctx.GetHeader(authorizationHeader), auth.PrefixToken)
+			if strings.HasPrefix(bearer, service.APIKeyPrefix) {
+				key = bearer
+			}
+		}
+		if key == "" {
+			verifyJWT(ctx)
+			return
+		}
+
+		claims, err := h.services.APIKeys.Authenticate(ctx.Request.Context(), key)
+		if err != nil {
+			if errors.Is(err, domain.ErrInvalidAPIKey) {
+				newResponse(ctx, http.StatusUnauthorized, err.Error())
+				return
+			}
+			newResponse(ctx, http.StatusInternalServerError, err.Error())
+			return
+		}
+		auth.SetClaims(ctx, claims)
+	}
+}
+
 func (h *Handler) Init() *gin.Engine {
 	router := gin.New()
 
-	router.GET("/auth/google/login", OauthGoogleLogin)
-	router.GET("/auth/google/callback", OauthGoogleCallback)
+	router.GET(oauthLoginURL, h.OauthLogin)
+	router.GET(oauthCallbackURL, h.OauthCallback)
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
+	router.GET(jwksURL, h.JWKS)
+	router.POST(oauthTokenURL, h.Token)
+	router.POST(oauthIntrospectURL, h.Introspect)
+	router.POST(oauthRevokeURL, h.RevokeToken)
 	h.initAPI(router)
 
 	return router
@@ -37,5 +113,14 @@ func (h *Handler) initAPI(router *gin.Engine) {
 	api := router.Group(auth.BasicURL + auth.Version)
 	{
 		h.initUsersRoutes(api)
+		h.initAuthRoutes(api)
+		h.initClientsRoutes(api)
+	}
+}
+
+func newDeviceDTO(ctx *gin.Context) dto.DeviceDTO {
+	return dto.DeviceDTO{
+		UserAgent: ctx.Request.UserAgent(),
+		IP:        ctx.ClientIP(),
 	}
 }
@@ -0,0 +1,64 @@
+package v1
+
+import (
+	"net/http"
+	"test/internal/service/dto"
+	"test/pkg/oauth"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	providerNameURL = "provider"
+	identityURL     = "/:id/identities/:provider"
+)
+
+// @Summary Link external account
+// @Tags user/:id/identities
+// @Description Start linking the account of the provider to the current user. The returned url
+// @Description is the consent screen, the link is completed by GET /auth/{provider}/callback
+// @ID link-identity
+// @Produce json
+// @Success 200 {object} dto.AuthCodeURLDTO
+// @Router /users/:id/identities/:provider [post]
+
+func (h *Handler) LinkIdentity(ctx *gin.Context) {
+	userId, provider := ctx.Param(idNameURL), ctx.Param(providerNameURL)
+	// Admins may unlink accounts of anyone, but an account is linked only by its owner.
+	if !isAccountOwner(ctx, userId) {
+		newResponse(ctx, http.StatusForbidden, "forbidden")
+		return
+	}
+
+	request, err := oauth.NewAuthRequest()
+	if err != nil {
+		newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	authCodeURL, linkToken, err := h.services.OAuth.LinkAuthCodeURL(ctx.Request.Context(), userId, provider, request)
+	if err != nil {
+		newOAuthErrorResponse(ctx, err)
+		return
+	}
+
+	setAuthRequestCookie(ctx.Writer, provider, request)
+	setCallbackCookie(ctx.Writer, provider, coockieOauth2LinkName, linkToken)
+	ctx.JSON(http.StatusOK, dto.AuthCodeURLDTO{URL: authCodeURL})
+}
+
+// @Summary Unlink external account
+// @Tags user/:id/identities
+// @Description Unlink the account of the provider from the user, the last way to sign in can't be unlinked
+// @ID unlink-identity
+// @Seccess 200 {integer} integer 1
+// @Router /users/:id/identities/:provider [delete]
+
+func (h *Handler) UnlinkIdentity(ctx *gin.Context) {
+	err := h.services.OAuth.UnlinkIdentity(ctx.Request.Context(), ctx.Param(idNameURL), ctx.Param(providerNameURL))
+	if err != nil {
+		newOAuthErrorResponse(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
@@ -0,0 +1,190 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"test/internal/domain"
+	"test/internal/service"
+	"test/internal/service/mocks"
+	"test/pkg/api/auth"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_LinkIdentity(t *testing.T) {
+	type mockBehavior func(s *mocks.MockOAuth, id, provider string)
+
+	claims := auth.Claims{Roles: []string{auth.UserRole}}
+	claims.Subject = "000000000001"
+	adminClaims := auth.Claims{Roles: []string{auth.AdminRole}}
+	adminClaims.Subject = "000000000001"
+
+	testTable := []struct {
+		name                string
+		id                  string
+		provider            string
+		claims              *auth.Claims
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name:     "OK",
+			id:       "000000000001",
+			provider: "google",
+			claims:   &claims,
+			mockBehavior: func(s *mocks.MockOAuth, id, provider string) {
+				s.EXPECT().LinkAuthCodeURL(context.Background(), id, provider, gomock.Any()).
+					Return("https://accounts.example.com/auth", "link token", nil)
+			},
+			expectedStatusCode:  200,
+			expectedRequestBody: `{"url":"https://accounts.example.com/auth"}`,
+		},
+		{
+			name:                "Account of another user",
+			id:                  "000000000002",
+			provider:            "google",
+			claims:              &adminClaims,
+			mockBehavior:        func(s *mocks.MockOAuth, id, provider string) {},
+			expectedStatusCode:  403,
+			expectedRequestBody: `{"message":"forbidden"}`,
+		},
+		{
+			name:     "Unknown provider",
+			id:       "000000000001",
+			provider: "unknown",
+			claims:   &claims,
+			mockBehavior: func(s *mocks.MockOAuth, id, provider string) {
+				s.EXPECT().LinkAuthCodeURL(context.Background(), id, provider, gomock.Any()).
+					Return("", "", domain.ErrUnknownOAuthProvider)
+			},
+			expectedStatusCode:  404,
+			expectedRequestBody: `{"message":"unknown oauth provider"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			oauthMockService := mocks.NewMockOAuth(c)
+			testCase.mockBehavior(oauthMockService, testCase.id, testCase.provider)
+
+			services := &service.Services{OAuth: oauthMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.POST(identityURL, func(ctx *gin.Context) {
+				ctx.Set(auth.ClaimsContextKey, testCase.claims)
+			}, handler.LinkIdentity)
+			req := httptest.NewRequest("POST", fmt.Sprintf("/%s/identities/%s", testCase.id, testCase.provider), nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+			if testCase.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			cookies := map[string]*http.Cookie{}
+			for _, cookie := range w.Result().Cookies() {
+				cookies[cookie.Name] = cookie
+			}
+			if assert.Contains(t, cookies, coockieOauth2LinkName) {
+				assert.Equal(t, "link token", cookies[coockieOauth2LinkName].Value)
+				assert.Equal(t, "/auth/"+testCase.provider+"/callback", cookies[coockieOauth2LinkName].Path)
+				assert.True(t, cookies[coockieOauth2LinkName].HttpOnly)
+			}
+			assert.Contains(t, cookies, coockieOauth2StateName)
+		})
+	}
+}
+
+func TestHandler_UnlinkIdentity(t *testing.T) {
+	type mockBehavior func(s *mocks.MockOAuth, id, provider string)
+
+	testTable := []struct {
+		name                string
+		id                  string
+		provider            string
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name:     "OK",
+			id:       "000000000001",
+			provider: "google",
+			mockBehavior: func(s *mocks.MockOAuth, id, provider string) {
+				s.EXPECT().UnlinkIdentity(context.Background(), id, provider).Return(nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:     "Identity not found",
+			id:       "000000000001",
+			provider: "github",
+			mockBehavior: func(s *mocks.MockOAuth, id, provider string) {
+				s.EXPECT().UnlinkIdentity(context.Background(), id, provider).Return(domain.ErrIdentityNotFound)
+			},
+			expectedStatusCode:  404,
+			expectedRequestBody: fmt.Sprintf(`{"message":%q}`, domain.ErrIdentityNotFound.Error()),
+		},
+		{
+			name:     "Last login method",
+			id:       "000000000001",
+			provider: "google",
+			mockBehavior: func(s *mocks.MockOAuth, id, provider string) {
+				s.EXPECT().UnlinkIdentity(context.Background(), id, provider).Return(domain.ErrLastLoginMethod)
+			},
+			expectedStatusCode:  409,
+			expectedRequestBody: fmt.Sprintf(`{"message":%q}`, domain.ErrLastLoginMethod.Error()),
+		},
+		{
+			name:     "Service Failure",
+			id:       "000000000001",
+			provider: "google",
+			mockBehavior: func(s *mocks.MockOAuth, id, provider string) {
+				s.EXPECT().UnlinkIdentity(context.Background(), id, provider).Return(errors.New("service failure"))
+			},
+			expectedStatusCode:  500,
+			expectedRequestBody: `{"message":"service failure"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			oauthMockService := mocks.NewMockOAuth(c)
+			testCase.mockBehavior(oauthMockService, testCase.id, testCase.provider)
+
+			services := &service.Services{OAuth: oauthMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.DELETE(identityURL, handler.UnlinkIdentity)
+			req := httptest.NewRequest("DELETE", fmt.Sprintf("/%s/identities/%s", testCase.id, testCase.provider), nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
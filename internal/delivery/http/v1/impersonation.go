@@ -0,0 +1,148 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"test/internal/domain"
+	"test/internal/service/dto"
+	apierrors "test/pkg/api/api_errors"
+	"test/pkg/api/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	impersonationIdNameURL = "impersonationId"
+	impersonationsURL      = "/:id/impersonations"
+	impersonationURL       = "/:id/impersonations/:impersonationId"
+	auditURL               = "/:id/audit"
+)
+
+// @Summary Impersonate user
+// @Tags user/:id/impersonations
+// @Description Issue a short-lived access token of the user to the admin, the token has the act claim with the admin. Sensitive actions are denied to it and every request made with it is written to the audit log
+// @ID start-impersonation
+// @Accept json
+// @Produce json
+// @Param impersonateDTO body dto.ImpersonateDTO true "reason of the impersonation"
+// @Success 201 {object} dto.ImpersonationDTO
+// @Router /users/:id/impersonations [post]
+
+func (h *Handler) StartImpersonation(ctx *gin.Context) {
+	claims, ok := auth.GetClaims(ctx)
+	if !ok {
+		newResponse(ctx, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var impersonateDTO dto.ImpersonateDTO
+	if err := ctx.BindJSON(&impersonateDTO); err != nil {
+		newResponse(ctx, http.StatusBadRequest, "failed to bind impersonation and json")
+		return
+	}
+
+	impersonationDTO, err := h.services.Impersonations.Start(ctx.Request.Context(), *claims, ctx.Param(idNameURL),
+		impersonateDTO, newDeviceDTO(ctx))
+	if err != nil {
+		newImpersonationErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, impersonationDTO)
+}
+
+// @Summary Find impersonations
+// @Tags user/:id/impersonations
+// @Description Find active impersonations of the user
+// @ID find-impersonations
+// @Produce json
+// @Success 200 {array} domain.Impersonation
+// @Router /users/:id/impersonations [get]
+
+func (h *Handler) FindImpersonations(ctx *gin.Context) {
+	impersonations, err := h.services.Impersonations.FindAll(ctx.Request.Context(), ctx.Param(idNameURL))
+	if err != nil {
+		newImpersonationErrorResponse(ctx, err)
+		return
+	}
+	if impersonations == nil {
+		impersonations = []domain.Impersonation{}
+	}
+	ctx.JSON(http.StatusOK, impersonations)
+}
+
+// @Summary Revoke impersonation
+// @Tags user/:id/impersonations
+// @Description Revoke the impersonation token, sessions of the user stay alive
+// @ID revoke-impersonation
+// @Seccess 200 {integer} integer 1
+// @Router /users/:id/impersonations/:impersonationId [delete]
+
+func (h *Handler) RevokeImpersonation(ctx *gin.Context) {
+	claims, ok := auth.GetClaims(ctx)
+	if !ok {
+		newResponse(ctx, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	err := h.services.Impersonations.Revoke(ctx.Request.Context(), *claims, ctx.Param(idNameURL),
+		ctx.Param(impersonationIdNameURL), newDeviceDTO(ctx))
+	if err != nil {
+		newImpersonationErrorResponse(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
+
+// @Summary Find audit events
+// @Tags user/:id/audit
+// @Description Find the latest audit events of the user, the newest first
+// @ID find-audit-events
+// @Produce json
+// @Success 200 {array} domain.AuditEvent
+// @Router /users/:id/audit [get]
+
+func (h *Handler) FindAuditEvents(ctx *gin.Context) {
+	events, err := h.services.Impersonations.FindAuditEvents(ctx.Request.Context(), ctx.Param(idNameURL))
+	if err != nil {
+		newImpersonationErrorResponse(ctx, err)
+		return
+	}
+	if events == nil {
+		events = []domain.AuditEvent{}
+	}
+	ctx.JSON(http.StatusOK, events)
+}
+
+// auditImpersonation writes requests made with impersonation tokens to the audit log
+// before they are handled, a request that can't be recorded isn't handled at all.
+func (h *Handler) auditImpersonation() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		claims, ok := auth.GetClaims(ctx)
+		if !ok || !claims.IsImpersonated() {
+			return
+		}
+		err := h.services.Impersonations.RecordRequest(ctx.Request.Context(), *claims, dto.AuditRequestDTO{
+			Method: ctx.Request.Method,
+			Path:   ctx.Request.URL.Path,
+			Device: newDeviceDTO(ctx),
+		})
+		if err != nil {
+			newResponse(ctx, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+}
+
+func newImpersonationErrorResponse(ctx *gin.Context, err error) {
+	var apiErr *apierrors.ApiError
+	switch {
+	case errors.Is(err, domain.ErrUserNotFound), errors.Is(err, domain.ErrImpersonationNotFound):
+		newResponse(ctx, http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrImpersonationForbidden):
+		newResponse(ctx, http.StatusForbidden, err.Error())
+	case errors.Is(err, domain.ErrReasonTooLong), errors.As(err, &apiErr):
+		newResponse(ctx, http.StatusBadRequest, err.Error())
+	default:
+		newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,348 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"test/internal/domain"
+	"test/internal/service"
+	"test/internal/service/dto"
+	"test/internal/service/mocks"
+	"test/pkg/api/auth"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestHandler_StartImpersonation(t *testing.T) {
+	type mockBehavior func(s *mocks.MockImpersonations, claims auth.Claims, id string)
+
+	claims := auth.Claims{Roles: []string{auth.AdminRole}}
+	claims.Subject = "000000000000000000000001"
+	impersonationId, _ := primitive.ObjectIDFromHex("000000000000000000000003")
+	userId, _ := primitive.ObjectIDFromHex("000000000000000000000002")
+	createdAt := time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)
+	device := dto.DeviceDTO{IP: "192.0.2.1"}
+
+	testTable := []struct {
+		name                string
+		id                  string
+		inputBody           string
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name:      "OK",
+			id:        "000000000000000000000002",
+			inputBody: `{"reason":"ticket 42"}`,
+			mockBehavior: func(s *mocks.MockImpersonations, claims auth.Claims, id string) {
+				s.EXPECT().Start(context.Background(), claims, id, dto.ImpersonateDTO{Reason: "ticket 42"}, device).
+					Return(dto.ImpersonationDTO{AccessToken: "Bearer token", Impersonation: domain.Impersonation{
+						Id:        impersonationId,
+						UserId:    userId,
+						ActorId:   userId,
+						Reason:    "ticket 42",
+						IP:        device.IP,
+						CreatedAt: createdAt,
+						ExpiresAt: createdAt.Add(15 * time.Minute),
+					}}, nil)
+			},
+			expectedStatusCode: 201,
+			expectedRequestBody: `{"access_token":"Bearer token","id":"000000000000000000000003",` +
+				`"user_id":"000000000000000000000002","actor_id":"000000000000000000000002","reason":"ticket 42",` +
+				`"ip":"192.0.2.1","created_at":"2022-11-01T00:00:00Z","expires_at":"2022-11-01T00:15:00Z"}`,
+		},
+		{
+			name:      "Admin can't be impersonated",
+			id:        "000000000000000000000002",
+			inputBody: `{"reason":"ticket 42"}`,
+			mockBehavior: func(s *mocks.MockImpersonations, claims auth.Claims, id string) {
+				s.EXPECT().Start(context.Background(), claims, id, dto.ImpersonateDTO{Reason: "ticket 42"}, device).
+					Return(dto.ImpersonationDTO{}, domain.ErrImpersonationForbidden)
+			},
+			expectedStatusCode:  403,
+			expectedRequestBody: `{"message":"user can't be impersonated by the caller"}`,
+		},
+		{
+			name:      "User not found",
+			id:        "000000000000000000000002",
+			inputBody: `{}`,
+			mockBehavior: func(s *mocks.MockImpersonations, claims auth.Claims, id string) {
+				s.EXPECT().Start(context.Background(), claims, id, dto.ImpersonateDTO{}, device).
+					Return(dto.ImpersonationDTO{}, domain.ErrUserNotFound)
+			},
+			expectedStatusCode:  404,
+			expectedRequestBody: `{"message":"user doesn't exists"}`,
+		},
+		{
+			name:                "Empty body",
+			id:                  "000000000000000000000002",
+			mockBehavior:        func(s *mocks.MockImpersonations, claims auth.Claims, id string) {},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"failed to bind impersonation and json"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			impersonationsMockService := mocks.NewMockImpersonations(c)
+			testCase.mockBehavior(impersonationsMockService, claims, testCase.id)
+
+			services := &service.Services{Impersonations: impersonationsMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.POST(impersonationsURL, func(ctx *gin.Context) {
+				ctx.Set(auth.ClaimsContextKey, &claims)
+			}, handler.StartImpersonation)
+			req := httptest.NewRequest("POST", fmt.Sprintf("/%s/impersonations", testCase.id),
+				bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_FindImpersonations(t *testing.T) {
+	type mockBehavior func(s *mocks.MockImpersonations, id string)
+
+	testTable := []struct {
+		name                string
+		id                  string
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name: "No impersonations",
+			id:   "000000000001",
+			mockBehavior: func(s *mocks.MockImpersonations, id string) {
+				s.EXPECT().FindAll(context.Background(), id).Return(nil, nil)
+			},
+			expectedStatusCode:  200,
+			expectedRequestBody: `[]`,
+		},
+		{
+			name: "Service Failure",
+			id:   "000000000001",
+			mockBehavior: func(s *mocks.MockImpersonations, id string) {
+				s.EXPECT().FindAll(context.Background(), id).Return(nil, errors.New("service failure"))
+			},
+			expectedStatusCode:  500,
+			expectedRequestBody: `{"message":"service failure"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			impersonationsMockService := mocks.NewMockImpersonations(c)
+			testCase.mockBehavior(impersonationsMockService, testCase.id)
+
+			services := &service.Services{Impersonations: impersonationsMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.GET(impersonationsURL, handler.FindImpersonations)
+			req := httptest.NewRequest("GET", fmt.Sprintf("/%s/impersonations", testCase.id), nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_RevokeImpersonation(t *testing.T) {
+	type mockBehavior func(s *mocks.MockImpersonations, claims auth.Claims, id, impersonationId string)
+
+	claims := auth.Claims{Roles: []string{auth.AdminRole}}
+	claims.Subject = "000000000000000000000001"
+	device := dto.DeviceDTO{IP: "192.0.2.1"}
+
+	testTable := []struct {
+		name                string
+		id                  string
+		impersonationId     string
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name:            "OK",
+			id:              "000000000002",
+			impersonationId: "000000000003",
+			mockBehavior: func(s *mocks.MockImpersonations, claims auth.Claims, id, impersonationId string) {
+				s.EXPECT().Revoke(context.Background(), claims, id, impersonationId, device).Return(nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:            "Impersonation not found",
+			id:              "000000000002",
+			impersonationId: "000000000003",
+			mockBehavior: func(s *mocks.MockImpersonations, claims auth.Claims, id, impersonationId string) {
+				s.EXPECT().Revoke(context.Background(), claims, id, impersonationId, device).
+					Return(domain.ErrImpersonationNotFound)
+			},
+			expectedStatusCode:  404,
+			expectedRequestBody: `{"message":"impersonation doesn't exists"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			impersonationsMockService := mocks.NewMockImpersonations(c)
+			testCase.mockBehavior(impersonationsMockService, claims, testCase.id, testCase.impersonationId)
+
+			services := &service.Services{Impersonations: impersonationsMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.DELETE(impersonationURL, func(ctx *gin.Context) {
+				ctx.Set(auth.ClaimsContextKey, &claims)
+			}, handler.RevokeImpersonation)
+			req := httptest.NewRequest("DELETE",
+				fmt.Sprintf("/%s/impersonations/%s", testCase.id, testCase.impersonationId), nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_AuditImpersonation(t *testing.T) {
+	type mockBehavior func(s *mocks.MockImpersonations, claims auth.Claims)
+
+	user := auth.Claims{Roles: []string{auth.UserRole}}
+	user.Subject = "000000000000000000000002"
+	impersonated := user
+	impersonated.Actor = &auth.Actor{Subject: "000000000000000000000001"}
+	request := dto.AuditRequestDTO{Method: "GET", Path: "/users/000000000000000000000002", Device: dto.DeviceDTO{IP: "192.0.2.1"}}
+
+	testTable := []struct {
+		name                string
+		claims              auth.Claims
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name:   "Impersonated request",
+			claims: impersonated,
+			mockBehavior: func(s *mocks.MockImpersonations, claims auth.Claims) {
+				s.EXPECT().RecordRequest(context.Background(), claims, request).Return(nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:               "Own request",
+			claims:             user,
+			mockBehavior:       func(s *mocks.MockImpersonations, claims auth.Claims) {},
+			expectedStatusCode: 200,
+		},
+		{
+			name:   "Audit log failure",
+			claims: impersonated,
+			mockBehavior: func(s *mocks.MockImpersonations, claims auth.Claims) {
+				s.EXPECT().RecordRequest(context.Background(), claims, request).Return(errors.New("service failure"))
+			},
+			expectedStatusCode:  500,
+			expectedRequestBody: `{"message":"service failure"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			impersonationsMockService := mocks.NewMockImpersonations(c)
+			testCase.mockBehavior(impersonationsMockService, testCase.claims)
+
+			services := &service.Services{Impersonations: impersonationsMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.GET("/users/:id", func(ctx *gin.Context) {
+				ctx.Set(auth.ClaimsContextKey, &testCase.claims)
+			}, handler.auditImpersonation(), func(ctx *gin.Context) {
+				ctx.Status(200)
+			})
+			req := httptest.NewRequest("GET", request.Path, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_LogoutImpersonation(t *testing.T) {
+	claims := auth.Claims{Roles: []string{auth.UserRole}, Actor: &auth.Actor{Subject: "000000000000000000000001"}}
+	claims.Subject = "000000000000000000000002"
+	device := dto.DeviceDTO{IP: "192.0.2.1"}
+
+	for _, logoutURL := range []string{"/auth/logout", "/auth/logout-all"} {
+		t.Run(logoutURL, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			usersMockService := mocks.NewMockUsers(c)
+			impersonationsMockService := mocks.NewMockImpersonations(c)
+			impersonationsMockService.EXPECT().End(context.Background(), claims, device).Return(nil)
+
+			services := &service.Services{Users: usersMockService, Impersonations: impersonationsMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			setClaims := func(ctx *gin.Context) {
+				ctx.Set(auth.ClaimsContextKey, &claims)
+			}
+			r.POST("/auth/logout", setClaims, handler.Logout)
+			r.POST("/auth/logout-all", setClaims, handler.LogoutAll)
+			req := httptest.NewRequest("POST", logoutURL, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, 200, w.Code)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package v1
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"test/internal/domain"
+	apierrors "test/pkg/api/api_errors"
+	"test/pkg/lockout"
+
+	"github.com/gin-gonic/gin"
+)
+
+const lockoutURL = "/:id/lockout"
+
+// @Summary Unlock account
+// @Tags user/:id/lockout
+// @Description Forget failed sign in attempts of the user, so the locked out user can sign in again, admins only
+// @ID unlock-user
+// @Seccess 200 {integer} integer 1
+// @Router /users/:id/lockout [delete]
+
+func (h *Handler) Unlock(ctx *gin.Context) {
+	err := h.services.Lockouts.Unlock(ctx.Request.Context(), ctx.Param(idNameURL))
+	if err != nil {
+		var apiErr *apierrors.ApiError
+		switch {
+		case errors.Is(err, domain.ErrUserNotFound):
+			newResponse(ctx, http.StatusNotFound, err.Error())
+		case errors.As(err, &apiErr):
+			newResponse(ctx, http.StatusBadRequest, err.Error())
+		default:
+			newResponse(ctx, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
+
+// newBlockedResponse answers attempts made too early with Retry-After in seconds,
+// 423 when the account is locked out and 429 otherwise. It returns false for other errors.
+func newBlockedResponse(ctx *gin.Context, err error) bool {
+	var blockedErr *lockout.BlockedError
+	if !errors.As(err, &blockedErr) {
+		return false
+	}
+
+	ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(blockedErr.RetryAfter.Seconds()))))
+	if errors.Is(err, lockout.ErrLocked) {
+		newResponse(ctx, http.StatusLocked, err.Error())
+		return true
+	}
+	newResponse(ctx, http.StatusTooManyRequests, err.Error())
+	return true
+}
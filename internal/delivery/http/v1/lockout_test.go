@@ -0,0 +1,124 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"test/internal/domain"
+	"test/internal/service"
+	"test/internal/service/mocks"
+	"test/pkg/api/auth"
+	"test/pkg/lockout"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_Unlock(t *testing.T) {
+	type mockBehavior func(s *mocks.MockLockouts, id string)
+
+	testTable := []struct {
+		name                string
+		id                  string
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name: "OK",
+			id:   "000000000000",
+			mockBehavior: func(s *mocks.MockLockouts, id string) {
+				s.EXPECT().Unlock(context.Background(), id).Return(nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name: "User not found",
+			id:   "000000000000",
+			mockBehavior: func(s *mocks.MockLockouts, id string) {
+				s.EXPECT().Unlock(context.Background(), id).Return(domain.ErrUserNotFound)
+			},
+			expectedStatusCode:  404,
+			expectedRequestBody: `{"message":"user doesn't exists"}`,
+		},
+		{
+			name: "Service Failure",
+			id:   "000000000000",
+			mockBehavior: func(s *mocks.MockLockouts, id string) {
+				s.EXPECT().Unlock(context.Background(), id).Return(errors.New("service failure"))
+			},
+			expectedStatusCode:  500,
+			expectedRequestBody: `{"message":"service failure"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			lockoutMockService := mocks.NewMockLockouts(c)
+			testCase.mockBehavior(lockoutMockService, testCase.id)
+
+			services := &service.Services{Lockouts: lockoutMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.DELETE("/users/:id/lockout", handler.Unlock)
+			req := httptest.NewRequest("DELETE", "/users/"+testCase.id+"/lockout", nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
+
+func TestNewBlockedResponse(t *testing.T) {
+	testTable := []struct {
+		name               string
+		err                error
+		expectedBlocked    bool
+		expectedStatusCode int
+		expectedRetryAfter string
+	}{
+		{
+			name:               "Too many attempts",
+			err:                &lockout.BlockedError{Err: lockout.ErrTooManyAttempts, RetryAfter: 1500 * time.Millisecond},
+			expectedBlocked:    true,
+			expectedStatusCode: 429,
+			expectedRetryAfter: "2",
+		},
+		{
+			name:               "Locked",
+			err:                &lockout.BlockedError{Err: lockout.ErrLocked, RetryAfter: time.Minute},
+			expectedBlocked:    true,
+			expectedStatusCode: 423,
+			expectedRetryAfter: "60",
+		},
+		{
+			name:               "Other error",
+			err:                domain.ErrWrongPassword,
+			expectedStatusCode: 200,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			gin.SetMode(gin.ReleaseMode)
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+
+			assert.Equal(t, testCase.expectedBlocked, newBlockedResponse(ctx, testCase.err))
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRetryAfter, w.Header().Get("Retry-After"))
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package v1
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"test/internal/domain"
+	"test/internal/service/dto"
+	"test/pkg/api/auth"
+	"test/pkg/lockout"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	magicLinkURL        = "/magic-link"
+	magicLinkConsumeURL = "/magic-link/consume"
+	// magicLinkCookie keeps the browser secret the magic link is bound to, it is only sent
+	// to the consume route.
+	magicLinkCookie = "magic_link_browser"
+)
+
+// @Summary Send magic link
+// @Tags auth
+// @Description Email a single-use link signing in without a password. The link works only in the browser that requested it, the response sets the cookie it is bound to and is the same for unknown emails
+// @ID send-magic-link
+// @Accept json
+// @Param emailDTO body dto.EmailDTO true "email"
+// @Seccess 202 {integer} integer 1
+// @Router /auth/magic-link [post]
+
+func (h *Handler) SendMagicLink(ctx *gin.Context) {
+	var emailDTO dto.EmailDTO
+	if err := ctx.BindJSON(&emailDTO); err != nil {
+		newResponse(ctx, http.StatusBadRequest, "failed to bind email and json")
+		return
+	}
+
+	browser, err := h.services.MagicLinks.Send(ctx.Request.Context(), emailDTO)
+	if err != nil {
+		newMagicLinkErrorResponse(ctx, err)
+		return
+	}
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(magicLinkCookie, browser, 0, auth.BasicURL+auth.Version+authGroup+magicLinkConsumeURL, "", true, true)
+	ctx.Status(http.StatusAccepted)
+}
+
+// @Summary Sign in with magic link
+// @Tags auth
+// @Description Exchange the magic link for tokens, in the browser the link was requested from. In cookie mode the tokens are set in cookies, otherwise a browser opening the link can't read the token headers, so the tokens are in the body too
+// @ID consume-magic-link
+// @Produce json
+// @Param token query string true "magic link token"
+// @Success 200 {object} dto.TokenDTO
+// @Success 202 {object} dto.MFAChallengeDTO
+// @Router /auth/magic-link/consume [get]
+
+func (h *Handler) ConsumeMagicLink(ctx *gin.Context) {
+	browser, _ := ctx.Cookie(magicLinkCookie)
+	tokenDTO, err := h.services.MagicLinks.Consume(ctx.Request.Context(), ctx.Query("token"), browser, newDeviceDTO(ctx))
+	if err != nil {
+		newMagicLinkErrorResponse(ctx, err)
+		return
+	}
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(magicLinkCookie, "", -1, auth.BasicURL+auth.Version+authGroup+magicLinkConsumeURL, "", true, true)
+	if h.cookies != nil || tokenDTO.MFAToken != "" {
+		h.newTokenResponse(ctx, tokenDTO)
+		return
+	}
+	if err := h.setTokens(ctx, tokenDTO); err != nil {
+		newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, tokenDTO)
+}
+
+func newMagicLinkErrorResponse(ctx *gin.Context, err error) {
+	var blockedErr *lockout.BlockedError
+	switch {
+	case errors.As(err, &blockedErr):
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(blockedErr.RetryAfter.Seconds()))))
+		newResponse(ctx, http.StatusTooManyRequests, "too many magic links requested, try again later")
+	case errors.Is(err, domain.ErrMagicLinkDisabled):
+		newResponse(ctx, http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrInvalidEmail), errors.Is(err, domain.ErrInvalidMagicLink):
+		newResponse(ctx, http.StatusBadRequest, err.Error())
+	default:
+		newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+}
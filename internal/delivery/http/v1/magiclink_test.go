@@ -0,0 +1,185 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"test/internal/domain"
+	"test/internal/service"
+	"test/internal/service/dto"
+	"test/internal/service/mocks"
+	"test/pkg/api/auth"
+	"test/pkg/lockout"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_SendMagicLink(t *testing.T) {
+	type mockBehavior func(s *mocks.MockMagicLinks, emailDTO dto.EmailDTO)
+
+	testTable := []struct {
+		name                string
+		inputBody           string
+		inputEmailDTO       dto.EmailDTO
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+		expectedCookie      string
+	}{
+		{
+			name:          "OK",
+			inputBody:     `{"email":"test@test.com"}`,
+			inputEmailDTO: dto.EmailDTO{Email: "test@test.com"},
+			mockBehavior: func(s *mocks.MockMagicLinks, emailDTO dto.EmailDTO) {
+				s.EXPECT().Send(context.Background(), emailDTO).Return("browser", nil)
+			},
+			expectedStatusCode: 202,
+			expectedCookie:     "browser",
+		},
+		{
+			name:          "Too many links",
+			inputBody:     `{"email":"test@test.com"}`,
+			inputEmailDTO: dto.EmailDTO{Email: "test@test.com"},
+			mockBehavior: func(s *mocks.MockMagicLinks, emailDTO dto.EmailDTO) {
+				s.EXPECT().Send(context.Background(), emailDTO).
+					Return("", &lockout.BlockedError{Err: lockout.ErrLocked, RetryAfter: time.Minute})
+			},
+			expectedStatusCode:  429,
+			expectedRequestBody: `{"message":"too many magic links requested, try again later"}`,
+		},
+		{
+			name:          "Disabled",
+			inputBody:     `{"email":"test@test.com"}`,
+			inputEmailDTO: dto.EmailDTO{Email: "test@test.com"},
+			mockBehavior: func(s *mocks.MockMagicLinks, emailDTO dto.EmailDTO) {
+				s.EXPECT().Send(context.Background(), emailDTO).Return("", domain.ErrMagicLinkDisabled)
+			},
+			expectedStatusCode:  404,
+			expectedRequestBody: `{"message":"magic link sign in is disabled"}`,
+		},
+		{
+			name:                "Empty body",
+			mockBehavior:        func(s *mocks.MockMagicLinks, emailDTO dto.EmailDTO) {},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"failed to bind email and json"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			magicLinksMockService := mocks.NewMockMagicLinks(c)
+			testCase.mockBehavior(magicLinksMockService, testCase.inputEmailDTO)
+
+			services := &service.Services{MagicLinks: magicLinksMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.POST("/auth/magic-link", handler.SendMagicLink)
+			req := httptest.NewRequest("POST", "/auth/magic-link", bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+			if testCase.expectedCookie == "" {
+				assert.Empty(t, w.Result().Cookies())
+				return
+			}
+			require.Len(t, w.Result().Cookies(), 1)
+			cookie := w.Result().Cookies()[0]
+			assert.Equal(t, magicLinkCookie, cookie.Name)
+			assert.Equal(t, testCase.expectedCookie, cookie.Value)
+			assert.Equal(t, "/api/v1/auth/magic-link/consume", cookie.Path)
+			assert.True(t, cookie.HttpOnly)
+			assert.True(t, cookie.Secure)
+			assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+		})
+	}
+}
+
+func TestHandler_ConsumeMagicLink(t *testing.T) {
+	type mockBehavior func(s *mocks.MockMagicLinks, browser string)
+
+	device := dto.DeviceDTO{IP: "192.0.2.1"}
+
+	testTable := []struct {
+		name                string
+		browser             string
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+		expectedAccessToken string
+	}{
+		{
+			name:    "OK",
+			browser: "browser",
+			mockBehavior: func(s *mocks.MockMagicLinks, browser string) {
+				s.EXPECT().Consume(context.Background(), "token", browser, device).
+					Return(dto.TokenDTO{AccessToken: "access", RefreshToken: "refresh"}, nil)
+			},
+			expectedStatusCode:  200,
+			expectedRequestBody: `{"access_token":"access","refresh_token":"refresh"}`,
+			expectedAccessToken: "access",
+		},
+		{
+			name:    "MFA",
+			browser: "browser",
+			mockBehavior: func(s *mocks.MockMagicLinks, browser string) {
+				s.EXPECT().Consume(context.Background(), "token", browser, device).
+					Return(dto.TokenDTO{MFAToken: "challenge"}, nil)
+			},
+			expectedStatusCode:  202,
+			expectedRequestBody: `{"mfa_token":"challenge"}`,
+		},
+		{
+			name: "Another browser",
+			mockBehavior: func(s *mocks.MockMagicLinks, browser string) {
+				s.EXPECT().Consume(context.Background(), "token", browser, device).
+					Return(dto.TokenDTO{}, domain.ErrInvalidMagicLink)
+			},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"magic link is invalid, expired or opened in another browser"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			magicLinksMockService := mocks.NewMockMagicLinks(c)
+			testCase.mockBehavior(magicLinksMockService, testCase.browser)
+
+			services := &service.Services{MagicLinks: magicLinksMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.GET("/auth/magic-link/consume", handler.ConsumeMagicLink)
+			req := httptest.NewRequest("GET", "/auth/magic-link/consume?token=token", nil)
+			if testCase.browser != "" {
+				req.AddCookie(&http.Cookie{Name: magicLinkCookie, Value: testCase.browser})
+			}
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+			assert.Equal(t, testCase.expectedAccessToken, w.Header().Get("Access-Token"))
+		})
+	}
+}
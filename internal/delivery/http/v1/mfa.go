@@ -0,0 +1,157 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"test/internal/domain"
+	"test/internal/service/dto"
+	apierrors "test/pkg/api/api_errors"
+	"test/pkg/api/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	mfaURL        = "/:id/mfa"
+	mfaConfirmURL = "/:id/mfa/confirm"
+)
+
+// @Summary Enroll two-factor authentication
+// @Tags user/:id/mfa
+// @Description Generate a TOTP secret for the authenticator app, it is enabled by POST /users/:id/mfa/confirm
+// @ID enroll-mfa
+// @Produce json
+// @Success 200 {object} dto.MFAEnrollmentDTO
+// @Router /users/:id/mfa [post]
+
+func (h *Handler) EnrollMFA(ctx *gin.Context) {
+	userId := ctx.Param(idNameURL)
+	if !isAccountOwner(ctx, userId) {
+		newResponse(ctx, http.StatusForbidden, "forbidden")
+		return
+	}
+
+	enrollmentDTO, err := h.services.MFA.Enroll(ctx.Request.Context(), userId)
+	if err != nil {
+		newMFAErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, enrollmentDTO)
+}
+
+// @Summary Confirm two-factor authentication
+// @Tags user/:id/mfa
+// @Description Enable the enrolled secret with the first code, recovery codes are returned once
+// @ID confirm-mfa
+// @Accept json
+// @Produce json
+// @Param codeDTO body dto.MFACodeDTO true "code of the authenticator app"
+// @Success 200 {object} dto.RecoveryCodesDTO
+// @Router /users/:id/mfa/confirm [post]
+
+func (h *Handler) ConfirmMFA(ctx *gin.Context) {
+	userId := ctx.Param(idNameURL)
+	if !isAccountOwner(ctx, userId) {
+		newResponse(ctx, http.StatusForbidden, "forbidden")
+		return
+	}
+
+	var codeDTO dto.MFACodeDTO
+	if err := ctx.BindJSON(&codeDTO); err != nil {
+		newResponse(ctx, http.StatusBadRequest, "failed to bind code and json")
+		return
+	}
+
+	recoveryCodesDTO, err := h.services.MFA.Confirm(ctx.Request.Context(), userId, codeDTO)
+	if err != nil {
+		newMFAErrorResponse(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, recoveryCodesDTO)
+}
+
+// @Summary Disable two-factor authentication
+// @Tags user/:id/mfa
+// @Description Disable the second factor with a code or a recovery code, admins disable it of others without one
+// @ID disable-mfa
+// @Accept json
+// @Param codeDTO body dto.MFACodeDTO false "code of the authenticator app or a recovery code"
+// @Seccess 200 {integer} integer 1
+// @Router /users/:id/mfa [delete]
+
+func (h *Handler) DisableMFA(ctx *gin.Context) {
+	userId := ctx.Param(idNameURL)
+	if !isAccountOwner(ctx, userId) {
+		// Only admins get here, the policy lets users delete their own second factor only.
+		if err := h.services.MFA.Reset(ctx.Request.Context(), userId); err != nil {
+			newMFAErrorResponse(ctx, err)
+			return
+		}
+		ctx.Status(http.StatusOK)
+		return
+	}
+
+	var codeDTO dto.MFACodeDTO
+	if err := ctx.BindJSON(&codeDTO); err != nil {
+		newResponse(ctx, http.StatusBadRequest, "failed to bind code and json")
+		return
+	}
+
+	if err := h.services.MFA.Disable(ctx.Request.Context(), userId, codeDTO); err != nil {
+		newMFAErrorResponse(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
+
+// @Summary Verify two-factor authentication
+// @Tags auth
+// @Description Complete sign in with the challenge token and a code of the authenticator app or a recovery code
+// @ID verify-mfa
+// @Accept json
+// @Param verifyDTO body dto.MFAVerifyDTO true "challenge token and code"
+// @Seccess 200 {integer} integer 1
+// @Router /auth/mfa/verify [post]
+
+func (h *Handler) VerifyMFA(ctx *gin.Context) {
+	var verifyDTO dto.MFAVerifyDTO
+	if err := ctx.BindJSON(&verifyDTO); err != nil {
+		newResponse(ctx, http.StatusBadRequest, "failed to bind code and json")
+		return
+	}
+
+	tokenDTO, err := h.services.MFA.Verify(ctx.Request.Context(), verifyDTO, newDeviceDTO(ctx))
+	if err != nil {
+		if newBlockedResponse(ctx, err) {
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidMFAChallenge) || errors.Is(err, domain.ErrInvalidMFACode) {
+			newResponse(ctx, http.StatusUnauthorized, err.Error())
+			return
+		}
+		newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.newTokenResponse(ctx, tokenDTO)
+}
+
+// isAccountOwner tells if the user of the access token is the user of the route.
+func isAccountOwner(ctx *gin.Context, userId string) bool {
+	claims, ok := auth.GetClaims(ctx)
+	return ok && claims.Subject == userId
+}
+
+func newMFAErrorResponse(ctx *gin.Context, err error) {
+	var apiErr *apierrors.ApiError
+	switch {
+	case errors.Is(err, domain.ErrUserNotFound):
+		newResponse(ctx, http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrInvalidMFACode), errors.As(err, &apiErr):
+		newResponse(ctx, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrMFAAlreadyEnabled), errors.Is(err, domain.ErrMFANotEnrolled),
+		errors.Is(err, domain.ErrMFANotEnabled):
+		newResponse(ctx, http.StatusConflict, err.Error())
+	default:
+		newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+}
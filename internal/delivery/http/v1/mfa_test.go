@@ -0,0 +1,360 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"test/internal/domain"
+	"test/internal/service"
+	"test/internal/service/dto"
+	"test/internal/service/mocks"
+	"test/pkg/api/auth"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_EnrollMFA(t *testing.T) {
+	type mockBehavior func(s *mocks.MockMFA, id string)
+
+	claims := auth.Claims{Roles: []string{auth.UserRole}}
+	claims.Subject = "000000000001"
+
+	testTable := []struct {
+		name                string
+		id                  string
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name: "OK",
+			id:   "000000000001",
+			mockBehavior: func(s *mocks.MockMFA, id string) {
+				s.EXPECT().Enroll(context.Background(), id).Return(dto.MFAEnrollmentDTO{
+					Secret: "SECRET",
+					URI:    "otpauth://totp/Users:test@test.ru?secret=SECRET",
+				}, nil)
+			},
+			expectedStatusCode:  200,
+			expectedRequestBody: `{"secret":"SECRET","uri":"otpauth://totp/Users:test@test.ru?secret=SECRET"}`,
+		},
+		{
+			name:                "Account of another user",
+			id:                  "000000000002",
+			mockBehavior:        func(s *mocks.MockMFA, id string) {},
+			expectedStatusCode:  403,
+			expectedRequestBody: `{"message":"forbidden"}`,
+		},
+		{
+			name: "Already enabled",
+			id:   "000000000001",
+			mockBehavior: func(s *mocks.MockMFA, id string) {
+				s.EXPECT().Enroll(context.Background(), id).Return(dto.MFAEnrollmentDTO{}, domain.ErrMFAAlreadyEnabled)
+			},
+			expectedStatusCode:  409,
+			expectedRequestBody: `{"message":"two-factor authentication is already enabled"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mfaMockService := mocks.NewMockMFA(c)
+			testCase.mockBehavior(mfaMockService, testCase.id)
+
+			services := &service.Services{MFA: mfaMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.POST(mfaURL, func(ctx *gin.Context) {
+				ctx.Set(auth.ClaimsContextKey, &claims)
+			}, handler.EnrollMFA)
+			req := httptest.NewRequest("POST", fmt.Sprintf("/%s/mfa", testCase.id), nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_ConfirmMFA(t *testing.T) {
+	type mockBehavior func(s *mocks.MockMFA, id string)
+
+	claims := auth.Claims{Roles: []string{auth.UserRole}}
+	claims.Subject = "000000000001"
+
+	testTable := []struct {
+		name                string
+		id                  string
+		inputBody           string
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name:      "OK",
+			id:        "000000000001",
+			inputBody: `{"code":"123456"}`,
+			mockBehavior: func(s *mocks.MockMFA, id string) {
+				s.EXPECT().Confirm(context.Background(), id, dto.MFACodeDTO{Code: "123456"}).
+					Return(dto.RecoveryCodesDTO{RecoveryCodes: []string{"abcd-efgh"}}, nil)
+			},
+			expectedStatusCode:  200,
+			expectedRequestBody: `{"recovery_codes":["abcd-efgh"]}`,
+		},
+		{
+			name:      "Wrong code",
+			id:        "000000000001",
+			inputBody: `{"code":"000000"}`,
+			mockBehavior: func(s *mocks.MockMFA, id string) {
+				s.EXPECT().Confirm(context.Background(), id, dto.MFACodeDTO{Code: "000000"}).
+					Return(dto.RecoveryCodesDTO{}, domain.ErrInvalidMFACode)
+			},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"invalid two-factor authentication code"}`,
+		},
+		{
+			name:      "Not enrolled",
+			id:        "000000000001",
+			inputBody: `{"code":"123456"}`,
+			mockBehavior: func(s *mocks.MockMFA, id string) {
+				s.EXPECT().Confirm(context.Background(), id, dto.MFACodeDTO{Code: "123456"}).
+					Return(dto.RecoveryCodesDTO{}, domain.ErrMFANotEnrolled)
+			},
+			expectedStatusCode:  409,
+			expectedRequestBody: `{"message":"two-factor authentication isn't enrolled"}`,
+		},
+		{
+			name:                "Account of another user",
+			id:                  "000000000002",
+			inputBody:           `{"code":"123456"}`,
+			mockBehavior:        func(s *mocks.MockMFA, id string) {},
+			expectedStatusCode:  403,
+			expectedRequestBody: `{"message":"forbidden"}`,
+		},
+		{
+			name:                "Empty body",
+			id:                  "000000000001",
+			mockBehavior:        func(s *mocks.MockMFA, id string) {},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"failed to bind code and json"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mfaMockService := mocks.NewMockMFA(c)
+			testCase.mockBehavior(mfaMockService, testCase.id)
+
+			services := &service.Services{MFA: mfaMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.POST(mfaConfirmURL, func(ctx *gin.Context) {
+				ctx.Set(auth.ClaimsContextKey, &claims)
+			}, handler.ConfirmMFA)
+			req := httptest.NewRequest("POST", fmt.Sprintf("/%s/mfa/confirm", testCase.id), bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_DisableMFA(t *testing.T) {
+	type mockBehavior func(s *mocks.MockMFA, id string)
+
+	claims := auth.Claims{Roles: []string{auth.UserRole}}
+	claims.Subject = "000000000001"
+	adminClaims := auth.Claims{Roles: []string{auth.AdminRole}}
+	adminClaims.Subject = "000000000002"
+
+	testTable := []struct {
+		name                string
+		id                  string
+		claims              *auth.Claims
+		inputBody           string
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name:      "OK",
+			id:        "000000000001",
+			claims:    &claims,
+			inputBody: `{"code":"abcd-efgh"}`,
+			mockBehavior: func(s *mocks.MockMFA, id string) {
+				s.EXPECT().Disable(context.Background(), id, dto.MFACodeDTO{Code: "abcd-efgh"}).Return(nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:      "Wrong code",
+			id:        "000000000001",
+			claims:    &claims,
+			inputBody: `{"code":"000000"}`,
+			mockBehavior: func(s *mocks.MockMFA, id string) {
+				s.EXPECT().Disable(context.Background(), id, dto.MFACodeDTO{Code: "000000"}).Return(domain.ErrMFACodeReused)
+			},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"invalid two-factor authentication code: code has already been used"}`,
+		},
+		{
+			name:                "Without code",
+			id:                  "000000000001",
+			claims:              &claims,
+			mockBehavior:        func(s *mocks.MockMFA, id string) {},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"failed to bind code and json"}`,
+		},
+		{
+			name:   "Admin resets another user",
+			id:     "000000000001",
+			claims: &adminClaims,
+			mockBehavior: func(s *mocks.MockMFA, id string) {
+				s.EXPECT().Reset(context.Background(), id).Return(nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:   "Service Failure",
+			id:     "000000000001",
+			claims: &adminClaims,
+			mockBehavior: func(s *mocks.MockMFA, id string) {
+				s.EXPECT().Reset(context.Background(), id).Return(errors.New("service failure"))
+			},
+			expectedStatusCode:  500,
+			expectedRequestBody: `{"message":"service failure"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mfaMockService := mocks.NewMockMFA(c)
+			testCase.mockBehavior(mfaMockService, testCase.id)
+
+			services := &service.Services{MFA: mfaMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.DELETE(mfaURL, func(ctx *gin.Context) {
+				ctx.Set(auth.ClaimsContextKey, testCase.claims)
+			}, handler.DisableMFA)
+			req := httptest.NewRequest("DELETE", fmt.Sprintf("/%s/mfa", testCase.id), bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_VerifyMFA(t *testing.T) {
+	type mockBehavior func(s *mocks.MockMFA, verifyDTO dto.MFAVerifyDTO)
+
+	testTable := []struct {
+		name                string
+		inputBody           string
+		inputVerify         dto.MFAVerifyDTO
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+		expectedTokens      bool
+	}{
+		{
+			name:        "OK",
+			inputBody:   `{"mfa_token":"mfa token","code":"123456"}`,
+			inputVerify: dto.MFAVerifyDTO{MFAToken: "mfa token", Code: "123456"},
+			mockBehavior: func(s *mocks.MockMFA, verifyDTO dto.MFAVerifyDTO) {
+				s.EXPECT().Verify(context.Background(), verifyDTO, testDevice).Return(dto.TokenDTO{
+					AccessToken:  "Access token",
+					RefreshToken: "Refresh token",
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedTokens:     true,
+		},
+		{
+			name:        "Wrong code",
+			inputBody:   `{"mfa_token":"mfa token","code":"000000"}`,
+			inputVerify: dto.MFAVerifyDTO{MFAToken: "mfa token", Code: "000000"},
+			mockBehavior: func(s *mocks.MockMFA, verifyDTO dto.MFAVerifyDTO) {
+				s.EXPECT().Verify(context.Background(), verifyDTO, testDevice).Return(dto.TokenDTO{}, domain.ErrInvalidMFACode)
+			},
+			expectedStatusCode:  401,
+			expectedRequestBody: `{"message":"invalid two-factor authentication code"}`,
+		},
+		{
+			name:        "Expired challenge",
+			inputBody:   `{"mfa_token":"mfa token","code":"123456"}`,
+			inputVerify: dto.MFAVerifyDTO{MFAToken: "mfa token", Code: "123456"},
+			mockBehavior: func(s *mocks.MockMFA, verifyDTO dto.MFAVerifyDTO) {
+				s.EXPECT().Verify(context.Background(), verifyDTO, testDevice).Return(dto.TokenDTO{}, domain.ErrInvalidMFAChallenge)
+			},
+			expectedStatusCode:  401,
+			expectedRequestBody: `{"message":"two-factor authentication challenge is invalid or expired"}`,
+		},
+		{
+			name:                "Empty body",
+			mockBehavior:        func(s *mocks.MockMFA, verifyDTO dto.MFAVerifyDTO) {},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"failed to bind code and json"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			mfaMockService := mocks.NewMockMFA(c)
+			testCase.mockBehavior(mfaMockService, testCase.inputVerify)
+
+			services := &service.Services{MFA: mfaMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.POST("/auth/mfa/verify", handler.VerifyMFA)
+			req := httptest.NewRequest("POST", "/auth/mfa/verify", bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+			if testCase.expectedTokens {
+				assert.Equal(t, "Access token", w.Header().Get("Access-Token"))
+				assert.Equal(t, "Refresh token", w.Header().Get("Refresh-Token"))
+			}
+		})
+	}
+}
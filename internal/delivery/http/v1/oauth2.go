@@ -0,0 +1,150 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"test/internal/domain"
+	apierrors "test/pkg/api/api_errors"
+	"test/pkg/oauth"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	oauthLoginURL          = "/auth/:provider/login"
+	oauthCallbackURL       = "/auth/:provider/callback"
+	coockieOauth2StateName = "oauthstate"
+	coockieOauth2LinkName  = "oauthlink"
+	oauth2StateTTL         = 10 * time.Minute
+)
+
+// @Summary OAuth sign in
+// @Tags auth
+// @Description Redirect to the consent screen of the provider
+// @ID oauth-login
+// @Param provider path string true "provider name, e.g. google"
+// @Router /auth/{provider}/login [get]
+
+func (h *Handler) OauthLogin(ctx *gin.Context) {
+	provider := ctx.Param("provider")
+	request, err := oauth.NewAuthRequest()
+	if err != nil {
+		newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	authCodeURL, err := h.services.OAuth.AuthCodeURL(ctx.Request.Context(), provider, request)
+	if err != nil {
+		newOAuthErrorResponse(ctx, err)
+		return
+	}
+
+	setAuthRequestCookie(ctx.Writer, provider, request)
+	ctx.Redirect(http.StatusTemporaryRedirect, authCodeURL)
+}
+
+// setAuthRequestCookie keeps the state, nonce and code verifier of the sign in till the
+// callback. They are base64url encoded, so a dot separates them.
+func setAuthRequestCookie(w http.ResponseWriter, provider string, request oauth.AuthRequest) {
+	setCallbackCookie(w, provider, coockieOauth2StateName,
+		strings.Join([]string{request.State, request.Nonce, request.CodeVerifier}, "."))
+}
+
+// setCallbackCookie sets the cookie sent only to the callback of the provider.
+func setCallbackCookie(w http.ResponseWriter, provider, name, value string) {
+	cookie := http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     callbackPath(provider),
+		Expires:  time.Now().Add(oauth2StateTTL),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	http.SetCookie(w, &cookie)
+}
+
+func deleteCallbackCookie(w http.ResponseWriter, provider, name string) {
+	http.SetCookie(w, &http.Cookie{Name: name, Path: callbackPath(provider), MaxAge: -1})
+}
+
+func authRequestFromCookie(r *http.Request) (oauth.AuthRequest, bool) {
+	cookie, err := r.Cookie(coockieOauth2StateName)
+	if err != nil {
+		return oauth.AuthRequest{}, false
+	}
+	parts := strings.Split(cookie.Value, ".")
+	if len(parts) != 3 || parts[0] == "" {
+		return oauth.AuthRequest{}, false
+	}
+	return oauth.AuthRequest{State: parts[0], Nonce: parts[1], CodeVerifier: parts[2]}, true
+}
+
+func callbackPath(provider string) string {
+	return strings.Replace(oauthCallbackURL, ":provider", provider, 1)
+}
+
+// @Summary OAuth sign in callback
+// @Tags auth
+// @Description Sign in or sign up with the account of the provider, tokens are returned in headers.
+// @Description Completes linking instead when it was started with POST /users/:id/identities/:provider
+// @ID oauth-callback
+// @Param provider path string true "provider name, e.g. google"
+// @Param state query string true "oauth state"
+// @Param code query string true "authorization code"
+// @Seccess 200 {integer} integer 1
+// @Router /auth/{provider}/callback [get]
+
+func (h *Handler) OauthCallback(ctx *gin.Context) {
+	provider := ctx.Param("provider")
+	request, ok := authRequestFromCookie(ctx.Request)
+	if !ok || ctx.Request.FormValue("state") != request.State {
+		newResponse(ctx, http.StatusBadRequest, "invalid oauth state")
+		return
+	}
+	deleteCallbackCookie(ctx.Writer, provider, coockieOauth2StateName)
+
+	code := ctx.Request.FormValue("code")
+	if code == "" {
+		newResponse(ctx, http.StatusBadRequest, "empty oauth code")
+		return
+	}
+
+	if linkCookie, err := ctx.Request.Cookie(coockieOauth2LinkName); err == nil && linkCookie.Value != "" {
+		deleteCallbackCookie(ctx.Writer, provider, coockieOauth2LinkName)
+		if err := h.services.OAuth.Link(ctx.Request.Context(), linkCookie.Value, provider, code, request); err != nil {
+			newOAuthErrorResponse(ctx, err)
+			return
+		}
+		ctx.Status(http.StatusOK)
+		return
+	}
+
+	tokenDTO, err := h.services.OAuth.SignIn(ctx.Request.Context(), provider, code, request, newDeviceDTO(ctx))
+	if err != nil {
+		newOAuthErrorResponse(ctx, err)
+		return
+	}
+	h.newTokenResponse(ctx, tokenDTO)
+}
+
+func newOAuthErrorResponse(ctx *gin.Context, err error) {
+	var apiErr *apierrors.ApiError
+	switch {
+	case errors.Is(err, domain.ErrUnknownOAuthProvider), errors.Is(err, domain.ErrUserNotFound),
+		errors.Is(err, domain.ErrIdentityNotFound):
+		newResponse(ctx, http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrOAuthExchange):
+		newResponse(ctx, http.StatusBadRequest, domain.ErrOAuthExchange.Error())
+	case errors.Is(err, domain.ErrInvalidLinkRequest), errors.As(err, &apiErr):
+		newResponse(ctx, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrOAuthEmailNotVerified):
+		newResponse(ctx, http.StatusForbidden, err.Error())
+	case errors.Is(err, domain.ErrOAuthEmailRegistered), errors.Is(err, domain.ErrIdentityLinked),
+		errors.Is(err, domain.ErrProviderLinked), errors.Is(err, domain.ErrLastLoginMethod):
+		newResponse(ctx, http.StatusConflict, err.Error())
+	default:
+		newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,313 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"test/internal/domain"
+	"test/internal/service"
+	"test/internal/service/dto"
+	"test/internal/service/mocks"
+	"test/pkg/api/auth"
+	"test/pkg/oauth"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_OauthCallback(t *testing.T) {
+	type mockBehavior func(s *mocks.MockOAuth, provider, code string, request oauth.AuthRequest)
+
+	request := oauth.AuthRequest{State: "state", Nonce: "nonce", CodeVerifier: "verifier"}
+	requestCookie := "state.nonce.verifier"
+
+	testTable := []struct {
+		name                 string
+		provider             string
+		cookie               string
+		linkCookie           string
+		state                string
+		code                 string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedRequestBody  string
+		expectedAccessToken  string
+		expectedRefreshToken string
+	}{
+		{
+			name:     "OK",
+			provider: "google",
+			cookie:   requestCookie,
+			state:    "state",
+			code:     "code",
+			mockBehavior: func(s *mocks.MockOAuth, provider, code string, request oauth.AuthRequest) {
+				s.EXPECT().SignIn(context.Background(), provider, code, request, testDevice).Return(dto.TokenDTO{
+					AccessToken:  "access token",
+					RefreshToken: "refresh token",
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedAccessToken:  "access token",
+			expectedRefreshToken: "refresh token",
+		},
+		{
+			name:       "Link",
+			provider:   "google",
+			cookie:     requestCookie,
+			linkCookie: "link token",
+			state:      "state",
+			code:       "code",
+			mockBehavior: func(s *mocks.MockOAuth, provider, code string, request oauth.AuthRequest) {
+				s.EXPECT().Link(context.Background(), "link token", provider, code, request).Return(nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:       "Link to account linked to another user",
+			provider:   "google",
+			cookie:     requestCookie,
+			linkCookie: "link token",
+			state:      "state",
+			code:       "code",
+			mockBehavior: func(s *mocks.MockOAuth, provider, code string, request oauth.AuthRequest) {
+				s.EXPECT().Link(context.Background(), "link token", provider, code, request).Return(domain.ErrIdentityLinked)
+			},
+			expectedStatusCode:  409,
+			expectedRequestBody: fmt.Sprintf(`{"message":%q}`, domain.ErrIdentityLinked.Error()),
+		},
+		{
+			name:       "Invalid link request",
+			provider:   "google",
+			cookie:     requestCookie,
+			linkCookie: "expired token",
+			state:      "state",
+			code:       "code",
+			mockBehavior: func(s *mocks.MockOAuth, provider, code string, request oauth.AuthRequest) {
+				s.EXPECT().Link(context.Background(), "expired token", provider, code, request).Return(domain.ErrInvalidLinkRequest)
+			},
+			expectedStatusCode:  400,
+			expectedRequestBody: fmt.Sprintf(`{"message":%q}`, domain.ErrInvalidLinkRequest.Error()),
+		},
+		{
+			name:                "Without state cookie",
+			provider:            "google",
+			state:               "state",
+			code:                "code",
+			mockBehavior:        func(s *mocks.MockOAuth, provider, code string, request oauth.AuthRequest) {},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"invalid oauth state"}`,
+		},
+		{
+			name:                "Malformed state cookie",
+			provider:            "google",
+			cookie:              "state",
+			state:               "state",
+			code:                "code",
+			mockBehavior:        func(s *mocks.MockOAuth, provider, code string, request oauth.AuthRequest) {},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"invalid oauth state"}`,
+		},
+		{
+			name:                "State mismatch",
+			provider:            "google",
+			cookie:              requestCookie,
+			state:               "another-state",
+			code:                "code",
+			mockBehavior:        func(s *mocks.MockOAuth, provider, code string, request oauth.AuthRequest) {},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"invalid oauth state"}`,
+		},
+		{
+			name:                "Empty code",
+			provider:            "google",
+			cookie:              requestCookie,
+			state:               "state",
+			mockBehavior:        func(s *mocks.MockOAuth, provider, code string, request oauth.AuthRequest) {},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"empty oauth code"}`,
+		},
+		{
+			name:     "Unknown provider",
+			provider: "unknown",
+			cookie:   requestCookie,
+			state:    "state",
+			code:     "code",
+			mockBehavior: func(s *mocks.MockOAuth, provider, code string, request oauth.AuthRequest) {
+				s.EXPECT().SignIn(context.Background(), provider, code, request, testDevice).
+					Return(dto.TokenDTO{}, domain.ErrUnknownOAuthProvider)
+			},
+			expectedStatusCode:  404,
+			expectedRequestBody: `{"message":"unknown oauth provider"}`,
+		},
+		{
+			name:     "Exchange failure",
+			provider: "google",
+			cookie:   requestCookie,
+			state:    "state",
+			code:     "code",
+			mockBehavior: func(s *mocks.MockOAuth, provider, code string, request oauth.AuthRequest) {
+				s.EXPECT().SignIn(context.Background(), provider, code, request, testDevice).
+					Return(dto.TokenDTO{}, fmt.Errorf("%w: invalid id token", domain.ErrOAuthExchange))
+			},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"failed to exchange authorization code"}`,
+		},
+		{
+			name:     "Email not verified",
+			provider: "google",
+			cookie:   requestCookie,
+			state:    "state",
+			code:     "code",
+			mockBehavior: func(s *mocks.MockOAuth, provider, code string, request oauth.AuthRequest) {
+				s.EXPECT().SignIn(context.Background(), provider, code, request, testDevice).
+					Return(dto.TokenDTO{}, domain.ErrOAuthEmailNotVerified)
+			},
+			expectedStatusCode:  403,
+			expectedRequestBody: fmt.Sprintf(`{"message":%q}`, domain.ErrOAuthEmailNotVerified.Error()),
+		},
+		{
+			name:     "Email registered",
+			provider: "google",
+			cookie:   requestCookie,
+			state:    "state",
+			code:     "code",
+			mockBehavior: func(s *mocks.MockOAuth, provider, code string, request oauth.AuthRequest) {
+				s.EXPECT().SignIn(context.Background(), provider, code, request, testDevice).
+					Return(dto.TokenDTO{}, domain.ErrOAuthEmailRegistered)
+			},
+			expectedStatusCode:  409,
+			expectedRequestBody: fmt.Sprintf(`{"message":%q}`, domain.ErrOAuthEmailRegistered.Error()),
+		},
+		{
+			name:     "Service Failure",
+			provider: "google",
+			cookie:   requestCookie,
+			state:    "state",
+			code:     "code",
+			mockBehavior: func(s *mocks.MockOAuth, provider, code string, request oauth.AuthRequest) {
+				s.EXPECT().SignIn(context.Background(), provider, code, request, testDevice).
+					Return(dto.TokenDTO{}, errors.New("service failure"))
+			},
+			expectedStatusCode:  500,
+			expectedRequestBody: `{"message":"service failure"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			oauthMockService := mocks.NewMockOAuth(c)
+			testCase.mockBehavior(oauthMockService, testCase.provider, testCase.code, request)
+
+			services := &service.Services{OAuth: oauthMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.GET(oauthCallbackURL, handler.OauthCallback)
+			req := httptest.NewRequest("GET", fmt.Sprintf("/auth/%s/callback?state=%s&code=%s", testCase.provider,
+				testCase.state, testCase.code), nil)
+			if testCase.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: coockieOauth2StateName, Value: testCase.cookie})
+			}
+			if testCase.linkCookie != "" {
+				req.AddCookie(&http.Cookie{Name: coockieOauth2LinkName, Value: testCase.linkCookie})
+			}
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+			assert.Equal(t, testCase.expectedAccessToken, w.Header().Get("Access-Token"))
+			assert.Equal(t, testCase.expectedRefreshToken, w.Header().Get("Refresh-Token"))
+		})
+	}
+}
+
+func TestHandler_OauthLogin(t *testing.T) {
+	type mockBehavior func(s *mocks.MockOAuth, provider string)
+
+	testTable := []struct {
+		name                string
+		provider            string
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name:     "OK",
+			provider: "keycloak",
+			mockBehavior: func(s *mocks.MockOAuth, provider string) {
+				s.EXPECT().AuthCodeURL(context.Background(), provider, gomock.Any()).DoAndReturn(
+					func(_ context.Context, _ string, request oauth.AuthRequest) (string, error) {
+						return "https://accounts.example.com/auth?state=" + request.State, nil
+					})
+			},
+			expectedStatusCode: http.StatusTemporaryRedirect,
+		},
+		{
+			name:     "Unknown provider",
+			provider: "unknown",
+			mockBehavior: func(s *mocks.MockOAuth, provider string) {
+				s.EXPECT().AuthCodeURL(context.Background(), provider, gomock.Any()).Return("", domain.ErrUnknownOAuthProvider)
+			},
+			expectedStatusCode:  404,
+			expectedRequestBody: `{"message":"unknown oauth provider"}`,
+		},
+		{
+			name:     "Discovery failure",
+			provider: "keycloak",
+			mockBehavior: func(s *mocks.MockOAuth, provider string) {
+				s.EXPECT().AuthCodeURL(context.Background(), provider, gomock.Any()).Return("", errors.New("service failure"))
+			},
+			expectedStatusCode:  500,
+			expectedRequestBody: `{"message":"service failure"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			oauthMockService := mocks.NewMockOAuth(c)
+			testCase.mockBehavior(oauthMockService, testCase.provider)
+
+			services := &service.Services{OAuth: oauthMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.GET(oauthLoginURL, handler.OauthLogin)
+			req := httptest.NewRequest("GET", "/auth/"+testCase.provider+"/login", nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			if testCase.expectedStatusCode != http.StatusTemporaryRedirect {
+				assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+				return
+			}
+
+			cookies := w.Result().Cookies()
+			if assert.Len(t, cookies, 1) {
+				assert.Equal(t, coockieOauth2StateName, cookies[0].Name)
+				assert.Equal(t, "/auth/"+testCase.provider+"/callback", cookies[0].Path)
+				assert.True(t, cookies[0].HttpOnly)
+				state, _, _ := strings.Cut(cookies[0].Value, ".")
+				assert.Equal(t, "https://accounts.example.com/auth?state="+state, w.Header().Get("Location"))
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"test/internal/domain"
+	"test/internal/service/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Forgot password
+// @Tags auth
+// @Description Send a password reset token to the email, the response is the same for unknown emails
+// @ID forgot-password
+// @Accept json
+// @Param forgotPasswordDTO body dto.ForgotPasswordDTO true "email"
+// @Seccess 202 {integer} integer 1
+// @Router /auth/password/forgot [post]
+
+func (h *Handler) ForgotPassword(ctx *gin.Context) {
+	var forgotDTO dto.ForgotPasswordDTO
+	if err := ctx.BindJSON(&forgotDTO); err != nil {
+		newResponse(ctx, http.StatusBadRequest, "failed to bind email and json")
+		return
+	}
+
+	if err := h.services.Passwords.ForgotPassword(ctx.Request.Context(), forgotDTO); err != nil {
+		if errors.Is(err, domain.ErrInvalidEmail) {
+			newResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.Status(http.StatusAccepted)
+}
+
+// @Summary Reset password
+// @Tags auth
+// @Description Set a new password with the reset token, all sessions of the user are ended
+// @ID reset-password
+// @Accept json
+// @Param resetPasswordDTO body dto.ResetPasswordDTO true "reset token and new password"
+// @Seccess 200 {integer} integer 1
+// @Router /auth/password/reset [post]
+
+func (h *Handler) ResetPassword(ctx *gin.Context) {
+	var resetDTO dto.ResetPasswordDTO
+	if err := ctx.BindJSON(&resetDTO); err != nil {
+		newResponse(ctx, http.StatusBadRequest, "failed to bind reset token and json")
+		return
+	}
+
+	if err := h.services.Passwords.ResetPassword(ctx.Request.Context(), resetDTO); err != nil {
+		if errors.Is(err, domain.ErrInvalidResetToken) || errors.Is(err, domain.ErrInvalidPassword) {
+			newResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
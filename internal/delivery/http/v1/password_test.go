@@ -0,0 +1,177 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"test/internal/domain"
+	"test/internal/service"
+	"test/internal/service/dto"
+	"test/internal/service/mocks"
+	"test/pkg/api/auth"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_ForgotPassword(t *testing.T) {
+	type mockBehavior func(s *mocks.MockPasswords, forgotDTO dto.ForgotPasswordDTO)
+
+	testTable := []struct {
+		name                string
+		inputBody           string
+		forgotDTO           dto.ForgotPasswordDTO
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name:      "OK",
+			inputBody: `{"email":"test@test.ru"}`,
+			forgotDTO: dto.ForgotPasswordDTO{Email: "test@test.ru"},
+			mockBehavior: func(s *mocks.MockPasswords, forgotDTO dto.ForgotPasswordDTO) {
+				s.EXPECT().ForgotPassword(context.Background(), forgotDTO).Return(nil)
+			},
+			expectedStatusCode: 202,
+		},
+		{
+			name:      "Invalid email",
+			inputBody: `{"email":"test"}`,
+			forgotDTO: dto.ForgotPasswordDTO{Email: "test"},
+			mockBehavior: func(s *mocks.MockPasswords, forgotDTO dto.ForgotPasswordDTO) {
+				s.EXPECT().ForgotPassword(context.Background(), forgotDTO).Return(domain.ErrInvalidEmail)
+			},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"invalid email"}`,
+		},
+		{
+			name:                "Empty body",
+			mockBehavior:        func(s *mocks.MockPasswords, forgotDTO dto.ForgotPasswordDTO) {},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"failed to bind email and json"}`,
+		},
+		{
+			name:      "Service Failure",
+			inputBody: `{"email":"test@test.ru"}`,
+			forgotDTO: dto.ForgotPasswordDTO{Email: "test@test.ru"},
+			mockBehavior: func(s *mocks.MockPasswords, forgotDTO dto.ForgotPasswordDTO) {
+				s.EXPECT().ForgotPassword(context.Background(), forgotDTO).Return(errors.New("service failure"))
+			},
+			expectedStatusCode:  500,
+			expectedRequestBody: `{"message":"service failure"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			passwordsMockService := mocks.NewMockPasswords(c)
+			testCase.mockBehavior(passwordsMockService, testCase.forgotDTO)
+
+			services := &service.Services{Passwords: passwordsMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.POST(forgotURL, handler.ForgotPassword)
+			req := httptest.NewRequest("POST", forgotURL, bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_ResetPassword(t *testing.T) {
+	type mockBehavior func(s *mocks.MockPasswords, resetDTO dto.ResetPasswordDTO)
+
+	testTable := []struct {
+		name                string
+		inputBody           string
+		resetDTO            dto.ResetPasswordDTO
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name:      "OK",
+			inputBody: `{"token":"token","password":"qwerty123"}`,
+			resetDTO:  dto.ResetPasswordDTO{Token: "token", Password: "qwerty123"},
+			mockBehavior: func(s *mocks.MockPasswords, resetDTO dto.ResetPasswordDTO) {
+				s.EXPECT().ResetPassword(context.Background(), resetDTO).Return(nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:      "Invalid token",
+			inputBody: `{"token":"token","password":"qwerty123"}`,
+			resetDTO:  dto.ResetPasswordDTO{Token: "token", Password: "qwerty123"},
+			mockBehavior: func(s *mocks.MockPasswords, resetDTO dto.ResetPasswordDTO) {
+				s.EXPECT().ResetPassword(context.Background(), resetDTO).Return(domain.ErrInvalidResetToken)
+			},
+			expectedStatusCode:  400,
+			expectedRequestBody: fmt.Sprintf(`{"message":%q}`, domain.ErrInvalidResetToken.Error()),
+		},
+		{
+			name:      "Invalid password",
+			inputBody: `{"token":"token","password":"123"}`,
+			resetDTO:  dto.ResetPasswordDTO{Token: "token", Password: "123"},
+			mockBehavior: func(s *mocks.MockPasswords, resetDTO dto.ResetPasswordDTO) {
+				s.EXPECT().ResetPassword(context.Background(), resetDTO).Return(domain.ErrInvalidPassword)
+			},
+			expectedStatusCode:  400,
+			expectedRequestBody: fmt.Sprintf(`{"message":%q}`, domain.ErrInvalidPassword.Error()),
+		},
+		{
+			name:                "Empty body",
+			mockBehavior:        func(s *mocks.MockPasswords, resetDTO dto.ResetPasswordDTO) {},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"failed to bind reset token and json"}`,
+		},
+		{
+			name:      "Service Failure",
+			inputBody: `{"token":"token","password":"qwerty123"}`,
+			resetDTO:  dto.ResetPasswordDTO{Token: "token", Password: "qwerty123"},
+			mockBehavior: func(s *mocks.MockPasswords, resetDTO dto.ResetPasswordDTO) {
+				s.EXPECT().ResetPassword(context.Background(), resetDTO).Return(errors.New("service failure"))
+			},
+			expectedStatusCode:  500,
+			expectedRequestBody: `{"message":"service failure"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			passwordsMockService := mocks.NewMockPasswords(c)
+			testCase.mockBehavior(passwordsMockService, testCase.resetDTO)
+
+			services := &service.Services{Passwords: passwordsMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.POST(resetURL, handler.ResetPassword)
+			req := httptest.NewRequest("POST", resetURL, bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
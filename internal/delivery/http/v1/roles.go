@@ -0,0 +1,69 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"test/internal/domain"
+	"test/internal/service/dto"
+	apierrors "test/pkg/api/api_errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	roleNameURL = "role"
+	rolesURL    = "/:id/roles"
+	roleURL     = "/:id/roles/:role"
+)
+
+// @Summary Grant role
+// @Tags user/:id/roles
+// @Description Grant role to the user, admins only
+// @ID grant-role
+// @Accept json
+// @Param roleDTO body dto.RoleDTO true "role"
+// @Seccess 200 {integer} integer 1
+// @Router /users/:id/roles [post]
+
+func (h *Handler) GrantRole(ctx *gin.Context) {
+	var roleDTO dto.RoleDTO
+	if err := ctx.BindJSON(&roleDTO); err != nil {
+		newResponse(ctx, http.StatusBadRequest, "failed to bind role and json")
+		return
+	}
+
+	err := h.services.Users.GrantRole(ctx.Request.Context(), ctx.Param(idNameURL), roleDTO.Role)
+	if err != nil {
+		newRoleErrorResponse(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
+
+// @Summary Revoke role
+// @Tags user/:id/roles
+// @Description Revoke role from the user, admins only
+// @ID revoke-role
+// @Seccess 200 {integer} integer 1
+// @Router /users/:id/roles/:role [delete]
+
+func (h *Handler) RevokeRole(ctx *gin.Context) {
+	err := h.services.Users.RevokeRole(ctx.Request.Context(), ctx.Param(idNameURL), ctx.Param(roleNameURL))
+	if err != nil {
+		newRoleErrorResponse(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
+
+func newRoleErrorResponse(ctx *gin.Context, err error) {
+	var apiErr *apierrors.ApiError
+	switch {
+	case errors.Is(err, domain.ErrUserNotFound):
+		newResponse(ctx, http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrUnknownRole), errors.As(err, &apiErr):
+		newResponse(ctx, http.StatusBadRequest, err.Error())
+	default:
+		newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,175 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"test/internal/domain"
+	"test/internal/service"
+	"test/internal/service/mocks"
+	"test/pkg/api/auth"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_GrantRole(t *testing.T) {
+	type mockBehavior func(s *mocks.MockUsers, id, role string)
+
+	testTable := []struct {
+		name                string
+		id                  string
+		inputBody           string
+		role                string
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name:      "OK",
+			id:        "000000000000",
+			inputBody: `{"role":"admin"}`,
+			role:      auth.AdminRole,
+			mockBehavior: func(s *mocks.MockUsers, id, role string) {
+				s.EXPECT().GrantRole(context.Background(), id, role).Return(nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:      "Unknown role",
+			id:        "000000000000",
+			inputBody: `{"role":"superuser"}`,
+			role:      "superuser",
+			mockBehavior: func(s *mocks.MockUsers, id, role string) {
+				s.EXPECT().GrantRole(context.Background(), id, role).Return(domain.ErrUnknownRole)
+			},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"unknown role"}`,
+		},
+		{
+			name:      "User not found",
+			id:        "000000000000",
+			inputBody: `{"role":"admin"}`,
+			role:      auth.AdminRole,
+			mockBehavior: func(s *mocks.MockUsers, id, role string) {
+				s.EXPECT().GrantRole(context.Background(), id, role).Return(domain.ErrUserNotFound)
+			},
+			expectedStatusCode:  404,
+			expectedRequestBody: `{"message":"user doesn't exists"}`,
+		},
+		{
+			name:                "Empty body",
+			id:                  "000000000000",
+			mockBehavior:        func(s *mocks.MockUsers, id, role string) {},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"failed to bind role and json"}`,
+		},
+		{
+			name:      "Service Failure",
+			id:        "000000000000",
+			inputBody: `{"role":"admin"}`,
+			role:      auth.AdminRole,
+			mockBehavior: func(s *mocks.MockUsers, id, role string) {
+				s.EXPECT().GrantRole(context.Background(), id, role).Return(fmt.Errorf("service failure"))
+			},
+			expectedStatusCode:  500,
+			expectedRequestBody: `{"message":"service failure"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			userMockService := mocks.NewMockUsers(c)
+			testCase.mockBehavior(userMockService, testCase.id, testCase.role)
+
+			services := &service.Services{Users: userMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.POST("/users/:id/roles", handler.GrantRole)
+			req := httptest.NewRequest("POST", "/users/"+testCase.id+"/roles", bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_RevokeRole(t *testing.T) {
+	type mockBehavior func(s *mocks.MockUsers, id, role string)
+
+	testTable := []struct {
+		name                string
+		id                  string
+		role                string
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name: "OK",
+			id:   "000000000000",
+			role: auth.AdminRole,
+			mockBehavior: func(s *mocks.MockUsers, id, role string) {
+				s.EXPECT().RevokeRole(context.Background(), id, role).Return(nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name: "Unknown role",
+			id:   "000000000000",
+			role: "superuser",
+			mockBehavior: func(s *mocks.MockUsers, id, role string) {
+				s.EXPECT().RevokeRole(context.Background(), id, role).Return(domain.ErrUnknownRole)
+			},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"unknown role"}`,
+		},
+		{
+			name: "Service Failure",
+			id:   "000000000000",
+			role: auth.AdminRole,
+			mockBehavior: func(s *mocks.MockUsers, id, role string) {
+				s.EXPECT().RevokeRole(context.Background(), id, role).Return(fmt.Errorf("service failure"))
+			},
+			expectedStatusCode:  500,
+			expectedRequestBody: `{"message":"service failure"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			userMockService := mocks.NewMockUsers(c)
+			testCase.mockBehavior(userMockService, testCase.id, testCase.role)
+
+			services := &service.Services{Users: userMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.DELETE("/users/:id/roles/:role", handler.RevokeRole)
+			req := httptest.NewRequest("DELETE", "/users/"+testCase.id+"/roles/"+testCase.role, &bytes.Reader{})
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"test/internal/domain"
+	apierrors "test/pkg/api/api_errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	sessionIdNameURL = "sessionId"
+	sessionsURL      = "/:id/sessions"
+	sessionURL       = "/:id/sessions/:sessionId"
+)
+
+// @Summary Find sessions
+// @Tags user/:id/sessions
+// @Description Find active sessions of the user
+// @ID find-sessions
+// @Produce json
+// @Seccess 200 {integer} integer 1
+// @Router /users/:id/sessions [get]
+
+func (h *Handler) FindSessions(ctx *gin.Context) {
+	id := ctx.Param(idNameURL)
+	sessions, err := h.services.Users.FindSessions(ctx.Request.Context(), id)
+	var apiErr *apierrors.ApiError
+	if err != nil {
+		if errors.As(err, &apiErr) {
+			newResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sessionsBytes, err := json.Marshal(sessions)
+	if err != nil {
+		newResponse(ctx, http.StatusBadRequest, "failed to marshal sessions to json")
+		return
+	}
+	ctx.Writer.Write(sessionsBytes)
+	ctx.Status(http.StatusOK)
+}
+
+// @Summary Revoke session
+// @Tags user/:id/sessions
+// @Description Revoke one session of the user
+// @ID revoke-session
+// @Seccess 200 {integer} integer 1
+// @Router /users/:id/sessions/:sessionId [delete]
+
+func (h *Handler) RevokeSession(ctx *gin.Context) {
+	id := ctx.Param(idNameURL)
+	sessionId := ctx.Param(sessionIdNameURL)
+	err := h.services.Users.RevokeSession(ctx.Request.Context(), id, sessionId)
+	var apiErr *apierrors.ApiError
+	if err != nil {
+		if errors.Is(err, domain.ErrSessionNotFound) {
+			newResponse(ctx, http.StatusNotFound, err.Error())
+			return
+		}
+		if errors.As(err, &apiErr) {
+			newResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
+
+// @Summary Revoke sessions
+// @Tags user/:id/sessions
+// @Description Revoke all sessions of the user
+// @ID revoke-sessions
+// @Seccess 200 {integer} integer 1
+// @Router /users/:id/sessions [delete]
+
+func (h *Handler) RevokeSessions(ctx *gin.Context) {
+	id := ctx.Param(idNameURL)
+	err := h.services.Users.RevokeSessions(ctx.Request.Context(), id)
+	var apiErr *apierrors.ApiError
+	if err != nil {
+		if errors.As(err, &apiErr) {
+			newResponse(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
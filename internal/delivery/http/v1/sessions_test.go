@@ -0,0 +1,220 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"test/internal/domain"
+	"test/internal/service"
+	"test/internal/service/mocks"
+	"test/pkg/api/auth"
+	"test/pkg/api/params"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_FindSessions(t *testing.T) {
+	type mockBehavior func(s *mocks.MockUsers, id string)
+
+	createdAt := time.Date(2022, 11, 1, 10, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                string
+		id                  string
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name: "OK",
+			id:   "000000000000",
+			mockBehavior: func(s *mocks.MockUsers, id string) {
+				s.EXPECT().FindSessions(context.Background(), id).Return([]domain.Session{
+					{
+						Id:               [12]byte{1},
+						RefreshTokenHash: "Rand string",
+						UserAgent:        "curl/7.79.1",
+						IP:               "192.0.2.1",
+						CreatedAt:        createdAt,
+						LastUsedAt:       createdAt,
+						ExpiresAt:        createdAt.Add(time.Hour),
+					},
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedRequestBody: `[{"id":"010000000000000000000000","user_agent":"curl/7.79.1","ip":"192.0.2.1",` +
+				`"created_at":"2022-11-01T10:00:00Z","last_used_at":"2022-11-01T10:00:00Z","expires_at":"2022-11-01T11:00:00Z"}]`,
+		},
+		{
+			name: "Id Invalid",
+			id:   "000000000000",
+			mockBehavior: func(s *mocks.MockUsers, id string) {
+				s.EXPECT().FindSessions(context.Background(), id).Return(nil, params.ErrInvalidIdParam)
+			},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"message":"invalid id param"}`,
+		},
+		{
+			name: "Service Failure",
+			id:   "000000000000",
+			mockBehavior: func(s *mocks.MockUsers, id string) {
+				s.EXPECT().FindSessions(context.Background(), id).Return(nil, fmt.Errorf("service failure"))
+			},
+			expectedStatusCode:  500,
+			expectedRequestBody: `{"message":"service failure"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			userMockService := mocks.NewMockUsers(c)
+			testCase.mockBehavior(userMockService, testCase.id)
+
+			services := &service.Services{Users: userMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.GET("/users/:id/sessions", handler.FindSessions)
+			req := httptest.NewRequest("GET", "/users/"+testCase.id+"/sessions", &bytes.Reader{})
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_RevokeSession(t *testing.T) {
+	type mockBehavior func(s *mocks.MockUsers, id, sessionId string)
+
+	testTable := []struct {
+		name                string
+		id                  string
+		sessionId           string
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name:      "OK",
+			id:        "000000000000",
+			sessionId: "000000000001",
+			mockBehavior: func(s *mocks.MockUsers, id, sessionId string) {
+				s.EXPECT().RevokeSession(context.Background(), id, sessionId).Return(nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:      "Session not found",
+			id:        "000000000000",
+			sessionId: "000000000001",
+			mockBehavior: func(s *mocks.MockUsers, id, sessionId string) {
+				s.EXPECT().RevokeSession(context.Background(), id, sessionId).Return(domain.ErrSessionNotFound)
+			},
+			expectedStatusCode:  404,
+			expectedRequestBody: `{"message":"session doesn't exists or expired"}`,
+		},
+		{
+			name:      "Service Failure",
+			id:        "000000000000",
+			sessionId: "000000000001",
+			mockBehavior: func(s *mocks.MockUsers, id, sessionId string) {
+				s.EXPECT().RevokeSession(context.Background(), id, sessionId).Return(fmt.Errorf("service failure"))
+			},
+			expectedStatusCode:  500,
+			expectedRequestBody: `{"message":"service failure"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			userMockService := mocks.NewMockUsers(c)
+			testCase.mockBehavior(userMockService, testCase.id, testCase.sessionId)
+
+			services := &service.Services{Users: userMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.DELETE("/users/:id/sessions/:sessionId", handler.RevokeSession)
+			req := httptest.NewRequest("DELETE", "/users/"+testCase.id+"/sessions/"+testCase.sessionId, &bytes.Reader{})
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandler_RevokeSessions(t *testing.T) {
+	type mockBehavior func(s *mocks.MockUsers, id string)
+
+	testTable := []struct {
+		name                string
+		id                  string
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name: "OK",
+			id:   "000000000000",
+			mockBehavior: func(s *mocks.MockUsers, id string) {
+				s.EXPECT().RevokeSessions(context.Background(), id).Return(nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name: "Service Failure",
+			id:   "000000000000",
+			mockBehavior: func(s *mocks.MockUsers, id string) {
+				s.EXPECT().RevokeSessions(context.Background(), id).Return(fmt.Errorf("service failure"))
+			},
+			expectedStatusCode:  500,
+			expectedRequestBody: `{"message":"service failure"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			userMockService := mocks.NewMockUsers(c)
+			testCase.mockBehavior(userMockService, testCase.id)
+
+			services := &service.Services{Users: userMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.DELETE("/users/:id/sessions", handler.RevokeSessions)
+			req := httptest.NewRequest("DELETE", "/users/"+testCase.id+"/sessions", &bytes.Reader{})
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
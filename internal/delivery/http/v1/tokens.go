@@ -0,0 +1,72 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	oauthIntrospectURL = "/oauth/introspect"
+	oauthRevokeURL     = "/oauth/revoke"
+	// Clients are allowed to introspect and revoke tokens of any user only with these scopes.
+	tokensIntrospect = "tokens:introspect"
+	tokensRevoke     = "tokens:revoke"
+)
+
+// @Summary Introspect token
+// @Tags oauth
+// @Description Tell whether an access token, refresh token or API key is active and return its claims, RFC 7662. The client authenticates as on the token endpoint and needs the tokens:introspect scope. token_type_hint is ignored, the kind of the token is told by its format
+// @ID oauth-introspect
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "token to introspect"
+// @Success 200 {object} dto.IntrospectionDTO
+// @Router /oauth/introspect [post]
+
+func (h *Handler) Introspect(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	if !h.authenticateClient(ctx, tokensIntrospect) {
+		return
+	}
+
+	token := ctx.PostForm("token")
+	if token == "" {
+		newTokenErrorResponse(ctx, http.StatusBadRequest, oauthInvalidRequest, "token is required")
+		return
+	}
+
+	introspectionDTO, err := h.services.Tokens.Introspect(ctx.Request.Context(), token)
+	if err != nil {
+		newTokenErrorResponse(ctx, http.StatusInternalServerError, oauthServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, introspectionDTO)
+}
+
+// @Summary Revoke token
+// @Tags oauth
+// @Description Revoke an access token, refresh token or API key, RFC 7009. The client authenticates as on the token endpoint and needs the tokens:revoke scope. Unknown tokens are answered with 200 as well
+// @ID oauth-revoke
+// @Accept x-www-form-urlencoded
+// @Param token formData string true "token to revoke"
+// @Seccess 200 {integer} integer 1
+// @Router /oauth/revoke [post]
+
+func (h *Handler) RevokeToken(ctx *gin.Context) {
+	if !h.authenticateClient(ctx, tokensRevoke) {
+		return
+	}
+
+	token := ctx.PostForm("token")
+	if token == "" {
+		newTokenErrorResponse(ctx, http.StatusBadRequest, oauthInvalidRequest, "token is required")
+		return
+	}
+
+	if err := h.services.Tokens.Revoke(ctx.Request.Context(), token); err != nil {
+		newTokenErrorResponse(ctx, http.StatusInternalServerError, oauthServerError, err.Error())
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
@@ -0,0 +1,196 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"test/internal/domain"
+	"test/internal/service"
+	"test/internal/service/dto"
+	"test/internal/service/mocks"
+	"test/pkg/api/auth"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_Introspect(t *testing.T) {
+	type mockBehavior func(c *mocks.MockClients, s *mocks.MockTokens)
+
+	introspector := domain.Client{Id: "gateway", Scopes: []string{"tokens:introspect"}}
+
+	testTable := []struct {
+		name                string
+		form                url.Values
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name: "Active",
+			form: url.Values{"token": {"token"}},
+			mockBehavior: func(c *mocks.MockClients, s *mocks.MockTokens) {
+				c.EXPECT().Authenticate(context.Background(), "gateway", "ucs_secret").Return(introspector, nil)
+				s.EXPECT().Introspect(context.Background(), "token").Return(dto.IntrospectionDTO{
+					Active:    true,
+					TokenType: "access_token",
+					Subject:   "000000000001",
+					ExpiresAt: 1667260800,
+				}, nil)
+			},
+			expectedStatusCode:  200,
+			expectedRequestBody: `{"active":true,"token_type":"access_token","sub":"000000000001","exp":1667260800}`,
+		},
+		{
+			name: "Inactive",
+			form: url.Values{"token": {"token"}},
+			mockBehavior: func(c *mocks.MockClients, s *mocks.MockTokens) {
+				c.EXPECT().Authenticate(context.Background(), "gateway", "ucs_secret").Return(introspector, nil)
+				s.EXPECT().Introspect(context.Background(), "token").Return(dto.IntrospectionDTO{}, nil)
+			},
+			expectedStatusCode:  200,
+			expectedRequestBody: `{"active":false}`,
+		},
+		{
+			name: "Missing token",
+			form: url.Values{},
+			mockBehavior: func(c *mocks.MockClients, s *mocks.MockTokens) {
+				c.EXPECT().Authenticate(context.Background(), "gateway", "ucs_secret").Return(introspector, nil)
+			},
+			expectedStatusCode:  400,
+			expectedRequestBody: `{"error":"invalid_request","error_description":"token is required"}`,
+		},
+		{
+			name: "Invalid client",
+			form: url.Values{"token": {"token"}},
+			mockBehavior: func(c *mocks.MockClients, s *mocks.MockTokens) {
+				c.EXPECT().Authenticate(context.Background(), "gateway", "ucs_secret").Return(domain.Client{}, domain.ErrInvalidClient)
+			},
+			expectedStatusCode:  401,
+			expectedRequestBody: `{"error":"invalid_client","error_description":"client authentication failed"}`,
+		},
+		{
+			name: "Client without scope",
+			form: url.Values{"token": {"token"}},
+			mockBehavior: func(c *mocks.MockClients, s *mocks.MockTokens) {
+				c.EXPECT().Authenticate(context.Background(), "gateway", "ucs_secret").
+					Return(domain.Client{Id: "gateway", Scopes: []string{"users:read", "tokens:revoke"}}, nil)
+			},
+			expectedStatusCode:  403,
+			expectedRequestBody: `{"error":"unauthorized_client","error_description":"client isn't allowed tokens:introspect"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			clientsMockService := mocks.NewMockClients(c)
+			tokensMockService := mocks.NewMockTokens(c)
+			testCase.mockBehavior(clientsMockService, tokensMockService)
+
+			services := &service.Services{Clients: clientsMockService, Tokens: tokensMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.POST(oauthIntrospectURL, handler.Introspect)
+			req := httptest.NewRequest("POST", oauthIntrospectURL, strings.NewReader(testCase.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.SetBasicAuth("gateway", "ucs_secret")
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+			assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+func TestHandler_RevokeToken(t *testing.T) {
+	type mockBehavior func(c *mocks.MockClients, s *mocks.MockTokens)
+
+	revoker := domain.Client{Id: "gateway", Scopes: []string{"tokens:revoke"}}
+
+	testTable := []struct {
+		name                string
+		form                url.Values
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name: "OK",
+			form: url.Values{"client_id": {"gateway"}, "client_secret": {"ucs_secret"}, "token": {"token"}},
+			mockBehavior: func(c *mocks.MockClients, s *mocks.MockTokens) {
+				c.EXPECT().Authenticate(context.Background(), "gateway", "ucs_secret").Return(revoker, nil)
+				s.EXPECT().Revoke(context.Background(), "token").Return(nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name: "Service Failure",
+			form: url.Values{"client_id": {"gateway"}, "client_secret": {"ucs_secret"}, "token": {"token"}},
+			mockBehavior: func(c *mocks.MockClients, s *mocks.MockTokens) {
+				c.EXPECT().Authenticate(context.Background(), "gateway", "ucs_secret").Return(revoker, nil)
+				s.EXPECT().Revoke(context.Background(), "token").Return(errors.New("service failure"))
+			},
+			expectedStatusCode:  500,
+			expectedRequestBody: `{"error":"server_error","error_description":"service failure"}`,
+		},
+		{
+			name: "No client credentials",
+			form: url.Values{"token": {"token"}},
+			mockBehavior: func(c *mocks.MockClients, s *mocks.MockTokens) {
+				c.EXPECT().Authenticate(context.Background(), "", "").Return(domain.Client{}, domain.ErrInvalidClient)
+			},
+			expectedStatusCode:  401,
+			expectedRequestBody: `{"error":"invalid_client","error_description":"client authentication failed"}`,
+		},
+		{
+			name: "Client without scope",
+			form: url.Values{"client_id": {"gateway"}, "client_secret": {"ucs_secret"}, "token": {"token"}},
+			mockBehavior: func(c *mocks.MockClients, s *mocks.MockTokens) {
+				c.EXPECT().Authenticate(context.Background(), "gateway", "ucs_secret").
+					Return(domain.Client{Id: "gateway", Scopes: []string{"tokens:introspect"}}, nil)
+			},
+			expectedStatusCode:  403,
+			expectedRequestBody: `{"error":"unauthorized_client","error_description":"client isn't allowed tokens:revoke"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			clientsMockService := mocks.NewMockClients(c)
+			tokensMockService := mocks.NewMockTokens(c)
+			testCase.mockBehavior(clientsMockService, tokensMockService)
+
+			services := &service.Services{Clients: clientsMockService, Tokens: tokensMockService}
+			handler := NewHandler(services, &auth.Manager{})
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.POST(oauthRevokeURL, handler.RevokeToken)
+			req := httptest.NewRequest("POST", oauthRevokeURL, strings.NewReader(testCase.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"test/internal/service/dto"
 	"test/pkg/api"
 	apierrors "test/pkg/api/api_errors"
-	"test/pkg/api/auth"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,6 +16,27 @@ const (
 	idNameURL  = "id"
 	usersGroup = "/users"
 	adminGroup = "/admins"
+
+	usersRead      = "users:read"
+	usersUpdate    = "users:update"
+	usersDelete    = "users:delete"
+	sessionsRead   = "sessions:read"
+	sessionsDelete = "sessions:delete"
+	rolesUpdate    = "roles:update"
+
+	identitiesUpdate = "identities:update"
+	identitiesDelete = "identities:delete"
+	mfaUpdate        = "mfa:update"
+	mfaDelete        = "mfa:delete"
+	lockoutDelete    = "lockout:delete"
+	apiKeysRead      = "api_keys:read"
+	apiKeysUpdate    = "api_keys:update"
+	apiKeysDelete    = "api_keys:delete"
+
+	impersonationsRead   = "impersonations:read"
+	impersonationsUpdate = "impersonations:update"
+	impersonationsDelete = "impersonations:delete"
+	auditRead            = "audit:read"
 )
 
 func (h *Handler) initUsersRoutes(api *gin.RouterGroup) {
@@ -25,19 +45,31 @@ func (h *Handler) initUsersRoutes(api *gin.RouterGroup) {
 	{
 
 		users.POST("/", h.Create)
-		users.GET(auth.RefreshURL, h.RefreshToken)
-
-		admin := users.Group("/").Use(h.tokenManager.VerifyJWTMiddleware(auth.AdminRole))
-		{
-			admin.GET("/", h.FindAll)
-		}
 
-		authencticated := users.Group("/").Use(h.tokenManager.VerifyJWTMiddleware(auth.UserRole, auth.AdminRole))
+		authencticated := users.Group("/").Use(h.authenticate(), h.auditImpersonation())
 		{
-			authencticated.GET("/:id", h.FindOne)
-			authencticated.PUT("/:id", h.Update)
-			authencticated.DELETE("/:id", h.Delete)
-
+			authencticated.GET("/", h.requirePermission(usersRead), h.FindAll)
+			authencticated.GET("/:id", h.requirePermission(usersRead), h.FindOne)
+			authencticated.PUT("/:id", h.requirePermission(usersUpdate), h.Update)
+			authencticated.DELETE("/:id", h.requirePermission(usersDelete), h.Delete)
+			authencticated.GET(sessionsURL, h.requirePermission(sessionsRead), h.FindSessions)
+			authencticated.DELETE(sessionsURL, h.requirePermission(sessionsDelete), h.RevokeSessions)
+			authencticated.DELETE(sessionURL, h.requirePermission(sessionsDelete), h.RevokeSession)
+			authencticated.POST(rolesURL, h.requirePermission(rolesUpdate), h.GrantRole)
+			authencticated.DELETE(roleURL, h.requirePermission(rolesUpdate), h.RevokeRole)
+			authencticated.POST(identityURL, h.requirePermission(identitiesUpdate), h.LinkIdentity)
+			authencticated.DELETE(identityURL, h.requirePermission(identitiesDelete), h.UnlinkIdentity)
+			authencticated.POST(mfaURL, h.requirePermission(mfaUpdate), h.EnrollMFA)
+			authencticated.POST(mfaConfirmURL, h.requirePermission(mfaUpdate), h.ConfirmMFA)
+			authencticated.DELETE(mfaURL, h.requirePermission(mfaDelete), h.DisableMFA)
+			authencticated.DELETE(lockoutURL, h.requirePermission(lockoutDelete), h.Unlock)
+			authencticated.GET(apiKeysURL, h.requirePermission(apiKeysRead), h.FindAPIKeys)
+			authencticated.POST(apiKeysURL, h.requirePermission(apiKeysUpdate), h.CreateAPIKey)
+			authencticated.DELETE(apiKeyURL, h.requirePermission(apiKeysDelete), h.RevokeAPIKey)
+			authencticated.GET(impersonationsURL, h.requirePermission(impersonationsRead), h.FindImpersonations)
+			authencticated.POST(impersonationsURL, h.requirePermission(impersonationsUpdate), h.StartImpersonation)
+			authencticated.DELETE(impersonationURL, h.requirePermission(impersonationsDelete), h.RevokeImpersonation)
+			authencticated.GET(auditURL, h.requirePermission(auditRead), h.FindAuditEvents)
 		}
 
 	}
@@ -62,7 +94,7 @@ func (h *Handler) Create(ctx *gin.Context) {
 		return
 	}
 
-	tokenDTO, err := h.services.Users.Create(ctx.Request.Context(), userDTO)
+	tokenDTO, err := h.services.Users.Create(ctx.Request.Context(), userDTO, newDeviceDTO(ctx))
 	if err != nil {
 		if errors.Is(err, domain.ErrUserAlreadyExists) {
 			newResponse(ctx, http.StatusBadRequest, err.Error())
@@ -71,8 +103,10 @@ func (h *Handler) Create(ctx *gin.Context) {
 		newResponse(ctx, http.StatusInternalServerError, err.Error())
 		return
 	}
-	ctx.Header("Access-Token", tokenDTO.AccessToken)
-	ctx.Header("Refresh-Token", tokenDTO.RefreshToken)
+	if err := h.setTokens(ctx, tokenDTO); err != nil {
+		newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
 	ctx.Status(http.StatusCreated)
 }
 
@@ -197,16 +231,3 @@ func (h *Handler) Delete(ctx *gin.Context) {
 	}
 	ctx.Status(http.StatusOK)
 }
-
-func (h *Handler) RefreshToken(ctx *gin.Context) {
-	userId := ctx.Param("id")
-	tokenDTO, err := h.services.Users.RefreshUserToken(ctx.Request.Context(), userId)
-	if err != nil {
-		newResponse(ctx, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	ctx.Header("Access-Token", tokenDTO.AccessToken)
-	ctx.Header("Refresh-Token", tokenDTO.RefreshToken)
-	ctx.Status(http.StatusOK)
-}
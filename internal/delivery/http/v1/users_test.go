@@ -20,6 +20,8 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+var testDevice = dto.DeviceDTO{IP: "192.0.2.1"}
+
 func TestHandler_Create(t *testing.T) {
 	type mockBehavior func(s *mocks.MockUsers, user dto.CreateUserDTO)
 
@@ -39,7 +41,7 @@ func TestHandler_Create(t *testing.T) {
 				Password: "qwerty",
 			},
 			mockBehavior: func(s *mocks.MockUsers, userDTO dto.CreateUserDTO) {
-				s.EXPECT().Create(context.Background(), userDTO).Return(dto.TokenDTO{
+				s.EXPECT().Create(context.Background(), userDTO, testDevice).Return(dto.TokenDTO{
 					AccessToken:  "Rand string",
 					RefreshToken: "Rand string",
 				}, nil)
@@ -55,7 +57,7 @@ func TestHandler_Create(t *testing.T) {
 				Password: "qwerty",
 			},
 			mockBehavior: func(s *mocks.MockUsers, userDTO dto.CreateUserDTO) {
-				s.EXPECT().Create(context.Background(), userDTO).Return(dto.TokenDTO{}, domain.ErrUserAlreadyExists)
+				s.EXPECT().Create(context.Background(), userDTO, testDevice).Return(dto.TokenDTO{}, domain.ErrUserAlreadyExists)
 			},
 			expectedStatusCode:  400,
 			expectedRequestBody: `{"message":"user with such email already exists"}`,
@@ -74,7 +76,7 @@ func TestHandler_Create(t *testing.T) {
 				Password: "qwerty",
 			},
 			mockBehavior: func(s *mocks.MockUsers, userDTO dto.CreateUserDTO) {
-				s.EXPECT().Create(context.Background(), userDTO).Return(dto.TokenDTO{}, errors.New("service failure"))
+				s.EXPECT().Create(context.Background(), userDTO, testDevice).Return(dto.TokenDTO{}, errors.New("service failure"))
 			},
 			expectedStatusCode:  500,
 			expectedRequestBody: `{"message":"service failure"}`,
@@ -126,7 +128,6 @@ func TestHandler_FindOne(t *testing.T) {
 					Id:           [12]byte{1},
 					PasswordHash: "password",
 					Email:        "email",
-					Session:      domain.Session{},
 				}, nil)
 			},
 			expectedStatusCode:  200,
@@ -213,13 +214,11 @@ func TestHandler_FindAll(t *testing.T) {
 						Id:           [12]byte{1},
 						PasswordHash: "password1",
 						Email:        "email1",
-						Session:      domain.Session{},
 					},
 					{
 						Id:           [12]byte{2},
 						PasswordHash: "password2",
 						Email:        "email2",
-						Session:      domain.Session{},
 					},
 				}, nil)
 			},
@@ -238,13 +237,11 @@ func TestHandler_FindAll(t *testing.T) {
 						Id:           [12]byte{1},
 						PasswordHash: "password1",
 						Email:        "email1",
-						Session:      domain.Session{},
 					},
 					{
 						Id:           [12]byte{2},
 						PasswordHash: "password2",
 						Email:        "email2",
-						Session:      domain.Session{},
 					},
 				}, nil)
 			},
@@ -498,61 +495,3 @@ func TestHandler_Delete(t *testing.T) {
 		})
 	}
 }
-
-func TestHandler_RefreshToken(t *testing.T) {
-	type mockBehavior func(s *mocks.MockUsers, id string)
-
-	testTable := []struct {
-		name                string
-		userId              string
-		mockBehavior        mockBehavior
-		expectedStatusCode  int
-		expectedRequestBody string
-	}{
-		{
-			name:   "OK",
-			userId: "000000000000",
-			mockBehavior: func(s *mocks.MockUsers, userId string) {
-				s.EXPECT().RefreshUserToken(context.Background(), userId).Return(dto.TokenDTO{
-					AccessToken:  "Rand string",
-					RefreshToken: "Rand string",
-				}, nil)
-			},
-			expectedStatusCode: 200,
-		},
-		{
-			name:   "Service Failure",
-			userId: "000000000000",
-			mockBehavior: func(s *mocks.MockUsers, userId string) {
-				s.EXPECT().RefreshUserToken(context.Background(), userId).Return(dto.TokenDTO{}, fmt.Errorf("service failure"))
-			},
-			expectedStatusCode:  500,
-			expectedRequestBody: `{"message":"service failure"}`,
-		},
-	}
-
-	for _, testCase := range testTable {
-		t.Run(testCase.name, func(t *testing.T) {
-			c := gomock.NewController(t)
-			defer c.Finish()
-
-			userMockService := mocks.NewMockUsers(c)
-			testCase.mockBehavior(userMockService, testCase.userId)
-
-			services := &service.Services{Users: userMockService}
-			handler := NewHandler(services, &auth.Manager{})
-
-			gin.SetMode(gin.ReleaseMode)
-			r := gin.New()
-			w := httptest.NewRecorder()
-
-			r.GET("/users/:id/auth/refresh", handler.RefreshToken)
-			req := httptest.NewRequest("GET", "/users/000000000000/auth/refresh", &bytes.Reader{})
-
-			r.ServeHTTP(w, req)
-
-			assert.Equal(t, testCase.expectedStatusCode, w.Code)
-			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
-		})
-	}
-}
@@ -0,0 +1,23 @@
+package domain
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// APIKey lets scripts call the API as the user without a session. Only KeyHash of the
+// key is stored, Prefix is the start of the key kept to tell keys apart. Scopes are
+// "resource:action" permissions the key is limited to, a key without scopes has every
+// permission of the user. Keys without ExpiresAt never expire.
+type APIKey struct {
+	Id         primitive.ObjectID `json:"id" bson:"_id"`
+	UserId     primitive.ObjectID `json:"-" bson:"user_id"`
+	Name       string             `json:"name" bson:"name"`
+	Prefix     string             `json:"prefix" bson:"prefix"`
+	KeyHash    string             `json:"-" bson:"key_hash"`
+	Scopes     []string           `json:"scopes,omitempty" bson:"scopes,omitempty"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+	LastUsedAt time.Time          `json:"last_used_at" bson:"last_used_at"`
+	ExpiresAt  *time.Time         `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+}
@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Actions of audit events.
+const (
+	AuditImpersonationStarted = "impersonation.started"
+	AuditImpersonationEnded   = "impersonation.ended"
+	AuditImpersonatedRequest  = "impersonation.request"
+)
+
+// AuditEvent records who did what to whose account. ActorId is the admin for events of
+// impersonations, Method and Path are set for requests made while impersonating.
+type AuditEvent struct {
+	Id              primitive.ObjectID `json:"id" bson:"_id"`
+	Action          string             `json:"action" bson:"action"`
+	ActorId         primitive.ObjectID `json:"actor_id" bson:"actor_id"`
+	UserId          primitive.ObjectID `json:"user_id" bson:"user_id"`
+	ImpersonationId primitive.ObjectID `json:"impersonation_id" bson:"impersonation_id"`
+	Reason          string             `json:"reason,omitempty" bson:"reason,omitempty"`
+	Method          string             `json:"method,omitempty" bson:"method,omitempty"`
+	Path            string             `json:"path,omitempty" bson:"path,omitempty"`
+	IP              string             `json:"ip" bson:"ip"`
+	UserAgent       string             `json:"user_agent" bson:"user_agent"`
+	CreatedAt       time.Time          `json:"created_at" bson:"created_at"`
+}
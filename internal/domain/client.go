@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// Client is a confidential OAuth client, a service calling the API on its own behalf
+// with the client credentials grant. Only SecretHash of the secret is stored. Scopes are
+// "resource:action" permissions the client may ask tokens for.
+type Client struct {
+	Id         string    `json:"client_id" bson:"_id"`
+	Name       string    `json:"name" bson:"name"`
+	SecretHash string    `json:"-" bson:"secret_hash"`
+	Scopes     []string  `json:"scopes" bson:"scopes"`
+	CreatedAt  time.Time `json:"created_at" bson:"created_at"`
+}
@@ -1,8 +1,51 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrUserNotFound            = errors.New("user doesn't exists")
 	ErrUserAlreadyExists       = errors.New("user with such email already exists")
+	ErrInvalidCredentials      = errors.New("invalid email or password")
+	ErrUnknownEmail            = fmt.Errorf("%w: unknown email", ErrInvalidCredentials)
+	ErrWrongPassword           = fmt.Errorf("%w: wrong password", ErrInvalidCredentials)
+	ErrSessionNotFound         = errors.New("session doesn't exists or expired")
+	ErrRefreshTokenReused      = errors.New("refresh token has already been used, session revoked")
+	ErrUnknownRole             = errors.New("unknown role")
+	ErrOAuthEmailNotVerified   = errors.New("email of the external account isn't verified")
+	ErrOAuthEmailRegistered    = errors.New("user with such email already exists, sign in and link the external account")
+	ErrIdentityLinked          = errors.New("external account is already linked to another user")
+	ErrProviderLinked          = errors.New("user is already linked to another account of the provider")
+	ErrIdentityNotFound        = errors.New("external account isn't linked to the user")
+	ErrLastLoginMethod         = errors.New("can't remove the last way to sign in")
+	ErrInvalidLinkRequest      = errors.New("link request is invalid or expired")
+	ErrOAuthExchange           = errors.New("failed to exchange authorization code")
+	ErrUnknownOAuthProvider    = errors.New("unknown oauth provider")
+	ErrInvalidResetToken       = errors.New("password reset token is invalid or expired")
+	ErrInvalidEmail            = errors.New("invalid email")
+	ErrEmailNotVerified        = errors.New("email isn't verified")
+	ErrInvalidVerificationLink = errors.New("email verification link is invalid or expired")
+	ErrInvalidPassword         = errors.New("password must be 8 to 30 letters, digits or underscores and start with a letter")
+	ErrMFAAlreadyEnabled       = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled           = errors.New("two-factor authentication isn't enabled")
+	ErrMFANotEnrolled          = errors.New("two-factor authentication isn't enrolled")
+	ErrInvalidMFACode          = errors.New("invalid two-factor authentication code")
+	ErrMFACodeReused           = fmt.Errorf("%w: code has already been used", ErrInvalidMFACode)
+	ErrInvalidMFAChallenge     = errors.New("two-factor authentication challenge is invalid or expired")
+	ErrAPIKeyNotFound          = errors.New("api key doesn't exists")
+	ErrInvalidAPIKey           = errors.New("api key is invalid, expired or revoked")
+	ErrInvalidAPIKeyName       = errors.New("api key name must be 1 to 100 characters")
+	ErrInvalidScope            = errors.New("scope must be resource:action")
+	ErrInvalidExpiry           = errors.New("expiry must be in the future")
+	ErrClientNotFound          = errors.New("client doesn't exists")
+	ErrInvalidClient           = errors.New("client authentication failed")
+	ErrInvalidClientName       = errors.New("client name must be 1 to 100 characters")
+	ErrScopeNotAllowed         = errors.New("requested scope isn't allowed for the client")
+	ErrImpersonationNotFound   = errors.New("impersonation doesn't exists")
+	ErrImpersonationForbidden  = errors.New("user can't be impersonated by the caller")
+	ErrReasonTooLong           = errors.New("impersonation reason must be at most 500 characters")
+	ErrInvalidMagicLink        = errors.New("magic link is invalid, expired or opened in another browser")
+	ErrMagicLinkDisabled       = errors.New("magic link sign in is disabled")
 )
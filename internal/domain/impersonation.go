@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Impersonation is an admin acting as the user with a short-lived access token, Id is
+// the jti of the token. Impersonations are kept apart from sessions, so the user signing
+// out everywhere doesn't end them and revoking one doesn't touch the user's sessions.
+type Impersonation struct {
+	Id        primitive.ObjectID `json:"id" bson:"_id"`
+	UserId    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	ActorId   primitive.ObjectID `json:"actor_id" bson:"actor_id"`
+	Reason    string             `json:"reason" bson:"reason"`
+	IP        string             `json:"ip" bson:"ip"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	ExpiresAt time.Time          `json:"expires_at" bson:"expires_at"`
+}
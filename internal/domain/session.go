@@ -1,8 +1,25 @@
 package domain
 
-import "time"
+import (
+	"time"
 
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session is a refresh token family of one device. Only RefreshTokenHash can be exchanged,
+// RotatedTokenHashes keeps the latest tokens already exchanged to detect their reuse.
+// AccessTokenId is the jti of the last access token issued for the session, it is
+// denylisted when the session is revoked.
 type Session struct {
-	RefreshToken string
-	ExpiresAt    time.Time
+	Id                   primitive.ObjectID `json:"id" bson:"_id"`
+	UserId               primitive.ObjectID `json:"-" bson:"user_id"`
+	RefreshTokenHash     string             `json:"-" bson:"refresh_token_hash"`
+	RotatedTokenHashes   []string           `json:"-" bson:"rotated_token_hashes,omitempty"`
+	AccessTokenId        string             `json:"-" bson:"access_token_id"`
+	AccessTokenExpiresAt time.Time          `json:"-" bson:"access_token_expires_at"`
+	UserAgent            string             `json:"user_agent" bson:"user_agent"`
+	IP                   string             `json:"ip" bson:"ip"`
+	CreatedAt            time.Time          `json:"created_at" bson:"created_at"`
+	LastUsedAt           time.Time          `json:"last_used_at" bson:"last_used_at"`
+	ExpiresAt            time.Time          `json:"expires_at" bson:"expires_at"`
 }
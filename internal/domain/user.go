@@ -1,10 +1,89 @@
 package domain
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type User struct {
 	Id           primitive.ObjectID `json:"-" bson:"_id,omitempty"`
 	PasswordHash string             `json:"-" bson:"password"`
 	Email        string             `json:"email" bson:"email"`
-	Session      Session            `json:"-" bson:"session,omitempty"`
+	// EmailVerified is set once the user follows the link sent to Email.
+	EmailVerified bool `json:"email_verified,omitempty" bson:"email_verified"`
+	// PendingEmail is the new email of the user, it replaces Email once verified.
+	PendingEmail string     `json:"pending_email,omitempty" bson:"pending_email,omitempty"`
+	Roles        []string   `json:"roles,omitempty" bson:"roles,omitempty"`
+	Identities   []Identity `json:"identities,omitempty" bson:"identities,omitempty"`
+	// PasswordReset is the pending password reset, it is removed once the password is reset.
+	PasswordReset *PasswordReset `json:"-" bson:"password_reset,omitempty"`
+	// MFA is the second factor, the user signs in with a code of it once it is enabled.
+	MFA *MFA `json:"-" bson:"mfa,omitempty"`
+	// MagicLink is the pending passwordless sign in, it is removed once the link is used.
+	MagicLink *MagicLink `json:"-" bson:"magic_link,omitempty"`
+}
+
+// PasswordReset keeps the hash of the reset token, the token itself is only sent to the user.
+type PasswordReset struct {
+	TokenHash string    `bson:"token_hash"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// MagicLink keeps the id of the signed link token and the hash of the secret kept by the
+// browser the link was requested from, the link signs in that browser only.
+type MagicLink struct {
+	TokenId     string    `bson:"token_id"`
+	BrowserHash string    `bson:"browser_hash"`
+	ExpiresAt   time.Time `bson:"expires_at"`
+}
+
+// MFA is a TOTP authenticator. It is enabled once the user confirms the secret with
+// the first code.
+type MFA struct {
+	Secret  string `bson:"secret"`
+	Enabled bool   `bson:"enabled"`
+	// LastStep is the time step of the last accepted code, codes of it and earlier
+	// steps are refused, so a code can't be replayed.
+	LastStep int64 `bson:"last_step"`
+	// RecoveryCodes are hashes of unused one-time recovery codes.
+	RecoveryCodes []string `bson:"recovery_codes,omitempty"`
+}
+
+// Identity is an account of the user at an external OAuth provider, it is a way
+// to sign in next to the password.
+type Identity struct {
+	Provider string    `json:"provider" bson:"provider"`
+	Subject  string    `json:"subject" bson:"subject"`
+	Email    string    `json:"email,omitempty" bson:"email,omitempty"`
+	LinkedAt time.Time `json:"linked_at" bson:"linked_at"`
+}
+
+func (u User) Identity(provider string) (Identity, bool) {
+	for _, identity := range u.Identities {
+		if identity.Provider == provider {
+			return identity, true
+		}
+	}
+	return Identity{}, false
+}
+
+func (u User) MFAEnabled() bool {
+	return u.MFA != nil && u.MFA.Enabled
+}
+
+func (u User) HasRole(role string) bool {
+	for _, r := range u.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeEmail makes the same address written in different case or with spaces around
+// it equal, emails are stored and looked up normalized.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
 }
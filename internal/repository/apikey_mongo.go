@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"test/internal/domain"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// createAPIKeyIndexes indexes keys by owner and hash and lets mongo drop them once they
+// expire. Keys without expires_at are kept until revoked.
+func (r *userRepository) createAPIKeyIndexes(ctx context.Context) error {
+	_, err := r.apiKeys.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "key_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
+
+func (r *userRepository) CreateAPIKey(ctx context.Context, key domain.APIKey) error {
+	if _, err := r.apiKeys.InsertOne(ctx, &key); err != nil {
+		return fmt.Errorf("failed to store api key due to error: %v", err)
+	}
+	return nil
+}
+
+func (r *userRepository) FindAPIKeys(ctx context.Context, userId primitive.ObjectID) (k []domain.APIKey, err error) {
+	filter := bson.M{"user_id": userId}
+	cursor, err := r.apiKeys.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return k, fmt.Errorf("failed to find api keys of user with oid=%s due to error: %v", userId, err)
+	}
+
+	if err = cursor.All(ctx, &k); err != nil {
+		return k, fmt.Errorf("failed to read all api keys from cursor due to error: %v", err)
+	}
+	return k, nil
+}
+
+// FindAPIKeyByHash returns the live key, the TTL index removes expired keys only once a minute.
+func (r *userRepository) FindAPIKeyByHash(ctx context.Context, keyHash string) (domain.APIKey, error) {
+
+	var key domain.APIKey
+	filter := bson.M{
+		"key_hash": keyHash,
+		"$or": bson.A{
+			bson.M{"expires_at": bson.M{"$exists": false}},
+			bson.M{"expires_at": bson.M{"$gt": time.Now()}},
+		},
+	}
+	if err := r.apiKeys.FindOne(ctx, filter).Decode(&key); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return domain.APIKey{}, domain.ErrAPIKeyNotFound
+		}
+		return domain.APIKey{}, fmt.Errorf("failed to find api key by hash due to error: %v", err)
+	}
+	return key, nil
+}
+
+func (r *userRepository) TouchAPIKey(ctx context.Context, keyId primitive.ObjectID, usedAt time.Time) error {
+	filter := bson.M{"_id": keyId}
+	update := bson.M{"$set": bson.M{"last_used_at": usedAt}}
+	if _, err := r.apiKeys.UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to update last use of api key with oid=%s due to error: %v", keyId, err)
+	}
+	return nil
+}
+
+func (r *userRepository) DeleteAPIKey(ctx context.Context, userId, keyId primitive.ObjectID) error {
+	filter := bson.M{"_id": keyId, "user_id": userId}
+	result, err := r.apiKeys.DeleteOne(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to delete api key with oid=%s due to error: %v", keyId, err)
+	}
+	if result.DeletedCount == 0 {
+		return domain.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) DeleteAPIKeys(ctx context.Context, userId primitive.ObjectID) error {
+	filter := bson.M{"user_id": userId}
+	if _, err := r.apiKeys.DeleteMany(ctx, filter); err != nil {
+		return fmt.Errorf("failed to delete api keys of user with oid=%s due to error: %v", userId, err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"test/pkg/lockout"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var _ lockout.Store = &attemptRepository{}
+
+type failedAttempts struct {
+	Key         string    `bson:"_id"`
+	Failures    int       `bson:"failures"`
+	LastFailure time.Time `bson:"last_failure"`
+	ExpiresAt   time.Time `bson:"expires_at"`
+}
+
+type attemptRepository struct {
+	collection *mongo.Collection
+}
+
+// NewAttemptRepository stores failed attempts in mongo, so every replica of the app counts them.
+// Documents are removed by a TTL index once they expire.
+func NewAttemptRepository(database *mongo.Database) lockout.Store {
+	r := &attemptRepository{
+		collection: database.Collection(failedAttemptsCollection),
+	}
+	_, err := r.collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		log.Printf("failed to create failed attempts indexes due to error: %v", err)
+	}
+	return r
+}
+
+func (r *attemptRepository) Get(ctx context.Context, key string) (lockout.Record, error) {
+	var attempts failedAttempts
+	filter := bson.M{"_id": key, "expires_at": bson.M{"$gt": time.Now()}}
+	if err := r.collection.FindOne(ctx, filter).Decode(&attempts); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return lockout.Record{}, nil
+		}
+		return lockout.Record{}, fmt.Errorf("failed to find failed attempts of key=%s due to error: %v", key, err)
+	}
+	return lockout.Record{Failures: attempts.Failures, LastFailure: attempts.LastFailure}, nil
+}
+
+// Fail increments the counter atomically and returns the document before it. The TTL
+// index removes documents only once a minute, so an expired document is deleted first
+// to start counting again.
+func (r *attemptRepository) Fail(ctx context.Context, key string, now time.Time, ttl time.Duration) (lockout.Record, error) {
+	expired := bson.M{"_id": key, "expires_at": bson.M{"$lte": now}}
+	if _, err := r.collection.DeleteOne(ctx, expired); err != nil {
+		return lockout.Record{}, fmt.Errorf("failed to delete expired failed attempts of key=%s due to error: %v", key, err)
+	}
+
+	filter := bson.M{"_id": key}
+	update := bson.M{
+		"$inc": bson.M{"failures": 1},
+		"$set": bson.M{"last_failure": now, "expires_at": now.Add(ttl)},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before)
+
+	var attempts failedAttempts
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&attempts)
+	if mongo.IsDuplicateKeyError(err) {
+		// Concurrent upserts of a new key, the other one has inserted the document.
+		err = r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&attempts)
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// The document was inserted, there were no failures before.
+		return lockout.Record{}, nil
+	}
+	if err != nil {
+		return lockout.Record{}, fmt.Errorf("failed to count failed attempt of key=%s due to error: %v", key, err)
+	}
+	return lockout.Record{Failures: attempts.Failures, LastFailure: attempts.LastFailure}, nil
+}
+
+func (r *attemptRepository) Forgive(ctx context.Context, key string) error {
+	filter := bson.M{"_id": key, "failures": bson.M{"$gt": 0}}
+	if _, err := r.collection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"failures": -1}}); err != nil {
+		return fmt.Errorf("failed to forgive failed attempt of key=%s due to error: %v", key, err)
+	}
+	return nil
+}
+
+func (r *attemptRepository) Reset(ctx context.Context, key string) error {
+	if _, err := r.collection.DeleteOne(ctx, bson.M{"_id": key}); err != nil {
+		return fmt.Errorf("failed to reset failed attempts of key=%s due to error: %v", key, err)
+	}
+	return nil
+}
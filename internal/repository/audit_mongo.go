@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"test/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var _ AuditRepository = &auditRepository{}
+
+type auditRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAuditRepository(database *mongo.Database) AuditRepository {
+	r := &auditRepository{
+		collection: database.Collection(auditCollection),
+	}
+	_, err := r.collection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "actor_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		log.Printf("failed to create audit log indexes due to error: %v", err)
+	}
+	return r
+}
+
+func (r *auditRepository) Create(ctx context.Context, event domain.AuditEvent) error {
+	if _, err := r.collection.InsertOne(ctx, &event); err != nil {
+		return fmt.Errorf("failed to store audit event due to error: %v", err)
+	}
+	return nil
+}
+
+// FindByUser returns the latest events on the user's account and by the user as an actor.
+func (r *auditRepository) FindByUser(ctx context.Context, userId primitive.ObjectID, limit int64) (e []domain.AuditEvent, err error) {
+	filter := bson.M{"$or": bson.A{bson.M{"user_id": userId}, bson.M{"actor_id": userId}}}
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return e, fmt.Errorf("failed to find audit events of user with oid=%s due to error: %v", userId, err)
+	}
+
+	if err = cursor.All(ctx, &e); err != nil {
+		return e, fmt.Errorf("failed to read all audit events from cursor due to error: %v", err)
+	}
+	return e, nil
+}
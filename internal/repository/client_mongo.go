@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"test/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var _ ClientRepository = &clientRepository{}
+
+type clientRepository struct {
+	collection *mongo.Collection
+}
+
+func NewClientRepository(database *mongo.Database) ClientRepository {
+	return &clientRepository{
+		collection: database.Collection(clientsCollection),
+	}
+}
+
+func (r *clientRepository) Create(ctx context.Context, client domain.Client) error {
+	if _, err := r.collection.InsertOne(ctx, &client); err != nil {
+		return fmt.Errorf("failed to store client due to error: %v", err)
+	}
+	return nil
+}
+
+func (r *clientRepository) FindOne(ctx context.Context, id string) (domain.Client, error) {
+	var client domain.Client
+	if err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&client); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return domain.Client{}, domain.ErrClientNotFound
+		}
+		return domain.Client{}, fmt.Errorf("failed to find client with id=%s due to error: %v", id, err)
+	}
+	return client, nil
+}
+
+func (r *clientRepository) FindAll(ctx context.Context) (c []domain.Client, err error) {
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return c, fmt.Errorf("failed to find clients due to error: %v", err)
+	}
+
+	if err = cursor.All(ctx, &c); err != nil {
+		return c, fmt.Errorf("failed to read all clients from cursor due to error: %v", err)
+	}
+	return c, nil
+}
+
+func (r *clientRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return fmt.Errorf("failed to delete client with id=%s due to error: %v", id, err)
+	}
+	if result.DeletedCount == 0 {
+		return domain.ErrClientNotFound
+	}
+	return nil
+}
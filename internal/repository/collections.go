@@ -1,5 +1,12 @@
 package repository
 
 const (
-	usersCollection = "users"
+	usersCollection          = "users"
+	sessionsCollection       = "sessions"
+	revokedTokensCollection  = "revoked_tokens"
+	failedAttemptsCollection = "failed_attempts"
+	apiKeysCollection        = "api_keys"
+	clientsCollection        = "clients"
+	impersonationsCollection = "impersonations"
+	auditCollection          = "audit_log"
 )
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"test/pkg/api/auth"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var _ auth.Denylist = &denylistRepository{}
+
+type revokedToken struct {
+	Id        string    `bson:"_id"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+type denylistRepository struct {
+	collection *mongo.Collection
+}
+
+// NewDenylistRepository stores revoked access tokens in mongo, so every replica of the app sees them.
+// Documents are removed by a TTL index once the token expires.
+func NewDenylistRepository(database *mongo.Database) auth.Denylist {
+	r := &denylistRepository{
+		collection: database.Collection(revokedTokensCollection),
+	}
+	_, err := r.collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		log.Printf("failed to create revoked tokens indexes due to error: %v", err)
+	}
+	return r
+}
+
+func (r *denylistRepository) Add(ctx context.Context, tokenId string, expiresAt time.Time) error {
+	filter := bson.M{"_id": tokenId}
+	update := bson.M{"$set": revokedToken{Id: tokenId, ExpiresAt: expiresAt}}
+
+	if _, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true)); err != nil {
+		return fmt.Errorf("failed to revoke token with jti=%s due to error: %v", tokenId, err)
+	}
+	return nil
+}
+
+func (r *denylistRepository) Contains(ctx context.Context, tokenId string) (bool, error) {
+	filter := bson.M{"_id": tokenId, "expires_at": bson.M{"$gt": time.Now()}}
+	if err := r.collection.FindOne(ctx, filter).Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check revoked token with jti=%s due to error: %v", tokenId, err)
+	}
+	return true, nil
+}
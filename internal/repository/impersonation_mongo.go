@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"test/internal/domain"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// createImpersonationIndexes lets mongo drop impersonations once their token expires,
+// the audit log keeps the history.
+func (r *userRepository) createImpersonationIndexes(ctx context.Context) error {
+	_, err := r.impersonations.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
+
+func (r *userRepository) CreateImpersonation(ctx context.Context, impersonation domain.Impersonation) error {
+	if _, err := r.impersonations.InsertOne(ctx, &impersonation); err != nil {
+		return fmt.Errorf("failed to store impersonation due to error: %v", err)
+	}
+	return nil
+}
+
+// FindImpersonations returns live impersonations of the user, the TTL index removes
+// expired ones only once a minute.
+func (r *userRepository) FindImpersonations(ctx context.Context, userId primitive.ObjectID) (i []domain.Impersonation, err error) {
+	filter := bson.M{"user_id": userId, "expires_at": bson.M{"$gt": time.Now()}}
+	cursor, err := r.impersonations.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return i, fmt.Errorf("failed to find impersonations of user with oid=%s due to error: %v", userId, err)
+	}
+
+	if err = cursor.All(ctx, &i); err != nil {
+		return i, fmt.Errorf("failed to read all impersonations from cursor due to error: %v", err)
+	}
+	return i, nil
+}
+
+func (r *userRepository) FindImpersonation(ctx context.Context, userId, impersonationId primitive.ObjectID) (domain.Impersonation, error) {
+
+	var impersonation domain.Impersonation
+	filter := bson.M{"_id": impersonationId, "user_id": userId, "expires_at": bson.M{"$gt": time.Now()}}
+	if err := r.impersonations.FindOne(ctx, filter).Decode(&impersonation); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return domain.Impersonation{}, domain.ErrImpersonationNotFound
+		}
+		return domain.Impersonation{}, fmt.Errorf("failed to find impersonation with oid=%s due to error: %v", impersonationId, err)
+	}
+	return impersonation, nil
+}
+
+func (r *userRepository) DeleteImpersonation(ctx context.Context, userId, impersonationId primitive.ObjectID) error {
+	filter := bson.M{"_id": impersonationId, "user_id": userId}
+	result, err := r.impersonations.DeleteOne(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to delete impersonation with oid=%s due to error: %v", impersonationId, err)
+	}
+	if result.DeletedCount == 0 {
+		return domain.ErrImpersonationNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) DeleteImpersonations(ctx context.Context, userId primitive.ObjectID) error {
+	filter := bson.M{"user_id": userId}
+	if _, err := r.impersonations.DeleteMany(ctx, filter); err != nil {
+		return fmt.Errorf("failed to delete impersonations of user with oid=%s due to error: %v", userId, err)
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	reflect "reflect"
 	domain "test/internal/domain"
 	api "test/pkg/api"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	primitive "go.mongodb.org/mongo-driver/bson/primitive"
@@ -37,6 +38,20 @@ func (m *MockUserRepository) EXPECT() *MockUserRepositoryMockRecorder {
 	return m.recorder
 }
 
+// AddRole mocks base method.
+func (m *MockUserRepository) AddRole(ctx context.Context, oid primitive.ObjectID, role string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddRole", ctx, oid, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddRole indicates an expected call of AddRole.
+func (mr *MockUserRepositoryMockRecorder) AddRole(ctx, oid, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddRole", reflect.TypeOf((*MockUserRepository)(nil).AddRole), ctx, oid, role)
+}
+
 // Create mocks base method.
 func (m *MockUserRepository) Create(ctx context.Context, user domain.User) (primitive.ObjectID, error) {
 	m.ctrl.T.Helper()
@@ -52,6 +67,48 @@ func (mr *MockUserRepositoryMockRecorder) Create(ctx, user interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockUserRepository)(nil).Create), ctx, user)
 }
 
+// CreateAPIKey mocks base method.
+func (m *MockUserRepository) CreateAPIKey(ctx context.Context, key domain.APIKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAPIKey", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAPIKey indicates an expected call of CreateAPIKey.
+func (mr *MockUserRepositoryMockRecorder) CreateAPIKey(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockUserRepository)(nil).CreateAPIKey), ctx, key)
+}
+
+// CreateImpersonation mocks base method.
+func (m *MockUserRepository) CreateImpersonation(ctx context.Context, impersonation domain.Impersonation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateImpersonation", ctx, impersonation)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateImpersonation indicates an expected call of CreateImpersonation.
+func (mr *MockUserRepositoryMockRecorder) CreateImpersonation(ctx, impersonation interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateImpersonation", reflect.TypeOf((*MockUserRepository)(nil).CreateImpersonation), ctx, impersonation)
+}
+
+// CreateSession mocks base method.
+func (m *MockUserRepository) CreateSession(ctx context.Context, session domain.Session) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSession", ctx, session)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateSession indicates an expected call of CreateSession.
+func (mr *MockUserRepositoryMockRecorder) CreateSession(ctx, session interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockUserRepository)(nil).CreateSession), ctx, session)
+}
+
 // Delete mocks base method.
 func (m *MockUserRepository) Delete(ctx context.Context, oid primitive.ObjectID) error {
 	m.ctrl.T.Helper()
@@ -66,6 +123,148 @@ func (mr *MockUserRepositoryMockRecorder) Delete(ctx, oid interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUserRepository)(nil).Delete), ctx, oid)
 }
 
+// DeleteAPIKey mocks base method.
+func (m *MockUserRepository) DeleteAPIKey(ctx context.Context, userId, keyId primitive.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAPIKey", ctx, userId, keyId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAPIKey indicates an expected call of DeleteAPIKey.
+func (mr *MockUserRepositoryMockRecorder) DeleteAPIKey(ctx, userId, keyId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAPIKey", reflect.TypeOf((*MockUserRepository)(nil).DeleteAPIKey), ctx, userId, keyId)
+}
+
+// DeleteAPIKeys mocks base method.
+func (m *MockUserRepository) DeleteAPIKeys(ctx context.Context, userId primitive.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAPIKeys", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAPIKeys indicates an expected call of DeleteAPIKeys.
+func (mr *MockUserRepositoryMockRecorder) DeleteAPIKeys(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAPIKeys", reflect.TypeOf((*MockUserRepository)(nil).DeleteAPIKeys), ctx, userId)
+}
+
+// DeleteImpersonation mocks base method.
+func (m *MockUserRepository) DeleteImpersonation(ctx context.Context, userId, impersonationId primitive.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteImpersonation", ctx, userId, impersonationId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteImpersonation indicates an expected call of DeleteImpersonation.
+func (mr *MockUserRepositoryMockRecorder) DeleteImpersonation(ctx, userId, impersonationId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteImpersonation", reflect.TypeOf((*MockUserRepository)(nil).DeleteImpersonation), ctx, userId, impersonationId)
+}
+
+// DeleteImpersonations mocks base method.
+func (m *MockUserRepository) DeleteImpersonations(ctx context.Context, userId primitive.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteImpersonations", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteImpersonations indicates an expected call of DeleteImpersonations.
+func (mr *MockUserRepositoryMockRecorder) DeleteImpersonations(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteImpersonations", reflect.TypeOf((*MockUserRepository)(nil).DeleteImpersonations), ctx, userId)
+}
+
+// DeleteSession mocks base method.
+func (m *MockUserRepository) DeleteSession(ctx context.Context, userId, sessionId primitive.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSession", ctx, userId, sessionId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSession indicates an expected call of DeleteSession.
+func (mr *MockUserRepositoryMockRecorder) DeleteSession(ctx, userId, sessionId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSession", reflect.TypeOf((*MockUserRepository)(nil).DeleteSession), ctx, userId, sessionId)
+}
+
+// DeleteSessions mocks base method.
+func (m *MockUserRepository) DeleteSessions(ctx context.Context, userId primitive.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSessions", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSessions indicates an expected call of DeleteSessions.
+func (mr *MockUserRepositoryMockRecorder) DeleteSessions(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSessions", reflect.TypeOf((*MockUserRepository)(nil).DeleteSessions), ctx, userId)
+}
+
+// DisableMFA mocks base method.
+func (m *MockUserRepository) DisableMFA(ctx context.Context, oid primitive.ObjectID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableMFA", ctx, oid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableMFA indicates an expected call of DisableMFA.
+func (mr *MockUserRepositoryMockRecorder) DisableMFA(ctx, oid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableMFA", reflect.TypeOf((*MockUserRepository)(nil).DisableMFA), ctx, oid)
+}
+
+// EnableMFA mocks base method.
+func (m *MockUserRepository) EnableMFA(ctx context.Context, oid primitive.ObjectID, secret string, step int64, recoveryCodes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableMFA", ctx, oid, secret, step, recoveryCodes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableMFA indicates an expected call of EnableMFA.
+func (mr *MockUserRepositoryMockRecorder) EnableMFA(ctx, oid, secret, step, recoveryCodes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableMFA", reflect.TypeOf((*MockUserRepository)(nil).EnableMFA), ctx, oid, secret, step, recoveryCodes)
+}
+
+// FindAPIKeyByHash mocks base method.
+func (m *MockUserRepository) FindAPIKeyByHash(ctx context.Context, keyHash string) (domain.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAPIKeyByHash", ctx, keyHash)
+	ret0, _ := ret[0].(domain.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAPIKeyByHash indicates an expected call of FindAPIKeyByHash.
+func (mr *MockUserRepositoryMockRecorder) FindAPIKeyByHash(ctx, keyHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAPIKeyByHash", reflect.TypeOf((*MockUserRepository)(nil).FindAPIKeyByHash), ctx, keyHash)
+}
+
+// FindAPIKeys mocks base method.
+func (m *MockUserRepository) FindAPIKeys(ctx context.Context, userId primitive.ObjectID) ([]domain.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAPIKeys", ctx, userId)
+	ret0, _ := ret[0].([]domain.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAPIKeys indicates an expected call of FindAPIKeys.
+func (mr *MockUserRepositoryMockRecorder) FindAPIKeys(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAPIKeys", reflect.TypeOf((*MockUserRepository)(nil).FindAPIKeys), ctx, userId)
+}
+
 // FindAll mocks base method.
 func (m *MockUserRepository) FindAll(ctx context.Context, pagination api.Pagination, filters []api.Filters, sortOptions []api.Options) ([]domain.User, error) {
 	m.ctrl.T.Helper()
@@ -96,6 +295,51 @@ func (mr *MockUserRepositoryMockRecorder) FindByEmail(ctx, email interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByEmail", reflect.TypeOf((*MockUserRepository)(nil).FindByEmail), ctx, email)
 }
 
+// FindByIdentity mocks base method.
+func (m *MockUserRepository) FindByIdentity(ctx context.Context, provider, subject string) (domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByIdentity", ctx, provider, subject)
+	ret0, _ := ret[0].(domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByIdentity indicates an expected call of FindByIdentity.
+func (mr *MockUserRepositoryMockRecorder) FindByIdentity(ctx, provider, subject interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByIdentity", reflect.TypeOf((*MockUserRepository)(nil).FindByIdentity), ctx, provider, subject)
+}
+
+// FindImpersonation mocks base method.
+func (m *MockUserRepository) FindImpersonation(ctx context.Context, userId, impersonationId primitive.ObjectID) (domain.Impersonation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindImpersonation", ctx, userId, impersonationId)
+	ret0, _ := ret[0].(domain.Impersonation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindImpersonation indicates an expected call of FindImpersonation.
+func (mr *MockUserRepositoryMockRecorder) FindImpersonation(ctx, userId, impersonationId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindImpersonation", reflect.TypeOf((*MockUserRepository)(nil).FindImpersonation), ctx, userId, impersonationId)
+}
+
+// FindImpersonations mocks base method.
+func (m *MockUserRepository) FindImpersonations(ctx context.Context, userId primitive.ObjectID) ([]domain.Impersonation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindImpersonations", ctx, userId)
+	ret0, _ := ret[0].([]domain.Impersonation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindImpersonations indicates an expected call of FindImpersonations.
+func (mr *MockUserRepositoryMockRecorder) FindImpersonations(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindImpersonations", reflect.TypeOf((*MockUserRepository)(nil).FindImpersonations), ctx, userId)
+}
+
 // FindOne mocks base method.
 func (m *MockUserRepository) FindOne(ctx context.Context, oid primitive.ObjectID) (domain.User, error) {
 	m.ctrl.T.Helper()
@@ -111,33 +355,205 @@ func (mr *MockUserRepositoryMockRecorder) FindOne(ctx, oid interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOne", reflect.TypeOf((*MockUserRepository)(nil).FindOne), ctx, oid)
 }
 
-// GetUserByRefreshToken mocks base method.
-func (m *MockUserRepository) GetUserByRefreshToken(ctx context.Context, id primitive.ObjectID) (domain.User, error) {
+// FindSession mocks base method.
+func (m *MockUserRepository) FindSession(ctx context.Context, userId, sessionId primitive.ObjectID) (domain.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindSession", ctx, userId, sessionId)
+	ret0, _ := ret[0].(domain.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindSession indicates an expected call of FindSession.
+func (mr *MockUserRepositoryMockRecorder) FindSession(ctx, userId, sessionId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindSession", reflect.TypeOf((*MockUserRepository)(nil).FindSession), ctx, userId, sessionId)
+}
+
+// FindSessions mocks base method.
+func (m *MockUserRepository) FindSessions(ctx context.Context, userId primitive.ObjectID) ([]domain.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindSessions", ctx, userId)
+	ret0, _ := ret[0].([]domain.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindSessions indicates an expected call of FindSessions.
+func (mr *MockUserRepositoryMockRecorder) FindSessions(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindSessions", reflect.TypeOf((*MockUserRepository)(nil).FindSessions), ctx, userId)
+}
+
+// GetSessionByRefreshToken mocks base method.
+func (m *MockUserRepository) GetSessionByRefreshToken(ctx context.Context, refreshTokenHash string) (domain.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessionByRefreshToken", ctx, refreshTokenHash)
+	ret0, _ := ret[0].(domain.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSessionByRefreshToken indicates an expected call of GetSessionByRefreshToken.
+func (mr *MockUserRepositoryMockRecorder) GetSessionByRefreshToken(ctx, refreshTokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessionByRefreshToken", reflect.TypeOf((*MockUserRepository)(nil).GetSessionByRefreshToken), ctx, refreshTokenHash)
+}
+
+// GetSessionByRotatedToken mocks base method.
+func (m *MockUserRepository) GetSessionByRotatedToken(ctx context.Context, refreshTokenHash string) (domain.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessionByRotatedToken", ctx, refreshTokenHash)
+	ret0, _ := ret[0].(domain.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSessionByRotatedToken indicates an expected call of GetSessionByRotatedToken.
+func (mr *MockUserRepositoryMockRecorder) GetSessionByRotatedToken(ctx, refreshTokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessionByRotatedToken", reflect.TypeOf((*MockUserRepository)(nil).GetSessionByRotatedToken), ctx, refreshTokenHash)
+}
+
+// LinkIdentity mocks base method.
+func (m *MockUserRepository) LinkIdentity(ctx context.Context, oid primitive.ObjectID, identity domain.Identity) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkIdentity", ctx, oid, identity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LinkIdentity indicates an expected call of LinkIdentity.
+func (mr *MockUserRepositoryMockRecorder) LinkIdentity(ctx, oid, identity interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkIdentity", reflect.TypeOf((*MockUserRepository)(nil).LinkIdentity), ctx, oid, identity)
+}
+
+// RemoveRole mocks base method.
+func (m *MockUserRepository) RemoveRole(ctx context.Context, oid primitive.ObjectID, role string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveRole", ctx, oid, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveRole indicates an expected call of RemoveRole.
+func (mr *MockUserRepositoryMockRecorder) RemoveRole(ctx, oid, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveRole", reflect.TypeOf((*MockUserRepository)(nil).RemoveRole), ctx, oid, role)
+}
+
+// ResetPassword mocks base method.
+func (m *MockUserRepository) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (domain.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserByRefreshToken", ctx, id)
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, tokenHash, passwordHash)
 	ret0, _ := ret[0].(domain.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetUserByRefreshToken indicates an expected call of GetUserByRefreshToken.
-func (mr *MockUserRepositoryMockRecorder) GetUserByRefreshToken(ctx, id interface{}) *gomock.Call {
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockUserRepositoryMockRecorder) ResetPassword(ctx, tokenHash, passwordHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockUserRepository)(nil).ResetPassword), ctx, tokenHash, passwordHash)
+}
+
+// RotateSession mocks base method.
+func (m *MockUserRepository) RotateSession(ctx context.Context, session domain.Session, previousTokenHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateSession", ctx, session, previousTokenHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RotateSession indicates an expected call of RotateSession.
+func (mr *MockUserRepositoryMockRecorder) RotateSession(ctx, session, previousTokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateSession", reflect.TypeOf((*MockUserRepository)(nil).RotateSession), ctx, session, previousTokenHash)
+}
+
+// SetMFASecret mocks base method.
+func (m *MockUserRepository) SetMFASecret(ctx context.Context, oid primitive.ObjectID, secret string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMFASecret", ctx, oid, secret)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetMFASecret indicates an expected call of SetMFASecret.
+func (mr *MockUserRepositoryMockRecorder) SetMFASecret(ctx, oid, secret interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByRefreshToken", reflect.TypeOf((*MockUserRepository)(nil).GetUserByRefreshToken), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMFASecret", reflect.TypeOf((*MockUserRepository)(nil).SetMFASecret), ctx, oid, secret)
 }
 
-// SetSession mocks base method.
-func (m *MockUserRepository) SetSession(ctx context.Context, oid primitive.ObjectID, session domain.Session) error {
+// SetMagicLink mocks base method.
+func (m *MockUserRepository) SetMagicLink(ctx context.Context, oid primitive.ObjectID, link domain.MagicLink) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetSession", ctx, oid, session)
+	ret := m.ctrl.Call(m, "SetMagicLink", ctx, oid, link)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// SetSession indicates an expected call of SetSession.
-func (mr *MockUserRepositoryMockRecorder) SetSession(ctx, oid, session interface{}) *gomock.Call {
+// SetMagicLink indicates an expected call of SetMagicLink.
+func (mr *MockUserRepositoryMockRecorder) SetMagicLink(ctx, oid, link interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSession", reflect.TypeOf((*MockUserRepository)(nil).SetSession), ctx, oid, session)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMagicLink", reflect.TypeOf((*MockUserRepository)(nil).SetMagicLink), ctx, oid, link)
+}
+
+// SetPasswordReset mocks base method.
+func (m *MockUserRepository) SetPasswordReset(ctx context.Context, oid primitive.ObjectID, reset domain.PasswordReset) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPasswordReset", ctx, oid, reset)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPasswordReset indicates an expected call of SetPasswordReset.
+func (mr *MockUserRepositoryMockRecorder) SetPasswordReset(ctx, oid, reset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPasswordReset", reflect.TypeOf((*MockUserRepository)(nil).SetPasswordReset), ctx, oid, reset)
+}
+
+// SetPendingEmail mocks base method.
+func (m *MockUserRepository) SetPendingEmail(ctx context.Context, oid primitive.ObjectID, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPendingEmail", ctx, oid, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPendingEmail indicates an expected call of SetPendingEmail.
+func (mr *MockUserRepositoryMockRecorder) SetPendingEmail(ctx, oid, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPendingEmail", reflect.TypeOf((*MockUserRepository)(nil).SetPendingEmail), ctx, oid, email)
+}
+
+// TouchAPIKey mocks base method.
+func (m *MockUserRepository) TouchAPIKey(ctx context.Context, keyId primitive.ObjectID, usedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchAPIKey", ctx, keyId, usedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchAPIKey indicates an expected call of TouchAPIKey.
+func (mr *MockUserRepositoryMockRecorder) TouchAPIKey(ctx, keyId, usedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchAPIKey", reflect.TypeOf((*MockUserRepository)(nil).TouchAPIKey), ctx, keyId, usedAt)
+}
+
+// UnlinkIdentity mocks base method.
+func (m *MockUserRepository) UnlinkIdentity(ctx context.Context, oid primitive.ObjectID, provider string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlinkIdentity", ctx, oid, provider)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnlinkIdentity indicates an expected call of UnlinkIdentity.
+func (mr *MockUserRepositoryMockRecorder) UnlinkIdentity(ctx, oid, provider interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlinkIdentity", reflect.TypeOf((*MockUserRepository)(nil).UnlinkIdentity), ctx, oid, provider)
 }
 
 // Update mocks base method.
@@ -153,3 +569,207 @@ func (mr *MockUserRepositoryMockRecorder) Update(ctx, user interface{}) *gomock.
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockUserRepository)(nil).Update), ctx, user)
 }
+
+// UpdatePasswordHash mocks base method.
+func (m *MockUserRepository) UpdatePasswordHash(ctx context.Context, oid primitive.ObjectID, passwordHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePasswordHash", ctx, oid, passwordHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePasswordHash indicates an expected call of UpdatePasswordHash.
+func (mr *MockUserRepositoryMockRecorder) UpdatePasswordHash(ctx, oid, passwordHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePasswordHash", reflect.TypeOf((*MockUserRepository)(nil).UpdatePasswordHash), ctx, oid, passwordHash)
+}
+
+// UseMFAStep mocks base method.
+func (m *MockUserRepository) UseMFAStep(ctx context.Context, oid primitive.ObjectID, step int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseMFAStep", ctx, oid, step)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseMFAStep indicates an expected call of UseMFAStep.
+func (mr *MockUserRepositoryMockRecorder) UseMFAStep(ctx, oid, step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseMFAStep", reflect.TypeOf((*MockUserRepository)(nil).UseMFAStep), ctx, oid, step)
+}
+
+// UseMagicLink mocks base method.
+func (m *MockUserRepository) UseMagicLink(ctx context.Context, oid primitive.ObjectID, tokenId, browserHash string) (domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseMagicLink", ctx, oid, tokenId, browserHash)
+	ret0, _ := ret[0].(domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseMagicLink indicates an expected call of UseMagicLink.
+func (mr *MockUserRepositoryMockRecorder) UseMagicLink(ctx, oid, tokenId, browserHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseMagicLink", reflect.TypeOf((*MockUserRepository)(nil).UseMagicLink), ctx, oid, tokenId, browserHash)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockUserRepository) UseRecoveryCode(ctx context.Context, oid primitive.ObjectID, codeHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", ctx, oid, codeHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockUserRepositoryMockRecorder) UseRecoveryCode(ctx, oid, codeHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockUserRepository)(nil).UseRecoveryCode), ctx, oid, codeHash)
+}
+
+// VerifyEmail mocks base method.
+func (m *MockUserRepository) VerifyEmail(ctx context.Context, oid primitive.ObjectID, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEmail", ctx, oid, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyEmail indicates an expected call of VerifyEmail.
+func (mr *MockUserRepositoryMockRecorder) VerifyEmail(ctx, oid, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockUserRepository)(nil).VerifyEmail), ctx, oid, email)
+}
+
+// MockClientRepository is a mock of ClientRepository interface.
+type MockClientRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockClientRepositoryMockRecorder
+}
+
+// MockClientRepositoryMockRecorder is the mock recorder for MockClientRepository.
+type MockClientRepositoryMockRecorder struct {
+	mock *MockClientRepository
+}
+
+// NewMockClientRepository creates a new mock instance.
+func NewMockClientRepository(ctrl *gomock.Controller) *MockClientRepository {
+	mock := &MockClientRepository{ctrl: ctrl}
+	mock.recorder = &MockClientRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockClientRepository) EXPECT() *MockClientRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockClientRepository) Create(ctx context.Context, client domain.Client) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, client)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockClientRepositoryMockRecorder) Create(ctx, client interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockClientRepository)(nil).Create), ctx, client)
+}
+
+// Delete mocks base method.
+func (m *MockClientRepository) Delete(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockClientRepositoryMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockClientRepository)(nil).Delete), ctx, id)
+}
+
+// FindAll mocks base method.
+func (m *MockClientRepository) FindAll(ctx context.Context) ([]domain.Client, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAll", ctx)
+	ret0, _ := ret[0].([]domain.Client)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAll indicates an expected call of FindAll.
+func (mr *MockClientRepositoryMockRecorder) FindAll(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAll", reflect.TypeOf((*MockClientRepository)(nil).FindAll), ctx)
+}
+
+// FindOne mocks base method.
+func (m *MockClientRepository) FindOne(ctx context.Context, id string) (domain.Client, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOne", ctx, id)
+	ret0, _ := ret[0].(domain.Client)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOne indicates an expected call of FindOne.
+func (mr *MockClientRepositoryMockRecorder) FindOne(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOne", reflect.TypeOf((*MockClientRepository)(nil).FindOne), ctx, id)
+}
+
+// MockAuditRepository is a mock of AuditRepository interface.
+type MockAuditRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditRepositoryMockRecorder
+}
+
+// MockAuditRepositoryMockRecorder is the mock recorder for MockAuditRepository.
+type MockAuditRepositoryMockRecorder struct {
+	mock *MockAuditRepository
+}
+
+// NewMockAuditRepository creates a new mock instance.
+func NewMockAuditRepository(ctrl *gomock.Controller) *MockAuditRepository {
+	mock := &MockAuditRepository{ctrl: ctrl}
+	mock.recorder = &MockAuditRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditRepository) EXPECT() *MockAuditRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockAuditRepository) Create(ctx context.Context, event domain.AuditEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockAuditRepositoryMockRecorder) Create(ctx, event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockAuditRepository)(nil).Create), ctx, event)
+}
+
+// FindByUser mocks base method.
+func (m *MockAuditRepository) FindByUser(ctx context.Context, userId primitive.ObjectID, limit int64) ([]domain.AuditEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByUser", ctx, userId, limit)
+	ret0, _ := ret[0].([]domain.AuditEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByUser indicates an expected call of FindByUser.
+func (mr *MockAuditRepositoryMockRecorder) FindByUser(ctx, userId, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByUser", reflect.TypeOf((*MockAuditRepository)(nil).FindByUser), ctx, userId, limit)
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"test/internal/domain"
 	"test/pkg/api"
+	"test/pkg/api/auth"
+	"test/pkg/lockout"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -14,19 +17,74 @@ type UserRepository interface {
 	Create(ctx context.Context, user domain.User) (primitive.ObjectID, error)
 	FindOne(ctx context.Context, oid primitive.ObjectID) (domain.User, error)
 	FindByEmail(ctx context.Context, email string) (domain.User, error)
+	FindByIdentity(ctx context.Context, provider, subject string) (domain.User, error)
+	LinkIdentity(ctx context.Context, oid primitive.ObjectID, identity domain.Identity) error
+	UnlinkIdentity(ctx context.Context, oid primitive.ObjectID, provider string) error
 	FindAll(ctx context.Context, pagination api.Pagination, filters []api.Filters, sortOptions []api.Options) (u []domain.User, err error)
 	Update(ctx context.Context, user domain.User) error
+	UpdatePasswordHash(ctx context.Context, oid primitive.ObjectID, passwordHash string) error
+	SetPasswordReset(ctx context.Context, oid primitive.ObjectID, reset domain.PasswordReset) error
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string) (domain.User, error)
+	SetMagicLink(ctx context.Context, oid primitive.ObjectID, link domain.MagicLink) error
+	UseMagicLink(ctx context.Context, oid primitive.ObjectID, tokenId, browserHash string) (domain.User, error)
+	SetPendingEmail(ctx context.Context, oid primitive.ObjectID, email string) error
+	VerifyEmail(ctx context.Context, oid primitive.ObjectID, email string) error
+	SetMFASecret(ctx context.Context, oid primitive.ObjectID, secret string) error
+	EnableMFA(ctx context.Context, oid primitive.ObjectID, secret string, step int64, recoveryCodes []string) error
+	UseMFAStep(ctx context.Context, oid primitive.ObjectID, step int64) error
+	UseRecoveryCode(ctx context.Context, oid primitive.ObjectID, codeHash string) error
+	DisableMFA(ctx context.Context, oid primitive.ObjectID) error
 	Delete(ctx context.Context, oid primitive.ObjectID) error
-	SetSession(ctx context.Context, oid primitive.ObjectID, session domain.Session) error
-	GetUserByRefreshToken(ctx context.Context, id primitive.ObjectID) (domain.User, error)
+	AddRole(ctx context.Context, oid primitive.ObjectID, role string) error
+	RemoveRole(ctx context.Context, oid primitive.ObjectID, role string) error
+	CreateSession(ctx context.Context, session domain.Session) error
+	RotateSession(ctx context.Context, session domain.Session, previousTokenHash string) error
+	GetSessionByRefreshToken(ctx context.Context, refreshTokenHash string) (domain.Session, error)
+	GetSessionByRotatedToken(ctx context.Context, refreshTokenHash string) (domain.Session, error)
+	FindSessions(ctx context.Context, userId primitive.ObjectID) ([]domain.Session, error)
+	FindSession(ctx context.Context, userId, sessionId primitive.ObjectID) (domain.Session, error)
+	DeleteSession(ctx context.Context, userId, sessionId primitive.ObjectID) error
+	DeleteSessions(ctx context.Context, userId primitive.ObjectID) error
+	CreateAPIKey(ctx context.Context, key domain.APIKey) error
+	FindAPIKeys(ctx context.Context, userId primitive.ObjectID) ([]domain.APIKey, error)
+	FindAPIKeyByHash(ctx context.Context, keyHash string) (domain.APIKey, error)
+	TouchAPIKey(ctx context.Context, keyId primitive.ObjectID, usedAt time.Time) error
+	DeleteAPIKey(ctx context.Context, userId, keyId primitive.ObjectID) error
+	DeleteAPIKeys(ctx context.Context, userId primitive.ObjectID) error
+	CreateImpersonation(ctx context.Context, impersonation domain.Impersonation) error
+	FindImpersonations(ctx context.Context, userId primitive.ObjectID) ([]domain.Impersonation, error)
+	FindImpersonation(ctx context.Context, userId, impersonationId primitive.ObjectID) (domain.Impersonation, error)
+	DeleteImpersonation(ctx context.Context, userId, impersonationId primitive.ObjectID) error
+	DeleteImpersonations(ctx context.Context, userId primitive.ObjectID) error
+}
+
+type ClientRepository interface {
+	Create(ctx context.Context, client domain.Client) error
+	FindOne(ctx context.Context, id string) (domain.Client, error)
+	FindAll(ctx context.Context) ([]domain.Client, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// AuditRepository is append only, events are never updated or deleted.
+type AuditRepository interface {
+	Create(ctx context.Context, event domain.AuditEvent) error
+	FindByUser(ctx context.Context, userId primitive.ObjectID, limit int64) ([]domain.AuditEvent, error)
 }
 
 type Repository struct {
 	UserRepositiry UserRepository
+	Denylist       auth.Denylist
+	Attempts       lockout.Store
+	Clients        ClientRepository
+	Audit          AuditRepository
 }
 
 func NewRepository(db *mongo.Database) *Repository {
 	return &Repository{
 		UserRepositiry: NewUserRepository(db),
+		Denylist:       NewDenylistRepository(db),
+		Attempts:       NewAttemptRepository(db),
+		Clients:        NewClientRepository(db),
+		Audit:          NewAuditRepository(db),
 	}
 }
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"test/internal/domain"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// rotatedTokenHashesLimit caps the refresh tokens a session remembers to detect reuse of,
+// so long-lived sessions don't grow without bound. Older tokens are just unknown.
+const rotatedTokenHashesLimit = 50
+
+// createSessionIndexes indexes sessions by owner and lets mongo drop them once they expire.
+func (r *userRepository) createSessionIndexes(ctx context.Context) error {
+	_, err := r.sessions.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "refresh_token_hash", Value: 1}}},
+		{Keys: bson.D{{Key: "rotated_token_hashes", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
+
+func (r *userRepository) CreateSession(ctx context.Context, session domain.Session) error {
+	if _, err := r.sessions.InsertOne(ctx, &session); err != nil {
+		return fmt.Errorf("failed to store session due to error: %v", err)
+	}
+	return nil
+}
+
+// RotateSession replaces the refresh token of the session only if it is still previousTokenHash,
+// so two concurrent exchanges of the same token can't both succeed. The last
+// rotatedTokenHashesLimit replaced tokens are kept to detect their reuse.
+func (r *userRepository) RotateSession(ctx context.Context, session domain.Session, previousTokenHash string) error {
+	filter := bson.M{"_id": session.Id, "user_id": session.UserId, "refresh_token_hash": previousTokenHash}
+	update := bson.M{
+		"$set": bson.M{
+			"refresh_token_hash":      session.RefreshTokenHash,
+			"access_token_id":         session.AccessTokenId,
+			"access_token_expires_at": session.AccessTokenExpiresAt,
+			"user_agent":              session.UserAgent,
+			"ip":                      session.IP,
+			"last_used_at":            session.LastUsedAt,
+			"expires_at":              session.ExpiresAt,
+		},
+		"$push": bson.M{"rotated_token_hashes": bson.M{
+			"$each":  bson.A{previousTokenHash},
+			"$slice": -rotatedTokenHashesLimit,
+		}},
+	}
+
+	result, err := r.sessions.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to rotate session with oid=%s due to error: %v", session.Id, err)
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrRefreshTokenReused
+	}
+	return nil
+}
+
+func (r *userRepository) GetSessionByRefreshToken(ctx context.Context, refreshTokenHash string) (domain.Session, error) {
+
+	var session domain.Session
+	filter := bson.M{
+		"refresh_token_hash": refreshTokenHash,
+		"expires_at":         bson.M{"$gt": time.Now()},
+	}
+	if err := r.sessions.FindOne(ctx, filter).Decode(&session); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return domain.Session{}, domain.ErrSessionNotFound
+		}
+		return domain.Session{}, fmt.Errorf("failed to find session by refresh token due to error: %v", err)
+	}
+	return session, nil
+}
+
+func (r *userRepository) GetSessionByRotatedToken(ctx context.Context, refreshTokenHash string) (domain.Session, error) {
+
+	var session domain.Session
+	filter := bson.M{"rotated_token_hashes": refreshTokenHash}
+	if err := r.sessions.FindOne(ctx, filter).Decode(&session); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return domain.Session{}, domain.ErrSessionNotFound
+		}
+		return domain.Session{}, fmt.Errorf("failed to find session by rotated refresh token due to error: %v", err)
+	}
+	return session, nil
+}
+
+func (r *userRepository) FindSessions(ctx context.Context, userId primitive.ObjectID) (s []domain.Session, err error) {
+	filter := bson.M{
+		"user_id":    userId,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+	cursor, err := r.sessions.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "last_used_at", Value: -1}}))
+	if err != nil {
+		return s, fmt.Errorf("failed to find sessions of user with oid=%s due to error: %v", userId, err)
+	}
+
+	if err = cursor.All(ctx, &s); err != nil {
+		return s, fmt.Errorf("failed to read all sessions from cursor due to error: %v", err)
+	}
+	return s, nil
+}
+
+func (r *userRepository) FindSession(ctx context.Context, userId, sessionId primitive.ObjectID) (domain.Session, error) {
+
+	var session domain.Session
+	filter := bson.M{"_id": sessionId, "user_id": userId}
+	if err := r.sessions.FindOne(ctx, filter).Decode(&session); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return domain.Session{}, domain.ErrSessionNotFound
+		}
+		return domain.Session{}, fmt.Errorf("failed to find session with oid=%s due to error: %v", sessionId, err)
+	}
+	return session, nil
+}
+
+func (r *userRepository) DeleteSession(ctx context.Context, userId, sessionId primitive.ObjectID) error {
+	filter := bson.M{"_id": sessionId, "user_id": userId}
+	result, err := r.sessions.DeleteOne(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to delete session with oid=%s due to error: %v", sessionId, err)
+	}
+	if result.DeletedCount == 0 {
+		return domain.ErrSessionNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) DeleteSessions(ctx context.Context, userId primitive.ObjectID) error {
+	filter := bson.M{"user_id": userId}
+	if _, err := r.sessions.DeleteMany(ctx, filter); err != nil {
+		return fmt.Errorf("failed to delete sessions of user with oid=%s due to error: %v", userId, err)
+	}
+	return nil
+}
@@ -4,33 +4,119 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"test/internal/domain"
 	"test/pkg/api"
-
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var _ UserRepository = &userRepository{}
 
 type userRepository struct {
-	collection *mongo.Collection
+	collection     *mongo.Collection
+	sessions       *mongo.Collection
+	apiKeys        *mongo.Collection
+	impersonations *mongo.Collection
 }
 
 func NewUserRepository(database *mongo.Database) UserRepository {
-	return &userRepository{
-		collection: database.Collection(usersCollection),
+	r := &userRepository{
+		collection:     database.Collection(usersCollection),
+		sessions:       database.Collection(sessionsCollection),
+		apiKeys:        database.Collection(apiKeysCollection),
+		impersonations: database.Collection(impersonationsCollection),
+	}
+	if err := r.migrateGoogleIds(context.Background()); err != nil {
+		log.Printf("failed to migrate google ids due to error: %v", err)
+	}
+	if err := r.createUserIndexes(context.Background()); err != nil {
+		log.Printf("failed to create users indexes due to error: %v", err)
+	}
+	if err := r.migrateEmails(context.Background()); err != nil {
+		log.Printf("failed to normalize emails due to error: %v", err)
+	}
+	// Sign up and sign in rely on emails being unique, so the server doesn't start
+	// without the index. It fails when users share an email, they have to be merged first.
+	if err := r.createEmailIndex(context.Background()); err != nil {
+		log.Fatalf("failed to create unique users email index due to error: %v", err)
+	}
+	if err := r.createSessionIndexes(context.Background()); err != nil {
+		log.Printf("failed to create sessions indexes due to error: %v", err)
 	}
+	if err := r.createAPIKeyIndexes(context.Background()); err != nil {
+		log.Printf("failed to create api keys indexes due to error: %v", err)
+	}
+	if err := r.createImpersonationIndexes(context.Background()); err != nil {
+		log.Printf("failed to create impersonations indexes due to error: %v", err)
+	}
+	return r
+}
+
+// createUserIndexes makes sure an external account is linked to one user at most
+// and lets users be found by the password reset token.
+func (r *userRepository) createUserIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "identities.provider", Value: 1}, {Key: "identities.subject", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "password_reset.token_hash", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+	})
+	return err
+}
+
+// createEmailIndex makes sure an email belongs to one user at most. Emails are stored
+// normalized, so the same address in another case is the same email.
+func (r *userRepository) createEmailIndex(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "email", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// migrateEmails normalizes emails stored before they were normalized on write.
+func (r *userRepository) migrateEmails(ctx context.Context) error {
+	filter := bson.M{"email": bson.M{"$regex": `[A-Z]|^\s|\s$`}}
+	result, err := r.collection.UpdateMany(ctx, filter, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"email": bson.M{"$toLower": bson.M{"$trim": bson.M{"input": "$email"}}}}}},
+	})
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount > 0 {
+		log.Printf("normalized emails of %d users", result.ModifiedCount)
+	}
+	return nil
+}
+
+// migrateGoogleIds moves Google accounts linked before providers became configurable
+// into identities.
+func (r *userRepository) migrateGoogleIds(ctx context.Context) error {
+	_, err := r.collection.UpdateMany(ctx, bson.M{"google_id": bson.M{"$exists": true}}, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"identities": bson.A{bson.M{"provider": "google", "subject": "$google_id"}}}}},
+		{{Key: "$unset", Value: "google_id"}},
+	})
+	return err
 }
 
 // Create implements user.Storage
 func (d *userRepository) Create(ctx context.Context, user domain.User) (primitive.ObjectID, error) {
+	user.Email = domain.NormalizeEmail(user.Email)
 
 	result, err := d.collection.InsertOne(ctx, &user)
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return primitive.ObjectID{}, domain.ErrUserAlreadyExists
+		}
 		return primitive.ObjectID{}, fmt.Errorf("failed to create user due to error: %v", err)
 	}
 
@@ -98,7 +184,7 @@ func (d *userRepository) FindOne(ctx context.Context, oid primitive.ObjectID) (u
 }
 
 func (d *userRepository) FindByEmail(ctx context.Context, email string) (u domain.User, err error) {
-	filter := bson.M{"email": email}
+	filter := bson.M{"email": domain.NormalizeEmail(email)}
 	result := d.collection.FindOne(ctx, filter)
 
 	if result.Err() != nil {
@@ -115,17 +201,88 @@ func (d *userRepository) FindByEmail(ctx context.Context, email string) (u domai
 	return u, nil
 }
 
+func (d *userRepository) FindByIdentity(ctx context.Context, provider, subject string) (u domain.User, err error) {
+	filter := bson.M{"identities": bson.M{"$elemMatch": bson.M{"provider": provider, "subject": subject}}}
+	result := d.collection.FindOne(ctx, filter)
+
+	if result.Err() != nil {
+		if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+			return u, domain.ErrUserNotFound
+		}
+		return u, fmt.Errorf("failed to find user by %s identity=%s, due to error:=%v", provider, subject, result.Err())
+	}
+
+	if err := result.Decode(&u); err != nil {
+		return u, fmt.Errorf("failed to decode user by %s identity=%s, from DB due to error: %v", provider, subject, err)
+	}
+
+	return u, nil
+}
+
+// LinkIdentity links the external account to the user unless the user is linked to another
+// account of the same provider. Linking the same account again changes nothing.
+func (d *userRepository) LinkIdentity(ctx context.Context, oid primitive.ObjectID, identity domain.Identity) error {
+	filter := bson.M{"_id": oid, "identities.provider": bson.M{"$ne": identity.Provider}}
+	result, err := d.collection.UpdateOne(ctx, filter, bson.M{"$push": bson.M{"identities": identity}})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return domain.ErrIdentityLinked
+		}
+		return fmt.Errorf("failed to link %s identity to user with oid=%s due to error: %v", identity.Provider, oid, err)
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	user, err := d.FindOne(ctx, oid)
+	if err != nil {
+		return err
+	}
+	if linked, ok := user.Identity(identity.Provider); ok && linked.Subject == identity.Subject {
+		return nil
+	}
+	return domain.ErrProviderLinked
+}
+
+// UnlinkIdentity removes the external account of the provider unless it is the last way
+// for the user to sign in, the check and the removal are a single update.
+func (d *userRepository) UnlinkIdentity(ctx context.Context, oid primitive.ObjectID, provider string) error {
+	filter := bson.M{"_id": oid, "identities.provider": provider, "$or": bson.A{
+		bson.M{"password": bson.M{"$nin": bson.A{"", nil}}},
+		bson.M{"identities": bson.M{"$elemMatch": bson.M{"provider": bson.M{"$ne": provider}}}},
+	}}
+	result, err := d.collection.UpdateOne(ctx, filter, bson.M{"$pull": bson.M{"identities": bson.M{"provider": provider}}})
+	if err != nil {
+		return fmt.Errorf("failed to unlink %s identity from user with oid=%s due to error: %v", provider, oid, err)
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	user, err := d.FindOne(ctx, oid)
+	if err != nil {
+		return err
+	}
+	if _, ok := user.Identity(provider); !ok {
+		return domain.ErrIdentityNotFound
+	}
+	return domain.ErrLastLoginMethod
+}
+
 // Update implements user.Storage
 func (d *userRepository) Update(ctx context.Context, user domain.User) error {
 
 	updateQuery := bson.M{}
-	updateQuery["email"] = user.Email
+	updateQuery["email"] = domain.NormalizeEmail(user.Email)
 	updateQuery["password"] = user.PasswordHash
 
 	filter := bson.M{"_id": user.Id}
 
 	result, err := d.collection.UpdateOne(ctx, filter, bson.D{{Key: "$set", Value: updateQuery}})
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return domain.ErrUserAlreadyExists
+		}
 		return fmt.Errorf("failed to exceute update user query due to error: %v", err)
 	}
 	if result.MatchedCount == 0 {
@@ -135,29 +292,225 @@ func (d *userRepository) Update(ctx context.Context, user domain.User) error {
 	return nil
 }
 
-func (r *userRepository) SetSession(ctx context.Context, oid primitive.ObjectID, session domain.Session) error {
+func (d *userRepository) UpdatePasswordHash(ctx context.Context, oid primitive.ObjectID, passwordHash string) error {
 	filter := bson.M{"_id": oid}
-	update := bson.M{"$set": bson.M{"session": session, "lastVisitAt": time.Now()}}
+	result, err := d.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"password": passwordHash}})
+	if err != nil {
+		return fmt.Errorf("failed to update password of user with oid=%s due to error: %v", oid, err)
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
 
-	if _, err := r.collection.UpdateOne(ctx, filter, update); err != nil {
-		return fmt.Errorf("Failed to store session")
+// SetPasswordReset stores the pending password reset, a previous one is replaced.
+func (d *userRepository) SetPasswordReset(ctx context.Context, oid primitive.ObjectID, reset domain.PasswordReset) error {
+	filter := bson.M{"_id": oid}
+	result, err := d.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"password_reset": reset}})
+	if err != nil {
+		return fmt.Errorf("failed to set password reset of user with oid=%s due to error: %v", oid, err)
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrUserNotFound
 	}
 	return nil
 }
 
-func (r *userRepository) GetUserByRefreshToken(ctx context.Context, oid primitive.ObjectID) (domain.User, error) {
+// ResetPassword sets the password of the user the live reset token was issued to and
+// removes the reset in the same update, so the token can't be used twice.
+func (d *userRepository) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (u domain.User, err error) {
+	filter := bson.M{
+		"password_reset.token_hash": tokenHash,
+		"password_reset.expires_at": bson.M{"$gt": time.Now()},
+	}
+	update := bson.M{
+		"$set":   bson.M{"password": passwordHash},
+		"$unset": bson.M{"password_reset": ""},
+	}
+	result := d.collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After))
+	if result.Err() != nil {
+		if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+			return u, domain.ErrInvalidResetToken
+		}
+		return u, fmt.Errorf("failed to reset password due to error: %v", result.Err())
+	}
+
+	if err := result.Decode(&u); err != nil {
+		return u, fmt.Errorf("failed to decode user from DB due to error: %v", err)
+	}
+	return u, nil
+}
 
-	var user domain.User
+// SetMagicLink stores the pending magic link, a previous one is replaced and can't be used.
+func (d *userRepository) SetMagicLink(ctx context.Context, oid primitive.ObjectID, link domain.MagicLink) error {
+	filter := bson.M{"_id": oid}
+	result, err := d.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"magic_link": link}})
+	if err != nil {
+		return fmt.Errorf("failed to set magic link of user with oid=%s due to error: %v", oid, err)
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+// UseMagicLink removes the live magic link of the user when it was requested from the
+// browser, in the same find, so the link can't be used twice.
+func (d *userRepository) UseMagicLink(ctx context.Context, oid primitive.ObjectID, tokenId, browserHash string) (u domain.User, err error) {
 	filter := bson.M{
-		"_id":               oid,
-		"session.expiresat": bson.M{"$gt": time.Now()},
+		"_id":                     oid,
+		"magic_link.token_id":     tokenId,
+		"magic_link.browser_hash": browserHash,
+		"magic_link.expires_at":   bson.M{"$gt": time.Now()},
 	}
-	if err := r.collection.FindOne(ctx, filter).Decode(&user); err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return domain.User{}, domain.ErrUserNotFound
+	update := bson.M{"$unset": bson.M{"magic_link": ""}}
+	result := d.collection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After))
+	if result.Err() != nil {
+		if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+			return u, domain.ErrInvalidMagicLink
 		}
-		return domain.User{}, fmt.Errorf("failed to find user by refresh token")
+		return u, fmt.Errorf("failed to use magic link of user with oid=%s due to error: %v", oid, result.Err())
+	}
 
+	if err := result.Decode(&u); err != nil {
+		return u, fmt.Errorf("failed to decode user from DB due to error: %v", err)
 	}
-	return user, nil
+	return u, nil
+}
+
+// SetPendingEmail remembers the new email of the user until it is verified.
+func (d *userRepository) SetPendingEmail(ctx context.Context, oid primitive.ObjectID, email string) error {
+	filter := bson.M{"_id": oid}
+	update := bson.M{"$set": bson.M{"pending_email": domain.NormalizeEmail(email)}}
+	result, err := d.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to set pending email of user with oid=%s due to error: %v", oid, err)
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+// VerifyEmail marks the email of the user verified. A verified pending email replaces
+// the current one unless another user has it. Emails the user doesn't have any more
+// can't be verified.
+func (d *userRepository) VerifyEmail(ctx context.Context, oid primitive.ObjectID, email string) error {
+	email = domain.NormalizeEmail(email)
+	filter := bson.M{"_id": oid, "email": email}
+	result, err := d.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"email_verified": true}})
+	if err != nil {
+		return fmt.Errorf("failed to verify email of user with oid=%s due to error: %v", oid, err)
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	filter = bson.M{"_id": oid, "pending_email": email}
+	update := bson.M{
+		"$set":   bson.M{"email": email, "email_verified": true},
+		"$unset": bson.M{"pending_email": ""},
+	}
+	result, err = d.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return domain.ErrUserAlreadyExists
+		}
+		return fmt.Errorf("failed to verify pending email of user with oid=%s due to error: %v", oid, err)
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrInvalidVerificationLink
+	}
+	return nil
+}
+
+// SetMFASecret starts enrollment with a new secret, replacing the one of an unfinished
+// enrollment. An enabled second factor has to be disabled first.
+func (d *userRepository) SetMFASecret(ctx context.Context, oid primitive.ObjectID, secret string) error {
+	filter := bson.M{"_id": oid, "mfa.enabled": bson.M{"$ne": true}}
+	update := bson.M{"$set": bson.M{"mfa": domain.MFA{Secret: secret}}}
+	result, err := d.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to set mfa secret of user with oid=%s due to error: %v", oid, err)
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrMFAAlreadyEnabled
+	}
+	return nil
+}
+
+// EnableMFA finishes enrollment of the secret, the code confirming it is of the step.
+func (d *userRepository) EnableMFA(ctx context.Context, oid primitive.ObjectID, secret string, step int64, recoveryCodes []string) error {
+	filter := bson.M{"_id": oid, "mfa.secret": secret, "mfa.enabled": false}
+	update := bson.M{"$set": bson.M{
+		"mfa.enabled":        true,
+		"mfa.last_step":      step,
+		"mfa.recovery_codes": recoveryCodes,
+	}}
+	result, err := d.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to enable mfa of user with oid=%s due to error: %v", oid, err)
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrMFANotEnrolled
+	}
+	return nil
+}
+
+// UseMFAStep accepts a code of the step once, codes of the last accepted step and
+// earlier ones are refused.
+func (d *userRepository) UseMFAStep(ctx context.Context, oid primitive.ObjectID, step int64) error {
+	filter := bson.M{"_id": oid, "mfa.enabled": true, "mfa.last_step": bson.M{"$lt": step}}
+	result, err := d.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"mfa.last_step": step}})
+	if err != nil {
+		return fmt.Errorf("failed to use mfa code of user with oid=%s due to error: %v", oid, err)
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrMFACodeReused
+	}
+	return nil
+}
+
+// UseRecoveryCode removes the recovery code, so it can't be used again.
+func (d *userRepository) UseRecoveryCode(ctx context.Context, oid primitive.ObjectID, codeHash string) error {
+	filter := bson.M{"_id": oid, "mfa.enabled": true, "mfa.recovery_codes": codeHash}
+	result, err := d.collection.UpdateOne(ctx, filter, bson.M{"$pull": bson.M{"mfa.recovery_codes": codeHash}})
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code of user with oid=%s due to error: %v", oid, err)
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (d *userRepository) DisableMFA(ctx context.Context, oid primitive.ObjectID) error {
+	result, err := d.collection.UpdateOne(ctx, bson.M{"_id": oid}, bson.M{"$unset": bson.M{"mfa": ""}})
+	if err != nil {
+		return fmt.Errorf("failed to disable mfa of user with oid=%s due to error: %v", oid, err)
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+func (d *userRepository) AddRole(ctx context.Context, oid primitive.ObjectID, role string) error {
+	return d.updateRoles(ctx, oid, bson.M{"$addToSet": bson.M{"roles": role}})
+}
+
+func (d *userRepository) RemoveRole(ctx context.Context, oid primitive.ObjectID, role string) error {
+	return d.updateRoles(ctx, oid, bson.M{"$pull": bson.M{"roles": role}})
+}
+
+func (d *userRepository) updateRoles(ctx context.Context, oid primitive.ObjectID, update bson.M) error {
+	result, err := d.collection.UpdateOne(ctx, bson.M{"_id": oid}, update)
+	if err != nil {
+		return fmt.Errorf("failed to update roles of user with oid=%s due to error: %v", oid, err)
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
 }
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"test/internal/domain"
+	"test/internal/repository"
+	"test/internal/service/dto"
+	"test/pkg/api/auth"
+	"test/pkg/api/params"
+	"test/pkg/hash"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// APIKeyPrefix starts every API key, so keys are told from access tokens and are easy
+	// to find by secret scanners.
+	APIKeyPrefix = "uak_"
+	// apiKeySize is in bytes, a key is 52 base32 letters after the prefix.
+	apiKeySize = 32
+	// apiKeyShownPrefix is how much of the key is kept to tell keys apart.
+	apiKeyShownPrefix = len(APIKeyPrefix) + 8
+	maxAPIKeyName     = 100
+)
+
+type APIKeyService struct {
+	repository repository.UserRepository
+	users      *UserService
+}
+
+func NewAPIKeyService(repository repository.UserRepository, users *UserService) *APIKeyService {
+	return &APIKeyService{
+		repository: repository,
+		users:      users,
+	}
+}
+
+// Create issues a new key of the user. The key is returned once, only its hash is stored.
+func (s *APIKeyService) Create(ctx context.Context, userId string, createDTO dto.CreateAPIKeyDTO) (dto.APIKeyDTO, error) {
+	if err := validateAPIKey(createDTO); err != nil {
+		return dto.APIKeyDTO{}, err
+	}
+	user, err := s.users.FindOne(ctx, userId)
+	if err != nil {
+		return dto.APIKeyDTO{}, err
+	}
+
+	key, err := generateSecret(APIKeyPrefix)
+	if err != nil {
+		return dto.APIKeyDTO{}, err
+	}
+	apiKey := domain.APIKey{
+		Id:        primitive.NewObjectID(),
+		UserId:    user.Id,
+		Name:      strings.TrimSpace(createDTO.Name),
+		Prefix:    key[:apiKeyShownPrefix],
+		KeyHash:   hash.HashToken(key),
+		Scopes:    createDTO.Scopes,
+		CreatedAt: time.Now(),
+		ExpiresAt: createDTO.ExpiresAt,
+	}
+	if err := s.repository.CreateAPIKey(ctx, apiKey); err != nil {
+		return dto.APIKeyDTO{}, err
+	}
+	return dto.APIKeyDTO{Key: key, APIKey: apiKey}, nil
+}
+
+func (s *APIKeyService) FindAll(ctx context.Context, userId string) ([]domain.APIKey, error) {
+	oid, err := params.ParseIdToObjectID(userId)
+	if err != nil {
+		return nil, err
+	}
+	return s.repository.FindAPIKeys(ctx, oid)
+}
+
+func (s *APIKeyService) Revoke(ctx context.Context, userId, keyId string) error {
+	oid, err := params.ParseIdToObjectID(userId)
+	if err != nil {
+		return err
+	}
+	keyOid, err := params.ParseIdToObjectID(keyId)
+	if err != nil {
+		return err
+	}
+	return s.repository.DeleteAPIKey(ctx, oid, keyOid)
+}
+
+// Authenticate returns claims of the key owner as an access token would carry them,
+// limited to the scopes of the key. Roles are read from the user on every request, so
+// revoked roles take effect at once.
+func (s *APIKeyService) Authenticate(ctx context.Context, key string) (*auth.Claims, error) {
+	apiKey, user, err := s.find(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Last use is informational, failing to store it doesn't fail the request.
+	if err := s.repository.TouchAPIKey(ctx, apiKey.Id, time.Now()); err != nil {
+		log.Default().Printf("failed to update last use of api key with oid=%s due to error: %v", apiKey.Id.Hex(), err)
+	}
+
+	return apiKeyClaims(apiKey, user), nil
+}
+
+// find returns the live key and its owner, ErrInvalidAPIKey when there is none.
+func (s *APIKeyService) find(ctx context.Context, key string) (domain.APIKey, domain.User, error) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return domain.APIKey{}, domain.User{}, domain.ErrInvalidAPIKey
+	}
+	apiKey, err := s.repository.FindAPIKeyByHash(ctx, hash.HashToken(key))
+	if err != nil {
+		if errors.Is(err, domain.ErrAPIKeyNotFound) {
+			return domain.APIKey{}, domain.User{}, domain.ErrInvalidAPIKey
+		}
+		return domain.APIKey{}, domain.User{}, err
+	}
+	user, err := s.repository.FindOne(ctx, apiKey.UserId)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return domain.APIKey{}, domain.User{}, domain.ErrInvalidAPIKey
+		}
+		return domain.APIKey{}, domain.User{}, err
+	}
+	return apiKey, user, nil
+}
+
+func apiKeyClaims(apiKey domain.APIKey, user domain.User) *auth.Claims {
+	claims := &auth.Claims{
+		Roles:         userRoles(user),
+		EmailVerified: user.EmailVerified,
+		Scopes:        apiKey.Scopes,
+		APIKeyId:      apiKey.Id.Hex(),
+	}
+	claims.Subject = user.Id.Hex()
+	return claims
+}
+
+func validateAPIKey(createDTO dto.CreateAPIKeyDTO) error {
+	name := strings.TrimSpace(createDTO.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxAPIKeyName {
+		return domain.ErrInvalidAPIKeyName
+	}
+	if err := validateScopes(createDTO.Scopes); err != nil {
+		return err
+	}
+	if createDTO.ExpiresAt != nil && !createDTO.ExpiresAt.After(time.Now()) {
+		return domain.ErrInvalidExpiry
+	}
+	return nil
+}
+
+func validateScopes(scopes []string) error {
+	for _, scope := range scopes {
+		resource, action, ok := strings.Cut(scope, ":")
+		if !ok || resource == "" || action == "" || strings.Contains(action, ":") {
+			return domain.ErrInvalidScope
+		}
+	}
+	return nil
+}
+
+// generateSecret returns a random secret of apiKeySize bytes after the prefix.
+func generateSecret(prefix string) (string, error) {
+	b := make([]byte, apiKeySize)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate secret due to error: %v", err)
+	}
+	return prefix + strings.ToLower(recoveryCodeEncoding.EncodeToString(b)), nil
+}
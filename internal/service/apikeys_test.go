@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"test/internal/domain"
+	db_mocks "test/internal/repository/mocks"
+	"test/internal/service/dto"
+	"test/pkg/api/auth"
+	"test/pkg/hash"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func mockAPIKeyService(t *testing.T) (*APIKeyService, *db_mocks.MockUserRepository) {
+	t.Helper()
+
+	userService, userRepoMock := mockUserService(t)
+	return NewAPIKeyService(userRepoMock, userService), userRepoMock
+}
+
+func TestAPIKeyService_Create(t *testing.T) {
+	type mockRepoBehavior func(dbmock *db_mocks.MockUserRepository, user domain.User)
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	testTable := []struct {
+		name             string
+		createDTO        dto.CreateAPIKeyDTO
+		mockRepoBehavior mockRepoBehavior
+		expectedError    error
+	}{
+		{
+			name:      "OK",
+			createDTO: dto.CreateAPIKeyDTO{Name: " ci ", Scopes: []string{"users:read"}, ExpiresAt: &future},
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {
+				dbmock.EXPECT().FindOne(context.Background(), user.Id).Return(user, nil)
+				dbmock.EXPECT().CreateAPIKey(context.Background(), gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name:             "Empty name",
+			createDTO:        dto.CreateAPIKeyDTO{Name: " "},
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {},
+			expectedError:    domain.ErrInvalidAPIKeyName,
+		},
+		{
+			name:             "Invalid scope",
+			createDTO:        dto.CreateAPIKeyDTO{Name: "ci", Scopes: []string{"users:read:any"}},
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {},
+			expectedError:    domain.ErrInvalidScope,
+		},
+		{
+			name:             "Expiry in the past",
+			createDTO:        dto.CreateAPIKeyDTO{Name: "ci", ExpiresAt: &past},
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {},
+			expectedError:    domain.ErrInvalidExpiry,
+		},
+		{
+			name:      "User not found",
+			createDTO: dto.CreateAPIKeyDTO{Name: "ci"},
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {
+				dbmock.EXPECT().FindOne(context.Background(), user.Id).Return(domain.User{}, domain.ErrUserNotFound)
+			},
+			expectedError: domain.ErrUserNotFound,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			apiKeyService, userRepoMock := mockAPIKeyService(t)
+			user := domain.User{Id: primitive.NewObjectID(), Email: "test@test.ru"}
+			testCase.mockRepoBehavior(userRepoMock, user)
+
+			apiKeyDTO, err := apiKeyService.Create(context.Background(), user.Id.Hex(), testCase.createDTO)
+			if testCase.expectedError != nil {
+				assert.ErrorIs(t, err, testCase.expectedError)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.True(t, strings.HasPrefix(apiKeyDTO.Key, APIKeyPrefix))
+			assert.True(t, strings.HasPrefix(apiKeyDTO.Key, apiKeyDTO.Prefix))
+			assert.Equal(t, hash.HashToken(apiKeyDTO.Key), apiKeyDTO.KeyHash)
+			assert.Equal(t, "ci", apiKeyDTO.Name)
+			assert.Equal(t, user.Id, apiKeyDTO.UserId)
+			assert.Equal(t, testCase.createDTO.Scopes, apiKeyDTO.Scopes)
+		})
+	}
+}
+
+func TestAPIKeyService_Authenticate(t *testing.T) {
+	type mockRepoBehavior func(dbmock *db_mocks.MockUserRepository, apiKey domain.APIKey)
+	key := APIKeyPrefix + "key"
+	user := domain.User{Id: primitive.NewObjectID(), Roles: []string{auth.AdminRole}, EmailVerified: true}
+	apiKey := domain.APIKey{Id: primitive.NewObjectID(), UserId: user.Id, Scopes: []string{"users:read"}}
+
+	testTable := []struct {
+		name             string
+		key              string
+		mockRepoBehavior mockRepoBehavior
+		expectedError    error
+	}{
+		{
+			name: "OK",
+			key:  key,
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, apiKey domain.APIKey) {
+				dbmock.EXPECT().FindAPIKeyByHash(context.Background(), hash.HashToken(key)).Return(apiKey, nil)
+				dbmock.EXPECT().FindOne(context.Background(), apiKey.UserId).Return(user, nil)
+				dbmock.EXPECT().TouchAPIKey(context.Background(), apiKey.Id, gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name: "Last use not stored",
+			key:  key,
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, apiKey domain.APIKey) {
+				dbmock.EXPECT().FindAPIKeyByHash(context.Background(), hash.HashToken(key)).Return(apiKey, nil)
+				dbmock.EXPECT().FindOne(context.Background(), apiKey.UserId).Return(user, nil)
+				dbmock.EXPECT().TouchAPIKey(context.Background(), apiKey.Id, gomock.Any()).Return(errors.New("repository failure"))
+			},
+		},
+		{
+			name:             "Not an api key",
+			key:              "Bearer token",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, apiKey domain.APIKey) {},
+			expectedError:    domain.ErrInvalidAPIKey,
+		},
+		{
+			name: "Unknown key",
+			key:  key,
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, apiKey domain.APIKey) {
+				dbmock.EXPECT().FindAPIKeyByHash(context.Background(), hash.HashToken(key)).Return(domain.APIKey{}, domain.ErrAPIKeyNotFound)
+			},
+			expectedError: domain.ErrInvalidAPIKey,
+		},
+		{
+			name: "Deleted user",
+			key:  key,
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, apiKey domain.APIKey) {
+				dbmock.EXPECT().FindAPIKeyByHash(context.Background(), hash.HashToken(key)).Return(apiKey, nil)
+				dbmock.EXPECT().FindOne(context.Background(), apiKey.UserId).Return(domain.User{}, domain.ErrUserNotFound)
+			},
+			expectedError: domain.ErrInvalidAPIKey,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			apiKeyService, userRepoMock := mockAPIKeyService(t)
+			testCase.mockRepoBehavior(userRepoMock, apiKey)
+
+			claims, err := apiKeyService.Authenticate(context.Background(), testCase.key)
+			if testCase.expectedError != nil {
+				assert.ErrorIs(t, err, testCase.expectedError)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, user.Id.Hex(), claims.Subject)
+			assert.Equal(t, user.Roles, claims.Roles)
+			assert.True(t, claims.EmailVerified)
+			assert.Equal(t, apiKey.Scopes, claims.Scopes)
+			assert.Equal(t, apiKey.Id.Hex(), claims.APIKeyId)
+		})
+	}
+}
+
+func TestAPIKeyService_Revoke(t *testing.T) {
+	apiKeyService, userRepoMock := mockAPIKeyService(t)
+	userId, keyId := primitive.NewObjectID(), primitive.NewObjectID()
+
+	userRepoMock.EXPECT().DeleteAPIKey(context.Background(), userId, keyId).Return(domain.ErrAPIKeyNotFound)
+	assert.ErrorIs(t, apiKeyService.Revoke(context.Background(), userId.Hex(), keyId.Hex()), domain.ErrAPIKeyNotFound)
+
+	assert.Error(t, apiKeyService.Revoke(context.Background(), userId.Hex(), "invalid"))
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"test/internal/domain"
+	"test/internal/repository"
+	"test/internal/service/dto"
+	"test/pkg/api/auth"
+	"test/pkg/hash"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// ClientSecretPrefix starts every client secret, so secrets are easy to find by secret scanners.
+	ClientSecretPrefix = "ucs_"
+	// TokenTypeBearer is the token_type of issued tokens.
+	TokenTypeBearer = "Bearer"
+	maxClientName   = 100
+)
+
+// ClientService keeps the registry of OAuth clients and issues them tokens with the
+// client credentials grant.
+type ClientService struct {
+	repository   repository.ClientRepository
+	tokenManager auth.TokenManager
+	tokenTTL     time.Duration
+}
+
+func NewClientService(repository repository.ClientRepository, tokenManager auth.TokenManager, tokenTTL time.Duration) *ClientService {
+	return &ClientService{
+		repository:   repository,
+		tokenManager: tokenManager,
+		tokenTTL:     tokenTTL,
+	}
+}
+
+// Create registers a client. The secret is returned once, only its hash is stored.
+func (s *ClientService) Create(ctx context.Context, createDTO dto.CreateClientDTO) (dto.ClientDTO, error) {
+	name := strings.TrimSpace(createDTO.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxClientName {
+		return dto.ClientDTO{}, domain.ErrInvalidClientName
+	}
+	if err := validateScopes(createDTO.Scopes); err != nil {
+		return dto.ClientDTO{}, err
+	}
+
+	id, err := auth.GenerateTokenId()
+	if err != nil {
+		return dto.ClientDTO{}, err
+	}
+	secret, err := generateSecret(ClientSecretPrefix)
+	if err != nil {
+		return dto.ClientDTO{}, err
+	}
+	client := domain.Client{
+		Id:         id,
+		Name:       name,
+		SecretHash: hash.HashToken(secret),
+		Scopes:     createDTO.Scopes,
+		CreatedAt:  time.Now(),
+	}
+	if err := s.repository.Create(ctx, client); err != nil {
+		return dto.ClientDTO{}, err
+	}
+	return dto.ClientDTO{ClientSecret: secret, Client: client}, nil
+}
+
+func (s *ClientService) FindAll(ctx context.Context) ([]domain.Client, error) {
+	return s.repository.FindAll(ctx)
+}
+
+// Delete removes the client, tokens issued before stay valid until they expire.
+func (s *ClientService) Delete(ctx context.Context, id string) error {
+	return s.repository.Delete(ctx, id)
+}
+
+// Token issues an access token to the client for the requested scopes, all scopes of the
+// client when none are requested. The token has the client_id claim and no subject.
+func (s *ClientService) Token(ctx context.Context, credentials dto.ClientCredentialsDTO) (dto.ClientTokenDTO, error) {
+	client, err := s.Authenticate(ctx, credentials.ClientId, credentials.ClientSecret)
+	if err != nil {
+		return dto.ClientTokenDTO{}, err
+	}
+
+	scopes := client.Scopes
+	if requested := strings.Fields(credentials.Scope); len(requested) > 0 {
+		for _, scope := range requested {
+			if !containsScope(client.Scopes, scope) {
+				return dto.ClientTokenDTO{}, domain.ErrScopeNotAllowed
+			}
+		}
+		scopes = requested
+	}
+	if len(scopes) == 0 {
+		return dto.ClientTokenDTO{}, domain.ErrScopeNotAllowed
+	}
+
+	token, err := s.tokenManager.GenerateAccessToken(auth.Claims{ClientId: client.Id, Scopes: scopes}, s.tokenTTL)
+	if err != nil {
+		return dto.ClientTokenDTO{}, err
+	}
+	return dto.ClientTokenDTO{
+		AccessToken: strings.TrimPrefix(token, auth.PrefixToken),
+		TokenType:   TokenTypeBearer,
+		ExpiresIn:   int(s.tokenTTL.Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+// Authenticate returns the client with the credentials, ErrInvalidClient when they are wrong.
+func (s *ClientService) Authenticate(ctx context.Context, id, secret string) (domain.Client, error) {
+	if id == "" || secret == "" {
+		return domain.Client{}, domain.ErrInvalidClient
+	}
+	client, err := s.repository.FindOne(ctx, id)
+	if err != nil {
+		if errors.Is(err, domain.ErrClientNotFound) {
+			return domain.Client{}, domain.ErrInvalidClient
+		}
+		return domain.Client{}, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hash.HashToken(secret)), []byte(client.SecretHash)) != 1 {
+		return domain.Client{}, domain.ErrInvalidClient
+	}
+	return client, nil
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"test/internal/domain"
+	db_mocks "test/internal/repository/mocks"
+	"test/internal/service/dto"
+	"test/pkg/api/auth"
+	"test/pkg/hash"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mockClientService(t *testing.T) (*ClientService, *auth.Manager, *db_mocks.MockClientRepository) {
+	t.Helper()
+
+	clientRepoMock := db_mocks.NewMockClientRepository(gomock.NewController(t))
+	tokenManager := newTokenManager(t, nil)
+	return NewClientService(clientRepoMock, tokenManager, time.Minute), tokenManager, clientRepoMock
+}
+
+func TestClientService_Create(t *testing.T) {
+	type mockRepoBehavior func(dbmock *db_mocks.MockClientRepository)
+
+	testTable := []struct {
+		name             string
+		createDTO        dto.CreateClientDTO
+		mockRepoBehavior mockRepoBehavior
+		expectedError    error
+	}{
+		{
+			name:      "OK",
+			createDTO: dto.CreateClientDTO{Name: " reports ", Scopes: []string{"users:read"}},
+			mockRepoBehavior: func(dbmock *db_mocks.MockClientRepository) {
+				dbmock.EXPECT().Create(context.Background(), gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name:             "Empty name",
+			createDTO:        dto.CreateClientDTO{Name: " "},
+			mockRepoBehavior: func(dbmock *db_mocks.MockClientRepository) {},
+			expectedError:    domain.ErrInvalidClientName,
+		},
+		{
+			name:             "Invalid scope",
+			createDTO:        dto.CreateClientDTO{Name: "reports", Scopes: []string{"users:read:any"}},
+			mockRepoBehavior: func(dbmock *db_mocks.MockClientRepository) {},
+			expectedError:    domain.ErrInvalidScope,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			clientService, _, clientRepoMock := mockClientService(t)
+			testCase.mockRepoBehavior(clientRepoMock)
+
+			clientDTO, err := clientService.Create(context.Background(), testCase.createDTO)
+			if testCase.expectedError != nil {
+				assert.ErrorIs(t, err, testCase.expectedError)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.NotEmpty(t, clientDTO.Id)
+			assert.True(t, strings.HasPrefix(clientDTO.ClientSecret, ClientSecretPrefix))
+			assert.Equal(t, hash.HashToken(clientDTO.ClientSecret), clientDTO.SecretHash)
+			assert.Equal(t, "reports", clientDTO.Name)
+			assert.Equal(t, testCase.createDTO.Scopes, clientDTO.Scopes)
+		})
+	}
+}
+
+func TestClientService_Token(t *testing.T) {
+	type mockRepoBehavior func(dbmock *db_mocks.MockClientRepository)
+	secret := ClientSecretPrefix + "secret"
+	client := domain.Client{
+		Id:         "reports",
+		SecretHash: hash.HashToken(secret),
+		Scopes:     []string{"users:read", "sessions:read"},
+	}
+
+	testTable := []struct {
+		name             string
+		credentials      dto.ClientCredentialsDTO
+		mockRepoBehavior mockRepoBehavior
+		expectedScopes   []string
+		expectedError    error
+	}{
+		{
+			name:        "All scopes",
+			credentials: dto.ClientCredentialsDTO{ClientId: client.Id, ClientSecret: secret},
+			mockRepoBehavior: func(dbmock *db_mocks.MockClientRepository) {
+				dbmock.EXPECT().FindOne(context.Background(), client.Id).Return(client, nil)
+			},
+			expectedScopes: client.Scopes,
+		},
+		{
+			name:        "Requested scope",
+			credentials: dto.ClientCredentialsDTO{ClientId: client.Id, ClientSecret: secret, Scope: " users:read "},
+			mockRepoBehavior: func(dbmock *db_mocks.MockClientRepository) {
+				dbmock.EXPECT().FindOne(context.Background(), client.Id).Return(client, nil)
+			},
+			expectedScopes: []string{"users:read"},
+		},
+		{
+			name:        "Scope not allowed",
+			credentials: dto.ClientCredentialsDTO{ClientId: client.Id, ClientSecret: secret, Scope: "users:read users:delete"},
+			mockRepoBehavior: func(dbmock *db_mocks.MockClientRepository) {
+				dbmock.EXPECT().FindOne(context.Background(), client.Id).Return(client, nil)
+			},
+			expectedError: domain.ErrScopeNotAllowed,
+		},
+		{
+			name:        "Wrong secret",
+			credentials: dto.ClientCredentialsDTO{ClientId: client.Id, ClientSecret: ClientSecretPrefix + "wrong"},
+			mockRepoBehavior: func(dbmock *db_mocks.MockClientRepository) {
+				dbmock.EXPECT().FindOne(context.Background(), client.Id).Return(client, nil)
+			},
+			expectedError: domain.ErrInvalidClient,
+		},
+		{
+			name:        "Unknown client",
+			credentials: dto.ClientCredentialsDTO{ClientId: "unknown", ClientSecret: secret},
+			mockRepoBehavior: func(dbmock *db_mocks.MockClientRepository) {
+				dbmock.EXPECT().FindOne(context.Background(), "unknown").Return(domain.Client{}, domain.ErrClientNotFound)
+			},
+			expectedError: domain.ErrInvalidClient,
+		},
+		{
+			name:             "No credentials",
+			mockRepoBehavior: func(dbmock *db_mocks.MockClientRepository) {},
+			expectedError:    domain.ErrInvalidClient,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			clientService, tokenManager, clientRepoMock := mockClientService(t)
+			testCase.mockRepoBehavior(clientRepoMock)
+
+			tokenDTO, err := clientService.Token(context.Background(), testCase.credentials)
+			if testCase.expectedError != nil {
+				assert.ErrorIs(t, err, testCase.expectedError)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, TokenTypeBearer, tokenDTO.TokenType)
+			assert.Equal(t, 60, tokenDTO.ExpiresIn)
+			assert.Equal(t, strings.Join(testCase.expectedScopes, " "), tokenDTO.Scope)
+
+			var claims auth.Claims
+			_, err = tokenManager.Parse(tokenDTO.AccessToken, &claims)
+			require.NoError(t, err)
+			assert.True(t, claims.IsService())
+			assert.Equal(t, client.Id, claims.ClientId)
+			assert.Empty(t, claims.Roles)
+			assert.Equal(t, testCase.expectedScopes, claims.Scopes)
+		})
+	}
+}
@@ -1,5 +1,10 @@
 package dto
 
+import (
+	"test/internal/domain"
+	"time"
+)
+
 type CreateUserDTO struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
@@ -11,7 +16,145 @@ type UpdateUserDTO struct {
 	Password string `json:"password"`
 }
 
+type SignInDTO struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type RefreshTokenDTO struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 type TokenDTO struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
+	// MFAToken is returned instead of the tokens when the user has to enter a code of
+	// the second factor, the tokens are issued once it is verified.
+	MFAToken string `json:"mfa_token,omitempty"`
+}
+
+type RoleDTO struct {
+	Role string `json:"role"`
+}
+
+type DeviceDTO struct {
+	UserAgent string
+	IP        string
+}
+
+type AuthCodeURLDTO struct {
+	URL string `json:"url"`
+}
+
+type ForgotPasswordDTO struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordDTO struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type EmailDTO struct {
+	Email string `json:"email"`
+}
+
+// MFAEnrollmentDTO is the secret for the authenticator app, URI is usually shown as a QR code.
+type MFAEnrollmentDTO struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// MFACodeDTO is a code of the authenticator app or a recovery code.
+type MFACodeDTO struct {
+	Code string `json:"code"`
+}
+
+type RecoveryCodesDTO struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type MFAChallengeDTO struct {
+	MFAToken string `json:"mfa_token"`
+}
+
+type MFAVerifyDTO struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+// CreateAPIKeyDTO asks for a new API key, keys without expiry never expire and keys
+// without scopes have every permission of the user.
+type CreateAPIKeyDTO struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// APIKeyDTO is the created API key, Key is shown once and can't be read again.
+type APIKeyDTO struct {
+	Key string `json:"key"`
+	domain.APIKey
+}
+
+// CreateClientDTO registers an OAuth client, Scopes are all the client may ask tokens for.
+type CreateClientDTO struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// ClientDTO is the registered client, ClientSecret is shown once and can't be read again.
+type ClientDTO struct {
+	ClientSecret string `json:"client_secret"`
+	domain.Client
+}
+
+// ClientCredentialsDTO is the token request of the client credentials grant, Scope is
+// space separated as in RFC 6749.
+type ClientCredentialsDTO struct {
+	ClientId     string
+	ClientSecret string
+	Scope        string
+}
+
+// ClientTokenDTO is the token response of RFC 6749, clients get no refresh token.
+type ClientTokenDTO struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// IntrospectionDTO is the introspection response of RFC 7662, inactive tokens have only
+// Active set. TokenType is access_token, refresh_token or api_key.
+type IntrospectionDTO struct {
+	Active    bool     `json:"active"`
+	TokenType string   `json:"token_type,omitempty"`
+	Scope     string   `json:"scope,omitempty"`
+	ClientId  string   `json:"client_id,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	SessionId string   `json:"sid,omitempty"`
+	TokenId   string   `json:"jti,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+}
+
+// ImpersonateDTO is why the admin acts as the user, it is kept in the audit log.
+type ImpersonateDTO struct {
+	Reason string `json:"reason"`
+}
+
+// ImpersonationDTO is the started impersonation with its access token, there is no
+// refresh token and the admin starts a new impersonation once the token expires.
+type ImpersonationDTO struct {
+	AccessToken string `json:"access_token"`
+	domain.Impersonation
+}
+
+// AuditRequestDTO is a request made with an impersonation token.
+type AuditRequestDTO struct {
+	Method string
+	Path   string
+	Device DeviceDTO
 }
@@ -10,3 +10,15 @@ func ValidUpdateUserDTO(userDTO UpdateUserDTO) bool {
 	return validator.ValidEmail(userDTO.Email) &&
 		validator.ValidPassword(userDTO.Password)
 }
+
+func ValidForgotPasswordDTO(forgotDTO ForgotPasswordDTO) bool {
+	return validator.ValidEmail(forgotDTO.Email)
+}
+
+func ValidResetPasswordDTO(resetDTO ResetPasswordDTO) bool {
+	return validator.ValidPassword(resetDTO.Password)
+}
+
+func ValidEmailDTO(emailDTO EmailDTO) bool {
+	return validator.ValidEmail(emailDTO.Email)
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"test/internal/domain"
+	"test/internal/repository"
+	"test/internal/service/dto"
+	"test/pkg/api/auth"
+	"test/pkg/api/params"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	verifyEmailPurpose = "verify_email"
+	// NotificationEmailVerification carries the "link" and the "token" verifying the email.
+	NotificationEmailVerification = "email_verification"
+)
+
+// EmailVerification configures how emails of users are verified.
+type EmailVerification struct {
+	// LinkURL is where the verification token is sent to in the "token" query parameter.
+	LinkURL  string
+	TokenTTL time.Duration
+	// RequiredForLogin makes users verify the email before they can sign in with a password.
+	RequiredForLogin bool
+}
+
+type EmailService struct {
+	repository   repository.UserRepository
+	tokenManager auth.TokenManager
+	notifier     Notifier
+	config       EmailVerification
+}
+
+func NewEmailService(repository repository.UserRepository, tokenManager auth.TokenManager, notifier Notifier,
+	config EmailVerification) *EmailService {
+	return &EmailService{
+		repository:   repository,
+		tokenManager: tokenManager,
+		notifier:     notifier,
+		config:       config,
+	}
+}
+
+// SendVerification sends the link verifying the email to it. The link is signed for the
+// user and the email, so it verifies nothing once the user has another email.
+func (s *EmailService) SendVerification(ctx context.Context, user domain.User, email string) error {
+	token, err := s.tokenManager.GeneratePurposeToken(verifyEmailPurpose, auth.Claims{
+		Email:          email,
+		StandardClaims: jwt.StandardClaims{Subject: user.Id.Hex()},
+	}, s.config.TokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link, err := url.Parse(s.config.LinkURL)
+	if err != nil {
+		return err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return s.notifier.Notify(ctx, Notification{
+		Type: NotificationEmailVerification,
+		To:   email,
+		Data: map[string]string{"link": link.String(), "token": token},
+	})
+}
+
+// ResendVerification sends the link again to the user with the unverified email. Unknown
+// and verified emails are not reported, like in ForgotPassword.
+func (s *EmailService) ResendVerification(ctx context.Context, emailDTO dto.EmailDTO) error {
+	if !dto.ValidEmailDTO(emailDTO) {
+		return domain.ErrInvalidEmail
+	}
+
+	user, err := s.repository.FindByEmail(ctx, emailDTO.Email)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.EmailVerified {
+		return nil
+	}
+	return s.SendVerification(ctx, user, user.Email)
+}
+
+// VerifyEmail verifies the email the token was issued for. A pending email becomes the
+// email of the user unless another user has taken it meanwhile, the unique index on
+// emails settles concurrent verifications.
+func (s *EmailService) VerifyEmail(ctx context.Context, token string) error {
+	claims, err := s.tokenManager.ParsePurposeToken(token, verifyEmailPurpose)
+	if err != nil || claims.Email == "" {
+		return domain.ErrInvalidVerificationLink
+	}
+	oid, err := params.ParseIdToObjectID(claims.Subject)
+	if err != nil {
+		return domain.ErrInvalidVerificationLink
+	}
+
+	user, err := s.repository.FindByEmail(ctx, claims.Email)
+	if err == nil && user.Id != oid {
+		return domain.ErrUserAlreadyExists
+	}
+	if err != nil && !errors.Is(err, domain.ErrUserNotFound) {
+		return err
+	}
+
+	if err := s.repository.VerifyEmail(ctx, oid, claims.Email); err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return domain.ErrInvalidVerificationLink
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"test/internal/domain"
+	db_mocks "test/internal/repository/mocks"
+	"test/internal/service/dto"
+	"test/pkg/api/auth"
+	"test/pkg/hash"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const testLinkURL = "http://localhost:4000/api/v1/auth/verify-email"
+
+func mockEmailService(t *testing.T, requiredForLogin bool) (*EmailService, *db_mocks.MockUserRepository, *Outbox) {
+	t.Helper()
+
+	userRepoMock := db_mocks.NewMockUserRepository(gomock.NewController(t))
+	outbox := NewOutbox()
+	emailService := NewEmailService(userRepoMock, newTokenManager(t, nil), outbox, EmailVerification{
+		LinkURL:          testLinkURL,
+		TokenTTL:         time.Minute,
+		RequiredForLogin: requiredForLogin,
+	})
+	return emailService, userRepoMock, outbox
+}
+
+// verificationToken returns the token of the latest verification link sent to the email.
+func verificationToken(t *testing.T, outbox *Outbox, email string) string {
+	t.Helper()
+
+	notification, ok := outbox.Last(NotificationEmailVerification, email)
+	require.True(t, ok, "verification link sent to %s", email)
+	link, err := url.Parse(notification.Data["link"])
+	require.NoError(t, err)
+	assert.Equal(t, testLinkURL, link.Scheme+"://"+link.Host+link.Path)
+	return link.Query().Get("token")
+}
+
+func TestEmailService_VerifyEmail(t *testing.T) {
+	type mockRepoBehavior func(dbmock *db_mocks.MockUserRepository, oid primitive.ObjectID)
+
+	testTable := []struct {
+		name             string
+		email            string
+		mockRepoBehavior mockRepoBehavior
+		expectedError    error
+	}{
+		{
+			name:  "OK",
+			email: "test@test.ru",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, oid primitive.ObjectID) {
+				dbmock.EXPECT().FindByEmail(context.Background(), "test@test.ru").Return(domain.User{Id: oid}, nil)
+				dbmock.EXPECT().VerifyEmail(context.Background(), oid, "test@test.ru").Return(nil)
+			},
+		},
+		{
+			name:  "Pending email",
+			email: "new@test.ru",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, oid primitive.ObjectID) {
+				dbmock.EXPECT().FindByEmail(context.Background(), "new@test.ru").Return(domain.User{}, domain.ErrUserNotFound)
+				dbmock.EXPECT().VerifyEmail(context.Background(), oid, "new@test.ru").Return(nil)
+			},
+		},
+		{
+			name:  "Email taken by another user",
+			email: "new@test.ru",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, oid primitive.ObjectID) {
+				dbmock.EXPECT().FindByEmail(context.Background(), "new@test.ru").Return(domain.User{Id: primitive.NewObjectID()}, nil)
+			},
+			expectedError: domain.ErrUserAlreadyExists,
+		},
+		{
+			name:  "Email taken by another user meanwhile",
+			email: "new@test.ru",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, oid primitive.ObjectID) {
+				dbmock.EXPECT().FindByEmail(context.Background(), "new@test.ru").Return(domain.User{}, domain.ErrUserNotFound)
+				dbmock.EXPECT().VerifyEmail(context.Background(), oid, "new@test.ru").Return(domain.ErrUserAlreadyExists)
+			},
+			expectedError: domain.ErrUserAlreadyExists,
+		},
+		{
+			name:  "Email changed since the link was sent",
+			email: "old@test.ru",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, oid primitive.ObjectID) {
+				dbmock.EXPECT().FindByEmail(context.Background(), "old@test.ru").Return(domain.User{}, domain.ErrUserNotFound)
+				dbmock.EXPECT().VerifyEmail(context.Background(), oid, "old@test.ru").Return(domain.ErrInvalidVerificationLink)
+			},
+			expectedError: domain.ErrInvalidVerificationLink,
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			emailService, userRepoMock, outbox := mockEmailService(t, false)
+			oid := primitive.NewObjectID()
+			testCase.mockRepoBehavior(userRepoMock, oid)
+
+			err := emailService.SendVerification(context.Background(), domain.User{Id: oid}, testCase.email)
+			require.NoError(t, err)
+
+			err = emailService.VerifyEmail(context.Background(), verificationToken(t, outbox, testCase.email))
+			if testCase.expectedError != nil {
+				assert.ErrorIs(t, err, testCase.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestEmailService_VerifyEmailInvalidToken(t *testing.T) {
+	emailService, _, _ := mockEmailService(t, false)
+	tokenManager := newTokenManager(t, nil)
+	oid := primitive.NewObjectID()
+
+	accessToken, err := tokenManager.GenerateAccessToken(auth.Claims{Roles: []string{auth.UserRole}}, time.Minute)
+	require.NoError(t, err)
+	linkToken, err := tokenManager.GeneratePurposeToken(linkIdentityPurpose, auth.Claims{
+		Email:          "test@test.ru",
+		StandardClaims: jwt.StandardClaims{Subject: oid.Hex()},
+	}, time.Minute)
+	require.NoError(t, err)
+	expiredToken, err := tokenManager.GeneratePurposeToken(verifyEmailPurpose, auth.Claims{
+		Email:          "test@test.ru",
+		StandardClaims: jwt.StandardClaims{Subject: oid.Hex()},
+	}, -time.Minute)
+	require.NoError(t, err)
+
+	for name, token := range map[string]string{
+		"Empty":         "",
+		"Malformed":     "token",
+		"Access token":  accessToken[len(auth.PrefixToken):],
+		"Other purpose": linkToken,
+		"Expired":       expiredToken,
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := emailService.VerifyEmail(context.Background(), token)
+			assert.ErrorIs(t, err, domain.ErrInvalidVerificationLink)
+		})
+	}
+}
+
+func TestEmailService_ResendVerification(t *testing.T) {
+	type mockRepoBehavior func(dbmock *db_mocks.MockUserRepository)
+
+	testTable := []struct {
+		name             string
+		emailDTO         dto.EmailDTO
+		mockRepoBehavior mockRepoBehavior
+		expectedError    error
+		expectedSent     bool
+	}{
+		{
+			name:     "OK",
+			emailDTO: dto.EmailDTO{Email: "test@test.ru"},
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().FindByEmail(context.Background(), "test@test.ru").
+					Return(domain.User{Id: primitive.NewObjectID(), Email: "test@test.ru"}, nil)
+			},
+			expectedSent: true,
+		},
+		{
+			name:     "Verified email",
+			emailDTO: dto.EmailDTO{Email: "test@test.ru"},
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().FindByEmail(context.Background(), "test@test.ru").
+					Return(domain.User{Id: primitive.NewObjectID(), Email: "test@test.ru", EmailVerified: true}, nil)
+			},
+		},
+		{
+			name:     "Unknown email",
+			emailDTO: dto.EmailDTO{Email: "test@test.ru"},
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().FindByEmail(context.Background(), "test@test.ru").Return(domain.User{}, domain.ErrUserNotFound)
+			},
+		},
+		{
+			name:             "Invalid email",
+			emailDTO:         dto.EmailDTO{Email: "test"},
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {},
+			expectedError:    domain.ErrInvalidEmail,
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			emailService, userRepoMock, outbox := mockEmailService(t, false)
+			testCase.mockRepoBehavior(userRepoMock)
+
+			err := emailService.ResendVerification(context.Background(), testCase.emailDTO)
+			if testCase.expectedError != nil {
+				assert.ErrorIs(t, err, testCase.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			_, sent := outbox.Last(NotificationEmailVerification, testCase.emailDTO.Email)
+			assert.Equal(t, testCase.expectedSent, sent)
+		})
+	}
+}
+
+func TestUserService_EmailVerification(t *testing.T) {
+	passwordHash, _ := (&hash.SHA1Hasher{}).Hash("test1234")
+
+	t.Run("Link sent on create", func(t *testing.T) {
+		emailService, userRepoMock, outbox := mockEmailService(t, false)
+		userService := NewUserService(userRepoMock, newTokenManager(t, nil), &hash.SHA1Hasher{}, time.Minute, time.Minute, emailService, nil)
+		oid := primitive.NewObjectID()
+
+		userRepoMock.EXPECT().FindByEmail(context.Background(), "test@test.ru").Return(domain.User{}, domain.ErrUserNotFound)
+		userRepoMock.EXPECT().Create(context.Background(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, user domain.User) (primitive.ObjectID, error) {
+				assert.False(t, user.EmailVerified)
+				return oid, nil
+			})
+		userRepoMock.EXPECT().CreateSession(context.Background(), gomock.Any()).Return(nil)
+
+		_, err := userService.Create(context.Background(), dto.CreateUserDTO{Email: "test@test.ru", Password: "test1234"}, dto.DeviceDTO{})
+		require.NoError(t, err)
+
+		claims, err := emailService.tokenManager.ParsePurposeToken(verificationToken(t, outbox, "test@test.ru"), verifyEmailPurpose)
+		require.NoError(t, err)
+		assert.Equal(t, oid.Hex(), claims.Subject)
+		assert.Equal(t, "test@test.ru", claims.Email)
+	})
+
+	t.Run("New email is pending", func(t *testing.T) {
+		emailService, userRepoMock, outbox := mockEmailService(t, false)
+		userService := NewUserService(userRepoMock, newTokenManager(t, nil), &hash.SHA1Hasher{}, time.Minute, time.Minute, emailService, nil)
+		oid := primitive.NewObjectID()
+
+		userRepoMock.EXPECT().FindOne(context.Background(), oid).Return(domain.User{Id: oid, Email: "old@test.ru"}, nil)
+		userRepoMock.EXPECT().FindByEmail(context.Background(), "new@test.ru").Return(domain.User{}, domain.ErrUserNotFound)
+		userRepoMock.EXPECT().Update(context.Background(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, user domain.User) error {
+				assert.Equal(t, "old@test.ru", user.Email)
+				return nil
+			})
+		userRepoMock.EXPECT().SetPendingEmail(context.Background(), oid, "new@test.ru").Return(nil)
+
+		err := userService.Update(context.Background(), dto.UpdateUserDTO{Id: oid.Hex(), Email: "new@test.ru", Password: "test1234"})
+		require.NoError(t, err)
+
+		verificationToken(t, outbox, "new@test.ru")
+		_, sent := outbox.Last(NotificationEmailVerification, "old@test.ru")
+		assert.False(t, sent)
+	})
+
+	testTable := []struct {
+		name             string
+		requiredForLogin bool
+		emailVerified    bool
+		expectedError    error
+	}{
+		{name: "Unverified email allowed", requiredForLogin: false, emailVerified: false},
+		{name: "Verified email required", requiredForLogin: true, emailVerified: true},
+		{name: "Unverified email blocked", requiredForLogin: true, emailVerified: false, expectedError: domain.ErrEmailNotVerified},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			emailService, userRepoMock, _ := mockEmailService(t, testCase.requiredForLogin)
+			tokenManager := newTokenManager(t, nil)
+			userService := NewUserService(userRepoMock, tokenManager, &hash.SHA1Hasher{}, time.Minute, time.Minute, emailService, nil)
+
+			userRepoMock.EXPECT().FindByEmail(context.Background(), "test@test.ru").Return(domain.User{
+				Id:            primitive.NewObjectID(),
+				Email:         "test@test.ru",
+				PasswordHash:  passwordHash,
+				EmailVerified: testCase.emailVerified,
+			}, nil)
+			if testCase.expectedError == nil {
+				userRepoMock.EXPECT().CreateSession(context.Background(), gomock.Any()).Return(nil)
+			}
+
+			tokenDTO, err := userService.SignIn(context.Background(), dto.SignInDTO{Email: "test@test.ru", Password: "test1234"}, dto.DeviceDTO{})
+			if testCase.expectedError != nil {
+				assert.ErrorIs(t, err, testCase.expectedError)
+				return
+			}
+			require.NoError(t, err)
+
+			claims := &auth.Claims{}
+			_, err = tokenManager.Parse(tokenDTO.AccessToken[len(auth.PrefixToken):], claims)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.emailVerified, claims.EmailVerified)
+		})
+	}
+}
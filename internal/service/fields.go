@@ -2,6 +2,7 @@ package service
 
 import (
 	"reflect"
+	"strings"
 	"test/internal/domain"
 )
 
@@ -20,7 +21,8 @@ func getUserFields() []string {
 	var field []string
 	v := reflect.ValueOf(domain.User{})
 	for i := 0; i < v.Type().NumField(); i++ {
-		field = append(field, v.Type().Field(i).Tag.Get("json"))
+		name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		field = append(field, name)
 	}
 	return field
 }
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"test/internal/domain"
+	"test/internal/repository"
+	"test/internal/service/dto"
+	"test/pkg/api/auth"
+	"test/pkg/api/params"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	maxImpersonationReason = 500
+	// auditEventsLimit is how many of the latest events are returned.
+	auditEventsLimit = 100
+)
+
+// ImpersonationService lets admins act as users with short-lived access tokens. Every
+// impersonation and every request made with its token is written to the audit log first,
+// nothing happens when the event can't be stored.
+type ImpersonationService struct {
+	repository repository.UserRepository
+	audit      repository.AuditRepository
+	users      *UserService
+	tokenTTL   time.Duration
+}
+
+func NewImpersonationService(repository repository.UserRepository, audit repository.AuditRepository, users *UserService,
+	tokenTTL time.Duration) *ImpersonationService {
+	return &ImpersonationService{
+		repository: repository,
+		audit:      audit,
+		users:      users,
+		tokenTTL:   tokenTTL,
+	}
+}
+
+// Start issues an impersonation token of the user to the actor. Admins can't be
+// impersonated, the token would carry the admin role.
+func (s *ImpersonationService) Start(ctx context.Context, actor auth.Claims, userId string, startDTO dto.ImpersonateDTO,
+	device dto.DeviceDTO) (dto.ImpersonationDTO, error) {
+	reason := strings.TrimSpace(startDTO.Reason)
+	if utf8.RuneCountInString(reason) > maxImpersonationReason {
+		return dto.ImpersonationDTO{}, domain.ErrReasonTooLong
+	}
+	actorOid, err := params.ParseIdToObjectID(actor.Subject)
+	if err != nil || actor.IsImpersonated() || actor.Subject == userId {
+		return dto.ImpersonationDTO{}, domain.ErrImpersonationForbidden
+	}
+	user, err := s.users.FindOne(ctx, userId)
+	if err != nil {
+		return dto.ImpersonationDTO{}, err
+	}
+	if user.HasRole(auth.AdminRole) {
+		return dto.ImpersonationDTO{}, domain.ErrImpersonationForbidden
+	}
+
+	now := time.Now()
+	impersonation := domain.Impersonation{
+		Id:        primitive.NewObjectID(),
+		UserId:    user.Id,
+		ActorId:   actorOid,
+		Reason:    reason,
+		IP:        device.IP,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.tokenTTL),
+	}
+	if err := s.record(ctx, domain.AuditImpersonationStarted, impersonation, device); err != nil {
+		return dto.ImpersonationDTO{}, err
+	}
+	if err := s.repository.CreateImpersonation(ctx, impersonation); err != nil {
+		return dto.ImpersonationDTO{}, err
+	}
+
+	claims := auth.Claims{
+		Roles:         userRoles(user),
+		EmailVerified: user.EmailVerified,
+		Actor:         &auth.Actor{Subject: actor.Subject},
+	}
+	claims.Subject = user.Id.Hex()
+	claims.Id = impersonation.Id.Hex()
+	accessToken, err := s.users.tokenManager.GenerateAccessToken(claims, s.tokenTTL)
+	if err != nil {
+		return dto.ImpersonationDTO{}, err
+	}
+	return dto.ImpersonationDTO{AccessToken: accessToken, Impersonation: impersonation}, nil
+}
+
+func (s *ImpersonationService) FindAll(ctx context.Context, userId string) ([]domain.Impersonation, error) {
+	oid, err := params.ParseIdToObjectID(userId)
+	if err != nil {
+		return nil, err
+	}
+	return s.repository.FindImpersonations(ctx, oid)
+}
+
+// Revoke ends the impersonation of the user, the caller is recorded as the actor.
+func (s *ImpersonationService) Revoke(ctx context.Context, caller auth.Claims, userId, impersonationId string, device dto.DeviceDTO) error {
+	callerOid, err := params.ParseIdToObjectID(caller.Subject)
+	if err != nil {
+		return domain.ErrImpersonationForbidden
+	}
+	oid, err := params.ParseIdToObjectID(userId)
+	if err != nil {
+		return err
+	}
+	impersonationOid, err := params.ParseIdToObjectID(impersonationId)
+	if err != nil {
+		return err
+	}
+	impersonation, err := s.repository.FindImpersonation(ctx, oid, impersonationOid)
+	if err != nil {
+		return err
+	}
+	return s.end(ctx, callerOid, impersonation, device)
+}
+
+// End ends the impersonation the token was issued for, when the admin signs out with it.
+func (s *ImpersonationService) End(ctx context.Context, claims auth.Claims, device dto.DeviceDTO) error {
+	oid, err := params.ParseIdToObjectID(claims.Subject)
+	if err != nil {
+		return err
+	}
+	impersonationOid, err := params.ParseIdToObjectID(claims.Id)
+	if err != nil {
+		return err
+	}
+	impersonation, err := s.repository.FindImpersonation(ctx, oid, impersonationOid)
+	if err != nil {
+		return err
+	}
+	return s.end(ctx, impersonation.ActorId, impersonation, device)
+}
+
+// RecordRequest writes the request made with an impersonation token to the audit log.
+func (s *ImpersonationService) RecordRequest(ctx context.Context, claims auth.Claims, request dto.AuditRequestDTO) error {
+	if !claims.IsImpersonated() {
+		return nil
+	}
+	actorOid, err := params.ParseIdToObjectID(claims.Actor.Subject)
+	if err != nil {
+		return err
+	}
+	oid, err := params.ParseIdToObjectID(claims.Subject)
+	if err != nil {
+		return err
+	}
+	impersonationOid, err := params.ParseIdToObjectID(claims.Id)
+	if err != nil {
+		return err
+	}
+	return s.audit.Create(ctx, domain.AuditEvent{
+		Id:              primitive.NewObjectID(),
+		Action:          domain.AuditImpersonatedRequest,
+		ActorId:         actorOid,
+		UserId:          oid,
+		ImpersonationId: impersonationOid,
+		Method:          request.Method,
+		Path:            request.Path,
+		IP:              request.Device.IP,
+		UserAgent:       request.Device.UserAgent,
+		CreatedAt:       time.Now(),
+	})
+}
+
+// FindAuditEvents returns the latest audit events on the user's account and by the user.
+func (s *ImpersonationService) FindAuditEvents(ctx context.Context, userId string) ([]domain.AuditEvent, error) {
+	oid, err := params.ParseIdToObjectID(userId)
+	if err != nil {
+		return nil, err
+	}
+	return s.audit.FindByUser(ctx, oid, auditEventsLimit)
+}
+
+// end revokes the token of the impersonation and records actorId as the one who ended it.
+func (s *ImpersonationService) end(ctx context.Context, actorId primitive.ObjectID, impersonation domain.Impersonation, device dto.DeviceDTO) error {
+	if err := s.users.tokenManager.RevokeToken(ctx, impersonation.Id.Hex(), impersonation.ExpiresAt); err != nil {
+		return err
+	}
+	if err := s.repository.DeleteImpersonation(ctx, impersonation.UserId, impersonation.Id); err != nil &&
+		!errors.Is(err, domain.ErrImpersonationNotFound) {
+		return err
+	}
+
+	impersonation.ActorId = actorId
+	impersonation.Reason = ""
+	if err := s.record(ctx, domain.AuditImpersonationEnded, impersonation, device); err != nil {
+		// The token is revoked already, a missing event doesn't bring it back.
+		log.Default().Printf("failed to record end of impersonation with oid=%s due to error: %v", impersonation.Id.Hex(), err)
+	}
+	return nil
+}
+
+func (s *ImpersonationService) record(ctx context.Context, action string, impersonation domain.Impersonation, device dto.DeviceDTO) error {
+	return s.audit.Create(ctx, domain.AuditEvent{
+		Id:              primitive.NewObjectID(),
+		Action:          action,
+		ActorId:         impersonation.ActorId,
+		UserId:          impersonation.UserId,
+		ImpersonationId: impersonation.Id,
+		Reason:          impersonation.Reason,
+		IP:              device.IP,
+		UserAgent:       device.UserAgent,
+		CreatedAt:       time.Now(),
+	})
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"test/internal/domain"
+	db_mocks "test/internal/repository/mocks"
+	"test/internal/service/dto"
+	"test/pkg/api/auth"
+	"test/pkg/hash"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func mockImpersonationService(t *testing.T) (*ImpersonationService, *auth.Manager, *db_mocks.MockUserRepository,
+	*db_mocks.MockAuditRepository) {
+	t.Helper()
+
+	mockCtl := gomock.NewController(t)
+	userRepoMock := db_mocks.NewMockUserRepository(mockCtl)
+	auditRepoMock := db_mocks.NewMockAuditRepository(mockCtl)
+	tokenManager := newTokenManager(t, auth.NewMemoryDenylist())
+	userService := NewUserService(userRepoMock, tokenManager, &hash.SHA1Hasher{}, time.Minute, time.Minute, nil, nil)
+	return NewImpersonationService(userRepoMock, auditRepoMock, userService, 15*time.Minute), tokenManager, userRepoMock, auditRepoMock
+}
+
+func TestImpersonationService_Start(t *testing.T) {
+	type mockRepoBehavior func(userRepo *db_mocks.MockUserRepository, auditRepo *db_mocks.MockAuditRepository)
+
+	actor := auth.Claims{Roles: []string{auth.AdminRole}}
+	actor.Subject = primitive.NewObjectID().Hex()
+	impersonatedActor := actor
+	impersonatedActor.Actor = &auth.Actor{Subject: primitive.NewObjectID().Hex()}
+	user := domain.User{Id: primitive.NewObjectID(), Roles: []string{auth.UserRole}, EmailVerified: true}
+	admin := domain.User{Id: primitive.NewObjectID(), Roles: []string{auth.AdminRole}}
+
+	testTable := []struct {
+		name             string
+		actor            auth.Claims
+		userId           string
+		reason           string
+		mockRepoBehavior mockRepoBehavior
+		expectedErr      error
+	}{
+		{
+			name:   "OK",
+			actor:  actor,
+			userId: user.Id.Hex(),
+			reason: " ticket 42 ",
+			mockRepoBehavior: func(userRepo *db_mocks.MockUserRepository, auditRepo *db_mocks.MockAuditRepository) {
+				userRepo.EXPECT().FindOne(context.Background(), user.Id).Return(user, nil)
+				started := auditRepo.EXPECT().Create(context.Background(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, event domain.AuditEvent) error {
+						assert.Equal(t, domain.AuditImpersonationStarted, event.Action)
+						assert.Equal(t, actor.Subject, event.ActorId.Hex())
+						assert.Equal(t, user.Id, event.UserId)
+						assert.Equal(t, "ticket 42", event.Reason)
+						return nil
+					})
+				userRepo.EXPECT().CreateImpersonation(context.Background(), gomock.Any()).Return(nil).After(started)
+			},
+		},
+		{
+			name:   "Admin",
+			actor:  actor,
+			userId: admin.Id.Hex(),
+			mockRepoBehavior: func(userRepo *db_mocks.MockUserRepository, auditRepo *db_mocks.MockAuditRepository) {
+				userRepo.EXPECT().FindOne(context.Background(), admin.Id).Return(admin, nil)
+			},
+			expectedErr: domain.ErrImpersonationForbidden,
+		},
+		{
+			name:             "Self",
+			actor:            actor,
+			userId:           actor.Subject,
+			mockRepoBehavior: func(userRepo *db_mocks.MockUserRepository, auditRepo *db_mocks.MockAuditRepository) {},
+			expectedErr:      domain.ErrImpersonationForbidden,
+		},
+		{
+			name:             "Impersonated actor",
+			actor:            impersonatedActor,
+			userId:           user.Id.Hex(),
+			mockRepoBehavior: func(userRepo *db_mocks.MockUserRepository, auditRepo *db_mocks.MockAuditRepository) {},
+			expectedErr:      domain.ErrImpersonationForbidden,
+		},
+		{
+			name:             "Reason too long",
+			actor:            actor,
+			userId:           user.Id.Hex(),
+			reason:           strings.Repeat("a", maxImpersonationReason+1),
+			mockRepoBehavior: func(userRepo *db_mocks.MockUserRepository, auditRepo *db_mocks.MockAuditRepository) {},
+			expectedErr:      domain.ErrReasonTooLong,
+		},
+		{
+			name:   "Audit log failure",
+			actor:  actor,
+			userId: user.Id.Hex(),
+			mockRepoBehavior: func(userRepo *db_mocks.MockUserRepository, auditRepo *db_mocks.MockAuditRepository) {
+				userRepo.EXPECT().FindOne(context.Background(), user.Id).Return(user, nil)
+				auditRepo.EXPECT().Create(context.Background(), gomock.Any()).Return(errors.New("db failure"))
+			},
+			expectedErr: errors.New("db failure"),
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			impersonationService, tokenManager, userRepoMock, auditRepoMock := mockImpersonationService(t)
+			testCase.mockRepoBehavior(userRepoMock, auditRepoMock)
+
+			impersonationDTO, err := impersonationService.Start(context.Background(), testCase.actor, testCase.userId,
+				dto.ImpersonateDTO{Reason: testCase.reason}, dto.DeviceDTO{IP: "192.0.2.1"})
+			if testCase.expectedErr != nil {
+				assert.Equal(t, testCase.expectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var claims auth.Claims
+			_, err = tokenManager.Parse(strings.TrimPrefix(impersonationDTO.AccessToken, auth.PrefixToken), &claims)
+			require.NoError(t, err)
+			assert.Equal(t, user.Id.Hex(), claims.Subject)
+			assert.Equal(t, impersonationDTO.Id.Hex(), claims.Id)
+			require.True(t, claims.IsImpersonated())
+			assert.Equal(t, actor.Subject, claims.Actor.Subject)
+			assert.Equal(t, []string{auth.UserRole}, claims.Roles)
+		})
+	}
+}
+
+func TestImpersonationService_Revoke(t *testing.T) {
+	impersonationService, tokenManager, userRepoMock, auditRepoMock := mockImpersonationService(t)
+
+	caller := auth.Claims{Roles: []string{auth.AdminRole}}
+	caller.Subject = primitive.NewObjectID().Hex()
+	impersonation := domain.Impersonation{
+		Id:        primitive.NewObjectID(),
+		UserId:    primitive.NewObjectID(),
+		ActorId:   primitive.NewObjectID(),
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+
+	userRepoMock.EXPECT().FindImpersonation(context.Background(), impersonation.UserId, impersonation.Id).Return(impersonation, nil)
+	userRepoMock.EXPECT().DeleteImpersonation(context.Background(), impersonation.UserId, impersonation.Id).Return(nil)
+	auditRepoMock.EXPECT().Create(context.Background(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, event domain.AuditEvent) error {
+			assert.Equal(t, domain.AuditImpersonationEnded, event.Action)
+			assert.Equal(t, caller.Subject, event.ActorId.Hex(), "the caller ended the impersonation")
+			return nil
+		})
+
+	err := impersonationService.Revoke(context.Background(), caller, impersonation.UserId.Hex(), impersonation.Id.Hex(), dto.DeviceDTO{})
+	require.NoError(t, err)
+
+	revoked, err := tokenManager.IsRevoked(context.Background(), impersonation.Id.Hex())
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	userRepoMock.EXPECT().FindImpersonation(context.Background(), impersonation.UserId, impersonation.Id).
+		Return(domain.Impersonation{}, domain.ErrImpersonationNotFound)
+	err = impersonationService.Revoke(context.Background(), caller, impersonation.UserId.Hex(), impersonation.Id.Hex(), dto.DeviceDTO{})
+	assert.Equal(t, domain.ErrImpersonationNotFound, err)
+}
+
+func TestImpersonationService_RecordRequest(t *testing.T) {
+	impersonationService, _, _, auditRepoMock := mockImpersonationService(t)
+
+	claims := auth.Claims{Roles: []string{auth.UserRole}}
+	claims.Subject = primitive.NewObjectID().Hex()
+	claims.Id = primitive.NewObjectID().Hex()
+	request := dto.AuditRequestDTO{Method: "GET", Path: "/api/v1/users/" + claims.Subject, Device: dto.DeviceDTO{IP: "192.0.2.1"}}
+
+	assert.NoError(t, impersonationService.RecordRequest(context.Background(), claims, request), "own requests aren't recorded")
+
+	claims.Actor = &auth.Actor{Subject: primitive.NewObjectID().Hex()}
+	auditRepoMock.EXPECT().Create(context.Background(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, event domain.AuditEvent) error {
+			assert.Equal(t, domain.AuditImpersonatedRequest, event.Action)
+			assert.Equal(t, claims.Actor.Subject, event.ActorId.Hex())
+			assert.Equal(t, claims.Subject, event.UserId.Hex())
+			assert.Equal(t, claims.Id, event.ImpersonationId.Hex())
+			assert.Equal(t, request.Method, event.Method)
+			assert.Equal(t, request.Path, event.Path)
+			assert.Equal(t, request.Device.IP, event.IP)
+			return nil
+		})
+	assert.NoError(t, impersonationService.RecordRequest(context.Background(), claims, request))
+}
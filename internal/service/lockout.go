@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"log"
+	"test/internal/domain"
+	"test/internal/repository"
+	"test/pkg/api/params"
+	"test/pkg/lockout"
+)
+
+const (
+	accountKeyPrefix = "account:"
+	ipKeyPrefix      = "ip:"
+)
+
+// Lockout limits failed attempts to authenticate. Accounts are keyed by email, so guesses
+// of unknown emails are limited too. A nil limiter doesn't limit anything.
+type Lockout struct {
+	Accounts *lockout.Limiter
+	IPs      *lockout.Limiter
+}
+
+type LockoutService struct {
+	repository repository.UserRepository
+	config     Lockout
+}
+
+func NewLockoutService(repository repository.UserRepository, config Lockout) *LockoutService {
+	return &LockoutService{
+		repository: repository,
+		config:     config,
+	}
+}
+
+// Unlock forgets failed attempts to sign in to the account, attempts from IPs are kept.
+func (s *LockoutService) Unlock(ctx context.Context, userId string) error {
+	oid, err := params.ParseIdToObjectID(userId)
+	if err != nil {
+		return err
+	}
+	user, err := s.repository.FindOne(ctx, oid)
+	if err != nil {
+		return err
+	}
+	if s.config.Accounts == nil {
+		return nil
+	}
+	return s.config.Accounts.Reset(ctx, accountKey(user.Email))
+}
+
+// attempt counts an attempt of the IP and of the account, if the email isn't empty,
+// before the credentials are checked and returns *lockout.BlockedError when either
+// can't be tried now. Counting up front keeps concurrent guesses within the rules.
+// A nil service doesn't limit anything.
+func (s *LockoutService) attempt(ctx context.Context, email, ip string) error {
+	if s == nil {
+		return nil
+	}
+	if s.config.IPs != nil && ip != "" {
+		if err := s.config.IPs.Attempt(ctx, ipKey(ip)); err != nil {
+			return err
+		}
+	}
+	if s.config.Accounts != nil && email != "" {
+		return s.config.Accounts.Attempt(ctx, accountKey(email))
+	}
+	return nil
+}
+
+// succeed takes back the attempt of the IP and forgets failed attempts to sign in to
+// the account, if the email isn't empty.
+func (s *LockoutService) succeed(ctx context.Context, email, ip string) {
+	if s == nil {
+		return
+	}
+	if s.config.IPs != nil && ip != "" {
+		logResetError(s.config.IPs.Forgive(ctx, ipKey(ip)))
+	}
+	if s.config.Accounts != nil && email != "" {
+		logResetError(s.config.Accounts.Reset(ctx, accountKey(email)))
+	}
+}
+
+func logResetError(err error) {
+	if err != nil {
+		log.Default().Printf("failed to reset failed attempts due to error: %v", err)
+	}
+}
+
+func accountKey(email string) string {
+	return accountKeyPrefix + domain.NormalizeEmail(email)
+}
+
+func ipKey(ip string) string {
+	return ipKeyPrefix + ip
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"test/internal/domain"
+	db_mocks "test/internal/repository/mocks"
+	"test/internal/service/dto"
+	"test/pkg/hash"
+	"test/pkg/lockout"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newLimiter(t *testing.T, rule lockout.Rule) *lockout.Limiter {
+	t.Helper()
+
+	limiter, err := lockout.NewLimiter(lockout.NewMemoryStore(), rule)
+	require.NoError(t, err)
+	return limiter
+}
+
+func mockLockedUserService(t *testing.T, config Lockout) (*UserService, *LockoutService, *db_mocks.MockUserRepository) {
+	t.Helper()
+
+	userRepoMock := db_mocks.NewMockUserRepository(gomock.NewController(t))
+	lockoutService := NewLockoutService(userRepoMock, config)
+	userService := NewUserService(userRepoMock, newTokenManager(t, nil), &hash.SHA1Hasher{},
+		time.Minute, time.Minute, nil, lockoutService)
+	return userService, lockoutService, userRepoMock
+}
+
+func TestLockoutService_SignIn(t *testing.T) {
+	userService, lockoutService, userRepoMock := mockLockedUserService(t, Lockout{
+		Accounts: newLimiter(t, lockout.Rule{
+			FreeAttempts: 1,
+			BaseDelay:    time.Hour,
+			MaxDelay:     time.Hour,
+			LockAfter:    2,
+			LockDuration: time.Hour,
+			Window:       time.Hour,
+		}),
+	})
+
+	passwordHash, _ := (&hash.SHA1Hasher{}).Hash("test1234")
+	user := domain.User{Id: primitive.NewObjectID(), Email: "test@test.ru", PasswordHash: passwordHash}
+	wrongPassword := dto.SignInDTO{Email: user.Email, Password: "wrong1234"}
+
+	userRepoMock.EXPECT().FindByEmail(context.Background(), user.Email).Return(user, nil).Times(2)
+	for i := 0; i < 2; i++ {
+		_, err := userService.SignIn(context.Background(), wrongPassword, dto.DeviceDTO{})
+		assert.ErrorIs(t, err, domain.ErrWrongPassword)
+	}
+
+	// Locked out accounts aren't even looked up, emails are matched in any case.
+	_, err := userService.SignIn(context.Background(), dto.SignInDTO{Email: "TEST@test.ru", Password: "test1234"}, dto.DeviceDTO{})
+	var blockedErr *lockout.BlockedError
+	assert.ErrorAs(t, err, &blockedErr)
+	assert.ErrorIs(t, err, lockout.ErrLocked)
+
+	userRepoMock.EXPECT().FindOne(context.Background(), user.Id).Return(user, nil)
+	require.NoError(t, lockoutService.Unlock(context.Background(), user.Id.Hex()))
+
+	userRepoMock.EXPECT().FindByEmail(context.Background(), user.Email).Return(user, nil)
+	userRepoMock.EXPECT().CreateSession(context.Background(), gomock.Any()).Return(nil)
+	token, err := userService.SignIn(context.Background(), dto.SignInDTO{Email: user.Email, Password: "test1234"}, dto.DeviceDTO{})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token.AccessToken)
+}
+
+func TestLockoutService_RefreshUserToken(t *testing.T) {
+	userService, _, userRepoMock := mockLockedUserService(t, Lockout{
+		IPs: newLimiter(t, lockout.Rule{
+			BaseDelay: time.Minute,
+			MaxDelay:  time.Minute,
+			Window:    time.Hour,
+		}),
+	})
+	device := dto.DeviceDTO{IP: "192.0.2.1"}
+
+	userRepoMock.EXPECT().GetSessionByRefreshToken(context.Background(), gomock.Any()).Return(domain.Session{}, domain.ErrSessionNotFound).Times(2)
+	userRepoMock.EXPECT().GetSessionByRotatedToken(context.Background(), gomock.Any()).Return(domain.Session{}, domain.ErrSessionNotFound).Times(2)
+
+	_, err := userService.RefreshUserToken(context.Background(), "guess", device)
+	assert.ErrorIs(t, err, domain.ErrSessionNotFound)
+
+	_, err = userService.RefreshUserToken(context.Background(), "guess", device)
+	assert.ErrorIs(t, err, lockout.ErrTooManyAttempts)
+
+	_, err = userService.RefreshUserToken(context.Background(), "guess", dto.DeviceDTO{IP: "192.0.2.2"})
+	assert.ErrorIs(t, err, domain.ErrSessionNotFound)
+}
+
+func TestLockoutService_Unlock(t *testing.T) {
+	type mockRepoBehavior func(dbmock *db_mocks.MockUserRepository, oid primitive.ObjectID)
+	oid := primitive.NewObjectID()
+
+	testTable := []struct {
+		name             string
+		userId           string
+		mockRepoBehavior mockRepoBehavior
+		expectedErr      error
+	}{
+		{
+			name:   "OK",
+			userId: oid.Hex(),
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, oid primitive.ObjectID) {
+				dbmock.EXPECT().FindOne(context.Background(), oid).Return(domain.User{Id: oid, Email: "test@test.ru"}, nil)
+			},
+		},
+		{
+			name:   "User not found",
+			userId: oid.Hex(),
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, oid primitive.ObjectID) {
+				dbmock.EXPECT().FindOne(context.Background(), oid).Return(domain.User{}, domain.ErrUserNotFound)
+			},
+			expectedErr: domain.ErrUserNotFound,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, lockoutService, userRepoMock := mockLockedUserService(t, Lockout{
+				Accounts: newLimiter(t, lockout.Rule{Window: time.Hour}),
+			})
+			testCase.mockRepoBehavior(userRepoMock, oid)
+
+			err := lockoutService.Unlock(context.Background(), testCase.userId)
+			if testCase.expectedErr != nil {
+				assert.ErrorIs(t, err, testCase.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	t.Run("Invalid id", func(t *testing.T) {
+		_, lockoutService, _ := mockLockedUserService(t, Lockout{})
+		assert.Error(t, lockoutService.Unlock(context.Background(), "invalid"))
+	})
+}
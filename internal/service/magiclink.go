@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"test/internal/domain"
+	"test/internal/repository"
+	"test/internal/service/dto"
+	"test/pkg/api/auth"
+	"test/pkg/api/params"
+	"test/pkg/hash"
+	"test/pkg/lockout"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	magicLinkPurpose = "magic_link"
+	// NotificationMagicLink carries the "link" and the "token" signing the user in.
+	NotificationMagicLink = "magic_link"
+	magicLinkKeyPrefix    = "magic_link:"
+)
+
+// MagicLink configures passwordless sign in with links sent to the email.
+type MagicLink struct {
+	Enabled bool
+	// LinkURL is where the link token is sent to in the "token" query parameter.
+	LinkURL  string
+	TokenTTL time.Duration
+	// Limiter counts links sent to an address, a nil limiter doesn't limit anything.
+	Limiter *lockout.Limiter
+}
+
+// MagicLinkService signs users in with single-use links sent to their email. The link
+// works only in the browser it was requested from, the browser keeps a secret the link
+// is bound to, so a link read from the mailbox by someone else is of no use.
+type MagicLinkService struct {
+	repository repository.UserRepository
+	users      *UserService
+	notifier   Notifier
+	config     MagicLink
+}
+
+func NewMagicLinkService(repository repository.UserRepository, users *UserService, notifier Notifier,
+	config MagicLink) *MagicLinkService {
+	return &MagicLinkService{
+		repository: repository,
+		users:      users,
+		notifier:   notifier,
+		config:     config,
+	}
+}
+
+// Send emails the link to the user with the email and returns the browser secret the
+// link is bound to. Unknown emails are not reported, like in ForgotPassword, they get
+// a secret too.
+func (s *MagicLinkService) Send(ctx context.Context, emailDTO dto.EmailDTO) (string, error) {
+	if !s.config.Enabled {
+		return "", domain.ErrMagicLinkDisabled
+	}
+	if !dto.ValidEmailDTO(emailDTO) {
+		return "", domain.ErrInvalidEmail
+	}
+	if s.config.Limiter != nil {
+		if err := s.config.Limiter.Attempt(ctx, magicLinkKey(emailDTO.Email)); err != nil {
+			return "", err
+		}
+	}
+
+	browser, err := generateSecret("")
+	if err != nil {
+		return "", err
+	}
+	user, err := s.repository.FindByEmail(ctx, emailDTO.Email)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return browser, nil
+		}
+		return "", err
+	}
+
+	tokenId, err := auth.GenerateTokenId()
+	if err != nil {
+		return "", err
+	}
+	link := domain.MagicLink{
+		TokenId:     tokenId,
+		BrowserHash: hash.HashToken(browser),
+		ExpiresAt:   time.Now().Add(s.config.TokenTTL),
+	}
+	if err := s.repository.SetMagicLink(ctx, user.Id, link); err != nil {
+		return "", err
+	}
+
+	token, err := s.users.tokenManager.GeneratePurposeToken(magicLinkPurpose, auth.Claims{
+		StandardClaims: jwt.StandardClaims{Subject: user.Id.Hex(), Id: tokenId},
+	}, s.config.TokenTTL)
+	if err != nil {
+		return "", err
+	}
+	linkURL, err := url.Parse(s.config.LinkURL)
+	if err != nil {
+		return "", err
+	}
+	query := linkURL.Query()
+	query.Set("token", token)
+	linkURL.RawQuery = query.Encode()
+
+	err = s.notifier.Notify(ctx, Notification{
+		Type: NotificationMagicLink,
+		To:   user.Email,
+		Data: map[string]string{"link": linkURL.String(), "token": token},
+	})
+	if err != nil {
+		return "", err
+	}
+	return browser, nil
+}
+
+// Consume signs in the user the link was sent to, when it is opened in the browser with
+// the secret. Users with the second factor get the challenge token, as on sign in.
+func (s *MagicLinkService) Consume(ctx context.Context, token, browser string, device dto.DeviceDTO) (dto.TokenDTO, error) {
+	if !s.config.Enabled {
+		return dto.TokenDTO{}, domain.ErrMagicLinkDisabled
+	}
+	claims, err := s.users.tokenManager.ParsePurposeToken(token, magicLinkPurpose)
+	if err != nil || claims.Id == "" || browser == "" {
+		return dto.TokenDTO{}, domain.ErrInvalidMagicLink
+	}
+	oid, err := params.ParseIdToObjectID(claims.Subject)
+	if err != nil {
+		return dto.TokenDTO{}, domain.ErrInvalidMagicLink
+	}
+
+	user, err := s.repository.UseMagicLink(ctx, oid, claims.Id, hash.HashToken(browser))
+	if err != nil {
+		return dto.TokenDTO{}, err
+	}
+	return s.users.beginSession(ctx, user, device)
+}
+
+// magicLinkKey limits links sent to an address however its case and spacing are written.
+func magicLinkKey(email string) string {
+	return magicLinkKeyPrefix + domain.NormalizeEmail(email)
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"test/internal/domain"
+	db_mocks "test/internal/repository/mocks"
+	"test/internal/service/dto"
+	"test/pkg/api/auth"
+	"test/pkg/hash"
+	"test/pkg/lockout"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func mockMagicLinkService(t *testing.T, config MagicLink) (*MagicLinkService, *db_mocks.MockUserRepository, *Outbox) {
+	t.Helper()
+
+	userRepoMock := db_mocks.NewMockUserRepository(gomock.NewController(t))
+	userService := NewUserService(userRepoMock, newTokenManager(t, nil), &hash.SHA1Hasher{}, time.Minute, time.Minute, nil, nil)
+	outbox := NewOutbox()
+	return NewMagicLinkService(userRepoMock, userService, outbox, config), userRepoMock, outbox
+}
+
+func TestMagicLinkService_SendAndConsume(t *testing.T) {
+	magicLinkService, userRepoMock, outbox := mockMagicLinkService(t, MagicLink{
+		Enabled:  true,
+		LinkURL:  "http://localhost/magic-link/consume",
+		TokenTTL: time.Minute,
+	})
+	user := domain.User{Id: primitive.NewObjectID(), Email: "test@test.com", Roles: []string{auth.UserRole}}
+
+	var link domain.MagicLink
+	userRepoMock.EXPECT().FindByEmail(context.Background(), user.Email).Return(user, nil)
+	userRepoMock.EXPECT().SetMagicLink(context.Background(), user.Id, gomock.Any()).
+		DoAndReturn(func(ctx context.Context, oid primitive.ObjectID, magicLink domain.MagicLink) error {
+			link = magicLink
+			return nil
+		})
+
+	browser, err := magicLinkService.Send(context.Background(), dto.EmailDTO{Email: user.Email})
+	require.NoError(t, err)
+	assert.NotEmpty(t, browser)
+	assert.Equal(t, hash.HashToken(browser), link.BrowserHash, "only the hash of the browser secret is stored")
+
+	notification, ok := outbox.Last(NotificationMagicLink, user.Email)
+	require.True(t, ok)
+	linkURL, err := url.Parse(notification.Data["link"])
+	require.NoError(t, err)
+	token := linkURL.Query().Get("token")
+	assert.Equal(t, notification.Data["token"], token)
+
+	_, err = magicLinkService.Consume(context.Background(), token, "", dto.DeviceDTO{})
+	assert.Equal(t, domain.ErrInvalidMagicLink, err, "browser without the secret")
+	_, err = magicLinkService.Consume(context.Background(), "forged", browser, dto.DeviceDTO{})
+	assert.Equal(t, domain.ErrInvalidMagicLink, err, "forged token")
+
+	userRepoMock.EXPECT().UseMagicLink(context.Background(), user.Id, link.TokenId, hash.HashToken("other")).
+		Return(domain.User{}, domain.ErrInvalidMagicLink)
+	_, err = magicLinkService.Consume(context.Background(), token, "other", dto.DeviceDTO{})
+	assert.Equal(t, domain.ErrInvalidMagicLink, err, "another browser")
+
+	userRepoMock.EXPECT().UseMagicLink(context.Background(), user.Id, link.TokenId, link.BrowserHash).Return(user, nil)
+	userRepoMock.EXPECT().CreateSession(context.Background(), gomock.Any()).Return(nil)
+	tokenDTO, err := magicLinkService.Consume(context.Background(), token, browser, dto.DeviceDTO{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, tokenDTO.AccessToken)
+	assert.NotEmpty(t, tokenDTO.RefreshToken)
+}
+
+func TestMagicLinkService_Send(t *testing.T) {
+	type mockRepoBehavior func(dbmock *db_mocks.MockUserRepository, email string)
+
+	testTable := []struct {
+		name             string
+		config           MagicLink
+		email            string
+		mockRepoBehavior mockRepoBehavior
+		expectedErr      error
+		expectedSent     bool
+	}{
+		{
+			name:   "Unknown email",
+			config: MagicLink{Enabled: true},
+			email:  "test@test.com",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, email string) {
+				dbmock.EXPECT().FindByEmail(context.Background(), email).Return(domain.User{}, domain.ErrUserNotFound)
+			},
+		},
+		{
+			name:             "Invalid email",
+			config:           MagicLink{Enabled: true},
+			email:            "test",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, email string) {},
+			expectedErr:      domain.ErrInvalidEmail,
+		},
+		{
+			name:             "Disabled",
+			email:            "test@test.com",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, email string) {},
+			expectedErr:      domain.ErrMagicLinkDisabled,
+		},
+		{
+			name:   "DB failure",
+			config: MagicLink{Enabled: true},
+			email:  "test@test.com",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, email string) {
+				dbmock.EXPECT().FindByEmail(context.Background(), email).Return(domain.User{}, errors.New("db failure"))
+			},
+			expectedErr: errors.New("db failure"),
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			magicLinkService, userRepoMock, outbox := mockMagicLinkService(t, testCase.config)
+			testCase.mockRepoBehavior(userRepoMock, testCase.email)
+
+			browser, err := magicLinkService.Send(context.Background(), dto.EmailDTO{Email: testCase.email})
+			assert.Equal(t, testCase.expectedErr, err)
+			if testCase.expectedErr == nil {
+				assert.NotEmpty(t, browser, "unknown emails get a secret too")
+			}
+			assert.Empty(t, outbox.Notifications())
+		})
+	}
+}
+
+func TestMagicLinkService_SendRateLimit(t *testing.T) {
+	limiter, err := lockout.NewLimiter(lockout.NewMemoryStore(), lockout.Rule{LockAfter: 2, LockDuration: time.Hour, Window: time.Hour})
+	require.NoError(t, err)
+	magicLinkService, userRepoMock, _ := mockMagicLinkService(t, MagicLink{Enabled: true, Limiter: limiter})
+	userRepoMock.EXPECT().FindByEmail(context.Background(), gomock.Any()).Return(domain.User{}, domain.ErrUserNotFound).Times(3)
+
+	for i := 0; i < 2; i++ {
+		_, err := magicLinkService.Send(context.Background(), dto.EmailDTO{Email: "test@test.com"})
+		require.NoError(t, err)
+	}
+
+	// The same address in another case is limited too.
+	_, err = magicLinkService.Send(context.Background(), dto.EmailDTO{Email: "TEST@test.com"})
+	var blockedErr *lockout.BlockedError
+	require.ErrorAs(t, err, &blockedErr)
+	assert.Greater(t, blockedErr.RetryAfter, time.Duration(0))
+
+	_, err = magicLinkService.Send(context.Background(), dto.EmailDTO{Email: "other@test.com"})
+	assert.NoError(t, err, "addresses are limited apart")
+}
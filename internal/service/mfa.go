@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"test/internal/domain"
+	"test/internal/repository"
+	"test/internal/service/dto"
+	"test/pkg/api/params"
+	"test/pkg/hash"
+	"test/pkg/totp"
+	"time"
+)
+
+const (
+	mfaPurpose = "mfa"
+	// mfaChallengeTTL is how long the user has to enter the code after the first factor.
+	mfaChallengeTTL = 5 * time.Minute
+	// recoveryCodeSize is in bytes, a code is 8 base32 letters.
+	recoveryCodeSize = 5
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTP configures the authenticator apps users enroll as the second factor.
+type TOTP struct {
+	// Issuer names the service in authenticator apps.
+	Issuer string
+	// Skew is how many time steps before and after the current one codes are accepted.
+	Skew          int
+	RecoveryCodes int
+}
+
+type MFAService struct {
+	repository repository.UserRepository
+	users      *UserService
+	config     TOTP
+}
+
+func NewMFAService(repository repository.UserRepository, users *UserService, config TOTP) *MFAService {
+	return &MFAService{
+		repository: repository,
+		users:      users,
+		config:     config,
+	}
+}
+
+// Enroll generates a new secret for the user, the second factor is enabled once the
+// user confirms it with a code.
+func (s *MFAService) Enroll(ctx context.Context, userId string) (dto.MFAEnrollmentDTO, error) {
+	user, err := s.users.FindOne(ctx, userId)
+	if err != nil {
+		return dto.MFAEnrollmentDTO{}, err
+	}
+	if user.MFAEnabled() {
+		return dto.MFAEnrollmentDTO{}, domain.ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return dto.MFAEnrollmentDTO{}, err
+	}
+	if err := s.repository.SetMFASecret(ctx, user.Id, secret); err != nil {
+		return dto.MFAEnrollmentDTO{}, err
+	}
+
+	return dto.MFAEnrollmentDTO{
+		Secret: secret,
+		URI:    totp.ProvisioningURI(s.config.Issuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm enables the second factor with the first code of the enrolled secret and
+// returns recovery codes. They are shown once, only their hashes are stored.
+func (s *MFAService) Confirm(ctx context.Context, userId string, codeDTO dto.MFACodeDTO) (dto.RecoveryCodesDTO, error) {
+	user, err := s.users.FindOne(ctx, userId)
+	if err != nil {
+		return dto.RecoveryCodesDTO{}, err
+	}
+	if user.MFA == nil {
+		return dto.RecoveryCodesDTO{}, domain.ErrMFANotEnrolled
+	}
+	if user.MFA.Enabled {
+		return dto.RecoveryCodesDTO{}, domain.ErrMFAAlreadyEnabled
+	}
+
+	step, ok := totp.Validate(user.MFA.Secret, normalizeCode(codeDTO.Code), time.Now(), s.config.Skew)
+	if !ok {
+		return dto.RecoveryCodesDTO{}, domain.ErrInvalidMFACode
+	}
+
+	codes, hashes, err := generateRecoveryCodes(s.config.RecoveryCodes)
+	if err != nil {
+		return dto.RecoveryCodesDTO{}, err
+	}
+	if err := s.repository.EnableMFA(ctx, user.Id, user.MFA.Secret, step, hashes); err != nil {
+		return dto.RecoveryCodesDTO{}, err
+	}
+	return dto.RecoveryCodesDTO{RecoveryCodes: codes}, nil
+}
+
+// Disable turns the second factor off for the user who proves having it.
+func (s *MFAService) Disable(ctx context.Context, userId string, codeDTO dto.MFACodeDTO) error {
+	user, err := s.users.FindOne(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if !user.MFAEnabled() {
+		return domain.ErrMFANotEnabled
+	}
+	if err := s.verifyCode(ctx, user, codeDTO.Code); err != nil {
+		return err
+	}
+	return s.repository.DisableMFA(ctx, user.Id)
+}
+
+// Reset turns the second factor off without a code, for admins helping users who lost
+// both the authenticator and the recovery codes.
+func (s *MFAService) Reset(ctx context.Context, userId string) error {
+	oid, err := params.ParseIdToObjectID(userId)
+	if err != nil {
+		return err
+	}
+	return s.repository.DisableMFA(ctx, oid)
+}
+
+// Verify completes sign in started with the first factor, the challenge token is the
+// one returned instead of the tokens.
+func (s *MFAService) Verify(ctx context.Context, verifyDTO dto.MFAVerifyDTO, device dto.DeviceDTO) (dto.TokenDTO, error) {
+	claims, err := s.users.tokenManager.ParsePurposeToken(verifyDTO.MFAToken, mfaPurpose)
+	if err != nil {
+		return dto.TokenDTO{}, domain.ErrInvalidMFAChallenge
+	}
+	user, err := s.users.FindOne(ctx, claims.Subject)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return dto.TokenDTO{}, domain.ErrInvalidMFAChallenge
+		}
+		return dto.TokenDTO{}, err
+	}
+	if !user.MFAEnabled() {
+		return dto.TokenDTO{}, domain.ErrInvalidMFAChallenge
+	}
+	if err := s.users.lockout.attempt(ctx, user.Email, device.IP); err != nil {
+		return dto.TokenDTO{}, err
+	}
+
+	if err := s.verifyCode(ctx, user, verifyDTO.Code); err != nil {
+		return dto.TokenDTO{}, err
+	}
+	s.users.lockout.succeed(ctx, user.Email, device.IP)
+	return s.users.CreateSession(ctx, user, device)
+}
+
+// verifyCode accepts a code of the authenticator once, or an unused recovery code.
+func (s *MFAService) verifyCode(ctx context.Context, user domain.User, code string) error {
+	code = normalizeCode(code)
+	if code == "" {
+		return domain.ErrInvalidMFACode
+	}
+	if len(code) != totp.Digits || strings.Trim(code, "0123456789") != "" {
+		return s.repository.UseRecoveryCode(ctx, user.Id, hash.HashToken(code))
+	}
+
+	step, ok := totp.Validate(user.MFA.Secret, code, time.Now(), s.config.Skew)
+	if !ok {
+		return domain.ErrInvalidMFACode
+	}
+	return s.repository.UseMFAStep(ctx, user.Id, step)
+}
+
+// normalizeCode lets users type codes with spaces, dashes and in any case.
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(code))
+}
+
+// generateRecoveryCodes returns codes formatted like "abcd-efgh" and hashes of them.
+func generateRecoveryCodes(n int) ([]string, []string, error) {
+	codes := make([]string, 0, n)
+	hashes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		b := make([]byte, recoveryCodeSize)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code due to error: %v", err)
+		}
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+		codes = append(codes, code[:4]+"-"+code[4:])
+		hashes = append(hashes, hash.HashToken(code))
+	}
+	return codes, hashes, nil
+}
@@ -0,0 +1,393 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"test/internal/domain"
+	db_mocks "test/internal/repository/mocks"
+	"test/internal/service/dto"
+	"test/pkg/api/auth"
+	"test/pkg/hash"
+	"test/pkg/totp"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func mockMFAService(t *testing.T) (*MFAService, *UserService, *db_mocks.MockUserRepository) {
+	t.Helper()
+
+	userService, userRepoMock := mockUserService(t)
+	return NewMFAService(userRepoMock, userService, TOTP{Issuer: "Users", Skew: 1, RecoveryCodes: 10}), userService, userRepoMock
+}
+
+func newTOTPSecret(t *testing.T) string {
+	t.Helper()
+
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	return secret
+}
+
+func codeAt(t *testing.T, secret string, step int64) string {
+	t.Helper()
+
+	code, err := totp.Code(secret, step)
+	require.NoError(t, err)
+	return code
+}
+
+func TestMFAService_Enroll(t *testing.T) {
+	type mockRepoBehavior func(dbmock *db_mocks.MockUserRepository, user domain.User)
+
+	testTable := []struct {
+		name             string
+		mfa              *domain.MFA
+		mockRepoBehavior mockRepoBehavior
+		expectedError    error
+	}{
+		{
+			name: "OK",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {
+				dbmock.EXPECT().FindOne(context.Background(), user.Id).Return(user, nil)
+				dbmock.EXPECT().SetMFASecret(context.Background(), user.Id, gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name: "Unfinished enrollment",
+			mfa:  &domain.MFA{Secret: "SECRET"},
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {
+				dbmock.EXPECT().FindOne(context.Background(), user.Id).Return(user, nil)
+				dbmock.EXPECT().SetMFASecret(context.Background(), user.Id, gomock.Not("SECRET")).Return(nil)
+			},
+		},
+		{
+			name: "Already enabled",
+			mfa:  &domain.MFA{Secret: "SECRET", Enabled: true},
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {
+				dbmock.EXPECT().FindOne(context.Background(), user.Id).Return(user, nil)
+			},
+			expectedError: domain.ErrMFAAlreadyEnabled,
+		},
+		{
+			name: "User not found",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {
+				dbmock.EXPECT().FindOne(context.Background(), user.Id).Return(domain.User{}, domain.ErrUserNotFound)
+			},
+			expectedError: domain.ErrUserNotFound,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			mfaService, _, userRepoMock := mockMFAService(t)
+			user := domain.User{Id: primitive.NewObjectID(), Email: "test@test.ru", MFA: testCase.mfa}
+			testCase.mockRepoBehavior(userRepoMock, user)
+
+			enrollmentDTO, err := mfaService.Enroll(context.Background(), user.Id.Hex())
+			if testCase.expectedError != nil {
+				assert.ErrorIs(t, err, testCase.expectedError)
+				return
+			}
+			require.NoError(t, err)
+
+			uri, err := url.Parse(enrollmentDTO.URI)
+			require.NoError(t, err)
+			assert.Equal(t, "/Users:test@test.ru", uri.Path)
+			assert.Equal(t, enrollmentDTO.Secret, uri.Query().Get("secret"))
+			_, err = totp.Code(enrollmentDTO.Secret, 1)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestMFAService_Confirm(t *testing.T) {
+	type mockRepoBehavior func(dbmock *db_mocks.MockUserRepository, user domain.User)
+
+	secret := newTOTPSecret(t)
+	step := totp.Step(time.Now())
+
+	testTable := []struct {
+		name             string
+		mfa              *domain.MFA
+		code             string
+		mockRepoBehavior mockRepoBehavior
+		expectedError    error
+	}{
+		{
+			name: "OK",
+			mfa:  &domain.MFA{Secret: secret},
+			code: codeAt(t, secret, step),
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {
+				dbmock.EXPECT().FindOne(context.Background(), user.Id).Return(user, nil)
+				dbmock.EXPECT().EnableMFA(context.Background(), user.Id, secret, step, gomock.Len(10)).Return(nil)
+			},
+		},
+		{
+			name: "Wrong code",
+			mfa:  &domain.MFA{Secret: secret},
+			code: "000000",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {
+				dbmock.EXPECT().FindOne(context.Background(), user.Id).Return(user, nil)
+			},
+			expectedError: domain.ErrInvalidMFACode,
+		},
+		{
+			name: "Not enrolled",
+			code: "000000",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {
+				dbmock.EXPECT().FindOne(context.Background(), user.Id).Return(user, nil)
+			},
+			expectedError: domain.ErrMFANotEnrolled,
+		},
+		{
+			name: "Already enabled",
+			mfa:  &domain.MFA{Secret: secret, Enabled: true},
+			code: codeAt(t, secret, step),
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {
+				dbmock.EXPECT().FindOne(context.Background(), user.Id).Return(user, nil)
+			},
+			expectedError: domain.ErrMFAAlreadyEnabled,
+		},
+		{
+			name: "Secret replaced meanwhile",
+			mfa:  &domain.MFA{Secret: secret},
+			code: codeAt(t, secret, step),
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {
+				dbmock.EXPECT().FindOne(context.Background(), user.Id).Return(user, nil)
+				dbmock.EXPECT().EnableMFA(context.Background(), user.Id, secret, gomock.Any(), gomock.Any()).Return(domain.ErrMFANotEnrolled)
+			},
+			expectedError: domain.ErrMFANotEnrolled,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			mfaService, _, userRepoMock := mockMFAService(t)
+			user := domain.User{Id: primitive.NewObjectID(), MFA: testCase.mfa}
+			testCase.mockRepoBehavior(userRepoMock, user)
+
+			recoveryCodesDTO, err := mfaService.Confirm(context.Background(), user.Id.Hex(), dto.MFACodeDTO{Code: testCase.code})
+			if testCase.expectedError != nil {
+				assert.ErrorIs(t, err, testCase.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, recoveryCodesDTO.RecoveryCodes, 10)
+			assert.Regexp(t, "^[a-z2-7]{4}-[a-z2-7]{4}$", recoveryCodesDTO.RecoveryCodes[0])
+		})
+	}
+}
+
+func TestMFAService_Verify(t *testing.T) {
+	type mockRepoBehavior func(dbmock *db_mocks.MockUserRepository, user domain.User)
+
+	secret := newTOTPSecret(t)
+	step := totp.Step(time.Now())
+	passwordHash, _ := (&hash.SHA1Hasher{}).Hash("test1234")
+
+	testTable := []struct {
+		name             string
+		code             string
+		mockRepoBehavior mockRepoBehavior
+		expectedError    error
+	}{
+		{
+			name: "OK",
+			code: codeAt(t, secret, step),
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {
+				dbmock.EXPECT().FindOne(context.Background(), user.Id).Return(user, nil)
+				dbmock.EXPECT().UseMFAStep(context.Background(), user.Id, step).Return(nil)
+				dbmock.EXPECT().CreateSession(context.Background(), gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name: "Code with spaces",
+			code: codeAt(t, secret, step)[:3] + " " + codeAt(t, secret, step)[3:],
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {
+				dbmock.EXPECT().FindOne(context.Background(), user.Id).Return(user, nil)
+				dbmock.EXPECT().UseMFAStep(context.Background(), user.Id, step).Return(nil)
+				dbmock.EXPECT().CreateSession(context.Background(), gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name: "Replayed code",
+			code: codeAt(t, secret, step),
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {
+				dbmock.EXPECT().FindOne(context.Background(), user.Id).Return(user, nil)
+				dbmock.EXPECT().UseMFAStep(context.Background(), user.Id, step).Return(domain.ErrMFACodeReused)
+			},
+			expectedError: domain.ErrInvalidMFACode,
+		},
+		{
+			name: "Wrong code",
+			code: "000000",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {
+				dbmock.EXPECT().FindOne(context.Background(), user.Id).Return(user, nil)
+			},
+			expectedError: domain.ErrInvalidMFACode,
+		},
+		{
+			name: "Recovery code",
+			code: "ABCD-EFGH",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {
+				dbmock.EXPECT().FindOne(context.Background(), user.Id).Return(user, nil)
+				dbmock.EXPECT().UseRecoveryCode(context.Background(), user.Id, hash.HashToken("abcdefgh")).Return(nil)
+				dbmock.EXPECT().CreateSession(context.Background(), gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name: "Used recovery code",
+			code: "abcd-efgh",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {
+				dbmock.EXPECT().FindOne(context.Background(), user.Id).Return(user, nil)
+				dbmock.EXPECT().UseRecoveryCode(context.Background(), user.Id, hash.HashToken("abcdefgh")).Return(domain.ErrInvalidMFACode)
+			},
+			expectedError: domain.ErrInvalidMFACode,
+		},
+		{
+			name: "Disabled since the challenge",
+			code: codeAt(t, secret, step),
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {
+				user.MFA = nil
+				dbmock.EXPECT().FindOne(context.Background(), user.Id).Return(user, nil)
+			},
+			expectedError: domain.ErrInvalidMFAChallenge,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			mfaService, userService, userRepoMock := mockMFAService(t)
+			user := domain.User{
+				Id:           primitive.NewObjectID(),
+				Email:        "test@test.ru",
+				PasswordHash: passwordHash,
+				MFA:          &domain.MFA{Secret: secret, Enabled: true},
+			}
+
+			userRepoMock.EXPECT().FindByEmail(context.Background(), user.Email).Return(user, nil)
+			challenge, err := userService.SignIn(context.Background(), dto.SignInDTO{Email: user.Email, Password: "test1234"}, dto.DeviceDTO{})
+			require.NoError(t, err)
+			require.NotEmpty(t, challenge.MFAToken)
+			assert.Empty(t, challenge.AccessToken)
+			assert.Empty(t, challenge.RefreshToken)
+
+			testCase.mockRepoBehavior(userRepoMock, user)
+			tokenDTO, err := mfaService.Verify(context.Background(), dto.MFAVerifyDTO{
+				MFAToken: challenge.MFAToken,
+				Code:     testCase.code,
+			}, dto.DeviceDTO{})
+			if testCase.expectedError != nil {
+				assert.ErrorIs(t, err, testCase.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, tokenDTO.AccessToken)
+			assert.NotEmpty(t, tokenDTO.RefreshToken)
+			assert.Empty(t, tokenDTO.MFAToken)
+		})
+	}
+}
+
+func TestMFAService_VerifyInvalidChallenge(t *testing.T) {
+	mfaService, userService, userRepoMock := mockMFAService(t)
+	user := domain.User{Id: primitive.NewObjectID(), MFA: &domain.MFA{Secret: newTOTPSecret(t), Enabled: true}}
+
+	userRepoMock.EXPECT().CreateSession(context.Background(), gomock.Any()).Return(nil)
+	tokenDTO, err := userService.CreateSession(context.Background(), user, dto.DeviceDTO{})
+	require.NoError(t, err)
+	claims := auth.Claims{StandardClaims: jwt.StandardClaims{Subject: user.Id.Hex()}}
+	verifyToken, err := userService.tokenManager.GeneratePurposeToken(verifyEmailPurpose, claims, time.Minute)
+	require.NoError(t, err)
+	expiredToken, err := userService.tokenManager.GeneratePurposeToken(mfaPurpose, claims, -time.Minute)
+	require.NoError(t, err)
+
+	for name, token := range map[string]string{
+		"Empty":         "",
+		"Access token":  tokenDTO.AccessToken[len(auth.PrefixToken):],
+		"Other purpose": verifyToken,
+		"Expired":       expiredToken,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := mfaService.Verify(context.Background(), dto.MFAVerifyDTO{MFAToken: token, Code: "000000"}, dto.DeviceDTO{})
+			assert.ErrorIs(t, err, domain.ErrInvalidMFAChallenge)
+		})
+	}
+}
+
+func TestMFAService_Disable(t *testing.T) {
+	type mockRepoBehavior func(dbmock *db_mocks.MockUserRepository, user domain.User)
+
+	secret := newTOTPSecret(t)
+	step := totp.Step(time.Now())
+	dbErr := errors.New("db error")
+
+	testTable := []struct {
+		name             string
+		mfa              *domain.MFA
+		code             string
+		mockRepoBehavior mockRepoBehavior
+		expectedError    error
+	}{
+		{
+			name: "OK",
+			mfa:  &domain.MFA{Secret: secret, Enabled: true},
+			code: codeAt(t, secret, step),
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {
+				dbmock.EXPECT().FindOne(context.Background(), user.Id).Return(user, nil)
+				dbmock.EXPECT().UseMFAStep(context.Background(), user.Id, gomock.Any()).Return(nil)
+				dbmock.EXPECT().DisableMFA(context.Background(), user.Id).Return(nil)
+			},
+		},
+		{
+			name: "Wrong code",
+			mfa:  &domain.MFA{Secret: secret, Enabled: true},
+			code: "000000",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {
+				dbmock.EXPECT().FindOne(context.Background(), user.Id).Return(user, nil)
+			},
+			expectedError: domain.ErrInvalidMFACode,
+		},
+		{
+			name: "Not enabled",
+			mfa:  &domain.MFA{Secret: secret},
+			code: codeAt(t, secret, step),
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {
+				dbmock.EXPECT().FindOne(context.Background(), user.Id).Return(user, nil)
+			},
+			expectedError: domain.ErrMFANotEnabled,
+		},
+		{
+			name: "Repository error",
+			mfa:  &domain.MFA{Secret: secret, Enabled: true},
+			code: codeAt(t, secret, step),
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {
+				dbmock.EXPECT().FindOne(context.Background(), user.Id).Return(user, nil)
+				dbmock.EXPECT().UseMFAStep(context.Background(), user.Id, gomock.Any()).Return(dbErr)
+			},
+			expectedError: dbErr,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			mfaService, _, userRepoMock := mockMFAService(t)
+			user := domain.User{Id: primitive.NewObjectID(), MFA: testCase.mfa}
+			testCase.mockRepoBehavior(userRepoMock, user)
+
+			err := mfaService.Disable(context.Background(), user.Id.Hex(), dto.MFACodeDTO{Code: testCase.code})
+			if testCase.expectedError != nil {
+				assert.ErrorIs(t, err, testCase.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
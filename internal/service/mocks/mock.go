@@ -9,9 +9,10 @@ import (
 	reflect "reflect"
 	domain "test/internal/domain"
 	dto "test/internal/service/dto"
+	auth "test/pkg/api/auth"
+	oauth "test/pkg/oauth"
 
 	gomock "github.com/golang/mock/gomock"
-	primitive "go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // MockUsers is a mock of Users interface.
@@ -37,34 +38,48 @@ func (m *MockUsers) EXPECT() *MockUsersMockRecorder {
 	return m.recorder
 }
 
+// BootstrapAdmin mocks base method.
+func (m *MockUsers) BootstrapAdmin(ctx context.Context, email, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BootstrapAdmin", ctx, email, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BootstrapAdmin indicates an expected call of BootstrapAdmin.
+func (mr *MockUsersMockRecorder) BootstrapAdmin(ctx, email, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BootstrapAdmin", reflect.TypeOf((*MockUsers)(nil).BootstrapAdmin), ctx, email, password)
+}
+
 // Create mocks base method.
-func (m *MockUsers) Create(ctx context.Context, userDTO dto.CreateUserDTO) (dto.TokenDTO, error) {
+func (m *MockUsers) Create(ctx context.Context, userDTO dto.CreateUserDTO, device dto.DeviceDTO) (dto.TokenDTO, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", ctx, userDTO)
+	ret := m.ctrl.Call(m, "Create", ctx, userDTO, device)
 	ret0, _ := ret[0].(dto.TokenDTO)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockUsersMockRecorder) Create(ctx, userDTO interface{}) *gomock.Call {
+func (mr *MockUsersMockRecorder) Create(ctx, userDTO, device interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockUsers)(nil).Create), ctx, userDTO)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockUsers)(nil).Create), ctx, userDTO, device)
 }
 
 // CreateSession mocks base method.
-func (m *MockUsers) CreateSession(ctx context.Context, oid primitive.ObjectID) (dto.TokenDTO, error) {
+func (m *MockUsers) CreateSession(ctx context.Context, user domain.User, device dto.DeviceDTO) (dto.TokenDTO, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateSession", ctx, oid)
+	ret := m.ctrl.Call(m, "CreateSession", ctx, user, device)
 	ret0, _ := ret[0].(dto.TokenDTO)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateSession indicates an expected call of CreateSession.
-func (mr *MockUsersMockRecorder) CreateSession(ctx, oid interface{}) *gomock.Call {
+func (mr *MockUsersMockRecorder) CreateSession(ctx, user, device interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockUsers)(nil).CreateSession), ctx, oid)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockUsers)(nil).CreateSession), ctx, user, device)
 }
 
 // Delete mocks base method.
@@ -126,19 +141,133 @@ func (mr *MockUsersMockRecorder) FindOne(ctx, id interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOne", reflect.TypeOf((*MockUsers)(nil).FindOne), ctx, id)
 }
 
+// FindSessions mocks base method.
+func (m *MockUsers) FindSessions(ctx context.Context, userId string) ([]domain.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindSessions", ctx, userId)
+	ret0, _ := ret[0].([]domain.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindSessions indicates an expected call of FindSessions.
+func (mr *MockUsersMockRecorder) FindSessions(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindSessions", reflect.TypeOf((*MockUsers)(nil).FindSessions), ctx, userId)
+}
+
+// GrantRole mocks base method.
+func (m *MockUsers) GrantRole(ctx context.Context, id, role string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GrantRole", ctx, id, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GrantRole indicates an expected call of GrantRole.
+func (mr *MockUsersMockRecorder) GrantRole(ctx, id, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GrantRole", reflect.TypeOf((*MockUsers)(nil).GrantRole), ctx, id, role)
+}
+
+// Logout mocks base method.
+func (m *MockUsers) Logout(ctx context.Context, claims auth.Claims) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Logout", ctx, claims)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Logout indicates an expected call of Logout.
+func (mr *MockUsersMockRecorder) Logout(ctx, claims interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockUsers)(nil).Logout), ctx, claims)
+}
+
+// LogoutAll mocks base method.
+func (m *MockUsers) LogoutAll(ctx context.Context, claims auth.Claims) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LogoutAll", ctx, claims)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LogoutAll indicates an expected call of LogoutAll.
+func (mr *MockUsersMockRecorder) LogoutAll(ctx, claims interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LogoutAll", reflect.TypeOf((*MockUsers)(nil).LogoutAll), ctx, claims)
+}
+
 // RefreshUserToken mocks base method.
-func (m *MockUsers) RefreshUserToken(ctx context.Context, userId string) (dto.TokenDTO, error) {
+func (m *MockUsers) RefreshUserToken(ctx context.Context, refreshToken string, device dto.DeviceDTO) (dto.TokenDTO, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RefreshUserToken", ctx, userId)
+	ret := m.ctrl.Call(m, "RefreshUserToken", ctx, refreshToken, device)
 	ret0, _ := ret[0].(dto.TokenDTO)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // RefreshUserToken indicates an expected call of RefreshUserToken.
-func (mr *MockUsersMockRecorder) RefreshUserToken(ctx, userId interface{}) *gomock.Call {
+func (mr *MockUsersMockRecorder) RefreshUserToken(ctx, refreshToken, device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshUserToken", reflect.TypeOf((*MockUsers)(nil).RefreshUserToken), ctx, refreshToken, device)
+}
+
+// RevokeRole mocks base method.
+func (m *MockUsers) RevokeRole(ctx context.Context, id, role string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeRole", ctx, id, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeRole indicates an expected call of RevokeRole.
+func (mr *MockUsersMockRecorder) RevokeRole(ctx, id, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeRole", reflect.TypeOf((*MockUsers)(nil).RevokeRole), ctx, id, role)
+}
+
+// RevokeSession mocks base method.
+func (m *MockUsers) RevokeSession(ctx context.Context, userId, sessionId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", ctx, userId, sessionId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockUsersMockRecorder) RevokeSession(ctx, userId, sessionId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockUsers)(nil).RevokeSession), ctx, userId, sessionId)
+}
+
+// RevokeSessions mocks base method.
+func (m *MockUsers) RevokeSessions(ctx context.Context, userId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSessions", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSessions indicates an expected call of RevokeSessions.
+func (mr *MockUsersMockRecorder) RevokeSessions(ctx, userId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshUserToken", reflect.TypeOf((*MockUsers)(nil).RefreshUserToken), ctx, userId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSessions", reflect.TypeOf((*MockUsers)(nil).RevokeSessions), ctx, userId)
+}
+
+// SignIn mocks base method.
+func (m *MockUsers) SignIn(ctx context.Context, signInDTO dto.SignInDTO, device dto.DeviceDTO) (dto.TokenDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SignIn", ctx, signInDTO, device)
+	ret0, _ := ret[0].(dto.TokenDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SignIn indicates an expected call of SignIn.
+func (mr *MockUsersMockRecorder) SignIn(ctx, signInDTO, device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignIn", reflect.TypeOf((*MockUsers)(nil).SignIn), ctx, signInDTO, device)
 }
 
 // Update mocks base method.
@@ -154,3 +283,729 @@ func (mr *MockUsersMockRecorder) Update(ctx, userDTO interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockUsers)(nil).Update), ctx, userDTO)
 }
+
+// MockOAuth is a mock of OAuth interface.
+type MockOAuth struct {
+	ctrl     *gomock.Controller
+	recorder *MockOAuthMockRecorder
+}
+
+// MockOAuthMockRecorder is the mock recorder for MockOAuth.
+type MockOAuthMockRecorder struct {
+	mock *MockOAuth
+}
+
+// NewMockOAuth creates a new mock instance.
+func NewMockOAuth(ctrl *gomock.Controller) *MockOAuth {
+	mock := &MockOAuth{ctrl: ctrl}
+	mock.recorder = &MockOAuthMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOAuth) EXPECT() *MockOAuthMockRecorder {
+	return m.recorder
+}
+
+// AuthCodeURL mocks base method.
+func (m *MockOAuth) AuthCodeURL(ctx context.Context, provider string, request oauth.AuthRequest) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthCodeURL", ctx, provider, request)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuthCodeURL indicates an expected call of AuthCodeURL.
+func (mr *MockOAuthMockRecorder) AuthCodeURL(ctx, provider, request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthCodeURL", reflect.TypeOf((*MockOAuth)(nil).AuthCodeURL), ctx, provider, request)
+}
+
+// Link mocks base method.
+func (m *MockOAuth) Link(ctx context.Context, linkToken, provider, code string, request oauth.AuthRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Link", ctx, linkToken, provider, code, request)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Link indicates an expected call of Link.
+func (mr *MockOAuthMockRecorder) Link(ctx, linkToken, provider, code, request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Link", reflect.TypeOf((*MockOAuth)(nil).Link), ctx, linkToken, provider, code, request)
+}
+
+// LinkAuthCodeURL mocks base method.
+func (m *MockOAuth) LinkAuthCodeURL(ctx context.Context, userId, provider string, request oauth.AuthRequest) (string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkAuthCodeURL", ctx, userId, provider, request)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// LinkAuthCodeURL indicates an expected call of LinkAuthCodeURL.
+func (mr *MockOAuthMockRecorder) LinkAuthCodeURL(ctx, userId, provider, request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkAuthCodeURL", reflect.TypeOf((*MockOAuth)(nil).LinkAuthCodeURL), ctx, userId, provider, request)
+}
+
+// SignIn mocks base method.
+func (m *MockOAuth) SignIn(ctx context.Context, provider, code string, request oauth.AuthRequest, device dto.DeviceDTO) (dto.TokenDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SignIn", ctx, provider, code, request, device)
+	ret0, _ := ret[0].(dto.TokenDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SignIn indicates an expected call of SignIn.
+func (mr *MockOAuthMockRecorder) SignIn(ctx, provider, code, request, device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignIn", reflect.TypeOf((*MockOAuth)(nil).SignIn), ctx, provider, code, request, device)
+}
+
+// UnlinkIdentity mocks base method.
+func (m *MockOAuth) UnlinkIdentity(ctx context.Context, userId, provider string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlinkIdentity", ctx, userId, provider)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnlinkIdentity indicates an expected call of UnlinkIdentity.
+func (mr *MockOAuthMockRecorder) UnlinkIdentity(ctx, userId, provider interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlinkIdentity", reflect.TypeOf((*MockOAuth)(nil).UnlinkIdentity), ctx, userId, provider)
+}
+
+// MockPasswords is a mock of Passwords interface.
+type MockPasswords struct {
+	ctrl     *gomock.Controller
+	recorder *MockPasswordsMockRecorder
+}
+
+// MockPasswordsMockRecorder is the mock recorder for MockPasswords.
+type MockPasswordsMockRecorder struct {
+	mock *MockPasswords
+}
+
+// NewMockPasswords creates a new mock instance.
+func NewMockPasswords(ctrl *gomock.Controller) *MockPasswords {
+	mock := &MockPasswords{ctrl: ctrl}
+	mock.recorder = &MockPasswordsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPasswords) EXPECT() *MockPasswordsMockRecorder {
+	return m.recorder
+}
+
+// ForgotPassword mocks base method.
+func (m *MockPasswords) ForgotPassword(ctx context.Context, forgotDTO dto.ForgotPasswordDTO) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForgotPassword", ctx, forgotDTO)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ForgotPassword indicates an expected call of ForgotPassword.
+func (mr *MockPasswordsMockRecorder) ForgotPassword(ctx, forgotDTO interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForgotPassword", reflect.TypeOf((*MockPasswords)(nil).ForgotPassword), ctx, forgotDTO)
+}
+
+// ResetPassword mocks base method.
+func (m *MockPasswords) ResetPassword(ctx context.Context, resetDTO dto.ResetPasswordDTO) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, resetDTO)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockPasswordsMockRecorder) ResetPassword(ctx, resetDTO interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockPasswords)(nil).ResetPassword), ctx, resetDTO)
+}
+
+// MockEmails is a mock of Emails interface.
+type MockEmails struct {
+	ctrl     *gomock.Controller
+	recorder *MockEmailsMockRecorder
+}
+
+// MockEmailsMockRecorder is the mock recorder for MockEmails.
+type MockEmailsMockRecorder struct {
+	mock *MockEmails
+}
+
+// NewMockEmails creates a new mock instance.
+func NewMockEmails(ctrl *gomock.Controller) *MockEmails {
+	mock := &MockEmails{ctrl: ctrl}
+	mock.recorder = &MockEmailsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEmails) EXPECT() *MockEmailsMockRecorder {
+	return m.recorder
+}
+
+// ResendVerification mocks base method.
+func (m *MockEmails) ResendVerification(ctx context.Context, emailDTO dto.EmailDTO) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResendVerification", ctx, emailDTO)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResendVerification indicates an expected call of ResendVerification.
+func (mr *MockEmailsMockRecorder) ResendVerification(ctx, emailDTO interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResendVerification", reflect.TypeOf((*MockEmails)(nil).ResendVerification), ctx, emailDTO)
+}
+
+// VerifyEmail mocks base method.
+func (m *MockEmails) VerifyEmail(ctx context.Context, token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEmail", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyEmail indicates an expected call of VerifyEmail.
+func (mr *MockEmailsMockRecorder) VerifyEmail(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockEmails)(nil).VerifyEmail), ctx, token)
+}
+
+// MockMagicLinks is a mock of MagicLinks interface.
+type MockMagicLinks struct {
+	ctrl     *gomock.Controller
+	recorder *MockMagicLinksMockRecorder
+}
+
+// MockMagicLinksMockRecorder is the mock recorder for MockMagicLinks.
+type MockMagicLinksMockRecorder struct {
+	mock *MockMagicLinks
+}
+
+// NewMockMagicLinks creates a new mock instance.
+func NewMockMagicLinks(ctrl *gomock.Controller) *MockMagicLinks {
+	mock := &MockMagicLinks{ctrl: ctrl}
+	mock.recorder = &MockMagicLinksMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMagicLinks) EXPECT() *MockMagicLinksMockRecorder {
+	return m.recorder
+}
+
+// Consume mocks base method.
+func (m *MockMagicLinks) Consume(ctx context.Context, token, browser string, device dto.DeviceDTO) (dto.TokenDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Consume", ctx, token, browser, device)
+	ret0, _ := ret[0].(dto.TokenDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Consume indicates an expected call of Consume.
+func (mr *MockMagicLinksMockRecorder) Consume(ctx, token, browser, device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Consume", reflect.TypeOf((*MockMagicLinks)(nil).Consume), ctx, token, browser, device)
+}
+
+// Send mocks base method.
+func (m *MockMagicLinks) Send(ctx context.Context, emailDTO dto.EmailDTO) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Send", ctx, emailDTO)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Send indicates an expected call of Send.
+func (mr *MockMagicLinksMockRecorder) Send(ctx, emailDTO interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockMagicLinks)(nil).Send), ctx, emailDTO)
+}
+
+// MockMFA is a mock of MFA interface.
+type MockMFA struct {
+	ctrl     *gomock.Controller
+	recorder *MockMFAMockRecorder
+}
+
+// MockMFAMockRecorder is the mock recorder for MockMFA.
+type MockMFAMockRecorder struct {
+	mock *MockMFA
+}
+
+// NewMockMFA creates a new mock instance.
+func NewMockMFA(ctrl *gomock.Controller) *MockMFA {
+	mock := &MockMFA{ctrl: ctrl}
+	mock.recorder = &MockMFAMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMFA) EXPECT() *MockMFAMockRecorder {
+	return m.recorder
+}
+
+// Confirm mocks base method.
+func (m *MockMFA) Confirm(ctx context.Context, userId string, codeDTO dto.MFACodeDTO) (dto.RecoveryCodesDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Confirm", ctx, userId, codeDTO)
+	ret0, _ := ret[0].(dto.RecoveryCodesDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Confirm indicates an expected call of Confirm.
+func (mr *MockMFAMockRecorder) Confirm(ctx, userId, codeDTO interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Confirm", reflect.TypeOf((*MockMFA)(nil).Confirm), ctx, userId, codeDTO)
+}
+
+// Disable mocks base method.
+func (m *MockMFA) Disable(ctx context.Context, userId string, codeDTO dto.MFACodeDTO) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Disable", ctx, userId, codeDTO)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Disable indicates an expected call of Disable.
+func (mr *MockMFAMockRecorder) Disable(ctx, userId, codeDTO interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Disable", reflect.TypeOf((*MockMFA)(nil).Disable), ctx, userId, codeDTO)
+}
+
+// Enroll mocks base method.
+func (m *MockMFA) Enroll(ctx context.Context, userId string) (dto.MFAEnrollmentDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enroll", ctx, userId)
+	ret0, _ := ret[0].(dto.MFAEnrollmentDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Enroll indicates an expected call of Enroll.
+func (mr *MockMFAMockRecorder) Enroll(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enroll", reflect.TypeOf((*MockMFA)(nil).Enroll), ctx, userId)
+}
+
+// Reset mocks base method.
+func (m *MockMFA) Reset(ctx context.Context, userId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reset", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Reset indicates an expected call of Reset.
+func (mr *MockMFAMockRecorder) Reset(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reset", reflect.TypeOf((*MockMFA)(nil).Reset), ctx, userId)
+}
+
+// Verify mocks base method.
+func (m *MockMFA) Verify(ctx context.Context, verifyDTO dto.MFAVerifyDTO, device dto.DeviceDTO) (dto.TokenDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Verify", ctx, verifyDTO, device)
+	ret0, _ := ret[0].(dto.TokenDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Verify indicates an expected call of Verify.
+func (mr *MockMFAMockRecorder) Verify(ctx, verifyDTO, device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Verify", reflect.TypeOf((*MockMFA)(nil).Verify), ctx, verifyDTO, device)
+}
+
+// MockAPIKeys is a mock of APIKeys interface.
+type MockAPIKeys struct {
+	ctrl     *gomock.Controller
+	recorder *MockAPIKeysMockRecorder
+}
+
+// MockAPIKeysMockRecorder is the mock recorder for MockAPIKeys.
+type MockAPIKeysMockRecorder struct {
+	mock *MockAPIKeys
+}
+
+// NewMockAPIKeys creates a new mock instance.
+func NewMockAPIKeys(ctrl *gomock.Controller) *MockAPIKeys {
+	mock := &MockAPIKeys{ctrl: ctrl}
+	mock.recorder = &MockAPIKeysMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAPIKeys) EXPECT() *MockAPIKeysMockRecorder {
+	return m.recorder
+}
+
+// Authenticate mocks base method.
+func (m *MockAPIKeys) Authenticate(ctx context.Context, key string) (*auth.Claims, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", ctx, key)
+	ret0, _ := ret[0].(*auth.Claims)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate.
+func (mr *MockAPIKeysMockRecorder) Authenticate(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockAPIKeys)(nil).Authenticate), ctx, key)
+}
+
+// Create mocks base method.
+func (m *MockAPIKeys) Create(ctx context.Context, userId string, createDTO dto.CreateAPIKeyDTO) (dto.APIKeyDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, userId, createDTO)
+	ret0, _ := ret[0].(dto.APIKeyDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockAPIKeysMockRecorder) Create(ctx, userId, createDTO interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockAPIKeys)(nil).Create), ctx, userId, createDTO)
+}
+
+// FindAll mocks base method.
+func (m *MockAPIKeys) FindAll(ctx context.Context, userId string) ([]domain.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAll", ctx, userId)
+	ret0, _ := ret[0].([]domain.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAll indicates an expected call of FindAll.
+func (mr *MockAPIKeysMockRecorder) FindAll(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAll", reflect.TypeOf((*MockAPIKeys)(nil).FindAll), ctx, userId)
+}
+
+// Revoke mocks base method.
+func (m *MockAPIKeys) Revoke(ctx context.Context, userId, keyId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", ctx, userId, keyId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockAPIKeysMockRecorder) Revoke(ctx, userId, keyId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockAPIKeys)(nil).Revoke), ctx, userId, keyId)
+}
+
+// MockClients is a mock of Clients interface.
+type MockClients struct {
+	ctrl     *gomock.Controller
+	recorder *MockClientsMockRecorder
+}
+
+// MockClientsMockRecorder is the mock recorder for MockClients.
+type MockClientsMockRecorder struct {
+	mock *MockClients
+}
+
+// NewMockClients creates a new mock instance.
+func NewMockClients(ctrl *gomock.Controller) *MockClients {
+	mock := &MockClients{ctrl: ctrl}
+	mock.recorder = &MockClientsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockClients) EXPECT() *MockClientsMockRecorder {
+	return m.recorder
+}
+
+// Authenticate mocks base method.
+func (m *MockClients) Authenticate(ctx context.Context, id, secret string) (domain.Client, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", ctx, id, secret)
+	ret0, _ := ret[0].(domain.Client)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate.
+func (mr *MockClientsMockRecorder) Authenticate(ctx, id, secret interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockClients)(nil).Authenticate), ctx, id, secret)
+}
+
+// Create mocks base method.
+func (m *MockClients) Create(ctx context.Context, createDTO dto.CreateClientDTO) (dto.ClientDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, createDTO)
+	ret0, _ := ret[0].(dto.ClientDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockClientsMockRecorder) Create(ctx, createDTO interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockClients)(nil).Create), ctx, createDTO)
+}
+
+// Delete mocks base method.
+func (m *MockClients) Delete(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockClientsMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockClients)(nil).Delete), ctx, id)
+}
+
+// FindAll mocks base method.
+func (m *MockClients) FindAll(ctx context.Context) ([]domain.Client, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAll", ctx)
+	ret0, _ := ret[0].([]domain.Client)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAll indicates an expected call of FindAll.
+func (mr *MockClientsMockRecorder) FindAll(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAll", reflect.TypeOf((*MockClients)(nil).FindAll), ctx)
+}
+
+// Token mocks base method.
+func (m *MockClients) Token(ctx context.Context, credentials dto.ClientCredentialsDTO) (dto.ClientTokenDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Token", ctx, credentials)
+	ret0, _ := ret[0].(dto.ClientTokenDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Token indicates an expected call of Token.
+func (mr *MockClientsMockRecorder) Token(ctx, credentials interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Token", reflect.TypeOf((*MockClients)(nil).Token), ctx, credentials)
+}
+
+// MockTokens is a mock of Tokens interface.
+type MockTokens struct {
+	ctrl     *gomock.Controller
+	recorder *MockTokensMockRecorder
+}
+
+// MockTokensMockRecorder is the mock recorder for MockTokens.
+type MockTokensMockRecorder struct {
+	mock *MockTokens
+}
+
+// NewMockTokens creates a new mock instance.
+func NewMockTokens(ctrl *gomock.Controller) *MockTokens {
+	mock := &MockTokens{ctrl: ctrl}
+	mock.recorder = &MockTokensMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTokens) EXPECT() *MockTokensMockRecorder {
+	return m.recorder
+}
+
+// Introspect mocks base method.
+func (m *MockTokens) Introspect(ctx context.Context, token string) (dto.IntrospectionDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Introspect", ctx, token)
+	ret0, _ := ret[0].(dto.IntrospectionDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Introspect indicates an expected call of Introspect.
+func (mr *MockTokensMockRecorder) Introspect(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Introspect", reflect.TypeOf((*MockTokens)(nil).Introspect), ctx, token)
+}
+
+// Revoke mocks base method.
+func (m *MockTokens) Revoke(ctx context.Context, token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockTokensMockRecorder) Revoke(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockTokens)(nil).Revoke), ctx, token)
+}
+
+// MockImpersonations is a mock of Impersonations interface.
+type MockImpersonations struct {
+	ctrl     *gomock.Controller
+	recorder *MockImpersonationsMockRecorder
+}
+
+// MockImpersonationsMockRecorder is the mock recorder for MockImpersonations.
+type MockImpersonationsMockRecorder struct {
+	mock *MockImpersonations
+}
+
+// NewMockImpersonations creates a new mock instance.
+func NewMockImpersonations(ctrl *gomock.Controller) *MockImpersonations {
+	mock := &MockImpersonations{ctrl: ctrl}
+	mock.recorder = &MockImpersonationsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockImpersonations) EXPECT() *MockImpersonationsMockRecorder {
+	return m.recorder
+}
+
+// End mocks base method.
+func (m *MockImpersonations) End(ctx context.Context, claims auth.Claims, device dto.DeviceDTO) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "End", ctx, claims, device)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// End indicates an expected call of End.
+func (mr *MockImpersonationsMockRecorder) End(ctx, claims, device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "End", reflect.TypeOf((*MockImpersonations)(nil).End), ctx, claims, device)
+}
+
+// FindAll mocks base method.
+func (m *MockImpersonations) FindAll(ctx context.Context, userId string) ([]domain.Impersonation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAll", ctx, userId)
+	ret0, _ := ret[0].([]domain.Impersonation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAll indicates an expected call of FindAll.
+func (mr *MockImpersonationsMockRecorder) FindAll(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAll", reflect.TypeOf((*MockImpersonations)(nil).FindAll), ctx, userId)
+}
+
+// FindAuditEvents mocks base method.
+func (m *MockImpersonations) FindAuditEvents(ctx context.Context, userId string) ([]domain.AuditEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAuditEvents", ctx, userId)
+	ret0, _ := ret[0].([]domain.AuditEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAuditEvents indicates an expected call of FindAuditEvents.
+func (mr *MockImpersonationsMockRecorder) FindAuditEvents(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAuditEvents", reflect.TypeOf((*MockImpersonations)(nil).FindAuditEvents), ctx, userId)
+}
+
+// RecordRequest mocks base method.
+func (m *MockImpersonations) RecordRequest(ctx context.Context, claims auth.Claims, request dto.AuditRequestDTO) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordRequest", ctx, claims, request)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordRequest indicates an expected call of RecordRequest.
+func (mr *MockImpersonationsMockRecorder) RecordRequest(ctx, claims, request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordRequest", reflect.TypeOf((*MockImpersonations)(nil).RecordRequest), ctx, claims, request)
+}
+
+// Revoke mocks base method.
+func (m *MockImpersonations) Revoke(ctx context.Context, caller auth.Claims, userId, impersonationId string, device dto.DeviceDTO) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", ctx, caller, userId, impersonationId, device)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockImpersonationsMockRecorder) Revoke(ctx, caller, userId, impersonationId, device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockImpersonations)(nil).Revoke), ctx, caller, userId, impersonationId, device)
+}
+
+// Start mocks base method.
+func (m *MockImpersonations) Start(ctx context.Context, actor auth.Claims, userId string, startDTO dto.ImpersonateDTO, device dto.DeviceDTO) (dto.ImpersonationDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Start", ctx, actor, userId, startDTO, device)
+	ret0, _ := ret[0].(dto.ImpersonationDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Start indicates an expected call of Start.
+func (mr *MockImpersonationsMockRecorder) Start(ctx, actor, userId, startDTO, device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Start", reflect.TypeOf((*MockImpersonations)(nil).Start), ctx, actor, userId, startDTO, device)
+}
+
+// MockLockouts is a mock of Lockouts interface.
+type MockLockouts struct {
+	ctrl     *gomock.Controller
+	recorder *MockLockoutsMockRecorder
+}
+
+// MockLockoutsMockRecorder is the mock recorder for MockLockouts.
+type MockLockoutsMockRecorder struct {
+	mock *MockLockouts
+}
+
+// NewMockLockouts creates a new mock instance.
+func NewMockLockouts(ctrl *gomock.Controller) *MockLockouts {
+	mock := &MockLockouts{ctrl: ctrl}
+	mock.recorder = &MockLockoutsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLockouts) EXPECT() *MockLockoutsMockRecorder {
+	return m.recorder
+}
+
+// Unlock mocks base method.
+func (m *MockLockouts) Unlock(ctx context.Context, userId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unlock", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unlock indicates an expected call of Unlock.
+func (mr *MockLockoutsMockRecorder) Unlock(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unlock", reflect.TypeOf((*MockLockouts)(nil).Unlock), ctx, userId)
+}
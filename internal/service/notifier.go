@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"test/pkg/notify"
+)
+
+const (
+	// NotificationPasswordReset carries the "token" the password is reset with.
+	NotificationPasswordReset = "password_reset"
+)
+
+// Notification is a message to the user. Type tells what it is about, the notifier
+// decides how Data is presented.
+type Notification struct {
+	Type string
+	To   string
+	Data map[string]string
+}
+
+// Notifier delivers notifications to users, e.g. by email.
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// MailNotifier emails notifications rendered from the template named after the
+// notification type, Data is the template data.
+type MailNotifier struct {
+	mailer    notify.Mailer
+	templates *notify.Templates
+	from      string
+}
+
+func NewMailNotifier(mailer notify.Mailer, templates *notify.Templates, from string) *MailNotifier {
+	return &MailNotifier{
+		mailer:    mailer,
+		templates: templates,
+		from:      from,
+	}
+}
+
+func (n *MailNotifier) Notify(ctx context.Context, notification Notification) error {
+	message, err := n.templates.Render(notification.Type, notification.Data)
+	if err != nil {
+		return fmt.Errorf("failed to render %s notification due to error: %v", notification.Type, err)
+	}
+	message.From = n.from
+	message.To = []string{notification.To}
+
+	return n.mailer.Send(ctx, message)
+}
+
+// Outbox is a Notifier keeping notifications in memory instead of delivering them.
+type Outbox struct {
+	mu            sync.Mutex
+	notifications []Notification
+}
+
+func NewOutbox() *Outbox {
+	return &Outbox{}
+}
+
+func (o *Outbox) Notify(_ context.Context, notification Notification) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.notifications = append(o.notifications, notification)
+	return nil
+}
+
+// Notifications returns notifications in the order they were sent.
+func (o *Outbox) Notifications() []Notification {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return append([]Notification(nil), o.notifications...)
+}
+
+// Last returns the latest notification of the type sent to the address.
+func (o *Outbox) Last(notificationType, to string) (Notification, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for i := len(o.notifications) - 1; i >= 0; i-- {
+		if n := o.notifications[i]; n.Type == notificationType && n.To == to {
+			return n, true
+		}
+	}
+	return Notification{}, false
+}
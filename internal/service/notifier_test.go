@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"test/pkg/notify"
+	"test/pkg/notify/smtptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// templatesDir holds the templates the server is configured with.
+const templatesDir = "../../configs/mail"
+
+func TestMailNotifier_Notify(t *testing.T) {
+	templates, err := notify.LoadTemplates(templatesDir)
+	require.NoError(t, err)
+
+	testTable := []struct {
+		name            string
+		notification    Notification
+		expectedSubject string
+		expectedText    string
+		expectError     bool
+	}{
+		{
+			name: "Password reset",
+			notification: Notification{
+				Type: NotificationPasswordReset,
+				To:   "test@test.ru",
+				Data: map[string]string{"token": "reset-token"},
+			},
+			expectedSubject: "Reset your password",
+			expectedText:    "reset-token",
+		},
+		{
+			name: "Email verification",
+			notification: Notification{
+				Type: NotificationEmailVerification,
+				To:   "test@test.ru",
+				Data: map[string]string{"link": "http://localhost/verify?token=abc", "token": "abc"},
+			},
+			expectedSubject: "Confirm your email",
+			expectedText:    "http://localhost/verify?token=abc",
+		},
+		{
+			name:         "Unknown type",
+			notification: Notification{Type: "unknown", To: "test@test.ru"},
+			expectError:  true,
+		},
+		{
+			name:         "Missing data",
+			notification: Notification{Type: NotificationPasswordReset, To: "test@test.ru"},
+			expectError:  true,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			server := smtptest.NewServer(t)
+			mailer, err := notify.NewSMTPMailer(notify.SMTPConfig{Host: server.Host(), Port: server.Port()})
+			require.NoError(t, err)
+			notifier := NewMailNotifier(mailer, templates, "Users <no-reply@test.ru>")
+
+			err = notifier.Notify(context.Background(), testCase.notification)
+			if testCase.expectError {
+				assert.Error(t, err)
+				assert.Empty(t, server.Messages())
+				return
+			}
+			require.NoError(t, err)
+
+			messages := server.Messages()
+			require.Len(t, messages, 1)
+			assert.Equal(t, []string{testCase.notification.To}, messages[0].To)
+
+			subject, err := messages[0].Header("Subject")
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedSubject, subject)
+
+			bodies, err := messages[0].Bodies()
+			require.NoError(t, err)
+			assert.Contains(t, bodies["text/plain"], testCase.expectedText)
+			assert.Contains(t, bodies["text/html"], testCase.expectedText)
+		})
+	}
+}
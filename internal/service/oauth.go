@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"test/internal/domain"
+	"test/internal/repository"
+	"test/internal/service/dto"
+	"test/pkg/api/auth"
+	"test/pkg/api/params"
+	"test/pkg/oauth"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	linkIdentityPurpose = "link_identity"
+	// linkRequestTTL is how long the user has to consent at the provider.
+	linkRequestTTL = 10 * time.Minute
+)
+
+type OAuthService struct {
+	repository repository.UserRepository
+	users      *UserService
+	providers  *oauth.Registry
+}
+
+func NewOAuthService(repository repository.UserRepository, users *UserService, providers *oauth.Registry) *OAuthService {
+	return &OAuthService{
+		repository: repository,
+		users:      users,
+		providers:  providers,
+	}
+}
+
+func (s *OAuthService) AuthCodeURL(ctx context.Context, providerName string, request oauth.AuthRequest) (string, error) {
+	provider, err := s.provider(providerName)
+	if err != nil {
+		return "", err
+	}
+	return provider.AuthCodeURL(ctx, request)
+}
+
+// SignIn exchanges the authorization code and signs in the owner of the external account.
+// The user is found by the linked identity and is created otherwise. Accounts are never
+// merged by email: when the email is taken, the user has to sign in and link the account.
+func (s *OAuthService) SignIn(ctx context.Context, providerName, code string, request oauth.AuthRequest,
+	device dto.DeviceDTO) (dto.TokenDTO, error) {
+	identity, err := s.exchange(ctx, providerName, code, request)
+	if err != nil {
+		return dto.TokenDTO{}, err
+	}
+
+	user, err := s.findOrCreateUser(ctx, identity)
+	if err != nil {
+		return dto.TokenDTO{}, err
+	}
+	return s.users.beginSession(ctx, user, device)
+}
+
+// LinkAuthCodeURL starts linking an external account to the user. The returned link token
+// is bound to the user and the auth request, it is given back to Link on the callback.
+func (s *OAuthService) LinkAuthCodeURL(ctx context.Context, userId, providerName string,
+	request oauth.AuthRequest) (string, string, error) {
+	if _, err := params.ParseIdToObjectID(userId); err != nil {
+		return "", "", err
+	}
+	authCodeURL, err := s.AuthCodeURL(ctx, providerName, request)
+	if err != nil {
+		return "", "", err
+	}
+
+	linkToken, err := s.users.tokenManager.GeneratePurposeToken(linkIdentityPurpose, auth.Claims{
+		StandardClaims: jwt.StandardClaims{
+			Subject:  userId,
+			Audience: providerName,
+			Id:       request.State,
+		},
+	}, linkRequestTTL)
+	if err != nil {
+		return "", "", err
+	}
+	return authCodeURL, linkToken, nil
+}
+
+// Link exchanges the authorization code and links the external account to the user the
+// link token was issued for.
+func (s *OAuthService) Link(ctx context.Context, linkToken, providerName, code string, request oauth.AuthRequest) error {
+	claims, err := s.users.tokenManager.ParsePurposeToken(linkToken, linkIdentityPurpose)
+	if err != nil || claims.Audience != providerName || claims.Id != request.State {
+		return domain.ErrInvalidLinkRequest
+	}
+	oid, err := params.ParseIdToObjectID(claims.Subject)
+	if err != nil {
+		return domain.ErrInvalidLinkRequest
+	}
+
+	identity, err := s.exchange(ctx, providerName, code, request)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.repository.FindByIdentity(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		if user.Id != oid {
+			return domain.ErrIdentityLinked
+		}
+		return nil
+	}
+	if !errors.Is(err, domain.ErrUserNotFound) {
+		return err
+	}
+
+	return s.repository.LinkIdentity(ctx, oid, newIdentity(identity))
+}
+
+// UnlinkIdentity removes the external account of the provider from the user, the last
+// way to sign in can't be removed.
+func (s *OAuthService) UnlinkIdentity(ctx context.Context, userId, providerName string) error {
+	oid, err := params.ParseIdToObjectID(userId)
+	if err != nil {
+		return err
+	}
+	return s.repository.UnlinkIdentity(ctx, oid, providerName)
+}
+
+func (s *OAuthService) exchange(ctx context.Context, providerName, code string, request oauth.AuthRequest) (oauth.Identity, error) {
+	provider, err := s.provider(providerName)
+	if err != nil {
+		return oauth.Identity{}, err
+	}
+
+	identity, err := provider.Exchange(ctx, code, request)
+	if err != nil {
+		return oauth.Identity{}, fmt.Errorf("%w: %v", domain.ErrOAuthExchange, err)
+	}
+	return identity, nil
+}
+
+func (s *OAuthService) provider(name string) (*oauth.Provider, error) {
+	if s.providers == nil {
+		return nil, domain.ErrUnknownOAuthProvider
+	}
+	provider, err := s.providers.Provider(name)
+	if errors.Is(err, oauth.ErrUnknownProvider) {
+		return nil, domain.ErrUnknownOAuthProvider
+	}
+	return provider, err
+}
+
+func (s *OAuthService) findOrCreateUser(ctx context.Context, identity oauth.Identity) (domain.User, error) {
+	user, err := s.repository.FindByIdentity(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, domain.ErrUserNotFound) {
+		return domain.User{}, err
+	}
+
+	// An unverified email could belong to someone else, who couldn't register with it then.
+	if !identity.EmailVerified || identity.Email == "" {
+		return domain.User{}, domain.ErrOAuthEmailNotVerified
+	}
+
+	_, err = s.repository.FindByEmail(ctx, identity.Email)
+	if err == nil {
+		return domain.User{}, domain.ErrOAuthEmailRegistered
+	}
+	if !errors.Is(err, domain.ErrUserNotFound) {
+		return domain.User{}, err
+	}
+
+	user = domain.User{
+		Email:         identity.Email,
+		EmailVerified: true,
+		Roles:         []string{auth.UserRole},
+		Identities:    []domain.Identity{newIdentity(identity)},
+	}
+	if user.Id, err = s.repository.Create(ctx, user); err != nil {
+		return domain.User{}, err
+	}
+	return user, nil
+}
+
+func newIdentity(identity oauth.Identity) domain.Identity {
+	return domain.Identity{
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+		LinkedAt: time.Now().UTC(),
+	}
+}
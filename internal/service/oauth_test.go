@@ -0,0 +1,360 @@
+package service
+
+import (
+	"context"
+	"test/internal/domain"
+	db_mocks "test/internal/repository/mocks"
+	"test/internal/service/dto"
+	"test/pkg/api/auth"
+	"test/pkg/oauth"
+	"test/pkg/oauth/oauthtest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const testProvider = "test"
+
+func mockOAuthService(t *testing.T, server *oauthtest.Server) (*OAuthService, *db_mocks.MockUserRepository) {
+	t.Helper()
+
+	userService, userRepoMock := mockUserService(t)
+	provider, err := oauth.NewProvider(oauth.Config{
+		Name:         testProvider,
+		Issuer:       server.Issuer(),
+		ClientId:     oauthtest.ClientId,
+		ClientSecret: oauthtest.ClientSecret,
+		PKCE:         true,
+	}, server.Client())
+	require.NoError(t, err)
+	providers, err := oauth.NewRegistry(provider)
+	require.NoError(t, err)
+
+	return NewOAuthService(userRepoMock, userService, providers), userRepoMock
+}
+
+// authorize runs the login half of the flow against the fake provider.
+func authorize(t *testing.T, server *oauthtest.Server, oauthService *OAuthService) (string, oauth.AuthRequest) {
+	t.Helper()
+
+	request, err := oauth.NewAuthRequest()
+	require.NoError(t, err)
+	authCodeURL, err := oauthService.AuthCodeURL(context.Background(), testProvider, request)
+	require.NoError(t, err)
+	return server.Authorize(t, authCodeURL), request
+}
+
+func TestOAuthService_SignIn(t *testing.T) {
+	type mockRepoBehavior func(dbmock *db_mocks.MockUserRepository, identity domain.Identity)
+
+	oid := primitive.NewObjectID()
+	identity := domain.Identity{Provider: testProvider, Subject: "subject", Email: "test@test.ru"}
+
+	testTable := []struct {
+		name               string
+		claims             map[string]interface{}
+		code               string
+		mockRepoBehavior   mockRepoBehavior
+		assertServiceTests func() []func(t *testing.T, err error, i ...interface{})
+	}{
+		{
+			name: "Linked user",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, identity domain.Identity) {
+				dbmock.EXPECT().FindByIdentity(context.Background(), identity.Provider, identity.Subject).
+					Return(domain.User{Id: oid, Identities: []domain.Identity{identity}}, nil)
+				dbmock.EXPECT().CreateSession(context.Background(), gomock.Any()).Return(nil)
+			},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.Nil(t, err)
+					},
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.NotEmpty(t, i)
+					},
+				}
+			},
+		},
+		{
+			name: "Linked user with second factor",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, identity domain.Identity) {
+				dbmock.EXPECT().FindByIdentity(context.Background(), identity.Provider, identity.Subject).
+					Return(domain.User{Id: oid, Identities: []domain.Identity{identity}, MFA: &domain.MFA{Enabled: true}}, nil)
+			},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.Nil(t, err)
+					},
+					func(t *testing.T, err error, i ...interface{}) {
+						tokenDTO := i[0].(dto.TokenDTO)
+						assert.NotEmpty(t, tokenDTO.MFAToken)
+						assert.Empty(t, tokenDTO.AccessToken)
+					},
+				}
+			},
+		},
+		{
+			name: "Email registered by another user",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, identity domain.Identity) {
+				dbmock.EXPECT().FindByIdentity(context.Background(), identity.Provider, identity.Subject).
+					Return(domain.User{}, domain.ErrUserNotFound)
+				dbmock.EXPECT().FindByEmail(context.Background(), "test@test.ru").Return(domain.User{Id: oid, Email: "test@test.ru"}, nil)
+			},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.ErrorIs(t, err, domain.ErrOAuthEmailRegistered)
+					},
+				}
+			},
+		},
+		{
+			name: "New user created",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, identity domain.Identity) {
+				dbmock.EXPECT().FindByIdentity(context.Background(), identity.Provider, identity.Subject).
+					Return(domain.User{}, domain.ErrUserNotFound)
+				dbmock.EXPECT().FindByEmail(context.Background(), "test@test.ru").Return(domain.User{}, domain.ErrUserNotFound)
+				dbmock.EXPECT().Create(context.Background(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, user domain.User) (primitive.ObjectID, error) {
+						assert.Equal(t, "test@test.ru", user.Email)
+						assert.True(t, user.EmailVerified, "email is verified by the provider")
+						assert.Equal(t, []string{auth.UserRole}, user.Roles)
+						assertIdentities(t, []domain.Identity{identity}, user.Identities)
+						return oid, nil
+					})
+				dbmock.EXPECT().CreateSession(context.Background(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, session domain.Session) error {
+						assert.Equal(t, oid, session.UserId)
+						return nil
+					})
+			},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.Nil(t, err)
+					},
+				}
+			},
+		},
+		{
+			name:   "Unverified email",
+			claims: map[string]interface{}{"sub": "subject", "email": "test@test.ru", "email_verified": false},
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, identity domain.Identity) {
+				dbmock.EXPECT().FindByIdentity(context.Background(), identity.Provider, identity.Subject).
+					Return(domain.User{}, domain.ErrUserNotFound)
+			},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.ErrorIs(t, err, domain.ErrOAuthEmailNotVerified)
+					},
+				}
+			},
+		},
+		{
+			name:             "Invalid code",
+			code:             "wrong code",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, identity domain.Identity) {},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.ErrorIs(t, err, domain.ErrOAuthExchange)
+					},
+				}
+			},
+		},
+		{
+			name:             "Invalid ID token",
+			claims:           map[string]interface{}{"sub": "subject", "aud": "another-client"},
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, identity domain.Identity) {},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.ErrorIs(t, err, domain.ErrOAuthExchange)
+					},
+				}
+			},
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			server := oauthtest.NewServer(t)
+			if testCase.claims != nil {
+				server.Claims = testCase.claims
+			}
+			oauthService, userRepoMock := mockOAuthService(t, server)
+			testCase.mockRepoBehavior(userRepoMock, identity)
+
+			code, request := authorize(t, server, oauthService)
+			if testCase.code != "" {
+				code = testCase.code
+			}
+			actualToken, err := oauthService.SignIn(context.Background(), testProvider, code, request, dto.DeviceDTO{})
+
+			for _, assert := range testCase.assertServiceTests() {
+				assert(t, err, actualToken)
+			}
+		})
+	}
+}
+
+func TestOAuthService_UnknownProvider(t *testing.T) {
+	oauthService, _ := mockOAuthService(t, oauthtest.NewServer(t))
+
+	_, err := oauthService.AuthCodeURL(context.Background(), "unknown", oauth.AuthRequest{})
+	assert.ErrorIs(t, err, domain.ErrUnknownOAuthProvider)
+
+	_, err = oauthService.SignIn(context.Background(), "unknown", "code", oauth.AuthRequest{}, dto.DeviceDTO{})
+	assert.ErrorIs(t, err, domain.ErrUnknownOAuthProvider)
+}
+
+// assertIdentities compares identities apart from the time they were linked at.
+func assertIdentities(t *testing.T, expected, actual []domain.Identity) {
+	t.Helper()
+
+	if !assert.Len(t, actual, len(expected)) {
+		return
+	}
+	for i := range expected {
+		assert.False(t, actual[i].LinkedAt.IsZero())
+		actual[i].LinkedAt = expected[i].LinkedAt
+	}
+	assert.Equal(t, expected, actual)
+}
+
+func TestOAuthService_Link(t *testing.T) {
+	type mockRepoBehavior func(dbmock *db_mocks.MockUserRepository, identity domain.Identity)
+
+	oid := primitive.NewObjectID()
+	identity := domain.Identity{Provider: testProvider, Subject: "subject", Email: "test@test.ru"}
+
+	testTable := []struct {
+		name             string
+		userId           string
+		linkProvider     string
+		state            string
+		mockRepoBehavior mockRepoBehavior
+		expectedError    error
+	}{
+		{
+			name: "OK",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, identity domain.Identity) {
+				dbmock.EXPECT().FindByIdentity(context.Background(), identity.Provider, identity.Subject).
+					Return(domain.User{}, domain.ErrUserNotFound)
+				dbmock.EXPECT().LinkIdentity(context.Background(), oid, gomock.Any()).DoAndReturn(
+					func(_ context.Context, _ primitive.ObjectID, linked domain.Identity) error {
+						assertIdentities(t, []domain.Identity{identity}, []domain.Identity{linked})
+						return nil
+					})
+			},
+		},
+		{
+			name: "Already linked to the user",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, identity domain.Identity) {
+				dbmock.EXPECT().FindByIdentity(context.Background(), identity.Provider, identity.Subject).
+					Return(domain.User{Id: oid, Identities: []domain.Identity{identity}}, nil)
+			},
+		},
+		{
+			name: "Linked to another user",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, identity domain.Identity) {
+				dbmock.EXPECT().FindByIdentity(context.Background(), identity.Provider, identity.Subject).
+					Return(domain.User{Id: primitive.NewObjectID(), Identities: []domain.Identity{identity}}, nil)
+			},
+			expectedError: domain.ErrIdentityLinked,
+		},
+		{
+			name: "User linked to another account of the provider",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, identity domain.Identity) {
+				dbmock.EXPECT().FindByIdentity(context.Background(), identity.Provider, identity.Subject).
+					Return(domain.User{}, domain.ErrUserNotFound)
+				dbmock.EXPECT().LinkIdentity(context.Background(), oid, gomock.Any()).Return(domain.ErrProviderLinked)
+			},
+			expectedError: domain.ErrProviderLinked,
+		},
+		{
+			name:             "Link token of another provider",
+			linkProvider:     "another",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, identity domain.Identity) {},
+			expectedError:    domain.ErrInvalidLinkRequest,
+		},
+		{
+			name:             "Link token of another auth request",
+			state:            "another-state",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, identity domain.Identity) {},
+			expectedError:    domain.ErrInvalidLinkRequest,
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			server := oauthtest.NewServer(t)
+			oauthService, userRepoMock := mockOAuthService(t, server)
+			testCase.mockRepoBehavior(userRepoMock, identity)
+
+			code, request := authorize(t, server, oauthService)
+			linkRequest := request
+			if testCase.state != "" {
+				linkRequest.State = testCase.state
+			}
+			linkProvider := testProvider
+			if testCase.linkProvider != "" {
+				linkProvider = testCase.linkProvider
+			}
+			linkToken, err := oauthService.users.tokenManager.GeneratePurposeToken(linkIdentityPurpose, auth.Claims{
+				StandardClaims: jwt.StandardClaims{
+					Subject:  oid.Hex(),
+					Audience: linkProvider,
+					Id:       linkRequest.State,
+				},
+			}, time.Minute)
+			require.NoError(t, err)
+
+			err = oauthService.Link(context.Background(), linkToken, testProvider, code, request)
+			if testCase.expectedError == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, testCase.expectedError)
+			}
+		})
+	}
+}
+
+func TestOAuthService_LinkAuthCodeURL(t *testing.T) {
+	oauthService, _ := mockOAuthService(t, oauthtest.NewServer(t))
+	oid := primitive.NewObjectID()
+	request := oauth.AuthRequest{State: "state", Nonce: "nonce", CodeVerifier: "verifier"}
+
+	authCodeURL, linkToken, err := oauthService.LinkAuthCodeURL(context.Background(), oid.Hex(), testProvider, request)
+	require.NoError(t, err)
+	assert.Contains(t, authCodeURL, "state=state")
+
+	claims, err := oauthService.users.tokenManager.ParsePurposeToken(linkToken, linkIdentityPurpose)
+	require.NoError(t, err)
+	assert.Equal(t, oid.Hex(), claims.Subject)
+	assert.Equal(t, testProvider, claims.Audience)
+	assert.Equal(t, request.State, claims.Id)
+
+	_, _, err = oauthService.LinkAuthCodeURL(context.Background(), "invalid id", testProvider, request)
+	assert.Error(t, err)
+	_, _, err = oauthService.LinkAuthCodeURL(context.Background(), oid.Hex(), "unknown", request)
+	assert.ErrorIs(t, err, domain.ErrUnknownOAuthProvider)
+}
+
+func TestOAuthService_UnlinkIdentity(t *testing.T) {
+	oid := primitive.NewObjectID()
+	oauthService, userRepoMock := mockOAuthService(t, oauthtest.NewServer(t))
+
+	userRepoMock.EXPECT().UnlinkIdentity(context.Background(), oid, testProvider).Return(nil)
+	assert.NoError(t, oauthService.UnlinkIdentity(context.Background(), oid.Hex(), testProvider))
+
+	userRepoMock.EXPECT().UnlinkIdentity(context.Background(), oid, testProvider).Return(domain.ErrLastLoginMethod)
+	assert.ErrorIs(t, oauthService.UnlinkIdentity(context.Background(), oid.Hex(), testProvider), domain.ErrLastLoginMethod)
+
+	assert.Error(t, oauthService.UnlinkIdentity(context.Background(), "invalid id", testProvider))
+}
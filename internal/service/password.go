@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"test/internal/domain"
+	"test/internal/repository"
+	"test/internal/service/dto"
+	"test/pkg/hash"
+	"time"
+)
+
+const resetTokenLength = 32
+
+type PasswordService struct {
+	repository    repository.UserRepository
+	users         *UserService
+	notifier      Notifier
+	resetTokenTTL time.Duration
+}
+
+func NewPasswordService(repository repository.UserRepository, users *UserService, notifier Notifier,
+	resetTokenTTL time.Duration) *PasswordService {
+	return &PasswordService{
+		repository:    repository,
+		users:         users,
+		notifier:      notifier,
+		resetTokenTTL: resetTokenTTL,
+	}
+}
+
+// ForgotPassword sends a password reset token to the user with the email. Unknown emails
+// are not reported, so the endpoint can't be used to find out who is registered.
+func (s *PasswordService) ForgotPassword(ctx context.Context, forgotDTO dto.ForgotPasswordDTO) error {
+	if !dto.ValidForgotPasswordDTO(forgotDTO) {
+		return domain.ErrInvalidEmail
+	}
+
+	user, err := s.repository.FindByEmail(ctx, forgotDTO.Email)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, err := generateResetToken()
+	if err != nil {
+		return err
+	}
+	reset := domain.PasswordReset{
+		TokenHash: hash.HashToken(token),
+		ExpiresAt: time.Now().Add(s.resetTokenTTL),
+	}
+	if err := s.repository.SetPasswordReset(ctx, user.Id, reset); err != nil {
+		return err
+	}
+
+	return s.notifier.Notify(ctx, Notification{
+		Type: NotificationPasswordReset,
+		To:   user.Email,
+		Data: map[string]string{"token": token},
+	})
+}
+
+// ResetPassword sets the new password with the reset token and ends all sessions of the
+// user, since they could be opened by someone who knew the old password.
+func (s *PasswordService) ResetPassword(ctx context.Context, resetDTO dto.ResetPasswordDTO) error {
+	if resetDTO.Token == "" {
+		return domain.ErrInvalidResetToken
+	}
+	if !dto.ValidResetPasswordDTO(resetDTO) {
+		return domain.ErrInvalidPassword
+	}
+
+	passwordHash, err := s.users.hasher.Hash(resetDTO.Password)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.repository.ResetPassword(ctx, hash.HashToken(resetDTO.Token), passwordHash)
+	if err != nil {
+		return err
+	}
+	return s.users.revokeSessions(ctx, user.Id)
+}
+
+func generateResetToken() (string, error) {
+	b := make([]byte, resetTokenLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate password reset token")
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"test/internal/domain"
+	db_mocks "test/internal/repository/mocks"
+	"test/internal/service/dto"
+	"test/pkg/hash"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func mockPasswordService(t *testing.T) (*PasswordService, *db_mocks.MockUserRepository, *Outbox) {
+	t.Helper()
+
+	userService, userRepoMock := mockUserService(t)
+	outbox := NewOutbox()
+	return NewPasswordService(userRepoMock, userService, outbox, time.Minute), userRepoMock, outbox
+}
+
+func TestPasswordService_ForgotPassword(t *testing.T) {
+	type mockRepoBehavior func(dbmock *db_mocks.MockUserRepository)
+
+	oid := primitive.NewObjectID()
+
+	testTable := []struct {
+		name             string
+		forgotDTO        dto.ForgotPasswordDTO
+		mockRepoBehavior mockRepoBehavior
+		expectedError    error
+		expectedSent     bool
+	}{
+		{
+			name:      "OK",
+			forgotDTO: dto.ForgotPasswordDTO{Email: "test@test.ru"},
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().FindByEmail(context.Background(), "test@test.ru").Return(domain.User{Id: oid, Email: "test@test.ru"}, nil)
+				dbmock.EXPECT().SetPasswordReset(context.Background(), oid, gomock.Any()).DoAndReturn(
+					func(_ context.Context, _ primitive.ObjectID, reset domain.PasswordReset) error {
+						assert.NotEmpty(t, reset.TokenHash)
+						assert.WithinDuration(t, time.Now().Add(time.Minute), reset.ExpiresAt, time.Second)
+						return nil
+					})
+			},
+			expectedSent: true,
+		},
+		{
+			name:      "Unknown email",
+			forgotDTO: dto.ForgotPasswordDTO{Email: "test@test.ru"},
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().FindByEmail(context.Background(), "test@test.ru").Return(domain.User{}, domain.ErrUserNotFound)
+			},
+		},
+		{
+			name:             "Invalid email",
+			forgotDTO:        dto.ForgotPasswordDTO{Email: "test"},
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {},
+			expectedError:    domain.ErrInvalidEmail,
+		},
+		{
+			name:      "Repository Failure",
+			forgotDTO: dto.ForgotPasswordDTO{Email: "test@test.ru"},
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().FindByEmail(context.Background(), "test@test.ru").Return(domain.User{Id: oid, Email: "test@test.ru"}, nil)
+				dbmock.EXPECT().SetPasswordReset(context.Background(), oid, gomock.Any()).Return(errors.New("repository failure"))
+			},
+			expectedError: errors.New("repository failure"),
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			passwordService, userRepoMock, outbox := mockPasswordService(t)
+			testCase.mockRepoBehavior(userRepoMock)
+
+			err := passwordService.ForgotPassword(context.Background(), testCase.forgotDTO)
+			if testCase.expectedError != nil {
+				assert.EqualError(t, err, testCase.expectedError.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+
+			notification, sent := outbox.Last(NotificationPasswordReset, testCase.forgotDTO.Email)
+			assert.Equal(t, testCase.expectedSent, sent)
+			if sent {
+				assert.NotEmpty(t, notification.Data["token"])
+			}
+		})
+	}
+}
+
+func TestPasswordService_ForgotPasswordTokenHash(t *testing.T) {
+	passwordService, userRepoMock, outbox := mockPasswordService(t)
+	oid := primitive.NewObjectID()
+
+	var stored domain.PasswordReset
+	userRepoMock.EXPECT().FindByEmail(context.Background(), "test@test.ru").Return(domain.User{Id: oid, Email: "test@test.ru"}, nil)
+	userRepoMock.EXPECT().SetPasswordReset(context.Background(), oid, gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ primitive.ObjectID, reset domain.PasswordReset) error {
+			stored = reset
+			return nil
+		})
+
+	err := passwordService.ForgotPassword(context.Background(), dto.ForgotPasswordDTO{Email: "test@test.ru"})
+	assert.NoError(t, err)
+
+	notification, _ := outbox.Last(NotificationPasswordReset, "test@test.ru")
+	assert.NotEqual(t, notification.Data["token"], stored.TokenHash, "token is stored hashed")
+	assert.Equal(t, hash.HashToken(notification.Data["token"]), stored.TokenHash)
+}
+
+func TestPasswordService_ResetPassword(t *testing.T) {
+	type mockRepoBehavior func(dbmock *db_mocks.MockUserRepository)
+
+	oid := primitive.NewObjectID()
+
+	testTable := []struct {
+		name             string
+		resetDTO         dto.ResetPasswordDTO
+		mockRepoBehavior mockRepoBehavior
+		expectedError    error
+	}{
+		{
+			name:     "OK",
+			resetDTO: dto.ResetPasswordDTO{Token: "token", Password: "qwerty123"},
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().ResetPassword(context.Background(), hash.HashToken("token"), gomock.Any()).DoAndReturn(
+					func(_ context.Context, _, passwordHash string) (domain.User, error) {
+						assert.NotEqual(t, "qwerty123", passwordHash)
+						return domain.User{Id: oid}, nil
+					})
+				dbmock.EXPECT().FindSessions(context.Background(), oid).Return([]domain.Session{}, nil)
+				dbmock.EXPECT().DeleteSessions(context.Background(), oid).Return(nil)
+			},
+		},
+		{
+			name:     "Invalid token",
+			resetDTO: dto.ResetPasswordDTO{Token: "token", Password: "qwerty123"},
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().ResetPassword(context.Background(), hash.HashToken("token"), gomock.Any()).
+					Return(domain.User{}, domain.ErrInvalidResetToken)
+			},
+			expectedError: domain.ErrInvalidResetToken,
+		},
+		{
+			name:             "Empty token",
+			resetDTO:         dto.ResetPasswordDTO{Password: "qwerty123"},
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {},
+			expectedError:    domain.ErrInvalidResetToken,
+		},
+		{
+			name:             "Invalid password",
+			resetDTO:         dto.ResetPasswordDTO{Token: "token", Password: "123"},
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {},
+			expectedError:    domain.ErrInvalidPassword,
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			passwordService, userRepoMock, _ := mockPasswordService(t)
+			testCase.mockRepoBehavior(userRepoMock)
+
+			err := passwordService.ResetPassword(context.Background(), testCase.resetDTO)
+			if testCase.expectedError != nil {
+				assert.ErrorIs(t, err, testCase.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
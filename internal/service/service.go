@@ -7,22 +7,94 @@ import (
 	"test/internal/service/dto"
 	"test/pkg/api/auth"
 	"test/pkg/hash"
+	"test/pkg/oauth"
 
 	"time"
-
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 //go:generate mockgen -source=service.go -destination=mocks/mock.go -package=mocks
 type Users interface {
-	Create(ctx context.Context, userDTO dto.CreateUserDTO) (dto.TokenDTO, error)
+	Create(ctx context.Context, userDTO dto.CreateUserDTO, device dto.DeviceDTO) (dto.TokenDTO, error)
+	SignIn(ctx context.Context, signInDTO dto.SignInDTO, device dto.DeviceDTO) (dto.TokenDTO, error)
 	FindOne(ctx context.Context, id string) (domain.User, error)
 	FindByEmail(ctx context.Context, email string) (domain.User, error)
 	FindAll(ctx context.Context, limit, offset, filter, sortBy string) (u []domain.User, err error)
 	Update(ctx context.Context, userDTO dto.UpdateUserDTO) error
 	Delete(ctx context.Context, id string) error
-	RefreshUserToken(ctx context.Context, userId string) (dto.TokenDTO, error)
-	CreateSession(ctx context.Context, oid primitive.ObjectID) (dto.TokenDTO, error)
+	RefreshUserToken(ctx context.Context, refreshToken string, device dto.DeviceDTO) (dto.TokenDTO, error)
+	CreateSession(ctx context.Context, user domain.User, device dto.DeviceDTO) (dto.TokenDTO, error)
+	FindSessions(ctx context.Context, userId string) ([]domain.Session, error)
+	RevokeSession(ctx context.Context, userId, sessionId string) error
+	RevokeSessions(ctx context.Context, userId string) error
+	Logout(ctx context.Context, claims auth.Claims) error
+	LogoutAll(ctx context.Context, claims auth.Claims) error
+	GrantRole(ctx context.Context, id, role string) error
+	RevokeRole(ctx context.Context, id, role string) error
+	BootstrapAdmin(ctx context.Context, email, password string) error
+}
+
+type OAuth interface {
+	AuthCodeURL(ctx context.Context, provider string, request oauth.AuthRequest) (string, error)
+	SignIn(ctx context.Context, provider, code string, request oauth.AuthRequest, device dto.DeviceDTO) (dto.TokenDTO, error)
+	LinkAuthCodeURL(ctx context.Context, userId, provider string, request oauth.AuthRequest) (string, string, error)
+	Link(ctx context.Context, linkToken, provider, code string, request oauth.AuthRequest) error
+	UnlinkIdentity(ctx context.Context, userId, provider string) error
+}
+
+type Passwords interface {
+	ForgotPassword(ctx context.Context, forgotDTO dto.ForgotPasswordDTO) error
+	ResetPassword(ctx context.Context, resetDTO dto.ResetPasswordDTO) error
+}
+
+type Emails interface {
+	ResendVerification(ctx context.Context, emailDTO dto.EmailDTO) error
+	VerifyEmail(ctx context.Context, token string) error
+}
+
+type MagicLinks interface {
+	Send(ctx context.Context, emailDTO dto.EmailDTO) (string, error)
+	Consume(ctx context.Context, token, browser string, device dto.DeviceDTO) (dto.TokenDTO, error)
+}
+
+type MFA interface {
+	Enroll(ctx context.Context, userId string) (dto.MFAEnrollmentDTO, error)
+	Confirm(ctx context.Context, userId string, codeDTO dto.MFACodeDTO) (dto.RecoveryCodesDTO, error)
+	Disable(ctx context.Context, userId string, codeDTO dto.MFACodeDTO) error
+	Reset(ctx context.Context, userId string) error
+	Verify(ctx context.Context, verifyDTO dto.MFAVerifyDTO, device dto.DeviceDTO) (dto.TokenDTO, error)
+}
+
+type APIKeys interface {
+	Create(ctx context.Context, userId string, createDTO dto.CreateAPIKeyDTO) (dto.APIKeyDTO, error)
+	FindAll(ctx context.Context, userId string) ([]domain.APIKey, error)
+	Revoke(ctx context.Context, userId, keyId string) error
+	Authenticate(ctx context.Context, key string) (*auth.Claims, error)
+}
+
+type Clients interface {
+	Create(ctx context.Context, createDTO dto.CreateClientDTO) (dto.ClientDTO, error)
+	FindAll(ctx context.Context) ([]domain.Client, error)
+	Delete(ctx context.Context, id string) error
+	Token(ctx context.Context, credentials dto.ClientCredentialsDTO) (dto.ClientTokenDTO, error)
+	Authenticate(ctx context.Context, id, secret string) (domain.Client, error)
+}
+
+type Tokens interface {
+	Introspect(ctx context.Context, token string) (dto.IntrospectionDTO, error)
+	Revoke(ctx context.Context, token string) error
+}
+
+type Impersonations interface {
+	Start(ctx context.Context, actor auth.Claims, userId string, startDTO dto.ImpersonateDTO, device dto.DeviceDTO) (dto.ImpersonationDTO, error)
+	FindAll(ctx context.Context, userId string) ([]domain.Impersonation, error)
+	Revoke(ctx context.Context, caller auth.Claims, userId, impersonationId string, device dto.DeviceDTO) error
+	End(ctx context.Context, claims auth.Claims, device dto.DeviceDTO) error
+	RecordRequest(ctx context.Context, claims auth.Claims, request dto.AuditRequestDTO) error
+	FindAuditEvents(ctx context.Context, userId string) ([]domain.AuditEvent, error)
+}
+
+type Lockouts interface {
+	Unlock(ctx context.Context, userId string) error
 }
 
 type Deps struct {
@@ -31,16 +103,63 @@ type Deps struct {
 	Hasher          hash.PasswordHasher
 	AccessTokenTTL  time.Duration
 	RefreshTokenTTL time.Duration
+	OAuthProviders  *oauth.Registry
+	// Notifier delivers messages to users, they are kept in an Outbox when it is nil.
+	Notifier          Notifier
+	PasswordResetTTL  time.Duration
+	EmailVerification EmailVerification
+	TOTP              TOTP
+	Lockout           Lockout
+	ClientTokenTTL    time.Duration
+	// ImpersonationTokenTTL is how long admins may act as a user with one impersonation.
+	ImpersonationTokenTTL time.Duration
+	MagicLink             MagicLink
 }
 
 type Services struct {
-	Users           Users
+	Users          Users
+	OAuth          OAuth
+	Passwords      Passwords
+	Emails         Emails
+	MFA            MFA
+	Lockouts       Lockouts
+	APIKeys        APIKeys
+	Clients        Clients
+	Tokens         Tokens
+	Impersonations Impersonations
+	MagicLinks     MagicLinks
 }
 
 func NewServices(deps Deps) *Services {
+	notifier := deps.Notifier
+	if notifier == nil {
+		notifier = NewOutbox()
+	}
+
+	emailService := NewEmailService(deps.Repos.UserRepositiry, deps.TokenManager, notifier, deps.EmailVerification)
+	lockoutService := NewLockoutService(deps.Repos.UserRepositiry, deps.Lockout)
 	usersService := NewUserService(deps.Repos.UserRepositiry, deps.TokenManager, deps.Hasher,
-		deps.AccessTokenTTL, deps.RefreshTokenTTL)
+		deps.AccessTokenTTL, deps.RefreshTokenTTL, emailService, lockoutService)
+	oauthService := NewOAuthService(deps.Repos.UserRepositiry, usersService, deps.OAuthProviders)
+	passwordService := NewPasswordService(deps.Repos.UserRepositiry, usersService, notifier, deps.PasswordResetTTL)
+	mfaService := NewMFAService(deps.Repos.UserRepositiry, usersService, deps.TOTP)
+	apiKeyService := NewAPIKeyService(deps.Repos.UserRepositiry, usersService)
+	clientService := NewClientService(deps.Repos.Clients, deps.TokenManager, deps.ClientTokenTTL)
+	tokenService := NewTokenService(deps.Repos.UserRepositiry, usersService, apiKeyService)
+	impersonationService := NewImpersonationService(deps.Repos.UserRepositiry, deps.Repos.Audit, usersService,
+		deps.ImpersonationTokenTTL)
+	magicLinkService := NewMagicLinkService(deps.Repos.UserRepositiry, usersService, notifier, deps.MagicLink)
 	return &Services{
-		Users: usersService,
+		Users:          usersService,
+		OAuth:          oauthService,
+		Passwords:      passwordService,
+		Emails:         emailService,
+		MFA:            mfaService,
+		Lockouts:       lockoutService,
+		APIKeys:        apiKeyService,
+		Clients:        clientService,
+		Tokens:         tokenService,
+		Impersonations: impersonationService,
+		MagicLinks:     magicLinkService,
 	}
 }
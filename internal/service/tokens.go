@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"test/internal/domain"
+	"test/internal/repository"
+	"test/internal/service/dto"
+	"test/pkg/api/auth"
+	"test/pkg/hash"
+	"time"
+)
+
+// Kinds of tokens in introspection responses, the same names token_type_hint uses.
+const (
+	TokenTypeAccessToken  = "access_token"
+	TokenTypeRefreshToken = "refresh_token"
+	TokenTypeAPIKey       = "api_key"
+)
+
+// TokenService introspects and revokes tokens for OAuth clients, RFC 7662 and RFC 7009.
+// The kind of the token is told by its format: API keys have APIKeyPrefix, access tokens
+// are JWTs and anything else is looked up as a refresh token.
+type TokenService struct {
+	repository repository.UserRepository
+	users      *UserService
+	apiKeys    *APIKeyService
+}
+
+func NewTokenService(repository repository.UserRepository, users *UserService, apiKeys *APIKeyService) *TokenService {
+	return &TokenService{
+		repository: repository,
+		users:      users,
+		apiKeys:    apiKeys,
+	}
+}
+
+// Introspect describes the token, unknown, expired and revoked tokens are just inactive.
+func (s *TokenService) Introspect(ctx context.Context, token string) (dto.IntrospectionDTO, error) {
+	switch s.tokenType(token) {
+	case TokenTypeAPIKey:
+		return s.introspectAPIKey(ctx, token)
+	case TokenTypeAccessToken:
+		return s.introspectAccessToken(ctx, token)
+	default:
+		return s.introspectRefreshToken(ctx, token)
+	}
+}
+
+// Revoke revokes the token. Revoking a refresh token ends its session with the last
+// access token, revoking an access token leaves the session alive. Unknown tokens are
+// ignored, the client can't do anything about them anyway.
+func (s *TokenService) Revoke(ctx context.Context, token string) error {
+	switch s.tokenType(token) {
+	case TokenTypeAPIKey:
+		apiKey, _, err := s.apiKeys.find(ctx, token)
+		if err != nil {
+			return ignoreInvalidToken(err)
+		}
+		return ignoreInvalidToken(s.repository.DeleteAPIKey(ctx, apiKey.UserId, apiKey.Id))
+	case TokenTypeAccessToken:
+		var claims auth.Claims
+		if _, err := s.users.tokenManager.Parse(token, &claims); err != nil {
+			return nil
+		}
+		return s.users.tokenManager.RevokeToken(ctx, claims.Id, time.Unix(claims.ExpiresAt, 0))
+	default:
+		session, err := s.repository.GetSessionByRefreshToken(ctx, hash.HashToken(token))
+		if err != nil {
+			return ignoreInvalidToken(err)
+		}
+		return ignoreInvalidToken(s.users.revokeSession(ctx, session))
+	}
+}
+
+func (s *TokenService) introspectAccessToken(ctx context.Context, token string) (dto.IntrospectionDTO, error) {
+	var claims auth.Claims
+	if _, err := s.users.tokenManager.Parse(token, &claims); err != nil {
+		return dto.IntrospectionDTO{}, nil
+	}
+	revoked, err := s.users.tokenManager.IsRevoked(ctx, claims.Id)
+	if err != nil {
+		return dto.IntrospectionDTO{}, err
+	}
+	if revoked {
+		return dto.IntrospectionDTO{}, nil
+	}
+	return dto.IntrospectionDTO{
+		Active:    true,
+		TokenType: TokenTypeAccessToken,
+		Scope:     strings.Join(claims.Scopes, " "),
+		ClientId:  claims.ClientId,
+		Subject:   claims.Subject,
+		Roles:     claims.Roles,
+		SessionId: claims.SessionId,
+		TokenId:   claims.Id,
+		IssuedAt:  claims.IssuedAt,
+		ExpiresAt: claims.ExpiresAt,
+	}, nil
+}
+
+func (s *TokenService) introspectRefreshToken(ctx context.Context, token string) (dto.IntrospectionDTO, error) {
+	if token == "" {
+		return dto.IntrospectionDTO{}, nil
+	}
+	session, err := s.repository.GetSessionByRefreshToken(ctx, hash.HashToken(token))
+	if err != nil {
+		return dto.IntrospectionDTO{}, ignoreInvalidToken(err)
+	}
+	return dto.IntrospectionDTO{
+		Active:    true,
+		TokenType: TokenTypeRefreshToken,
+		Subject:   session.UserId.Hex(),
+		SessionId: session.Id.Hex(),
+		IssuedAt:  session.LastUsedAt.Unix(),
+		ExpiresAt: session.ExpiresAt.Unix(),
+	}, nil
+}
+
+func (s *TokenService) introspectAPIKey(ctx context.Context, token string) (dto.IntrospectionDTO, error) {
+	apiKey, user, err := s.apiKeys.find(ctx, token)
+	if err != nil {
+		return dto.IntrospectionDTO{}, ignoreInvalidToken(err)
+	}
+	introspection := dto.IntrospectionDTO{
+		Active:    true,
+		TokenType: TokenTypeAPIKey,
+		Scope:     strings.Join(apiKey.Scopes, " "),
+		Subject:   user.Id.Hex(),
+		Roles:     userRoles(user),
+		TokenId:   apiKey.Id.Hex(),
+		IssuedAt:  apiKey.CreatedAt.Unix(),
+	}
+	if apiKey.ExpiresAt != nil {
+		introspection.ExpiresAt = apiKey.ExpiresAt.Unix()
+	}
+	return introspection, nil
+}
+
+// tokenType tells the kind of the token by its format, JWTs have three parts and those
+// of refresh tokens have the refresh purpose.
+func (s *TokenService) tokenType(token string) string {
+	switch {
+	case strings.HasPrefix(token, APIKeyPrefix):
+		return TokenTypeAPIKey
+	case s.isRefreshJWT(token):
+		return TokenTypeRefreshToken
+	case strings.Count(token, ".") == 2:
+		return TokenTypeAccessToken
+	default:
+		return TokenTypeRefreshToken
+	}
+}
+
+// isRefreshJWT tells whether the token is a refresh token of the JWT format.
+func (s *TokenService) isRefreshJWT(token string) bool {
+	_, err := s.users.tokenManager.ParsePurposeToken(token, auth.RefreshTokenPurpose)
+	return err == nil
+}
+
+// ignoreInvalidToken drops errors of tokens that don't exist (any more), they are
+// inactive and there is nothing to revoke.
+func ignoreInvalidToken(err error) error {
+	if errors.Is(err, domain.ErrInvalidAPIKey) || errors.Is(err, domain.ErrAPIKeyNotFound) ||
+		errors.Is(err, domain.ErrSessionNotFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"test/internal/domain"
+	db_mocks "test/internal/repository/mocks"
+	"test/pkg/api/auth"
+	"test/pkg/hash"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func mockTokenService(t *testing.T) (*TokenService, *auth.Manager, *db_mocks.MockUserRepository) {
+	t.Helper()
+
+	userRepoMock := db_mocks.NewMockUserRepository(gomock.NewController(t))
+	tokenManager := newTokenManager(t, auth.NewMemoryDenylist())
+	userService := NewUserService(userRepoMock, tokenManager, &hash.SHA1Hasher{}, time.Minute, time.Minute, nil, nil)
+	apiKeyService := NewAPIKeyService(userRepoMock, userService)
+	return NewTokenService(userRepoMock, userService, apiKeyService), tokenManager, userRepoMock
+}
+
+func TestTokenService_IntrospectAccessToken(t *testing.T) {
+	tokenService, tokenManager, _ := mockTokenService(t)
+
+	claims := auth.Claims{Roles: []string{auth.UserRole}, SessionId: "000000000002", Scopes: []string{"users:read"}}
+	claims.Subject = "000000000001"
+	token, err := tokenManager.GenerateAccessToken(claims, time.Minute)
+	require.NoError(t, err)
+	token = strings.TrimPrefix(token, auth.PrefixToken)
+
+	introspection, err := tokenService.Introspect(context.Background(), token)
+	require.NoError(t, err)
+	assert.True(t, introspection.Active)
+	assert.Equal(t, TokenTypeAccessToken, introspection.TokenType)
+	assert.Equal(t, "000000000001", introspection.Subject)
+	assert.Equal(t, "000000000002", introspection.SessionId)
+	assert.Equal(t, "users:read", introspection.Scope)
+	assert.Equal(t, claims.Roles, introspection.Roles)
+	assert.NotEmpty(t, introspection.TokenId)
+	assert.NotZero(t, introspection.IssuedAt)
+	assert.NotZero(t, introspection.ExpiresAt)
+
+	require.NoError(t, tokenService.Revoke(context.Background(), token))
+	introspection, err = tokenService.Introspect(context.Background(), token)
+	require.NoError(t, err)
+	assert.False(t, introspection.Active, "revoked")
+
+	expired, err := tokenManager.GenerateAccessToken(claims, -time.Minute)
+	require.NoError(t, err)
+	introspection, err = tokenService.Introspect(context.Background(), strings.TrimPrefix(expired, auth.PrefixToken))
+	require.NoError(t, err)
+	assert.False(t, introspection.Active, "expired")
+
+	forged := token[:strings.LastIndex(token, ".")+1] + "forged"
+	introspection, err = tokenService.Introspect(context.Background(), forged)
+	require.NoError(t, err)
+	assert.False(t, introspection.Active, "forged signature")
+}
+
+func TestTokenService_IntrospectRefreshToken(t *testing.T) {
+	type mockRepoBehavior func(dbmock *db_mocks.MockUserRepository, session domain.Session)
+	refreshToken := "refresh"
+	session := domain.Session{
+		Id:         primitive.NewObjectID(),
+		UserId:     primitive.NewObjectID(),
+		LastUsedAt: time.Now(),
+		ExpiresAt:  time.Now().Add(time.Hour),
+	}
+
+	testTable := []struct {
+		name             string
+		mockRepoBehavior mockRepoBehavior
+		expectedActive   bool
+	}{
+		{
+			name: "Active",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, session domain.Session) {
+				dbmock.EXPECT().GetSessionByRefreshToken(context.Background(), hash.HashToken(refreshToken)).Return(session, nil)
+			},
+			expectedActive: true,
+		},
+		{
+			name: "Unknown token",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, session domain.Session) {
+				dbmock.EXPECT().GetSessionByRefreshToken(context.Background(), hash.HashToken(refreshToken)).
+					Return(domain.Session{}, domain.ErrSessionNotFound)
+			},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			tokenService, _, userRepoMock := mockTokenService(t)
+			testCase.mockRepoBehavior(userRepoMock, session)
+
+			introspection, err := tokenService.Introspect(context.Background(), refreshToken)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedActive, introspection.Active)
+			if !testCase.expectedActive {
+				return
+			}
+			assert.Equal(t, TokenTypeRefreshToken, introspection.TokenType)
+			assert.Equal(t, session.UserId.Hex(), introspection.Subject)
+			assert.Equal(t, session.Id.Hex(), introspection.SessionId)
+			assert.Equal(t, session.ExpiresAt.Unix(), introspection.ExpiresAt)
+		})
+	}
+}
+
+func TestTokenService_IntrospectRefreshJWT(t *testing.T) {
+	tokenService, _, userRepoMock := mockTokenService(t)
+	keys, err := auth.NewHMACKeySet("secret")
+	require.NoError(t, err)
+	jwtManager, err := auth.NewManager(keys, nil, nil, auth.ManagerConfig{RefreshTokenFormat: auth.RefreshTokenJWT})
+	require.NoError(t, err)
+	session := domain.Session{Id: primitive.NewObjectID(), UserId: primitive.NewObjectID(), ExpiresAt: time.Now().Add(time.Hour)}
+	refreshToken, err := jwtManager.GenerateRefreshToken(session.Id.Hex(), time.Hour)
+	require.NoError(t, err)
+
+	userRepoMock.EXPECT().GetSessionByRefreshToken(context.Background(), hash.HashToken(refreshToken)).Return(session, nil)
+
+	introspection, err := tokenService.Introspect(context.Background(), refreshToken)
+	require.NoError(t, err)
+	assert.True(t, introspection.Active)
+	assert.Equal(t, TokenTypeRefreshToken, introspection.TokenType, "refresh tokens of the JWT format aren't access tokens")
+	assert.Equal(t, session.Id.Hex(), introspection.SessionId)
+}
+
+func TestTokenService_IntrospectAPIKey(t *testing.T) {
+	tokenService, _, userRepoMock := mockTokenService(t)
+	key := APIKeyPrefix + "key"
+	user := domain.User{Id: primitive.NewObjectID(), Roles: []string{auth.UserRole}}
+	apiKey := domain.APIKey{Id: primitive.NewObjectID(), UserId: user.Id, Scopes: []string{"users:read"}, CreatedAt: time.Now()}
+
+	userRepoMock.EXPECT().FindAPIKeyByHash(context.Background(), hash.HashToken(key)).Return(apiKey, nil)
+	userRepoMock.EXPECT().FindOne(context.Background(), user.Id).Return(user, nil)
+
+	introspection, err := tokenService.Introspect(context.Background(), key)
+	require.NoError(t, err)
+	assert.True(t, introspection.Active)
+	assert.Equal(t, TokenTypeAPIKey, introspection.TokenType)
+	assert.Equal(t, user.Id.Hex(), introspection.Subject)
+	assert.Equal(t, apiKey.Id.Hex(), introspection.TokenId)
+	assert.Equal(t, "users:read", introspection.Scope)
+	assert.Zero(t, introspection.ExpiresAt, "key without expiry")
+
+	userRepoMock.EXPECT().FindAPIKeyByHash(context.Background(), hash.HashToken(key)).Return(domain.APIKey{}, domain.ErrAPIKeyNotFound)
+	introspection, err = tokenService.Introspect(context.Background(), key)
+	require.NoError(t, err)
+	assert.False(t, introspection.Active)
+}
+
+func TestTokenService_Revoke(t *testing.T) {
+	type mockRepoBehavior func(dbmock *db_mocks.MockUserRepository)
+	refreshToken := "refresh"
+	key := APIKeyPrefix + "key"
+	session := domain.Session{Id: primitive.NewObjectID(), UserId: primitive.NewObjectID()}
+	apiKey := domain.APIKey{Id: primitive.NewObjectID(), UserId: session.UserId}
+
+	testTable := []struct {
+		name             string
+		token            string
+		mockRepoBehavior mockRepoBehavior
+	}{
+		{
+			name:  "Refresh token",
+			token: refreshToken,
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().GetSessionByRefreshToken(context.Background(), hash.HashToken(refreshToken)).Return(session, nil)
+				dbmock.EXPECT().DeleteSession(context.Background(), session.UserId, session.Id).Return(nil)
+			},
+		},
+		{
+			name:  "Unknown refresh token",
+			token: refreshToken,
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().GetSessionByRefreshToken(context.Background(), hash.HashToken(refreshToken)).
+					Return(domain.Session{}, domain.ErrSessionNotFound)
+			},
+		},
+		{
+			name:  "API key",
+			token: key,
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().FindAPIKeyByHash(context.Background(), hash.HashToken(key)).Return(apiKey, nil)
+				dbmock.EXPECT().FindOne(context.Background(), apiKey.UserId).Return(domain.User{Id: apiKey.UserId}, nil)
+				dbmock.EXPECT().DeleteAPIKey(context.Background(), apiKey.UserId, apiKey.Id).Return(nil)
+			},
+		},
+		{
+			name:             "Invalid access token",
+			token:            "header.payload.signature",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			tokenService, _, userRepoMock := mockTokenService(t)
+			testCase.mockRepoBehavior(userRepoMock)
+
+			assert.NoError(t, tokenService.Revoke(context.Background(), testCase.token))
+		})
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"test/pkg/hash"
 	"time"
 
+	"github.com/golang-jwt/jwt"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -23,24 +24,31 @@ type UserService struct {
 	hasher          hash.PasswordHasher
 	accessTokenTTL  time.Duration
 	refreshTokenTTL time.Duration
+	// emails verifies emails of users, emails are trusted without verification when it is nil.
+	emails *EmailService
+	// lockout limits failed attempts to authenticate, nothing is limited when it is nil.
+	lockout *LockoutService
 }
 
 func NewUserService(repository repository.UserRepository, tokenManager auth.TokenManager, hasher hash.PasswordHasher,
-	accessTokenTTL, refreshTokenTTL time.Duration) *UserService {
+	accessTokenTTL, refreshTokenTTL time.Duration, emails *EmailService, lockout *LockoutService) *UserService {
 	return &UserService{
 		repository:      repository,
 		tokenManager:    tokenManager,
 		hasher:          hasher,
 		accessTokenTTL:  accessTokenTTL,
 		refreshTokenTTL: refreshTokenTTL,
+		emails:          emails,
+		lockout:         lockout,
 	}
 }
 
-func (s *UserService) Create(ctx context.Context, userDTO dto.CreateUserDTO) (dto.TokenDTO, error) {
+func (s *UserService) Create(ctx context.Context, userDTO dto.CreateUserDTO, device dto.DeviceDTO) (dto.TokenDTO, error) {
 
 	if !dto.ValidCreateUserDTO(userDTO) {
 		return dto.TokenDTO{}, fmt.Errorf("Invalid userDTO parameters")
 	}
+	userDTO.Email = domain.NormalizeEmail(userDTO.Email)
 
 	if _, err := s.FindByEmail(ctx, userDTO.Email); err != nil {
 		if !errors.Is(err, domain.ErrUserNotFound) {
@@ -57,14 +65,94 @@ func (s *UserService) Create(ctx context.Context, userDTO dto.CreateUserDTO) (dt
 	}
 	userDTO.Password = string(passwordHash)
 	user := dto.ConvertCreateUserDTO(userDTO)
+	user.Roles = []string{auth.UserRole}
 	id, err := s.repository.Create(ctx, user)
 	if err != nil {
 		return dto.TokenDTO{}, err
 	}
 
 	user.Id = id
+	s.sendVerification(ctx, user, user.Email)
 
-	return s.CreateSession(ctx, user.Id)
+	return s.CreateSession(ctx, user, device)
+}
+
+// sendVerification sends the link verifying the email, failure is only logged since
+// the user can ask for the link again.
+func (s *UserService) sendVerification(ctx context.Context, user domain.User, email string) {
+	if s.emails == nil {
+		return
+	}
+	if err := s.emails.SendVerification(ctx, user, email); err != nil {
+		log.Default().Printf("failed to send email verification to user with oid=%s due to error: %v", user.Id.Hex(), err)
+	}
+}
+
+// SignIn checks the credentials against the stored password hash and opens a new session.
+// Unknown email and wrong password are reported as different errors, both wrapping
+// domain.ErrInvalidCredentials, so callers can tell them apart without exposing it to clients.
+// Hashes made by an outdated algorithm or with outdated parameters are upgraded on success.
+func (s *UserService) SignIn(ctx context.Context, signInDTO dto.SignInDTO, device dto.DeviceDTO) (dto.TokenDTO, error) {
+
+	if err := s.lockout.attempt(ctx, signInDTO.Email, device.IP); err != nil {
+		return dto.TokenDTO{}, err
+	}
+
+	user, err := s.repository.FindByEmail(ctx, signInDTO.Email)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			// Hash anyway, so unknown emails take as long as wrong passwords.
+			s.hasher.Hash(signInDTO.Password) //nolint:errcheck
+			return dto.TokenDTO{}, domain.ErrUnknownEmail
+		}
+		return dto.TokenDTO{}, err
+	}
+
+	ok, err := s.hasher.Verify(signInDTO.Password, user.PasswordHash)
+	if err != nil {
+		return dto.TokenDTO{}, err
+	}
+	if !ok {
+		return dto.TokenDTO{}, domain.ErrWrongPassword
+	}
+	s.lockout.succeed(ctx, signInDTO.Email, device.IP)
+	if s.emails != nil && s.emails.config.RequiredForLogin && !user.EmailVerified {
+		return dto.TokenDTO{}, domain.ErrEmailNotVerified
+	}
+
+	if s.hasher.NeedsRehash(user.PasswordHash) {
+		s.rehashPassword(ctx, user, signInDTO.Password)
+	}
+
+	return s.beginSession(ctx, user, device)
+}
+
+// beginSession opens a session for the user who passed the first factor. Users with
+// the second factor get a challenge token instead, see MFAService.Verify.
+func (s *UserService) beginSession(ctx context.Context, user domain.User, device dto.DeviceDTO) (dto.TokenDTO, error) {
+	if !user.MFAEnabled() {
+		return s.CreateSession(ctx, user, device)
+	}
+
+	token, err := s.tokenManager.GeneratePurposeToken(mfaPurpose, auth.Claims{
+		StandardClaims: jwt.StandardClaims{Subject: user.Id.Hex()},
+	}, mfaChallengeTTL)
+	if err != nil {
+		return dto.TokenDTO{}, err
+	}
+	return dto.TokenDTO{MFAToken: token}, nil
+}
+
+// rehashPassword replaces the stored hash, failure is only logged since the user has
+// already proved the password and the old hash is still valid.
+func (s *UserService) rehashPassword(ctx context.Context, user domain.User, password string) {
+	passwordHash, err := s.hasher.Hash(password)
+	if err == nil {
+		err = s.repository.UpdatePasswordHash(ctx, user.Id, passwordHash)
+	}
+	if err != nil {
+		log.Default().Printf("failed to rehash password of user with oid=%s due to error: %v", user.Id.Hex(), err)
+	}
 }
 
 func (s *UserService) FindOne(ctx context.Context, id string) (domain.User, error) {
@@ -110,13 +198,34 @@ func (s *UserService) FindAll(ctx context.Context, limit, offset, filter, sortBy
 	return s.repository.FindAll(ctx, pagination, filters, sortOptions)
 }
 
+// Update sets the email and the password of the user. When emails are verified, a new
+// email only becomes pending and replaces the current one once the user verifies it.
 func (s *UserService) Update(ctx context.Context, userDTO dto.UpdateUserDTO) error {
 
 	if !dto.ValidUpdateUserDTO(userDTO) {
 		return fmt.Errorf("Invalid userDTO parameters")
 	}
 
-	if _, err := s.FindByEmail(ctx, userDTO.Email); err != nil {
+	passwordHash, err := s.hasher.Hash(userDTO.Password)
+	if err != nil {
+		return err
+	}
+	userDTO.Password = string(passwordHash)
+	user, err := dto.ConvertUpdateUserDTO(userDTO)
+	if err != nil {
+		return err
+	}
+	user.Email = domain.NormalizeEmail(user.Email)
+
+	current, err := s.repository.FindOne(ctx, user.Id)
+	if err != nil {
+		return err
+	}
+	if user.Email == current.Email {
+		return s.repository.Update(ctx, user)
+	}
+
+	if _, err := s.FindByEmail(ctx, user.Email); err != nil {
 		if !errors.Is(err, domain.ErrUserNotFound) {
 			return err
 		}
@@ -124,17 +233,19 @@ func (s *UserService) Update(ctx context.Context, userDTO dto.UpdateUserDTO) err
 		return domain.ErrUserAlreadyExists
 	}
 
-	passwordHash, err := s.hasher.Hash(userDTO.Password)
-	if err != nil {
+	if s.emails == nil {
+		return s.repository.Update(ctx, user)
+	}
+
+	newEmail := user.Email
+	user.Email = current.Email
+	if err := s.repository.Update(ctx, user); err != nil {
 		return err
 	}
-	userDTO.Password = string(passwordHash)
-	user, err := dto.ConvertUpdateUserDTO(userDTO)
-	if err != nil {
+	if err := s.repository.SetPendingEmail(ctx, user.Id, newEmail); err != nil {
 		return err
 	}
-
-	return s.repository.Update(ctx, user)
+	return s.emails.SendVerification(ctx, user, newEmail)
 }
 
 func (s *UserService) Delete(ctx context.Context, id string) error {
@@ -144,44 +255,292 @@ func (s *UserService) Delete(ctx context.Context, id string) error {
 		return err
 	}
 
-	return s.repository.Delete(ctx, oid)
+	if err := s.repository.Delete(ctx, oid); err != nil {
+		return err
+	}
+	if err := s.repository.DeleteAPIKeys(ctx, oid); err != nil {
+		return err
+	}
+	if err := s.revokeImpersonations(ctx, oid); err != nil {
+		return err
+	}
+	return s.revokeSessions(ctx, oid)
 }
 
-func (s *UserService) RefreshUserToken(ctx context.Context, userid string) (dto.TokenDTO, error) {
+// RefreshUserToken exchanges a live refresh token for a new token pair and rotates it.
+// A token that was already exchanged means it leaked, so the whole session is revoked.
+func (s *UserService) RefreshUserToken(ctx context.Context, refreshToken string, device dto.DeviceDTO) (dto.TokenDTO, error) {
 
-	oid, err := params.ParseIdToObjectID(userid)
-	if err != nil {
+	if err := s.lockout.attempt(ctx, "", device.IP); err != nil {
 		return dto.TokenDTO{}, err
 	}
-	user, err := s.repository.GetUserByRefreshToken(ctx, oid)
+	if refreshToken == "" {
+		return dto.TokenDTO{}, domain.ErrSessionNotFound
+	}
+	tokenHash := hash.HashToken(refreshToken)
+
+	session, err := s.repository.GetSessionByRefreshToken(ctx, tokenHash)
 	if err != nil {
+		if errors.Is(err, domain.ErrSessionNotFound) {
+			return dto.TokenDTO{}, s.detectRefreshTokenReuse(ctx, tokenHash)
+		}
 		return dto.TokenDTO{}, err
 	}
-	return s.CreateSession(ctx, user.Id)
-}
 
-func (s *UserService) CreateSession(ctx context.Context, oid primitive.ObjectID) (dto.TokenDTO, error) {
-	id := oid.Hex()
-	accessToken, err := s.tokenManager.GenerateAccessToken(id, s.accessTokenTTL)
+	// Roles are read again, so granted and revoked roles take effect on refresh.
+	user, err := s.repository.FindOne(ctx, session.UserId)
 	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return dto.TokenDTO{}, domain.ErrSessionNotFound
+		}
 		return dto.TokenDTO{}, err
 	}
-	refreshToken, err := s.tokenManager.GenerateRefreshToken()
+
+	previous := session
+	tokenDTO, err := s.generateTokens(&session, user)
 	if err != nil {
 		return dto.TokenDTO{}, err
 	}
+	// The session remembers only its latest access token, so the one it replaces is
+	// revoked now, revoking the session later wouldn't reach it.
+	if err := s.tokenManager.RevokeToken(ctx, previous.AccessTokenId, previous.AccessTokenExpiresAt); err != nil {
+		return dto.TokenDTO{}, err
+	}
+
+	now := time.Now()
+	session.UserAgent = device.UserAgent
+	session.IP = device.IP
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(s.refreshTokenTTL)
+
+	if err := s.repository.RotateSession(ctx, session, tokenHash); err != nil {
+		if errors.Is(err, domain.ErrRefreshTokenReused) {
+			return dto.TokenDTO{}, s.revokeReusedSession(ctx, session)
+		}
+		return dto.TokenDTO{}, err
+	}
+	s.lockout.succeed(ctx, "", device.IP)
+	return tokenDTO, nil
+}
 
+func (s *UserService) detectRefreshTokenReuse(ctx context.Context, tokenHash string) error {
+	session, err := s.repository.GetSessionByRotatedToken(ctx, tokenHash)
+	if err != nil {
+		return err
+	}
+	return s.revokeReusedSession(ctx, session)
+}
+
+func (s *UserService) revokeReusedSession(ctx context.Context, session domain.Session) error {
+	log.Default().Printf("refresh token reuse detected, revoking session with oid=%s of user with oid=%s",
+		session.Id.Hex(), session.UserId.Hex())
+
+	if err := s.revokeSession(ctx, session); err != nil && !errors.Is(err, domain.ErrSessionNotFound) {
+		return err
+	}
+	return domain.ErrRefreshTokenReused
+}
+
+// CreateSession opens a new session for the device, other sessions of the user stay alive.
+func (s *UserService) CreateSession(ctx context.Context, user domain.User, device dto.DeviceDTO) (dto.TokenDTO, error) {
 	session := domain.Session{
-		RefreshToken: refreshToken,
-		ExpiresAt:    time.Now().Add(s.refreshTokenTTL),
+		Id:     primitive.NewObjectID(),
+		UserId: user.Id,
 	}
+	tokenDTO, err := s.generateTokens(&session, user)
+	if err != nil {
+		return dto.TokenDTO{}, err
+	}
+
+	now := time.Now()
+	session.UserAgent = device.UserAgent
+	session.IP = device.IP
+	session.CreatedAt = now
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(s.refreshTokenTTL)
 
-	if err := s.repository.SetSession(ctx, oid, session); err != nil {
+	if err := s.repository.CreateSession(ctx, session); err != nil {
 		return dto.TokenDTO{}, err
 	}
 
+	return tokenDTO, nil
+}
+
+func (s *UserService) FindSessions(ctx context.Context, userId string) ([]domain.Session, error) {
+	oid, err := params.ParseIdToObjectID(userId)
+	if err != nil {
+		return nil, err
+	}
+	return s.repository.FindSessions(ctx, oid)
+}
+
+func (s *UserService) RevokeSession(ctx context.Context, userId, sessionId string) error {
+	oid, err := params.ParseIdToObjectID(userId)
+	if err != nil {
+		return err
+	}
+	sessionOid, err := params.ParseIdToObjectID(sessionId)
+	if err != nil {
+		return err
+	}
+	session, err := s.repository.FindSession(ctx, oid, sessionOid)
+	if err != nil {
+		return err
+	}
+	return s.revokeSession(ctx, session)
+}
+
+func (s *UserService) RevokeSessions(ctx context.Context, userId string) error {
+	oid, err := params.ParseIdToObjectID(userId)
+	if err != nil {
+		return err
+	}
+	return s.revokeSessions(ctx, oid)
+}
+
+// Logout ends the session the access token was issued for and denylists the token.
+func (s *UserService) Logout(ctx context.Context, claims auth.Claims) error {
+	if err := s.tokenManager.RevokeToken(ctx, claims.Id, time.Unix(claims.ExpiresAt, 0)); err != nil {
+		return err
+	}
+	return s.RevokeSession(ctx, claims.Subject, claims.SessionId)
+}
+
+// LogoutAll ends every session of the token owner.
+func (s *UserService) LogoutAll(ctx context.Context, claims auth.Claims) error {
+	if err := s.tokenManager.RevokeToken(ctx, claims.Id, time.Unix(claims.ExpiresAt, 0)); err != nil {
+		return err
+	}
+	return s.RevokeSessions(ctx, claims.Subject)
+}
+
+func (s *UserService) revokeSession(ctx context.Context, session domain.Session) error {
+	if err := s.tokenManager.RevokeToken(ctx, session.AccessTokenId, session.AccessTokenExpiresAt); err != nil {
+		return err
+	}
+	return s.repository.DeleteSession(ctx, session.UserId, session.Id)
+}
+
+func (s *UserService) revokeSessions(ctx context.Context, oid primitive.ObjectID) error {
+	sessions, err := s.repository.FindSessions(ctx, oid)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if err := s.tokenManager.RevokeToken(ctx, session.AccessTokenId, session.AccessTokenExpiresAt); err != nil {
+			return err
+		}
+	}
+	return s.repository.DeleteSessions(ctx, oid)
+}
+
+func (s *UserService) revokeImpersonations(ctx context.Context, oid primitive.ObjectID) error {
+	impersonations, err := s.repository.FindImpersonations(ctx, oid)
+	if err != nil {
+		return err
+	}
+	for _, impersonation := range impersonations {
+		if err := s.tokenManager.RevokeToken(ctx, impersonation.Id.Hex(), impersonation.ExpiresAt); err != nil {
+			return err
+		}
+	}
+	return s.repository.DeleteImpersonations(ctx, oid)
+}
+
+// generateTokens issues a token pair for the session and remembers the access token id
+// and the refresh token hash in it.
+func (s *UserService) generateTokens(session *domain.Session, user domain.User) (dto.TokenDTO, error) {
+	tokenId, err := auth.GenerateTokenId()
+	if err != nil {
+		return dto.TokenDTO{}, err
+	}
+	claims := auth.Claims{
+		Roles:         userRoles(user),
+		SessionId:     session.Id.Hex(),
+		EmailVerified: user.EmailVerified,
+	}
+	claims.Id = tokenId
+	claims.Subject = session.UserId.Hex()
+
+	accessToken, err := s.tokenManager.GenerateAccessToken(claims, s.accessTokenTTL)
+	if err != nil {
+		return dto.TokenDTO{}, err
+	}
+	refreshToken, err := s.tokenManager.GenerateRefreshToken(session.Id.Hex(), s.refreshTokenTTL)
+	if err != nil {
+		return dto.TokenDTO{}, err
+	}
+
+	session.AccessTokenId = tokenId
+	session.AccessTokenExpiresAt = time.Now().Add(s.accessTokenTTL)
+	session.RefreshTokenHash = hash.HashToken(refreshToken)
+
 	return dto.TokenDTO{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 	}, nil
 }
+
+// userRoles returns roles of the user, users created before roles were stored are regular users.
+func userRoles(user domain.User) []string {
+	if len(user.Roles) == 0 {
+		return []string{auth.UserRole}
+	}
+	return user.Roles
+}
+
+// GrantRole adds the role to the user. Roles get into access tokens issued after it,
+// so the user sees the change on the next token refresh.
+func (s *UserService) GrantRole(ctx context.Context, id, role string) error {
+	if !auth.ValidRole(role) {
+		return domain.ErrUnknownRole
+	}
+	oid, err := params.ParseIdToObjectID(id)
+	if err != nil {
+		return err
+	}
+	return s.repository.AddRole(ctx, oid, role)
+}
+
+func (s *UserService) RevokeRole(ctx context.Context, id, role string) error {
+	if !auth.ValidRole(role) {
+		return domain.ErrUnknownRole
+	}
+	oid, err := params.ParseIdToObjectID(id)
+	if err != nil {
+		return err
+	}
+	return s.repository.RemoveRole(ctx, oid, role)
+}
+
+// BootstrapAdmin makes sure the user with the email exists and is an admin. It is how
+// the first admin appears, password is used only when the user has to be created.
+func (s *UserService) BootstrapAdmin(ctx context.Context, email, password string) error {
+	user, err := s.repository.FindByEmail(ctx, email)
+	if err == nil {
+		if user.HasRole(auth.AdminRole) {
+			return nil
+		}
+		return s.repository.AddRole(ctx, user.Id, auth.AdminRole)
+	}
+	if !errors.Is(err, domain.ErrUserNotFound) {
+		return err
+	}
+
+	userDTO := dto.CreateUserDTO{Email: email, Password: password}
+	if !dto.ValidCreateUserDTO(userDTO) {
+		return fmt.Errorf("Invalid admin email or password")
+	}
+	passwordHash, err := s.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+	userDTO.Password = string(passwordHash)
+	user = dto.ConvertCreateUserDTO(userDTO)
+	user.Roles = []string{auth.UserRole, auth.AdminRole}
+	// The email comes from the configuration, there is nobody to verify it.
+	user.EmailVerified = true
+
+	_, err = s.repository.Create(ctx, user)
+	return err
+}
@@ -13,7 +13,9 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func mockUserService(t *testing.T) (*UserService, *db_mocks.MockUserRepository) {
@@ -23,18 +25,35 @@ func mockUserService(t *testing.T) (*UserService, *db_mocks.MockUserRepository)
 	defer mockCtl.Finish()
 
 	userRepoMock := db_mocks.NewMockUserRepository(mockCtl)
+	tokenManager := newTokenManager(t, nil)
 
 	userService := NewUserService(
 		userRepoMock,
-		&auth.Manager{},
+		tokenManager,
 		&hash.SHA1Hasher{},
 		1*time.Minute,
 		1*time.Minute,
+		nil,
+		nil,
 	)
 
 	return userService, userRepoMock
 }
 
+func newTokenManager(t *testing.T, denylist auth.Denylist) *auth.Manager {
+	t.Helper()
+
+	keys, err := auth.NewHMACKeySet("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenManager, err := auth.NewManager(keys, denylist, nil, auth.ManagerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokenManager
+}
+
 func TestUserRepository_Create(t *testing.T) {
 	userService, userRepoMock := mockUserService(t)
 	type mockRepoBehavior func(dbmock *db_mocks.MockUserRepository)
@@ -55,7 +74,7 @@ func TestUserRepository_Create(t *testing.T) {
 			},
 			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
 				dbmock.EXPECT().Create(context.Background(), gomock.Any()).Return(primitive.NewObjectID(), nil)
-				dbmock.EXPECT().SetSession(context.Background(), gomock.Any(), gomock.Any()).Return(nil)
+				dbmock.EXPECT().CreateSession(context.Background(), gomock.Any()).Return(nil)
 				dbmock.EXPECT().FindByEmail(context.Background(), gomock.Any()).Return(domain.User{}, domain.ErrUserNotFound)
 			},
 			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
@@ -83,6 +102,20 @@ func TestUserRepository_Create(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:    "User already exists in another case",
+			userDTO: dto.CreateUserDTO{Email: "TEST@test.ru", Password: "test1234"},
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().FindByEmail(context.Background(), "test@test.ru").Return(domain.User{Id: primitive.NewObjectID()}, nil)
+			},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.ErrorIs(t, err, domain.ErrUserAlreadyExists)
+					},
+				}
+			},
+		},
 		{
 			name:             "Email Invalid",
 			userDTO:          dto.CreateUserDTO{Email: "testest.ru", Password: "test1234"},
@@ -128,7 +161,7 @@ func TestUserRepository_Create(t *testing.T) {
 			userDTO: dto.CreateUserDTO{Email: "test@test.ru", Password: "test1234"},
 			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
 				dbmock.EXPECT().Create(context.Background(), gomock.Any()).Return(primitive.NewObjectID(), nil)
-				dbmock.EXPECT().SetSession(context.Background(), gomock.Any(), gomock.Any()).Return(fmt.Errorf("create session service failure"))
+				dbmock.EXPECT().CreateSession(context.Background(), gomock.Any()).Return(fmt.Errorf("create session service failure"))
 				dbmock.EXPECT().FindByEmail(context.Background(), gomock.Any()).Return(domain.User{}, domain.ErrUserNotFound)
 			},
 			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
@@ -163,7 +196,7 @@ func TestUserRepository_Create(t *testing.T) {
 
 			testCase.mockRepoBehavior(userRepoMock)
 
-			actualToken, err := userService.Create(context.Background(), testCase.userDTO)
+			actualToken, err := userService.Create(context.Background(), testCase.userDTO, dto.DeviceDTO{})
 
 			for _, assert := range testCase.assertServiceTests() {
 				assert(t, err, testCase.expectedResult, actualToken)
@@ -172,6 +205,168 @@ func TestUserRepository_Create(t *testing.T) {
 	}
 }
 
+func TestUserRepository_SignIn(t *testing.T) {
+	userService, userRepoMock := mockUserService(t)
+	type mockRepoBehavior func(dbmock *db_mocks.MockUserRepository)
+
+	passwordHash, _ := (&hash.SHA1Hasher{}).Hash("test1234")
+
+	testTable := []struct {
+		name               string
+		signInDTO          dto.SignInDTO
+		mockRepoBehavior   mockRepoBehavior
+		assertServiceTests func() []func(t *testing.T, err error, i ...interface{})
+	}{
+		{
+			name:      "OK",
+			signInDTO: dto.SignInDTO{Email: "test@test.ru", Password: "test1234"},
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().FindByEmail(context.Background(), "test@test.ru").Return(domain.User{
+					Id:           primitive.NewObjectID(),
+					PasswordHash: passwordHash,
+					Email:        "test@test.ru",
+				}, nil)
+				dbmock.EXPECT().CreateSession(context.Background(), gomock.Any()).Return(nil)
+			},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.NotEmpty(t, i)
+					},
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.Nil(t, err)
+					},
+				}
+			},
+		},
+		{
+			name:      "Unknown email",
+			signInDTO: dto.SignInDTO{Email: "test@test.ru", Password: "test1234"},
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().FindByEmail(context.Background(), "test@test.ru").Return(domain.User{}, domain.ErrUserNotFound)
+			},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.ErrorIs(t, err, domain.ErrUnknownEmail)
+					},
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+					},
+				}
+			},
+		},
+		{
+			name:      "Wrong password",
+			signInDTO: dto.SignInDTO{Email: "test@test.ru", Password: "wrong1234"},
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().FindByEmail(context.Background(), "test@test.ru").Return(domain.User{
+					Id:           primitive.NewObjectID(),
+					PasswordHash: passwordHash,
+					Email:        "test@test.ru",
+				}, nil)
+			},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.ErrorIs(t, err, domain.ErrWrongPassword)
+					},
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+					},
+				}
+			},
+		},
+		{
+			name:      "Repository Failure",
+			signInDTO: dto.SignInDTO{Email: "test@test.ru", Password: "test1234"},
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().FindByEmail(context.Background(), "test@test.ru").Return(domain.User{}, fmt.Errorf("repository failure"))
+			},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.EqualError(t, err, "repository failure")
+					},
+				}
+			},
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			testCase.mockRepoBehavior(userRepoMock)
+
+			actualToken, err := userService.SignIn(context.Background(), testCase.signInDTO, dto.DeviceDTO{})
+
+			for _, assert := range testCase.assertServiceTests() {
+				assert(t, err, actualToken)
+			}
+		})
+	}
+}
+
+func TestUserRepository_SignInRehash(t *testing.T) {
+	userRepoMock := db_mocks.NewMockUserRepository(gomock.NewController(t))
+	legacy := hash.NewSHA1Hasher("salt")
+	bcryptHasher, _ := hash.NewBcryptHasher(bcrypt.MinCost)
+	hasher := hash.NewMigratingHasher(bcryptHasher, legacy)
+	userService := NewUserService(userRepoMock, newTokenManager(t, nil), hasher, time.Minute, time.Minute, nil, nil)
+
+	legacyHash, _ := legacy.Hash("test1234")
+	currentHash, _ := bcryptHasher.Hash("test1234")
+	type mockRepoBehavior func(dbmock *db_mocks.MockUserRepository, user domain.User)
+
+	testTable := []struct {
+		name             string
+		passwordHash     string
+		mockRepoBehavior mockRepoBehavior
+	}{
+		{
+			name:         "Legacy hash upgraded",
+			passwordHash: legacyHash,
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {
+				dbmock.EXPECT().UpdatePasswordHash(context.Background(), user.Id, gomock.Any()).DoAndReturn(
+					func(_ context.Context, _ primitive.ObjectID, passwordHash string) error {
+						ok, err := bcryptHasher.Verify("test1234", passwordHash)
+						assert.True(t, ok)
+						assert.Nil(t, err)
+						return nil
+					})
+				dbmock.EXPECT().CreateSession(context.Background(), gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name:         "Upgrade failure doesn't block sign in",
+			passwordHash: legacyHash,
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {
+				dbmock.EXPECT().UpdatePasswordHash(context.Background(), user.Id, gomock.Any()).Return(fmt.Errorf("repository failure"))
+				dbmock.EXPECT().CreateSession(context.Background(), gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name:         "Current hash kept",
+			passwordHash: currentHash,
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, user domain.User) {
+				dbmock.EXPECT().CreateSession(context.Background(), gomock.Any()).Return(nil)
+			},
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			user := domain.User{Id: primitive.NewObjectID(), Email: "test@test.ru", PasswordHash: testCase.passwordHash}
+			userRepoMock.EXPECT().FindByEmail(context.Background(), user.Email).Return(user, nil)
+			testCase.mockRepoBehavior(userRepoMock, user)
+
+			token, err := userService.SignIn(context.Background(), dto.SignInDTO{Email: user.Email, Password: "test1234"}, dto.DeviceDTO{})
+			assert.Nil(t, err)
+			assert.NotEmpty(t, token)
+		})
+	}
+}
+
 func TestUserRepository_FindOne(t *testing.T) {
 	userService, userRepoMock := mockUserService(t)
 	type mockRepoBehavior func(dbmock *db_mocks.MockUserRepository)
@@ -527,6 +722,7 @@ func TestUserRepository_Update(t *testing.T) {
 			},
 
 			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().FindOne(context.Background(), gomock.Any()).Return(domain.User{Email: "old@test.ru"}, nil)
 				dbmock.EXPECT().Update(context.Background(), gomock.Any()).Return(nil)
 				dbmock.EXPECT().FindByEmail(context.Background(), gomock.Any()).Return(domain.User{}, domain.ErrUserNotFound)
 			},
@@ -548,6 +744,7 @@ func TestUserRepository_Update(t *testing.T) {
 			},
 
 			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().FindOne(context.Background(), gomock.Any()).Return(domain.User{Email: "old@test.ru"}, nil)
 				dbmock.EXPECT().FindByEmail(context.Background(), gomock.Any()).Return(domain.User{}, domain.ErrUserAlreadyExists)
 			},
 			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
@@ -558,6 +755,27 @@ func TestUserRepository_Update(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "Same email",
+
+			userDTO: dto.UpdateUserDTO{
+				Id:       primitive.NewObjectID().Hex(),
+				Email:    "test@test.ru",
+				Password: "test1234",
+			},
+
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().FindOne(context.Background(), gomock.Any()).Return(domain.User{Email: "test@test.ru"}, nil)
+				dbmock.EXPECT().Update(context.Background(), gomock.Any()).Return(nil)
+			},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.Nil(t, err)
+					},
+				}
+			},
+		},
 		{
 			name: "Email Invalid",
 
@@ -673,6 +891,11 @@ func TestUserRepository_Delete(t *testing.T) {
 			id:   primitive.NewObjectID().Hex(),
 			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
 				dbmock.EXPECT().Delete(context.Background(), gomock.Any()).Return(nil)
+				dbmock.EXPECT().DeleteAPIKeys(context.Background(), gomock.Any()).Return(nil)
+				dbmock.EXPECT().FindImpersonations(context.Background(), gomock.Any()).Return([]domain.Impersonation{}, nil)
+				dbmock.EXPECT().DeleteImpersonations(context.Background(), gomock.Any()).Return(nil)
+				dbmock.EXPECT().FindSessions(context.Background(), gomock.Any()).Return([]domain.Session{}, nil)
+				dbmock.EXPECT().DeleteSessions(context.Background(), gomock.Any()).Return(nil)
 			},
 			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
 				return []func(t *testing.T, err error, i ...interface{}){
@@ -729,23 +952,32 @@ func TestUserRepository_RefreshUserToken(t *testing.T) {
 	userService, userRepoMock := mockUserService(t)
 	type mockRepoBehavior func(dbmock *db_mocks.MockUserRepository)
 
+	refreshTokenHash := hash.HashToken("Refresh token")
+	session := domain.Session{
+		Id:               primitive.NewObjectID(),
+		UserId:           primitive.NewObjectID(),
+		RefreshTokenHash: refreshTokenHash,
+		ExpiresAt:        time.Now().Add(time.Minute),
+	}
+
 	testTable := []struct {
 		name               string
-		id                 string
+		refreshToken       string
 		mockRepoBehavior   mockRepoBehavior
 		assertServiceTests func() []func(t *testing.T, err error, i ...interface{})
 	}{
 		{
-			name: "OK",
-			id:   primitive.NewObjectID().Hex(),
+			name:         "OK",
+			refreshToken: "Refresh token",
 			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
-				dbmock.EXPECT().GetUserByRefreshToken(context.Background(), gomock.Any()).Return(domain.User{
-					Id:           [12]byte{},
-					PasswordHash: "123456781234",
-					Email:        "test@test.ru",
-					Session:      domain.Session{},
-				}, nil)
-				dbmock.EXPECT().SetSession(context.Background(), gomock.Any(), gomock.Any()).Return(nil)
+				dbmock.EXPECT().GetSessionByRefreshToken(context.Background(), refreshTokenHash).Return(session, nil)
+				dbmock.EXPECT().FindOne(context.Background(), session.UserId).Return(domain.User{Id: session.UserId}, nil)
+				dbmock.EXPECT().RotateSession(context.Background(), gomock.Any(), refreshTokenHash).DoAndReturn(
+					func(_ context.Context, rotated domain.Session, _ string) error {
+						assert.Equal(t, session.Id, rotated.Id)
+						assert.NotEqual(t, refreshTokenHash, rotated.RefreshTokenHash)
+						return nil
+					})
 			},
 			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
 				return []func(t *testing.T, err error, i ...interface{}){
@@ -759,49 +991,94 @@ func TestUserRepository_RefreshUserToken(t *testing.T) {
 			},
 		},
 		{
-			name:             "Id Invalid",
-			id:               "0000000000000",
+			name:             "Empty refresh token",
 			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {},
 			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
 				return []func(t *testing.T, err error, i ...interface{}){
 					func(t *testing.T, err error, i ...interface{}) {
-						assert.Error(t, err, "invalid id param")
+						assert.ErrorIs(t, err, domain.ErrSessionNotFound)
 					},
 				}
 			},
 		},
 		{
-			name: "Create session service Failure",
-			id:   primitive.NewObjectID().Hex(),
+			name:         "Unknown refresh token",
+			refreshToken: "Refresh token",
 			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
-				dbmock.EXPECT().GetUserByRefreshToken(context.Background(), gomock.Any()).Return(domain.User{
-					Id:           [12]byte{},
-					PasswordHash: "123456781234",
-					Email:        "test@test.ru",
-					Session:      domain.Session{},
-				}, nil)
-				dbmock.EXPECT().SetSession(context.Background(), gomock.Any(), gomock.Any()).Return(fmt.Errorf("create session service failure"))
+				dbmock.EXPECT().GetSessionByRefreshToken(context.Background(), refreshTokenHash).Return(domain.Session{}, domain.ErrSessionNotFound)
+				dbmock.EXPECT().GetSessionByRotatedToken(context.Background(), refreshTokenHash).Return(domain.Session{}, domain.ErrSessionNotFound)
 			},
 			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
 				return []func(t *testing.T, err error, i ...interface{}){
 					func(t *testing.T, err error, i ...interface{}) {
-						assert.Error(t, err, "create session service failure")
+						assert.ErrorIs(t, err, domain.ErrSessionNotFound)
 					},
 				}
 			},
 		},
 		{
-			name: "Repository Failure",
-			id:   primitive.NewObjectID().Hex(),
+			name:         "Rotated refresh token reused",
+			refreshToken: "Refresh token",
 			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
-				dbmock.EXPECT().GetUserByRefreshToken(context.Background(), gomock.Any()).Return(
-					domain.User{},
+				dbmock.EXPECT().GetSessionByRefreshToken(context.Background(), refreshTokenHash).Return(domain.Session{}, domain.ErrSessionNotFound)
+				dbmock.EXPECT().GetSessionByRotatedToken(context.Background(), refreshTokenHash).Return(session, nil)
+				dbmock.EXPECT().DeleteSession(context.Background(), session.UserId, session.Id).Return(nil)
+			},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.ErrorIs(t, err, domain.ErrRefreshTokenReused)
+					},
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.Equal(t, dto.TokenDTO{}, i[0])
+					},
+				}
+			},
+		},
+		{
+			name:         "User deleted",
+			refreshToken: "Refresh token",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().GetSessionByRefreshToken(context.Background(), refreshTokenHash).Return(session, nil)
+				dbmock.EXPECT().FindOne(context.Background(), session.UserId).Return(domain.User{}, domain.ErrUserNotFound)
+			},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.ErrorIs(t, err, domain.ErrSessionNotFound)
+					},
+				}
+			},
+		},
+		{
+			name:         "Concurrent rotation of the same token",
+			refreshToken: "Refresh token",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().GetSessionByRefreshToken(context.Background(), refreshTokenHash).Return(session, nil)
+				dbmock.EXPECT().FindOne(context.Background(), session.UserId).Return(domain.User{Id: session.UserId}, nil)
+				dbmock.EXPECT().RotateSession(context.Background(), gomock.Any(), refreshTokenHash).Return(domain.ErrRefreshTokenReused)
+				dbmock.EXPECT().DeleteSession(context.Background(), session.UserId, session.Id).Return(nil)
+			},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.ErrorIs(t, err, domain.ErrRefreshTokenReused)
+					},
+				}
+			},
+		},
+		{
+			name:         "Repository Failure",
+			refreshToken: "Refresh token",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().GetSessionByRefreshToken(context.Background(), refreshTokenHash).Return(
+					domain.Session{},
 					fmt.Errorf("repository failure"))
 			},
 			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
 				return []func(t *testing.T, err error, i ...interface{}){
 					func(t *testing.T, err error, i ...interface{}) {
-						assert.Error(t, err, "repository failure")
+						assert.EqualError(t, err, "repository failure")
 					},
 				}
 			},
@@ -814,7 +1091,7 @@ func TestUserRepository_RefreshUserToken(t *testing.T) {
 
 			testCase.mockRepoBehavior(userRepoMock)
 
-			actualToken, err := userService.RefreshUserToken(context.Background(), testCase.id)
+			actualToken, err := userService.RefreshUserToken(context.Background(), testCase.refreshToken, dto.DeviceDTO{})
 
 			for _, assert := range testCase.assertServiceTests() {
 				assert(t, err, actualToken)
@@ -823,21 +1100,47 @@ func TestUserRepository_RefreshUserToken(t *testing.T) {
 	}
 }
 
+func TestUserService_RefreshUserTokenRevokesPreviousAccessToken(t *testing.T) {
+	userRepoMock := db_mocks.NewMockUserRepository(gomock.NewController(t))
+	tokenManager := newTokenManager(t, auth.NewMemoryDenylist())
+	userService := NewUserService(userRepoMock, tokenManager, &hash.SHA1Hasher{}, time.Minute, time.Minute, nil, nil)
+
+	refreshTokenHash := hash.HashToken("Refresh token")
+	session := domain.Session{
+		Id:                   primitive.NewObjectID(),
+		UserId:               primitive.NewObjectID(),
+		RefreshTokenHash:     refreshTokenHash,
+		AccessTokenId:        "previous",
+		AccessTokenExpiresAt: time.Now().Add(time.Minute),
+		ExpiresAt:            time.Now().Add(time.Minute),
+	}
+	userRepoMock.EXPECT().GetSessionByRefreshToken(context.Background(), refreshTokenHash).Return(session, nil)
+	userRepoMock.EXPECT().FindOne(context.Background(), session.UserId).Return(domain.User{Id: session.UserId}, nil)
+	userRepoMock.EXPECT().RotateSession(context.Background(), gomock.Any(), refreshTokenHash).Return(nil)
+
+	_, err := userService.RefreshUserToken(context.Background(), "Refresh token", dto.DeviceDTO{})
+	require.NoError(t, err)
+
+	revoked, err := tokenManager.IsRevoked(context.Background(), "previous")
+	require.NoError(t, err)
+	assert.True(t, revoked, "the replaced access token doesn't outlive the session")
+}
+
 func TestUserRepository_CreateSession(t *testing.T) {
 	userService, userRepoMock := mockUserService(t)
 	type mockRepoBehavior func(dbmock *db_mocks.MockUserRepository)
 
 	testTable := []struct {
 		name               string
-		id                 primitive.ObjectID
+		user               domain.User
 		mockRepoBehavior   mockRepoBehavior
 		assertServiceTests func() []func(t *testing.T, err error, i ...interface{})
 	}{
 		{
 			name: "OK",
-			id:   primitive.NewObjectID(),
+			user: domain.User{Id: primitive.NewObjectID()},
 			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
-				dbmock.EXPECT().SetSession(context.Background(), gomock.Any(), gomock.Any()).Return(nil)
+				dbmock.EXPECT().CreateSession(context.Background(), gomock.Any()).Return(nil)
 			},
 			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
 				return []func(t *testing.T, err error, i ...interface{}){
@@ -852,9 +1155,9 @@ func TestUserRepository_CreateSession(t *testing.T) {
 		},
 		{
 			name: "Repository Failure",
-			id:   primitive.NewObjectID(),
+			user: domain.User{Id: primitive.NewObjectID()},
 			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
-				dbmock.EXPECT().SetSession(context.Background(), gomock.Any(), gomock.Any()).Return(fmt.Errorf("repository failure"))
+				dbmock.EXPECT().CreateSession(context.Background(), gomock.Any()).Return(fmt.Errorf("repository failure"))
 
 			},
 			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
@@ -873,7 +1176,7 @@ func TestUserRepository_CreateSession(t *testing.T) {
 
 			testCase.mockRepoBehavior(userRepoMock)
 
-			actualToken, err := userService.CreateSession(context.Background(), testCase.id)
+			actualToken, err := userService.CreateSession(context.Background(), testCase.user, dto.DeviceDTO{})
 
 			for _, assert := range testCase.assertServiceTests() {
 				assert(t, err, actualToken)
@@ -881,3 +1184,348 @@ func TestUserRepository_CreateSession(t *testing.T) {
 		})
 	}
 }
+
+func TestUserRepository_RevokeSession(t *testing.T) {
+	userService, userRepoMock := mockUserService(t)
+	type mockRepoBehavior func(dbmock *db_mocks.MockUserRepository)
+
+	testTable := []struct {
+		name               string
+		id                 string
+		sessionId          string
+		mockRepoBehavior   mockRepoBehavior
+		assertServiceTests func() []func(t *testing.T, err error, i ...interface{})
+	}{
+		{
+			name:      "OK",
+			id:        primitive.NewObjectID().Hex(),
+			sessionId: primitive.NewObjectID().Hex(),
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().FindSession(context.Background(), gomock.Any(), gomock.Any()).Return(domain.Session{}, nil)
+				dbmock.EXPECT().DeleteSession(context.Background(), gomock.Any(), gomock.Any()).Return(nil)
+			},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.Nil(t, err)
+					},
+				}
+			},
+		},
+		{
+			name:             "Session Id Invalid",
+			id:               primitive.NewObjectID().Hex(),
+			sessionId:        "0000000000000",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.Error(t, err, "invalid id param")
+					},
+				}
+			},
+		},
+		{
+			name:      "Session not found",
+			id:        primitive.NewObjectID().Hex(),
+			sessionId: primitive.NewObjectID().Hex(),
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().FindSession(context.Background(), gomock.Any(), gomock.Any()).Return(domain.Session{}, domain.ErrSessionNotFound)
+			},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.ErrorIs(t, err, domain.ErrSessionNotFound)
+					},
+				}
+			},
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			testCase.mockRepoBehavior(userRepoMock)
+
+			err := userService.RevokeSession(context.Background(), testCase.id, testCase.sessionId)
+
+			for _, assert := range testCase.assertServiceTests() {
+				assert(t, err)
+			}
+		})
+	}
+}
+
+func TestUserRepository_Logout(t *testing.T) {
+	userService, userRepoMock := mockUserService(t)
+	type mockRepoBehavior func(dbmock *db_mocks.MockUserRepository)
+
+	session := domain.Session{
+		Id:            primitive.NewObjectID(),
+		UserId:        primitive.NewObjectID(),
+		AccessTokenId: "token id",
+	}
+	claims := auth.Claims{SessionId: session.Id.Hex()}
+	claims.Id = session.AccessTokenId
+	claims.Subject = session.UserId.Hex()
+	claims.ExpiresAt = time.Now().Add(time.Minute).Unix()
+
+	testTable := []struct {
+		name               string
+		claims             auth.Claims
+		mockRepoBehavior   mockRepoBehavior
+		assertServiceTests func() []func(t *testing.T, err error, i ...interface{})
+	}{
+		{
+			name:   "OK",
+			claims: claims,
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().FindSession(context.Background(), session.UserId, session.Id).Return(session, nil)
+				dbmock.EXPECT().DeleteSession(context.Background(), session.UserId, session.Id).Return(nil)
+			},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.Nil(t, err)
+					},
+				}
+			},
+		},
+		{
+			name:   "Session already revoked",
+			claims: claims,
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().FindSession(context.Background(), session.UserId, session.Id).Return(domain.Session{}, domain.ErrSessionNotFound)
+			},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.ErrorIs(t, err, domain.ErrSessionNotFound)
+					},
+				}
+			},
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			testCase.mockRepoBehavior(userRepoMock)
+
+			err := userService.Logout(context.Background(), testCase.claims)
+
+			for _, assert := range testCase.assertServiceTests() {
+				assert(t, err)
+			}
+		})
+	}
+}
+
+func TestUserRepository_LogoutAll(t *testing.T) {
+	userRepoMock := db_mocks.NewMockUserRepository(gomock.NewController(t))
+	denylist := auth.NewMemoryDenylist()
+	tokenManager := newTokenManager(t, denylist)
+	userService := NewUserService(userRepoMock, tokenManager, &hash.SHA1Hasher{}, time.Minute, time.Minute, nil, nil)
+
+	userId := primitive.NewObjectID()
+	sessions := []domain.Session{
+		{Id: primitive.NewObjectID(), UserId: userId, AccessTokenId: "first", AccessTokenExpiresAt: time.Now().Add(time.Minute)},
+		{Id: primitive.NewObjectID(), UserId: userId, AccessTokenId: "second", AccessTokenExpiresAt: time.Now().Add(time.Minute)},
+	}
+	claims := auth.Claims{SessionId: sessions[0].Id.Hex()}
+	claims.Id = "current"
+	claims.Subject = userId.Hex()
+	claims.ExpiresAt = time.Now().Add(time.Minute).Unix()
+
+	userRepoMock.EXPECT().FindSessions(context.Background(), userId).Return(sessions, nil)
+	userRepoMock.EXPECT().DeleteSessions(context.Background(), userId).Return(nil)
+
+	err := userService.LogoutAll(context.Background(), claims)
+	assert.Nil(t, err)
+
+	for _, tokenId := range []string{"current", "first", "second"} {
+		revoked, _ := denylist.Contains(context.Background(), tokenId)
+		assert.True(t, revoked, tokenId)
+	}
+}
+
+func TestUserRepository_GrantRole(t *testing.T) {
+	userService, userRepoMock := mockUserService(t)
+	type mockRepoBehavior func(dbmock *db_mocks.MockUserRepository, oid primitive.ObjectID)
+
+	oid := primitive.NewObjectID()
+
+	testTable := []struct {
+		name               string
+		id                 string
+		role               string
+		mockRepoBehavior   mockRepoBehavior
+		assertServiceTests func() []func(t *testing.T, err error, i ...interface{})
+	}{
+		{
+			name: "OK",
+			id:   oid.Hex(),
+			role: auth.AdminRole,
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, oid primitive.ObjectID) {
+				dbmock.EXPECT().AddRole(context.Background(), oid, auth.AdminRole).Return(nil)
+			},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.Nil(t, err)
+					},
+				}
+			},
+		},
+		{
+			name:             "Unknown role",
+			id:               oid.Hex(),
+			role:             "superuser",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, oid primitive.ObjectID) {},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.ErrorIs(t, err, domain.ErrUnknownRole)
+					},
+				}
+			},
+		},
+		{
+			name: "User not found",
+			id:   oid.Hex(),
+			role: auth.AdminRole,
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository, oid primitive.ObjectID) {
+				dbmock.EXPECT().AddRole(context.Background(), oid, auth.AdminRole).Return(domain.ErrUserNotFound)
+			},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.ErrorIs(t, err, domain.ErrUserNotFound)
+					},
+				}
+			},
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			testCase.mockRepoBehavior(userRepoMock, oid)
+
+			err := userService.GrantRole(context.Background(), testCase.id, testCase.role)
+
+			for _, assert := range testCase.assertServiceTests() {
+				assert(t, err)
+			}
+		})
+	}
+}
+
+func TestUserRepository_RevokeRole(t *testing.T) {
+	userService, userRepoMock := mockUserService(t)
+	oid := primitive.NewObjectID()
+
+	userRepoMock.EXPECT().RemoveRole(context.Background(), oid, auth.AdminRole).Return(nil)
+	assert.Nil(t, userService.RevokeRole(context.Background(), oid.Hex(), auth.AdminRole))
+
+	assert.ErrorIs(t, userService.RevokeRole(context.Background(), oid.Hex(), "superuser"), domain.ErrUnknownRole)
+}
+
+func TestUserRepository_BootstrapAdmin(t *testing.T) {
+	userService, userRepoMock := mockUserService(t)
+	type mockRepoBehavior func(dbmock *db_mocks.MockUserRepository)
+
+	oid := primitive.NewObjectID()
+
+	testTable := []struct {
+		name               string
+		password           string
+		mockRepoBehavior   mockRepoBehavior
+		assertServiceTests func() []func(t *testing.T, err error, i ...interface{})
+	}{
+		{
+			name:     "Create admin",
+			password: "admin1234",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().FindByEmail(context.Background(), "admin@test.ru").Return(domain.User{}, domain.ErrUserNotFound)
+				dbmock.EXPECT().Create(context.Background(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, user domain.User) (primitive.ObjectID, error) {
+						assert.Equal(t, "admin@test.ru", user.Email)
+						assert.True(t, user.HasRole(auth.AdminRole))
+						assert.NotEqual(t, "admin1234", user.PasswordHash)
+						return oid, nil
+					})
+			},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.Nil(t, err)
+					},
+				}
+			},
+		},
+		{
+			name: "Promote existing user",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().FindByEmail(context.Background(), "admin@test.ru").Return(domain.User{
+					Id:    oid,
+					Roles: []string{auth.UserRole},
+				}, nil)
+				dbmock.EXPECT().AddRole(context.Background(), oid, auth.AdminRole).Return(nil)
+			},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.Nil(t, err)
+					},
+				}
+			},
+		},
+		{
+			name: "Already admin",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().FindByEmail(context.Background(), "admin@test.ru").Return(domain.User{
+					Id:    oid,
+					Roles: []string{auth.UserRole, auth.AdminRole},
+				}, nil)
+			},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.Nil(t, err)
+					},
+				}
+			},
+		},
+		{
+			name: "No password for new admin",
+			mockRepoBehavior: func(dbmock *db_mocks.MockUserRepository) {
+				dbmock.EXPECT().FindByEmail(context.Background(), "admin@test.ru").Return(domain.User{}, domain.ErrUserNotFound)
+			},
+			assertServiceTests: func() []func(t *testing.T, err error, i ...interface{}) {
+				return []func(t *testing.T, err error, i ...interface{}){
+					func(t *testing.T, err error, i ...interface{}) {
+						assert.Error(t, err, "Invalid admin email or password")
+					},
+				}
+			},
+		},
+	}
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			testCase.mockRepoBehavior(userRepoMock)
+
+			err := userService.BootstrapAdmin(context.Background(), "admin@test.ru", testCase.password)
+
+			for _, assert := range testCase.assertServiceTests() {
+				assert(t, err)
+			}
+		})
+	}
+}
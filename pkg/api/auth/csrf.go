@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cookies tokens are kept in by browsers when the API sends tokens in cookies.
+// CSRFCookie is readable by scripts, they send its value back in CSRFHeader.
+const (
+	AccessTokenCookie  = "access_token"
+	RefreshTokenCookie = "refresh_token"
+	CSRFCookie         = "csrf_token"
+	CSRFHeader         = "X-CSRF-Token"
+)
+
+// ValidCSRF tells whether the request is safe or carries the value of CSRFCookie in
+// CSRFHeader. Other sites can make the browser send the cookies, but can't read them,
+// so they can't set the header (double submit).
+func ValidCSRF(ctx *gin.Context) bool {
+	switch ctx.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	cookie, err := ctx.Cookie(CSRFCookie)
+	if err != nil || cookie == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie), []byte(ctx.GetHeader(CSRFHeader))) == 1
+}
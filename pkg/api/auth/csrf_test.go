@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidCSRF(t *testing.T) {
+	testTable := []struct {
+		name     string
+		method   string
+		cookie   string
+		header   string
+		expected bool
+	}{
+		{name: "Safe method", method: "GET", expected: true},
+		{name: "Matching token", method: "POST", cookie: "csrf", header: "csrf", expected: true},
+		{name: "No header", method: "POST", cookie: "csrf", expected: false},
+		{name: "Other token", method: "DELETE", cookie: "csrf", header: "other", expected: false},
+		{name: "No cookie", method: "PUT", header: "csrf", expected: false},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			gin.SetMode(gin.ReleaseMode)
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(testCase.method, "/", nil)
+			if testCase.cookie != "" {
+				ctx.Request.AddCookie(&http.Cookie{Name: CSRFCookie, Value: testCase.cookie})
+			}
+			if testCase.header != "" {
+				ctx.Request.Header.Set(CSRFHeader, testCase.header)
+			}
+
+			assert.Equal(t, testCase.expected, ValidCSRF(ctx))
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const denylistSweepInterval = time.Minute
+
+// Denylist keeps ids (jti) of revoked access tokens until the tokens expire by themselves.
+type Denylist interface {
+	Add(ctx context.Context, tokenId string, expiresAt time.Time) error
+	Contains(ctx context.Context, tokenId string) (bool, error)
+}
+
+var _ Denylist = &MemoryDenylist{}
+
+// MemoryDenylist is a Denylist for a single instance of the app.
+type MemoryDenylist struct {
+	mu        sync.RWMutex
+	tokens    map[string]time.Time
+	lastSweep time.Time
+}
+
+func NewMemoryDenylist() *MemoryDenylist {
+	return &MemoryDenylist{
+		tokens:    make(map[string]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+func (d *MemoryDenylist) Add(ctx context.Context, tokenId string, expiresAt time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(d.lastSweep) > denylistSweepInterval {
+		for id, exp := range d.tokens {
+			if now.After(exp) {
+				delete(d.tokens, id)
+			}
+		}
+		d.lastSweep = now
+	}
+	d.tokens[tokenId] = expiresAt
+	return nil
+}
+
+func (d *MemoryDenylist) Contains(ctx context.Context, tokenId string) (bool, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	expiresAt, ok := d.tokens[tokenId]
+	return ok && time.Now().Before(expiresAt), nil
+}
@@ -0,0 +1,281 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	keyUseSignature = "sig"
+	kidHeader       = "kid"
+)
+
+// Key is a key tokens are signed or verified with. Keys loaded only from a public
+// key can verify tokens but can't sign them, this is how retired keys are kept
+// during rotation.
+type Key struct {
+	Id        string
+	Method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// NewHMACKey returns a symmetric HS256 key. HMAC keys are never published in JWKS.
+func NewHMACKey(id, secret string) (*Key, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("empty secret key")
+	}
+	return &Key{
+		Id:        id,
+		Method:    jwt.SigningMethodHS256,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}, nil
+}
+
+// LoadKey reads an asymmetric key for the algorithm (RS256, RS384, RS512, ES256, ES384,
+// ES512 or EdDSA) from PEM files. The private key may be empty for verification only keys,
+// the public key may be empty when the private key is set.
+func LoadKey(id, algorithm, privateKeyFile, publicKeyFile string) (*Key, error) {
+	method := jwt.GetSigningMethod(algorithm)
+	if method == nil {
+		return nil, fmt.Errorf("unknown signing algorithm %q of key %q", algorithm, id)
+	}
+	if privateKeyFile == "" && publicKeyFile == "" {
+		return nil, fmt.Errorf("key %q has neither private nor public key file", id)
+	}
+
+	key := &Key{Id: id, Method: method}
+	if privateKeyFile != "" {
+		pem, err := os.ReadFile(privateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read private key of key %q due to error: %v", id, err)
+		}
+		if key.signKey, err = parsePrivateKey(method, pem); err != nil {
+			return nil, fmt.Errorf("failed to parse private key of key %q due to error: %v", id, err)
+		}
+		key.verifyKey = key.signKey.(crypto.Signer).Public()
+	}
+	if publicKeyFile != "" {
+		pem, err := os.ReadFile(publicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read public key of key %q due to error: %v", id, err)
+		}
+		if key.verifyKey, err = parsePublicKey(method, pem); err != nil {
+			return nil, fmt.Errorf("failed to parse public key of key %q due to error: %v", id, err)
+		}
+	}
+	return key, nil
+}
+
+func parsePrivateKey(method jwt.SigningMethod, pem []byte) (interface{}, error) {
+	switch method.(type) {
+	case *jwt.SigningMethodRSA:
+		return jwt.ParseRSAPrivateKeyFromPEM(pem)
+	case *jwt.SigningMethodECDSA:
+		return jwt.ParseECPrivateKeyFromPEM(pem)
+	case *jwt.SigningMethodEd25519:
+		return jwt.ParseEdPrivateKeyFromPEM(pem)
+	}
+	return nil, fmt.Errorf("algorithm %s doesn't use PEM keys", method.Alg())
+}
+
+func parsePublicKey(method jwt.SigningMethod, pem []byte) (interface{}, error) {
+	switch method.(type) {
+	case *jwt.SigningMethodRSA:
+		return jwt.ParseRSAPublicKeyFromPEM(pem)
+	case *jwt.SigningMethodECDSA:
+		return jwt.ParseECPublicKeyFromPEM(pem)
+	case *jwt.SigningMethodEd25519:
+		return jwt.ParseEdPublicKeyFromPEM(pem)
+	}
+	return nil, fmt.Errorf("algorithm %s doesn't use PEM keys", method.Alg())
+}
+
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+// JWK describes the public part of an asymmetric key, see RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK returns the public key in JWK format, false for symmetric keys.
+func (k *Key) JWK() (JWK, bool) {
+	jwk := JWK{Kid: k.Id, Use: keyUseSignature, Alg: k.Method.Alg()}
+
+	switch key := k.verifyKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeSegment(key.N.Bytes())
+		jwk.E = encodeSegment(big.NewInt(int64(key.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = key.Curve.Params().Name
+		jwk.X = encodeSegment(key.X.FillBytes(make([]byte, size)))
+		jwk.Y = encodeSegment(key.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encodeSegment(key)
+	default:
+		return JWK{}, false
+	}
+	return jwk, true
+}
+
+// PublicKey decodes the key published by another issuer, the result can be used
+// to verify its tokens.
+func (j JWK) PublicKey() (interface{}, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := decodeSegment(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeSegment(j.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q of key %q", j.Crv, j.Kid)
+		}
+		x, err := decodeSegment(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeSegment(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		x, err := decodeSegment(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if j.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("unsupported curve %q of key %q", j.Crv, j.Kid)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q of key %q", j.Kty, j.Kid)
+}
+
+func encodeSegment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeSegment(s string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode key due to error: %v", err)
+	}
+	return b, nil
+}
+
+// KeySet holds every key accepted for verification and the one new tokens are signed with.
+// Tokens without kid header are verified with the key with empty id, which is how tokens
+// issued before key ids were introduced keep working.
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+	order   []string
+}
+
+func NewKeySet(signingKeyId string, keys ...*Key) (*KeySet, error) {
+	set := &KeySet{keys: make(map[string]*Key)}
+	for _, key := range keys {
+		if _, ok := set.keys[key.Id]; ok {
+			return nil, fmt.Errorf("duplicated key id %q", key.Id)
+		}
+		set.keys[key.Id] = key
+		set.order = append(set.order, key.Id)
+	}
+
+	signing, ok := set.keys[signingKeyId]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key id %q", signingKeyId)
+	}
+	if !signing.CanSign() {
+		return nil, fmt.Errorf("signing key %q has no private key", signingKeyId)
+	}
+	set.signing = signing
+	return set, nil
+}
+
+// NewHMACKeySet returns a key set with the only HS256 key without id.
+func NewHMACKeySet(secret string) (*KeySet, error) {
+	key, err := NewHMACKey("", secret)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeySet("", key)
+}
+
+// Sign signs the token with the signing key and sets its kid header.
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.signing.Method, claims)
+	if s.signing.Id != "" {
+		token.Header[kidHeader] = s.signing.Id
+	}
+	return token.SignedString(s.signing.signKey)
+}
+
+// Keyfunc picks the verification key by the kid header and refuses tokens signed
+// with an algorithm other than the one of the key.
+func (s *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header[kidHeader].(string)
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.verifyKey, nil
+}
+
+// JWKS returns public keys of the set, symmetric keys are skipped.
+func (s *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, id := range s.order {
+		if jwk, ok := s.keys[id].JWK(); ok {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+	return jwks
+}
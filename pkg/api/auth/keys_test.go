@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeKeyFiles(t *testing.T, id string, private interface{}, public interface{}) (string, string) {
+	t.Helper()
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	privateFile := filepath.Join(dir, id+".pem")
+	publicFile := filepath.Join(dir, id+".pub.pem")
+	require.NoError(t, os.WriteFile(privateFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600))
+	require.NoError(t, os.WriteFile(publicFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0600))
+	return privateFile, publicFile
+}
+
+func generateKeyFiles(t *testing.T, id, algorithm string) (string, string) {
+	t.Helper()
+
+	switch algorithm {
+	case "RS256":
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		return writeKeyFiles(t, id, key, &key.PublicKey)
+	case "ES256":
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		return writeKeyFiles(t, id, key, &key.PublicKey)
+	case "EdDSA":
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		return writeKeyFiles(t, id, private, public)
+	}
+	t.Fatalf("unsupported algorithm %s", algorithm)
+	return "", ""
+}
+
+func TestManager_SignAndParse(t *testing.T) {
+	testTable := []struct {
+		name        string
+		algorithm   string
+		expectedKty string
+	}{
+		{name: "RS256", algorithm: "RS256", expectedKty: "RSA"},
+		{name: "ES256", algorithm: "ES256", expectedKty: "EC"},
+		{name: "EdDSA", algorithm: "EdDSA", expectedKty: "OKP"},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			privateFile, _ := generateKeyFiles(t, "key-1", testCase.algorithm)
+			key, err := LoadKey("key-1", testCase.algorithm, privateFile, "")
+			require.NoError(t, err)
+			legacy, err := NewHMACKey("", "secret")
+			require.NoError(t, err)
+
+			keys, err := NewKeySet("key-1", key, legacy)
+			require.NoError(t, err)
+			manager, err := NewManager(keys, nil, nil, ManagerConfig{})
+			require.NoError(t, err)
+
+			token, err := manager.GenerateAccessToken(Claims{Roles: []string{UserRole}}, time.Minute)
+			require.NoError(t, err)
+
+			var claims Claims
+			parsed, err := manager.GetTokenFromString(strings.TrimPrefix(token, PrefixToken), &claims)
+			require.NoError(t, err)
+			assert.True(t, parsed.Valid)
+			assert.Equal(t, "key-1", parsed.Header[kidHeader])
+			assert.Equal(t, testCase.algorithm, parsed.Method.Alg())
+			assert.Equal(t, []string{UserRole}, claims.Roles)
+
+			jwks := manager.JWKS()
+			require.Len(t, jwks.Keys, 1)
+			assert.Equal(t, "key-1", jwks.Keys[0].Kid)
+			assert.Equal(t, testCase.expectedKty, jwks.Keys[0].Kty)
+			assert.Equal(t, testCase.algorithm, jwks.Keys[0].Alg)
+		})
+	}
+}
+
+func TestManager_KeyRotation(t *testing.T) {
+	oldPrivateFile, oldPublicFile := generateKeyFiles(t, "old", "RS256")
+	newPrivateFile, _ := generateKeyFiles(t, "new", "ES256")
+
+	oldKey, err := LoadKey("old", "RS256", oldPrivateFile, "")
+	require.NoError(t, err)
+	legacyKey, err := NewHMACKey("", "secret")
+	require.NoError(t, err)
+
+	oldKeys, err := NewKeySet("old", oldKey)
+	require.NoError(t, err)
+	oldManager, err := NewManager(oldKeys, nil, nil, ManagerConfig{})
+	require.NoError(t, err)
+	legacyKeys, err := NewKeySet("", legacyKey)
+	require.NoError(t, err)
+	legacyManager, err := NewManager(legacyKeys, nil, nil, ManagerConfig{})
+	require.NoError(t, err)
+
+	oldToken, err := oldManager.GenerateAccessToken(Claims{Roles: []string{UserRole}}, time.Minute)
+	require.NoError(t, err)
+	legacyToken, err := legacyManager.GenerateAccessToken(Claims{Roles: []string{UserRole}}, time.Minute)
+	require.NoError(t, err)
+
+	retiredKey, err := LoadKey("old", "RS256", "", oldPublicFile)
+	require.NoError(t, err)
+	newKey, err := LoadKey("new", "ES256", newPrivateFile, "")
+	require.NoError(t, err)
+
+	keys, err := NewKeySet("new", newKey, retiredKey, legacyKey)
+	require.NoError(t, err)
+	manager, err := NewManager(keys, nil, nil, ManagerConfig{})
+	require.NoError(t, err)
+
+	for _, token := range []string{oldToken, legacyToken} {
+		parsed, err := manager.GetTokenFromString(strings.TrimPrefix(token, PrefixToken), &Claims{})
+		require.NoError(t, err)
+		assert.True(t, parsed.Valid)
+	}
+
+	_, err = NewKeySet("old", retiredKey)
+	assert.Error(t, err, "key without private key can't sign")
+
+	jwks := manager.JWKS()
+	require.Len(t, jwks.Keys, 2)
+	assert.Equal(t, "new", jwks.Keys[0].Kid)
+	assert.Equal(t, "old", jwks.Keys[1].Kid)
+}
+
+func TestKeySet_Keyfunc(t *testing.T) {
+	privateFile, _ := generateKeyFiles(t, "rsa", "RS256")
+	rsaKey, err := LoadKey("rsa", "RS256", privateFile, "")
+	require.NoError(t, err)
+
+	rsaKeys, err := NewKeySet("rsa", rsaKey)
+	require.NoError(t, err)
+	rsaManager, err := NewManager(rsaKeys, nil, nil, ManagerConfig{})
+	require.NoError(t, err)
+
+	otherKey, err := NewHMACKey("", "other secret")
+	require.NoError(t, err)
+	otherKeys, err := NewKeySet("", otherKey)
+	require.NoError(t, err)
+	otherManager, err := NewManager(otherKeys, nil, nil, ManagerConfig{})
+	require.NoError(t, err)
+
+	// HS256 token pretending to be signed with the RSA key.
+	hmacKey := &Key{Id: "rsa", Method: otherKey.Method, signKey: []byte("forged"), verifyKey: []byte("forged")}
+	forgedKeys, err := NewKeySet("rsa", hmacKey)
+	require.NoError(t, err)
+	forgedManager, err := NewManager(forgedKeys, nil, nil, ManagerConfig{})
+	require.NoError(t, err)
+
+	testTable := []struct {
+		name    string
+		manager *Manager
+	}{
+		{name: "Algorithm mismatch", manager: forgedManager},
+		{name: "Unknown kid", manager: otherManager},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			token, err := testCase.manager.GenerateAccessToken(Claims{Roles: []string{UserRole}}, time.Minute)
+			require.NoError(t, err)
+
+			_, err = rsaManager.GetTokenFromString(strings.TrimPrefix(token, PrefixToken), &Claims{})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestJWK_PublicKey(t *testing.T) {
+	for _, algorithm := range []string{"RS256", "ES256", "EdDSA"} {
+		t.Run(algorithm, func(t *testing.T) {
+			privateFile, _ := generateKeyFiles(t, "key", algorithm)
+			key, err := LoadKey("key", algorithm, privateFile, "")
+			require.NoError(t, err)
+
+			jwk, ok := key.JWK()
+			require.True(t, ok)
+			publicKey, err := jwk.PublicKey()
+			require.NoError(t, err)
+			assert.Equal(t, key.verifyKey, publicKey)
+		})
+	}
+
+	_, err := JWK{Kty: "oct"}.PublicKey()
+	assert.Error(t, err)
+	_, err = JWK{Kty: "EC", Crv: "P-192"}.PublicKey()
+	assert.Error(t, err)
+}
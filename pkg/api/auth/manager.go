@@ -1,8 +1,11 @@
 package auth
 
 import (
+	"context"
+	crand "crypto/rand"
+	"errors"
 	"fmt"
-	"math/rand"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -16,42 +19,170 @@ const (
 	PrefixToken         = "Bearer "
 )
 
+// Refresh token formats. Opaque tokens are random strings, JWT ones are signed purpose
+// tokens with the session id. Either way the session is looked up by the token hash.
+const (
+	RefreshTokenOpaque = "opaque"
+	RefreshTokenJWT    = "jwt"
+	// RefreshTokenPurpose is the purpose of refresh tokens of the JWT format.
+	RefreshTokenPurpose = "refresh"
+)
+
+var errInvalidToken = errors.New("invalid access token")
+
 type Claims struct {
-	Role string `json:"role"`
+	Roles     []string `json:"roles"`
+	SessionId string   `json:"sid,omitempty"`
+	// EmailVerified tells whether the email of the user was verified when the token was issued.
+	EmailVerified bool `json:"email_verified,omitempty"`
+	// Purpose is set on purpose tokens only, see GeneratePurposeToken.
+	Purpose string `json:"purpose,omitempty"`
+	// Email is the address a purpose token was issued for.
+	Email string `json:"email,omitempty"`
+	// Scopes limit the permissions of the roles to these "resource:action" ones, see AllowsScope.
+	Scopes []string `json:"scopes,omitempty"`
+	// APIKeyId is set when the caller authenticated with an API key instead of a token.
+	APIKeyId string `json:"api_key_id,omitempty"`
+	// ClientId is set on tokens of services instead of the subject, see IsService.
+	ClientId string `json:"client_id,omitempty"`
+	// Actor is the admin acting as the subject, set on impersonation tokens only.
+	Actor *Actor `json:"act,omitempty"`
 	jwt.StandardClaims
 }
 
+// Actor is the act claim of RFC 8693, the one who really makes requests with the token.
+type Actor struct {
+	Subject string `json:"sub"`
+}
+
+// Roles are the roles that can be granted to users.
+var Roles = []string{UserRole, AdminRole}
+
+func ValidRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Claims) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if strings.EqualFold(r, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsService tells whether the token was issued to an OAuth client acting on its own
+// behalf. Services have no roles, they are allowed what their scopes name.
+func (c *Claims) IsService() bool {
+	return c.ClientId != "" && c.Subject == ""
+}
+
+// IsImpersonated tells whether an admin acts as the subject with the token.
+func (c *Claims) IsImpersonated() bool {
+	return c.Actor != nil
+}
+
+// AllowsScope reports whether the scopes cover the "resource:action" permission, "*"
+// matches any resource or action. Claims without scopes aren't limited.
+func (c *Claims) AllowsScope(resourceAction string) bool {
+	if len(c.Scopes) == 0 {
+		return true
+	}
+	resource, action, ok := strings.Cut(resourceAction, ":")
+	if !ok {
+		return false
+	}
+	for _, scope := range c.Scopes {
+		scopeResource, scopeAction, ok := strings.Cut(scope, ":")
+		if ok && (scopeResource == wildcard || scopeResource == resource) &&
+			(scopeAction == wildcard || scopeAction == action) {
+			return true
+		}
+	}
+	return false
+}
+
 type TokenManager interface {
-	GenerateAccessToken(id string, ttl time.Duration) (string, error)
+	GenerateAccessToken(claims Claims, ttl time.Duration) (string, error)
+	RevokeToken(ctx context.Context, tokenId string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, tokenId string) (bool, error)
 	VerifyJWTMiddleware(roles ...string) gin.HandlerFunc
+	RequirePermission(resourceAction string) gin.HandlerFunc
 	Parse(token string, claims *Claims) (string, error)
-	GenerateRefreshToken() (string, error)
+	GenerateRefreshToken(sessionId string, ttl time.Duration) (string, error)
 	GetTokenFromString(token string, claims *Claims) (*jwt.Token, error)
 	ValidateToken(token *jwt.Token, claims *Claims) error
+	GeneratePurposeToken(purpose string, claims Claims, ttl time.Duration) (string, error)
+	ParsePurposeToken(token, purpose string) (*Claims, error)
+	JWKS() JWKS
+}
+
+// ManagerConfig tells whom tokens are issued by and for. Empty Issuer and Audiences
+// aren't checked, so tokens of any service sharing the keys are accepted.
+type ManagerConfig struct {
+	// Issuer is the iss claim of issued tokens, tokens of other issuers are refused.
+	Issuer string
+	// Audiences are accepted in the aud claim, tokens are issued for the first one.
+	Audiences []string
+	// Leeway allows clocks of services to differ when exp, nbf and iat are checked.
+	Leeway time.Duration
+	// RefreshTokenFormat is RefreshTokenOpaque or RefreshTokenJWT, empty is opaque.
+	RefreshTokenFormat string
+	// Cookies reads the access token from AccessTokenCookie of requests without the
+	// Authorization header, it is on when tokens are sent to browsers in cookies.
+	Cookies bool
 }
 
 type Manager struct {
-	secretKey string
+	keys     *KeySet
+	denylist Denylist
+	policy   *Policy
+	config   ManagerConfig
 }
 
-func NewManager(secretKey string) (*Manager, error) {
-	if secretKey == "" {
-		return nil, fmt.Errorf("empty secret key")
+// NewManager returns the token manager, DefaultPolicy is used when policy is nil.
+func NewManager(keys *KeySet, denylist Denylist, policy *Policy, config ManagerConfig) (*Manager, error) {
+	if keys == nil {
+		return nil, fmt.Errorf("empty key set")
+	}
+	if config.Leeway < 0 {
+		return nil, fmt.Errorf("negative leeway")
+	}
+	switch config.RefreshTokenFormat {
+	case "":
+		config.RefreshTokenFormat = RefreshTokenOpaque
+	case RefreshTokenOpaque, RefreshTokenJWT:
+	default:
+		return nil, fmt.Errorf("unknown refresh token format %q", config.RefreshTokenFormat)
 	}
-	return &Manager{secretKey: secretKey}, nil
+	if policy == nil {
+		policy = DefaultPolicy()
+	}
+	return &Manager{keys: keys, denylist: denylist, policy: policy, config: config}, nil
 }
 
-func (m *Manager) GenerateAccessToken(id string, ttl time.Duration) (string, error) {
-	claims := &Claims{
-		Role: UserRole,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(ttl).Unix(),
-			Subject:   id,
-		},
+// GenerateAccessToken signs the claims for ttl. Token id (jti) is generated
+// when the caller hasn't set one, it is what RevokeToken expects later.
+func (m *Manager) GenerateAccessToken(claims Claims, ttl time.Duration) (string, error) {
+	if claims.Id == "" {
+		tokenId, err := GenerateTokenId()
+		if err != nil {
+			return "", err
+		}
+		claims.Id = tokenId
 	}
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	claims.Audience = ""
+	if len(m.config.Audiences) > 0 {
+		claims.Audience = m.config.Audiences[0]
+	}
+	m.setRegisteredClaims(&claims, ttl)
 
-	token, err := accessToken.SignedString([]byte(m.secretKey))
+	token, err := m.keys.Sign(&claims)
 	if err != nil {
 		return "", fmt.Errorf("can't signed jwt")
 	}
@@ -59,40 +190,172 @@ func (m *Manager) GenerateAccessToken(id string, ttl time.Duration) (string, err
 	return PrefixToken + token, nil
 }
 
+// Parse verifies the signature and expiry of the access token and fills the claims.
+// Revocation isn't checked, see IsRevoked.
 func (m *Manager) Parse(token string, claims *Claims) (string, error) {
-	jwt, _ := m.GetTokenFromString(token, claims)
+	jwt, err := m.GetTokenFromString(token, claims)
+	if err != nil {
+		return "", err
+	}
 
 	if err := m.ValidateToken(jwt, claims); err != nil {
 		return "", err
 	}
-	return jwt.SignedString([]byte(m.secretKey))
+	return jwt.Raw, nil
 }
 
-func (m *Manager) GenerateRefreshToken() (string, error) {
-	b := make([]byte, 32)
+// GenerateRefreshToken returns a new refresh token of the session in the configured format.
+func (m *Manager) GenerateRefreshToken(sessionId string, ttl time.Duration) (string, error) {
+	if m.config.RefreshTokenFormat != RefreshTokenJWT {
+		b := make([]byte, 32)
+		if _, err := crand.Read(b); err != nil {
+			return "", fmt.Errorf("failed to generate refresh token")
+		}
+		return fmt.Sprintf("%x", b), nil
+	}
+
+	tokenId, err := GenerateTokenId()
+	if err != nil {
+		return "", err
+	}
+	claims := Claims{SessionId: sessionId, Purpose: RefreshTokenPurpose}
+	claims.Id = tokenId
+	m.setRegisteredClaims(&claims, ttl)
+
+	token, err := m.keys.Sign(&claims)
+	if err != nil {
+		return "", fmt.Errorf("can't signed jwt")
+	}
+	return token, nil
+}
 
-	s := rand.NewSource(time.Now().Unix())
-	r := rand.New(s)
+// RevokeToken denylists the access token until it expires.
+func (m *Manager) RevokeToken(ctx context.Context, tokenId string, expiresAt time.Time) error {
+	if m.denylist == nil || tokenId == "" || time.Now().After(expiresAt) {
+		return nil
+	}
+	return m.denylist.Add(ctx, tokenId, expiresAt)
+}
 
-	if _, err := r.Read(b); err != nil {
-		return "", fmt.Errorf("failed to generate resfresh token")
+// IsRevoked tells whether the access token with the id was revoked before it expired.
+func (m *Manager) IsRevoked(ctx context.Context, tokenId string) (bool, error) {
+	if m.denylist == nil {
+		return false, nil
 	}
+	return m.denylist.Contains(ctx, tokenId)
+}
 
+// GenerateTokenId returns a random id for the jti claim.
+func GenerateTokenId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := crand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token id")
+	}
 	return fmt.Sprintf("%x", b), nil
 }
 
+// setRegisteredClaims sets the issuer and the times the token is valid.
+func (m *Manager) setRegisteredClaims(claims *Claims, ttl time.Duration) {
+	now := time.Now()
+	claims.Issuer = m.config.Issuer
+	claims.IssuedAt = now.Unix()
+	claims.NotBefore = now.Unix()
+	claims.ExpiresAt = now.Add(ttl).Unix()
+}
+
+// GetTokenFromString verifies the signature and the times of the token with the leeway.
+// Errors are *jwt.ValidationError, as of jwt.ParseWithClaims.
 func (m *Manager) GetTokenFromString(token string, claims *Claims) (*jwt.Token, error) {
-	return jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(m.secretKey), nil
-	})
+	parser := jwt.Parser{SkipClaimsValidation: true}
+	parsed, err := parser.ParseWithClaims(token, claims, m.keys.Keyfunc)
+	if err != nil {
+		return parsed, err
+	}
+	if err := m.verifyTimes(claims); err != nil {
+		parsed.Valid = false
+		return parsed, err
+	}
+	return parsed, nil
+}
+
+// verifyTimes is StandardClaims.Valid that allows the leeway.
+func (m *Manager) verifyTimes(claims *Claims) error {
+	now := time.Now()
+	var flags uint32
+	if !claims.VerifyExpiresAt(now.Add(-m.config.Leeway).Unix(), false) {
+		flags |= jwt.ValidationErrorExpired
+	}
+	if !claims.VerifyIssuedAt(now.Add(m.config.Leeway).Unix(), false) {
+		flags |= jwt.ValidationErrorIssuedAt
+	}
+	if !claims.VerifyNotBefore(now.Add(m.config.Leeway).Unix(), false) {
+		flags |= jwt.ValidationErrorNotValidYet
+	}
+	if flags == 0 {
+		return nil
+	}
+	return jwt.NewValidationError("token is not valid at this time", flags)
 }
 
+// ValidateToken checks the claims of the access token parsed by GetTokenFromString.
 func (m *Manager) ValidateToken(token *jwt.Token, claims *Claims) error {
-	if _, ok := token.Claims.(jwt.Claims); !ok && !token.Valid {
-		return fmt.Errorf("token is not valid")
+	if !token.Valid || claims.Purpose != "" || !m.verifyIssuer(claims) || !m.verifyAudience(claims) {
+		return errInvalidToken
 	}
 	return nil
 }
+
+func (m *Manager) verifyIssuer(claims *Claims) bool {
+	return m.config.Issuer == "" || claims.VerifyIssuer(m.config.Issuer, true)
+}
+
+// verifyAudience tells whether the access token was issued for one of the accepted
+// audiences. Purpose tokens keep their own audience, it isn't checked.
+func (m *Manager) verifyAudience(claims *Claims) bool {
+	if len(m.config.Audiences) == 0 {
+		return true
+	}
+	for _, audience := range m.config.Audiences {
+		if claims.VerifyAudience(audience, true) {
+			return true
+		}
+	}
+	return false
+}
+
+// GeneratePurposeToken signs a short-lived token for a single action, like linking an
+// external account. Purpose tokens carry no roles and aren't accepted as access tokens.
+func (m *Manager) GeneratePurposeToken(purpose string, claims Claims, ttl time.Duration) (string, error) {
+	if purpose == "" {
+		return "", fmt.Errorf("empty token purpose")
+	}
+	claims.Roles = nil
+	claims.SessionId = ""
+	claims.EmailVerified = false
+	claims.Purpose = purpose
+	m.setRegisteredClaims(&claims, ttl)
+
+	token, err := m.keys.Sign(&claims)
+	if err != nil {
+		return "", fmt.Errorf("can't signed jwt")
+	}
+	return token, nil
+}
+
+// ParsePurposeToken verifies the token and returns its claims if it was issued for the purpose.
+func (m *Manager) ParsePurposeToken(token, purpose string) (*Claims, error) {
+	claims := &Claims{}
+	parsed, err := m.GetTokenFromString(token, claims)
+	if err != nil || !parsed.Valid || !m.verifyIssuer(claims) {
+		return nil, fmt.Errorf("token is not valid")
+	}
+	if purpose == "" || claims.Purpose != purpose {
+		return nil, fmt.Errorf("token is not valid")
+	}
+	return claims, nil
+}
+
+// JWKS returns public keys tokens can be verified with.
+func (m *Manager) JWKS() JWKS {
+	return m.keys.JWKS()
+}
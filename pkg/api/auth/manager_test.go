@@ -0,0 +1,284 @@
+package auth
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_PurposeToken(t *testing.T) {
+	keys, err := NewHMACKeySet("secret")
+	require.NoError(t, err)
+	manager, err := NewManager(keys, nil, nil, ManagerConfig{})
+	require.NoError(t, err)
+
+	token, err := manager.GeneratePurposeToken("link", Claims{
+		Roles:          []string{AdminRole},
+		StandardClaims: jwt.StandardClaims{Subject: "000000000001", Id: "state"},
+	}, time.Minute)
+	require.NoError(t, err)
+
+	claims, err := manager.ParsePurposeToken(token, "link")
+	require.NoError(t, err)
+	assert.Equal(t, "000000000001", claims.Subject)
+	assert.Equal(t, "state", claims.Id)
+	assert.Empty(t, claims.Roles, "purpose tokens carry no roles")
+
+	_, err = manager.ParsePurposeToken(token, "verify_email")
+	assert.Error(t, err, "other purpose")
+
+	_, err = manager.Parse(token, &Claims{})
+	assert.Error(t, err, "purpose token used as access token")
+
+	accessToken, err := manager.GenerateAccessToken(Claims{Roles: []string{UserRole}}, time.Minute)
+	require.NoError(t, err)
+	_, err = manager.ParsePurposeToken(strings.TrimPrefix(accessToken, PrefixToken), "")
+	assert.Error(t, err, "access token used as purpose token")
+
+	expired, err := manager.GeneratePurposeToken("link", Claims{StandardClaims: jwt.StandardClaims{Subject: "000000000001"}}, -time.Minute)
+	require.NoError(t, err)
+	_, err = manager.ParsePurposeToken(expired, "link")
+	assert.Error(t, err, "expired")
+
+	_, err = manager.GeneratePurposeToken("", Claims{}, time.Minute)
+	assert.Error(t, err)
+}
+
+func TestManager_Parse(t *testing.T) {
+	keys, err := NewHMACKeySet("secret")
+	require.NoError(t, err)
+	manager, err := NewManager(keys, nil, nil, ManagerConfig{})
+	require.NoError(t, err)
+
+	token, err := manager.GenerateAccessToken(Claims{Roles: []string{UserRole}}, time.Minute)
+	require.NoError(t, err)
+	var claims Claims
+	_, err = manager.Parse(strings.TrimPrefix(token, PrefixToken), &claims)
+	require.NoError(t, err)
+	assert.Equal(t, []string{UserRole}, claims.Roles)
+	assert.NotZero(t, claims.IssuedAt)
+
+	expired, err := manager.GenerateAccessToken(Claims{}, -time.Minute)
+	require.NoError(t, err)
+	_, err = manager.Parse(strings.TrimPrefix(expired, PrefixToken), &Claims{})
+	assert.Error(t, err, "expired")
+
+	otherKeys, err := NewHMACKeySet("other")
+	require.NoError(t, err)
+	otherManager, err := NewManager(otherKeys, nil, nil, ManagerConfig{})
+	require.NoError(t, err)
+	foreign, err := otherManager.GenerateAccessToken(Claims{}, time.Minute)
+	require.NoError(t, err)
+	_, err = manager.Parse(strings.TrimPrefix(foreign, PrefixToken), &Claims{})
+	assert.Error(t, err, "signed with another key")
+
+	_, err = manager.Parse("malformed", &Claims{})
+	assert.Error(t, err)
+}
+
+func TestManager_RegisteredClaims(t *testing.T) {
+	keys, err := NewHMACKeySet("secret")
+	require.NoError(t, err)
+	manager, err := NewManager(keys, nil, nil, ManagerConfig{Issuer: "users", Audiences: []string{"api", "admin"}})
+	require.NoError(t, err)
+
+	claims := Claims{Roles: []string{UserRole}, StandardClaims: jwt.StandardClaims{Subject: "000000000001"}}
+	token, err := manager.GenerateAccessToken(claims, time.Minute)
+	require.NoError(t, err)
+
+	var parsed Claims
+	_, err = manager.Parse(strings.TrimPrefix(token, PrefixToken), &parsed)
+	require.NoError(t, err)
+	assert.Equal(t, "000000000001", parsed.Subject)
+	assert.Equal(t, "users", parsed.Issuer)
+	assert.Equal(t, "api", parsed.Audience, "tokens are issued for the first audience")
+	assert.NotEmpty(t, parsed.Id)
+	assert.NotZero(t, parsed.IssuedAt)
+	assert.Equal(t, parsed.IssuedAt, parsed.NotBefore)
+	assert.Equal(t, parsed.IssuedAt+60, parsed.ExpiresAt)
+
+	claims.Audience = "google"
+	purpose, err := manager.GeneratePurposeToken("link", claims, time.Minute)
+	require.NoError(t, err)
+	purposeClaims, err := manager.ParsePurposeToken(purpose, "link")
+	require.NoError(t, err)
+	assert.Equal(t, "users", purposeClaims.Issuer)
+	assert.Equal(t, "google", purposeClaims.Audience, "purpose tokens keep their audience")
+}
+
+func TestManager_ParseRegisteredClaims(t *testing.T) {
+	keys, err := NewHMACKeySet("secret")
+	require.NoError(t, err)
+	config := ManagerConfig{Issuer: "users", Audiences: []string{"api", "admin"}, Leeway: time.Minute}
+	manager, err := NewManager(keys, nil, nil, config)
+	require.NoError(t, err)
+
+	now := time.Now()
+	testTable := []struct {
+		name          string
+		claims        jwt.StandardClaims
+		expectedValid bool
+	}{
+		{
+			name:          "Valid",
+			claims:        jwt.StandardClaims{Issuer: "users", Audience: "api", ExpiresAt: now.Add(time.Minute).Unix()},
+			expectedValid: true,
+		},
+		{
+			name:          "Other accepted audience",
+			claims:        jwt.StandardClaims{Issuer: "users", Audience: "admin", ExpiresAt: now.Add(time.Minute).Unix()},
+			expectedValid: true,
+		},
+		{
+			name:   "Other issuer",
+			claims: jwt.StandardClaims{Issuer: "other", Audience: "api", ExpiresAt: now.Add(time.Minute).Unix()},
+		},
+		{
+			name:   "No issuer",
+			claims: jwt.StandardClaims{Audience: "api", ExpiresAt: now.Add(time.Minute).Unix()},
+		},
+		{
+			name:   "Other audience",
+			claims: jwt.StandardClaims{Issuer: "users", Audience: "other", ExpiresAt: now.Add(time.Minute).Unix()},
+		},
+		{
+			name:   "No audience",
+			claims: jwt.StandardClaims{Issuer: "users", ExpiresAt: now.Add(time.Minute).Unix()},
+		},
+		{
+			name:          "Expired within leeway",
+			claims:        jwt.StandardClaims{Issuer: "users", Audience: "api", ExpiresAt: now.Add(-30 * time.Second).Unix()},
+			expectedValid: true,
+		},
+		{
+			name:   "Expired",
+			claims: jwt.StandardClaims{Issuer: "users", Audience: "api", ExpiresAt: now.Add(-2 * time.Minute).Unix()},
+		},
+		{
+			name: "Not valid yet within leeway",
+			claims: jwt.StandardClaims{Issuer: "users", Audience: "api", ExpiresAt: now.Add(time.Hour).Unix(),
+				NotBefore: now.Add(30 * time.Second).Unix(), IssuedAt: now.Add(30 * time.Second).Unix()},
+			expectedValid: true,
+		},
+		{
+			name: "Not valid yet",
+			claims: jwt.StandardClaims{Issuer: "users", Audience: "api", ExpiresAt: now.Add(time.Hour).Unix(),
+				NotBefore: now.Add(2 * time.Minute).Unix()},
+		},
+		{
+			name: "Issued in the future",
+			claims: jwt.StandardClaims{Issuer: "users", Audience: "api", ExpiresAt: now.Add(time.Hour).Unix(),
+				IssuedAt: now.Add(2 * time.Minute).Unix()},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			token, err := keys.Sign(&Claims{Roles: []string{UserRole}, StandardClaims: testCase.claims})
+			require.NoError(t, err)
+
+			_, err = manager.Parse(token, &Claims{})
+			if testCase.expectedValid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+
+	_, err = NewManager(keys, nil, nil, ManagerConfig{Leeway: -time.Second})
+	assert.Error(t, err, "negative leeway")
+}
+
+func TestManager_GenerateRefreshToken(t *testing.T) {
+	keys, err := NewHMACKeySet("secret")
+	require.NoError(t, err)
+
+	testTable := []struct {
+		name   string
+		format string
+	}{
+		{name: "Default", format: ""},
+		{name: "Opaque", format: RefreshTokenOpaque},
+		{name: "JWT", format: RefreshTokenJWT},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			manager, err := NewManager(keys, nil, nil, ManagerConfig{Issuer: "users", RefreshTokenFormat: testCase.format})
+			require.NoError(t, err)
+
+			const goroutines, perGoroutine = 16, 64
+			tokens := make(chan string, goroutines*perGoroutine)
+			var wg sync.WaitGroup
+			for i := 0; i < goroutines; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < perGoroutine; j++ {
+						token, err := manager.GenerateRefreshToken("000000000001", time.Minute)
+						assert.NoError(t, err)
+						tokens <- token
+					}
+				}()
+			}
+			wg.Wait()
+			close(tokens)
+
+			seen := make(map[string]bool, goroutines*perGoroutine)
+			for token := range tokens {
+				require.NotEmpty(t, token)
+				require.False(t, seen[token], "refresh tokens must be unique")
+				seen[token] = true
+			}
+			assert.Len(t, seen, goroutines*perGoroutine)
+
+			token, err := manager.GenerateRefreshToken("000000000001", time.Minute)
+			require.NoError(t, err)
+			if testCase.format != RefreshTokenJWT {
+				assert.Len(t, token, 64)
+				_, err = manager.ParsePurposeToken(token, RefreshTokenPurpose)
+				assert.Error(t, err)
+				return
+			}
+			claims, err := manager.ParsePurposeToken(token, RefreshTokenPurpose)
+			require.NoError(t, err)
+			assert.Equal(t, "000000000001", claims.SessionId)
+			assert.NotEmpty(t, claims.Id)
+			assert.Equal(t, "users", claims.Issuer)
+			_, err = manager.Parse(token, &Claims{})
+			assert.Error(t, err, "refresh token used as access token")
+		})
+	}
+
+	_, err = NewManager(keys, nil, nil, ManagerConfig{RefreshTokenFormat: "other"})
+	assert.Error(t, err, "unknown format")
+}
+
+func TestClaims_AllowsScope(t *testing.T) {
+	testTable := []struct {
+		name       string
+		scopes     []string
+		permission string
+		expected   bool
+	}{
+		{name: "No scopes", permission: "users:update", expected: true},
+		{name: "Same scope", scopes: []string{"users:read"}, permission: "users:read", expected: true},
+		{name: "Other action", scopes: []string{"users:read"}, permission: "users:update", expected: false},
+		{name: "Any action", scopes: []string{"users:*"}, permission: "users:update", expected: true},
+		{name: "Any resource", scopes: []string{"*:read"}, permission: "sessions:read", expected: true},
+		{name: "Invalid permission", scopes: []string{"users:read"}, permission: "users", expected: false},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			claims := &Claims{Scopes: testCase.scopes}
+			assert.Equal(t, testCase.expected, claims.AllowsScope(testCase.permission))
+		})
+	}
+}
@@ -1,70 +1,174 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
 )
 
 const (
-	RefreshURL          = "/:id/auth/refresh"
 	BasicURL            = "/api"
 	Version             = "/v1"
 	authorizationHeader = "Authorization"
-	userURLAPI          = "http://localhost:4000/api/v1/users/:id"
-	refreshTokenURI     = "/auth/refresh"
+	ClaimsContextKey    = "claims"
+	ActorContextKey     = "actor"
+	PrincipalContextKey = "principal"
 )
 
+// Principal is who makes the request, as told by the verified access token. UserId is
+// empty for services, see Claims.IsService.
+type Principal struct {
+	UserId  string
+	Roles   []string
+	TokenId string
+}
+
+// VerifyJWTMiddleware verifies the access token and, if roles are given, checks them.
+// With cookies on, requests without the Authorization header use AccessTokenCookie and
+// unsafe ones must pass ValidCSRF. Failures abort with 401 or 403, otherwise SetClaims
+// is called.
 func (m *Manager) VerifyJWTMiddleware(roles ...string) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		jwtToken, err := parseAuthHeader(ctx)
-		if err != nil {
-			return
+		var jwtToken string
+		if m.config.Cookies && ctx.GetHeader(authorizationHeader) == "" {
+			cookie, err := ctx.Cookie(AccessTokenCookie)
+			if err != nil || cookie == "" {
+				abortWithMessage(ctx, http.StatusUnauthorized, "missing access token")
+				return
+			}
+			if !ValidCSRF(ctx) {
+				abortWithMessage(ctx, http.StatusForbidden, "invalid csrf token")
+				return
+			}
+			jwtToken = cookie
+		} else {
+			var err error
+			if jwtToken, err = parseAuthHeader(ctx); err != nil {
+				abortWithMessage(ctx, http.StatusUnauthorized, err.Error())
+				return
+			}
 		}
-		userid := ctx.Param(IdNameURL)
+
 		claims := &Claims{}
 		token, err := m.GetTokenFromString(jwtToken, claims)
 		if err != nil {
-			ctx.Redirect(http.StatusTemporaryRedirect, userURLAPI+userid+refreshTokenURI)
+			abortWithMessage(ctx, http.StatusUnauthorized, tokenErrorMessage(err))
 			return
 		}
 		if err := m.ValidateToken(token, claims); err != nil {
-			ctx.Status(http.StatusForbidden)
-			ctx.Writer.Write([]byte(err.Error()))
-			ctx.Next()
+			abortWithMessage(ctx, http.StatusUnauthorized, err.Error())
+			return
 		}
-		if !hasPermission(roles, claims, userid) {
-			ctx.Status(http.StatusForbidden)
-			ctx.Writer.Write([]byte("Forbidden"))
-			ctx.Next()
+		revoked, err := m.IsRevoked(ctx.Request.Context(), claims.Id)
+		if err != nil {
+			abortWithMessage(ctx, http.StatusInternalServerError, "failed to check access token")
+			return
 		}
-		return
+		if revoked {
+			abortWithMessage(ctx, http.StatusUnauthorized, "access token is revoked")
+			return
+		}
+		if len(roles) > 0 && !hasPermission(roles, claims, ctx.Param(IdNameURL)) {
+			abortWithMessage(ctx, http.StatusForbidden, "forbidden")
+			return
+		}
+		SetClaims(ctx, claims)
+		ctx.Next()
+	}
+}
+
+// SetClaims stores the verified claims in the context with the actor and the principal
+// they make, for GetClaims, GetActor and GetPrincipal.
+func SetClaims(ctx *gin.Context, claims *Claims) {
+	ctx.Set(ClaimsContextKey, claims)
+	if claims.Actor != nil {
+		ctx.Set(ActorContextKey, claims.Actor)
 	}
-	
+	ctx.Set(PrincipalContextKey, &Principal{
+		UserId:  claims.Subject,
+		Roles:   claims.Roles,
+		TokenId: claims.Id,
+	})
+}
+
+// GetPrincipal returns who makes the request, set by SetClaims.
+func GetPrincipal(ctx *gin.Context) (*Principal, bool) {
+	value, ok := ctx.Get(PrincipalContextKey)
+	if !ok {
+		return nil, false
+	}
+	principal, ok := value.(*Principal)
+	return principal, ok
+}
+
+// GetClaims returns claims of the access token verified by VerifyJWTMiddleware.
+func GetClaims(ctx *gin.Context) (*Claims, bool) {
+	value, ok := ctx.Get(ClaimsContextKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := value.(*Claims)
+	return claims, ok
+}
+
+// GetActor returns the admin acting as the subject of an impersonation token.
+func GetActor(ctx *gin.Context) (*Actor, bool) {
+	value, ok := ctx.Get(ActorContextKey)
+	if !ok {
+		return nil, false
+	}
+	actor, ok := value.(*Actor)
+	return actor, ok
 }
 
 func parseAuthHeader(ctx *gin.Context) (string, error) {
 	if ctx.GetHeader(authorizationHeader) == "" {
-		return "", fmt.Errorf("empty auth header")
+		return "", fmt.Errorf("missing access token")
 	}
 	authHeader := strings.Split(ctx.GetHeader(authorizationHeader), PrefixToken)
 	if len(authHeader) != 2 {
-		return "", fmt.Errorf("invalid auth header")
+		return "", fmt.Errorf("invalid authorization header")
 	}
 	if len(authHeader[1]) == 0 {
-		return "", fmt.Errorf("token is empty")
+		return "", fmt.Errorf("missing access token")
 	}
 	return authHeader[1], nil
 }
 
+func abortWithMessage(ctx *gin.Context, status int, message string) {
+	ctx.AbortWithStatusJSON(status, gin.H{"message": message})
+}
+
+// tokenErrorMessage tells clients whether refreshing the token may help, other
+// parse errors aren't detailed.
+func tokenErrorMessage(err error) string {
+	var validationErr *jwt.ValidationError
+	if !errors.As(err, &validationErr) ||
+		validationErr.Errors&(jwt.ValidationErrorMalformed|jwt.ValidationErrorUnverifiable|jwt.ValidationErrorSignatureInvalid) != 0 {
+		return errInvalidToken.Error()
+	}
+	switch {
+	case validationErr.Errors&jwt.ValidationErrorExpired != 0:
+		return "access token is expired"
+	case validationErr.Errors&jwt.ValidationErrorNotValidYet != 0:
+		return "access token is not valid yet"
+	default:
+		return errInvalidToken.Error()
+	}
+}
+
+// hasPermission checks the roles, users may act only on their own /:id routes
+// while admins may act on any.
 func hasPermission(roles []string, claims *Claims, id string) bool {
-	if claims.Id != id {
+	if id != "" && claims.Subject != id && !claims.HasRole(AdminRole) {
 		return false
 	}
 	for _, role := range roles {
-		if strings.ToLower(claims.Role) == strings.ToLower(role) {
+		if claims.HasRole(role) {
 			return true
 		}
 	}
@@ -0,0 +1,370 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasPermission(t *testing.T) {
+	userClaims := &Claims{Roles: []string{UserRole}}
+	userClaims.Subject = "000000000001"
+	adminClaims := &Claims{Roles: []string{UserRole, AdminRole}}
+	adminClaims.Subject = "000000000002"
+
+	testTable := []struct {
+		name     string
+		roles    []string
+		claims   *Claims
+		id       string
+		expected bool
+	}{
+		{name: "User on own id", roles: []string{UserRole, AdminRole}, claims: userClaims, id: "000000000001", expected: true},
+		{name: "User on other id", roles: []string{UserRole, AdminRole}, claims: userClaims, id: "000000000002", expected: false},
+		{name: "User on admin route", roles: []string{AdminRole}, claims: userClaims, expected: false},
+		{name: "Admin on other id", roles: []string{UserRole, AdminRole}, claims: adminClaims, id: "000000000001", expected: true},
+		{name: "Admin on admin route", roles: []string{AdminRole}, claims: adminClaims, expected: true},
+		{name: "No roles", roles: []string{UserRole}, claims: &Claims{}, expected: false},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, hasPermission(testCase.roles, testCase.claims, testCase.id))
+		})
+	}
+}
+
+type failingDenylist struct{}
+
+func (failingDenylist) Add(ctx context.Context, tokenId string, expiresAt time.Time) error {
+	return errors.New("db failure")
+}
+
+func (failingDenylist) Contains(ctx context.Context, tokenId string) (bool, error) {
+	return false, errors.New("db failure")
+}
+
+func TestVerifyJWTMiddleware(t *testing.T) {
+	keys, err := NewHMACKeySet("secret")
+	require.NoError(t, err)
+	denylist := NewMemoryDenylist()
+	manager, err := NewManager(keys, denylist, nil, ManagerConfig{Issuer: "users", Audiences: []string{"api", "admin"}})
+	require.NoError(t, err)
+
+	otherKeys, err := NewHMACKeySet("other")
+	require.NoError(t, err)
+	otherManager, err := NewManager(otherKeys, nil, nil, ManagerConfig{})
+	require.NoError(t, err)
+	failingManager, err := NewManager(keys, failingDenylist{}, nil, ManagerConfig{Issuer: "users", Audiences: []string{"api"}})
+	require.NoError(t, err)
+
+	newClaims := func(roles ...string) Claims {
+		claims := Claims{Roles: roles}
+		claims.Subject = "000000000001"
+		claims.Issuer = "users"
+		claims.Audience = "api"
+		return claims
+	}
+	sign := func(claims Claims) string {
+		token, err := keys.Sign(&claims)
+		require.NoError(t, err)
+		return PrefixToken + token
+	}
+	generate := func(manager *Manager, claims Claims) string {
+		token, err := manager.GenerateAccessToken(claims, time.Minute)
+		require.NoError(t, err)
+		return token
+	}
+
+	expired := newClaims(UserRole)
+	expired.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+	notValidYet := newClaims(UserRole)
+	notValidYet.NotBefore = time.Now().Add(time.Minute).Unix()
+	otherIssuer := newClaims(UserRole)
+	otherIssuer.Issuer = "other"
+	otherAudience := newClaims(UserRole)
+	otherAudience.Audience = "other"
+	adminAudience := newClaims(UserRole)
+	adminAudience.Audience = "admin"
+	purpose, err := manager.GeneratePurposeToken("link", newClaims(), time.Minute)
+	require.NoError(t, err)
+	revoked := newClaims(UserRole)
+	revoked.Id = "revoked"
+	require.NoError(t, denylist.Add(context.Background(), revoked.Id, time.Now().Add(time.Minute)))
+
+	testTable := []struct {
+		name                string
+		manager             *Manager
+		roles               []string
+		path                string
+		header              string
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{
+			name:                "OK",
+			header:              generate(manager, newClaims(UserRole)),
+			expectedStatusCode:  200,
+			expectedRequestBody: "000000000001",
+		},
+		{
+			name:                "Accepted audience",
+			header:              sign(adminAudience),
+			expectedStatusCode:  200,
+			expectedRequestBody: "000000000001",
+		},
+		{
+			name:                "Role",
+			roles:               []string{UserRole},
+			path:                "/users/000000000001",
+			header:              generate(manager, newClaims(UserRole)),
+			expectedStatusCode:  200,
+			expectedRequestBody: "000000000001",
+		},
+		{
+			name:                "No header",
+			expectedStatusCode:  401,
+			expectedRequestBody: `{"message":"missing access token"}`,
+		},
+		{
+			name:                "Empty bearer",
+			header:              PrefixToken,
+			expectedStatusCode:  401,
+			expectedRequestBody: `{"message":"missing access token"}`,
+		},
+		{
+			name:                "Not bearer",
+			header:              "Basic dXNlcjpwYXNz",
+			expectedStatusCode:  401,
+			expectedRequestBody: `{"message":"invalid authorization header"}`,
+		},
+		{
+			name:                "Malformed token",
+			header:              PrefixToken + "token",
+			expectedStatusCode:  401,
+			expectedRequestBody: `{"message":"invalid access token"}`,
+		},
+		{
+			name:                "Other signing key",
+			header:              generate(otherManager, newClaims(UserRole)),
+			expectedStatusCode:  401,
+			expectedRequestBody: `{"message":"invalid access token"}`,
+		},
+		{
+			name:                "Expired",
+			header:              sign(expired),
+			expectedStatusCode:  401,
+			expectedRequestBody: `{"message":"access token is expired"}`,
+		},
+		{
+			name:                "Not valid yet",
+			header:              sign(notValidYet),
+			expectedStatusCode:  401,
+			expectedRequestBody: `{"message":"access token is not valid yet"}`,
+		},
+		{
+			name:                "Other issuer",
+			header:              sign(otherIssuer),
+			expectedStatusCode:  401,
+			expectedRequestBody: `{"message":"invalid access token"}`,
+		},
+		{
+			name:                "Other audience",
+			header:              sign(otherAudience),
+			expectedStatusCode:  401,
+			expectedRequestBody: `{"message":"invalid access token"}`,
+		},
+		{
+			name:                "Purpose token",
+			header:              PrefixToken + purpose,
+			expectedStatusCode:  401,
+			expectedRequestBody: `{"message":"invalid access token"}`,
+		},
+		{
+			name:                "Revoked",
+			header:              generate(manager, revoked),
+			expectedStatusCode:  401,
+			expectedRequestBody: `{"message":"access token is revoked"}`,
+		},
+		{
+			name:                "Denylist failure",
+			manager:             failingManager,
+			header:              generate(manager, newClaims(UserRole)),
+			expectedStatusCode:  500,
+			expectedRequestBody: `{"message":"failed to check access token"}`,
+		},
+		{
+			name:                "Missing role",
+			roles:               []string{AdminRole},
+			header:              generate(manager, newClaims(UserRole)),
+			expectedStatusCode:  403,
+			expectedRequestBody: `{"message":"forbidden"}`,
+		},
+		{
+			name:                "Other user",
+			roles:               []string{UserRole},
+			path:                "/users/000000000002",
+			header:              generate(manager, newClaims(UserRole)),
+			expectedStatusCode:  403,
+			expectedRequestBody: `{"message":"forbidden"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			m := manager
+			if testCase.manager != nil {
+				m = testCase.manager
+			}
+			path := testCase.path
+			if path == "" {
+				path = "/users/000000000001"
+			}
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			r.GET("/users/:id", m.VerifyJWTMiddleware(testCase.roles...), func(ctx *gin.Context) {
+				principal, ok := GetPrincipal(ctx)
+				require.True(t, ok)
+				ctx.String(http.StatusOK, principal.UserId)
+			})
+			req := httptest.NewRequest("GET", path, nil)
+			if testCase.header != "" {
+				req.Header.Set(authorizationHeader, testCase.header)
+			}
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+			assert.Empty(t, w.Header().Get("Location"), "clients are never redirected")
+		})
+	}
+}
+
+func TestSetClaims(t *testing.T) {
+	claims := &Claims{Roles: []string{UserRole}, StandardClaims: jwt.StandardClaims{Subject: "000000000001", Id: "jti"}}
+
+	gin.SetMode(gin.ReleaseMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	SetClaims(ctx, claims)
+
+	principal, ok := GetPrincipal(ctx)
+	require.True(t, ok)
+	assert.Equal(t, &Principal{UserId: "000000000001", Roles: []string{UserRole}, TokenId: "jti"}, principal)
+	stored, ok := GetClaims(ctx)
+	require.True(t, ok)
+	assert.Same(t, claims, stored)
+}
+
+func TestVerifyJWTMiddleware_Actor(t *testing.T) {
+	keys, err := NewHMACKeySet("secret")
+	require.NoError(t, err)
+	manager, err := NewManager(keys, nil, nil, ManagerConfig{})
+	require.NoError(t, err)
+
+	claims := Claims{Roles: []string{UserRole}, Actor: &Actor{Subject: "000000000001"}}
+	claims.Subject = "000000000002"
+	impersonationToken, err := manager.GenerateAccessToken(claims, time.Minute)
+	require.NoError(t, err)
+	claims.Actor = nil
+	accessToken, err := manager.GenerateAccessToken(claims, time.Minute)
+	require.NoError(t, err)
+
+	testTable := []struct {
+		name          string
+		token         string
+		expectedActor string
+	}{
+		{name: "Impersonation token", token: impersonationToken, expectedActor: "000000000001"},
+		{name: "Access token", token: accessToken},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			gin.SetMode(gin.ReleaseMode)
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest("GET", "/", nil)
+			ctx.Request.Header.Set(authorizationHeader, testCase.token)
+
+			manager.VerifyJWTMiddleware()(ctx)
+
+			claims, ok := GetClaims(ctx)
+			require.True(t, ok)
+			actor, ok := GetActor(ctx)
+			if testCase.expectedActor == "" {
+				assert.False(t, ok)
+				assert.False(t, claims.IsImpersonated())
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, testCase.expectedActor, actor.Subject)
+			assert.True(t, claims.IsImpersonated())
+		})
+	}
+}
+
+func TestVerifyJWTMiddleware_Cookie(t *testing.T) {
+	keys, err := NewHMACKeySet("secret")
+	require.NoError(t, err)
+	manager, err := NewManager(keys, nil, nil, ManagerConfig{Cookies: true})
+	require.NoError(t, err)
+	headerManager, err := NewManager(keys, nil, nil, ManagerConfig{})
+	require.NoError(t, err)
+
+	claims := Claims{Roles: []string{UserRole}}
+	claims.Subject = "000000000001"
+	token, err := manager.GenerateAccessToken(claims, time.Minute)
+	require.NoError(t, err)
+
+	testTable := []struct {
+		name               string
+		manager            *Manager
+		method             string
+		authHeader         string
+		csrfHeader         string
+		expectedClaims     bool
+		expectedStatusCode int
+	}{
+		{name: "Safe method", manager: manager, method: "GET", expectedClaims: true, expectedStatusCode: 200},
+		{name: "CSRF token", manager: manager, method: "POST", csrfHeader: "csrf", expectedClaims: true, expectedStatusCode: 200},
+		{name: "No CSRF token", manager: manager, method: "POST", expectedStatusCode: 403},
+		{name: "Wrong CSRF token", manager: manager, method: "DELETE", csrfHeader: "wrong", expectedStatusCode: 403},
+		{name: "Invalid authorization header", manager: manager, method: "GET", authHeader: "Basic token", expectedStatusCode: 401},
+		{name: "Cookies off", manager: headerManager, method: "GET", expectedStatusCode: 401},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			gin.SetMode(gin.ReleaseMode)
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Request = httptest.NewRequest(testCase.method, "/", nil)
+			ctx.Request.AddCookie(&http.Cookie{Name: AccessTokenCookie, Value: strings.TrimPrefix(token, PrefixToken)})
+			ctx.Request.AddCookie(&http.Cookie{Name: CSRFCookie, Value: "csrf"})
+			if testCase.authHeader != "" {
+				ctx.Request.Header.Set(authorizationHeader, testCase.authHeader)
+			}
+			if testCase.csrfHeader != "" {
+				ctx.Request.Header.Set(CSRFHeader, testCase.csrfHeader)
+			}
+
+			testCase.manager.VerifyJWTMiddleware()(ctx)
+
+			_, ok := GetClaims(ctx)
+			assert.Equal(t, testCase.expectedClaims, ok)
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+		})
+	}
+}
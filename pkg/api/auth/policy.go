@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"test/configs"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ilyakaznacheev/cleanenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Permissions are "resource:action:scope" strings, e.g. "users:update:self". Scope "self"
+// allows the action only on the caller's own /:id, scope "any" on every id. "*" matches
+// any resource or action.
+const (
+	ScopeSelf = "self"
+	ScopeAny  = "any"
+	wildcard  = "*"
+)
+
+// PolicyConfig maps role names to permissions granted to them. ImpersonationDenied are
+// "resource:action" permissions impersonation tokens never get, whatever the roles.
+type PolicyConfig struct {
+	Roles               map[string][]string `yaml:"roles"`
+	ImpersonationDenied []string            `yaml:"impersonation_denied"`
+}
+
+type Policy struct {
+	mu                  sync.RWMutex
+	path                string
+	roles               map[string][]permission
+	impersonationDenied []permission
+}
+
+type permission struct {
+	resource string
+	action   string
+	scope    string
+}
+
+func NewPolicy(cfg PolicyConfig) (*Policy, error) {
+	roles, err := parseRoles(cfg)
+	if err != nil {
+		return nil, err
+	}
+	denied, err := parseDenied(cfg.ImpersonationDenied)
+	if err != nil {
+		return nil, err
+	}
+	return &Policy{roles: roles, impersonationDenied: denied}, nil
+}
+
+// LoadPolicy reads the policy from the YAML file, Reload reads the same file again.
+func LoadPolicy(path string) (*Policy, error) {
+	p := &Policy{path: path}
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// DefaultPolicy is configs/policy.yml as embedded at build time. It lets users manage
+// their own account and admins manage every account, impersonating admins can't change
+// credentials, delete the account or impersonate further.
+func DefaultPolicy() *Policy {
+	var cfg PolicyConfig
+	if err := yaml.Unmarshal(configs.Policy, &cfg); err != nil {
+		panic(fmt.Sprintf("failed to parse embedded policy due to error: %v", err))
+	}
+	p, err := NewPolicy(cfg)
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded policy: %v", err))
+	}
+	return p
+}
+
+// Reload replaces the permissions with the ones from the policy file. The current
+// permissions are kept when the file can't be read or is invalid.
+func (p *Policy) Reload() error {
+	if p.path == "" {
+		return fmt.Errorf("policy wasn't loaded from a file")
+	}
+
+	var cfg PolicyConfig
+	if err := cleanenv.ReadConfig(p.path, &cfg); err != nil {
+		return fmt.Errorf("failed to read policy file %s due to error: %v", p.path, err)
+	}
+	roles, err := parseRoles(cfg)
+	if err != nil {
+		return err
+	}
+	denied, err := parseDenied(cfg.ImpersonationDenied)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.roles = roles
+	p.impersonationDenied = denied
+	p.mu.Unlock()
+	return nil
+}
+
+func parseRoles(cfg PolicyConfig) (map[string][]permission, error) {
+	roles := make(map[string][]permission, len(cfg.Roles))
+	for role, permissions := range cfg.Roles {
+		for _, value := range permissions {
+			perm, err := parsePermission(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid permission of role %s: %v", role, err)
+			}
+			roles[strings.ToLower(role)] = append(roles[strings.ToLower(role)], perm)
+		}
+	}
+	return roles, nil
+}
+
+func parseDenied(values []string) ([]permission, error) {
+	denied := make([]permission, 0, len(values))
+	for _, value := range values {
+		resource, action, ok := strings.Cut(value, ":")
+		if !ok || resource == "" || action == "" || strings.Contains(action, ":") {
+			return nil, fmt.Errorf("invalid impersonation denied permission %q isn't resource:action", value)
+		}
+		denied = append(denied, permission{resource: resource, action: action})
+	}
+	return denied, nil
+}
+
+func parsePermission(value string) (permission, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		return permission{}, fmt.Errorf("%q isn't resource:action:scope", value)
+	}
+	if parts[2] != ScopeSelf && parts[2] != ScopeAny {
+		return permission{}, fmt.Errorf("%q has unknown scope %q", value, parts[2])
+	}
+	return permission{resource: parts[0], action: parts[1], scope: parts[2]}, nil
+}
+
+// Allowed reports whether any of the roles grants the "resource:action" permission.
+// owner tells whether the caller acts on their own resource, which is enough for "self" scope.
+func (p *Policy) Allowed(roles []string, resourceAction string, owner bool) bool {
+	resource, action, ok := strings.Cut(resourceAction, ":")
+	if !ok {
+		return false
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, role := range roles {
+		for _, perm := range p.roles[strings.ToLower(role)] {
+			if perm.matches(resource, action) && (perm.scope == ScopeAny || owner) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ImpersonationAllowed reports whether impersonation tokens may have the "resource:action" permission.
+func (p *Policy) ImpersonationAllowed(resourceAction string) bool {
+	resource, action, ok := strings.Cut(resourceAction, ":")
+	if !ok {
+		return false
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, perm := range p.impersonationDenied {
+		if perm.matches(resource, action) {
+			return false
+		}
+	}
+	return true
+}
+
+func (perm permission) matches(resource, action string) bool {
+	return (perm.resource == wildcard || perm.resource == resource) &&
+		(perm.action == wildcard || perm.action == action)
+}
+
+// RequirePermission allows the request when the policy grants the "resource:action" permission
+// to the roles of the access token and its scopes allow it. The token owner is the one whose
+// id is in the /:id param. Services are allowed by their scopes alone, on any id. Impersonation
+// tokens are also denied the policy's ImpersonationDenied permissions.
+// It expects claims set by VerifyJWTMiddleware earlier in the chain.
+func (m *Manager) RequirePermission(resourceAction string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		claims, ok := GetClaims(ctx)
+		if !ok {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "unauthorized"})
+			return
+		}
+
+		id := ctx.Param(IdNameURL)
+		owner := id != "" && id == claims.Subject
+		allowed := claims.AllowsScope(resourceAction) && m.policy.Allowed(claims.Roles, resourceAction, owner)
+		if claims.IsService() {
+			allowed = len(claims.Scopes) > 0 && claims.AllowsScope(resourceAction)
+		}
+		if claims.IsImpersonated() && !m.policy.ImpersonationAllowed(resourceAction) {
+			allowed = false
+		}
+		if !allowed {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "forbidden"})
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy_Allowed(t *testing.T) {
+	policy, err := NewPolicy(PolicyConfig{Roles: map[string][]string{
+		"user":    {"users:read:self", "users:update:self"},
+		"support": {"users:read:any", "sessions:*:self"},
+		"admin":   {"*:*:any"},
+	}})
+	require.NoError(t, err)
+
+	testTable := []struct {
+		name       string
+		roles      []string
+		permission string
+		owner      bool
+		expected   bool
+	}{
+		{name: "Self scope on own resource", roles: []string{"user"}, permission: "users:read", owner: true, expected: true},
+		{name: "Self scope on other resource", roles: []string{"user"}, permission: "users:read", expected: false},
+		{name: "Action not granted", roles: []string{"user"}, permission: "users:delete", owner: true, expected: false},
+		{name: "Any scope on other resource", roles: []string{"support"}, permission: "users:read", expected: true},
+		{name: "Wildcard action", roles: []string{"support"}, permission: "sessions:delete", owner: true, expected: true},
+		{name: "Wildcard action with self scope", roles: []string{"support"}, permission: "sessions:delete", expected: false},
+		{name: "Wildcard resource", roles: []string{"admin"}, permission: "roles:update", expected: true},
+		{name: "Role names are case insensitive", roles: []string{"ADMIN"}, permission: "users:delete", expected: true},
+		{name: "One of several roles", roles: []string{"user", "support"}, permission: "users:read", expected: true},
+		{name: "Unknown role", roles: []string{"guest"}, permission: "users:read", owner: true, expected: false},
+		{name: "No roles", permission: "users:read", owner: true, expected: false},
+		{name: "Malformed permission", roles: []string{"admin"}, permission: "users", expected: false},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, policy.Allowed(testCase.roles, testCase.permission, testCase.owner))
+		})
+	}
+}
+
+func TestNewPolicy_InvalidPermission(t *testing.T) {
+	testTable := []struct {
+		name       string
+		permission string
+	}{
+		{name: "Without scope", permission: "users:read"},
+		{name: "Unknown scope", permission: "users:read:team"},
+		{name: "Empty action", permission: "users::any"},
+		{name: "Too many parts", permission: "users:read:any:now"},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := NewPolicy(PolicyConfig{Roles: map[string][]string{"user": {testCase.permission}}})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNewPolicy_InvalidImpersonationDenied(t *testing.T) {
+	for _, permission := range []string{"users", "users:", "users:update:any"} {
+		_, err := NewPolicy(PolicyConfig{ImpersonationDenied: []string{permission}})
+		assert.Error(t, err, permission)
+	}
+}
+
+func TestPolicy_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yml")
+	require.NoError(t, os.WriteFile(path, []byte("roles:\n  user:\n    - users:read:self\n"), 0600))
+
+	policy, err := LoadPolicy(path)
+	require.NoError(t, err)
+	assert.True(t, policy.Allowed([]string{UserRole}, "users:read", true))
+	assert.False(t, policy.Allowed([]string{UserRole}, "users:read", false))
+
+	require.NoError(t, os.WriteFile(path, []byte("roles:\n  user:\n    - users:read:any\nimpersonation_denied:\n  - users:*\n"), 0600))
+	require.NoError(t, policy.Reload())
+	assert.True(t, policy.Allowed([]string{UserRole}, "users:read", false))
+	assert.False(t, policy.ImpersonationAllowed("users:read"))
+	assert.True(t, policy.ImpersonationAllowed("sessions:read"))
+
+	require.NoError(t, os.WriteFile(path, []byte("roles:\n  user:\n    - users:read\n"), 0600))
+	assert.Error(t, policy.Reload())
+	assert.True(t, policy.Allowed([]string{UserRole}, "users:read", false), "previous policy is kept")
+
+	assert.Error(t, DefaultPolicy().Reload())
+}
+
+func TestManager_RequirePermission(t *testing.T) {
+	userClaims := &Claims{Roles: []string{UserRole}}
+	userClaims.Subject = "000000000001"
+	adminClaims := &Claims{Roles: []string{AdminRole}}
+	adminClaims.Subject = "000000000002"
+	scopedClaims := &Claims{Roles: []string{UserRole}, Scopes: []string{"users:read"}}
+	scopedClaims.Subject = "000000000001"
+	serviceClaims := &Claims{ClientId: "reports", Scopes: []string{"users:read"}}
+	impersonatedClaims := &Claims{Roles: []string{UserRole}, Actor: &Actor{Subject: "000000000002"}}
+	impersonatedClaims.Subject = "000000000001"
+
+	testTable := []struct {
+		name                string
+		claims              *Claims
+		permission          string
+		id                  string
+		expectedStatusCode  int
+		expectedRequestBody string
+	}{
+		{name: "Owner", claims: userClaims, permission: "users:update", id: "000000000001", expectedStatusCode: 200},
+		{
+			name:                "Not owner",
+			claims:              userClaims,
+			permission:          "users:update",
+			id:                  "000000000002",
+			expectedStatusCode:  403,
+			expectedRequestBody: `{"message":"forbidden"}`,
+		},
+		{name: "Admin", claims: adminClaims, permission: "users:delete", id: "000000000001", expectedStatusCode: 200},
+		{name: "Within scopes", claims: scopedClaims, permission: "users:read", id: "000000000001", expectedStatusCode: 200},
+		{name: "Impersonated read", claims: impersonatedClaims, permission: "users:read", id: "000000000001", expectedStatusCode: 200},
+		{
+			name:                "Impersonated password change",
+			claims:              impersonatedClaims,
+			permission:          "users:update",
+			id:                  "000000000001",
+			expectedStatusCode:  403,
+			expectedRequestBody: `{"message":"forbidden"}`,
+		},
+		{
+			name:                "Impersonated wildcard denial",
+			claims:              impersonatedClaims,
+			permission:          "mfa:delete",
+			id:                  "000000000001",
+			expectedStatusCode:  403,
+			expectedRequestBody: `{"message":"forbidden"}`,
+		},
+		{name: "Service within scopes", claims: serviceClaims, permission: "users:read", id: "000000000001", expectedStatusCode: 200},
+		{
+			name:                "Service out of scopes",
+			claims:              serviceClaims,
+			permission:          "users:delete",
+			id:                  "000000000001",
+			expectedStatusCode:  403,
+			expectedRequestBody: `{"message":"forbidden"}`,
+		},
+		{
+			name:                "Service without scopes",
+			claims:              &Claims{ClientId: "reports"},
+			permission:          "users:read",
+			id:                  "000000000001",
+			expectedStatusCode:  403,
+			expectedRequestBody: `{"message":"forbidden"}`,
+		},
+		{
+			name:                "Out of scopes",
+			claims:              scopedClaims,
+			permission:          "users:update",
+			id:                  "000000000001",
+			expectedStatusCode:  403,
+			expectedRequestBody: `{"message":"forbidden"}`,
+		},
+		{
+			name:                "No claims",
+			permission:          "users:read",
+			id:                  "000000000001",
+			expectedStatusCode:  401,
+			expectedRequestBody: `{"message":"unauthorized"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			manager := &Manager{policy: DefaultPolicy()}
+
+			gin.SetMode(gin.ReleaseMode)
+			r := gin.New()
+			w := httptest.NewRecorder()
+
+			setClaims := func(ctx *gin.Context) {
+				if testCase.claims != nil {
+					ctx.Set(ClaimsContextKey, testCase.claims)
+				}
+			}
+			r.GET("/users/:id", setClaims, manager.RequirePermission(testCase.permission), func(ctx *gin.Context) {
+				ctx.Status(200)
+			})
+			req := httptest.NewRequest("GET", "/users/"+testCase.id, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedRequestBody, w.Body.String())
+		})
+	}
+}
@@ -1,14 +1,32 @@
 package hash
 
 import (
+	"crypto/rand"
 	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
 )
 
+var ErrUnknownHashFormat = errors.New("unknown password hash format")
+
+// PasswordHasher hashes passwords into self-describing strings: the algorithm and its
+// parameters are stored next to the salt, so hashes stay verifiable after parameters change.
 type PasswordHasher interface {
 	Hash(password string) (string, error)
+	Verify(password, passwordHash string) (bool, error)
+	// NeedsRehash reports whether the hash was made by another algorithm or with other
+	// parameters and should be replaced the next time the password is known.
+	NeedsRehash(passwordHash string) bool
 }
 
+// SHA1Hasher is the legacy hasher. It appends the salt to the digest instead of hashing it,
+// so it is kept only to verify old hashes until they are upgraded.
 type SHA1Hasher struct {
 	salt string
 }
@@ -26,3 +44,175 @@ func (h *SHA1Hasher) Hash(password string) (string, error) {
 	}
 	return fmt.Sprintf("%x", hash.Sum([]byte(h.salt))), nil
 }
+
+func (h *SHA1Hasher) Verify(password, passwordHash string) (bool, error) {
+	hash, err := h.Hash(password)
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(passwordHash)) == 1, nil
+}
+
+func (h *SHA1Hasher) NeedsRehash(passwordHash string) bool {
+	return false
+}
+
+const (
+	argon2idPrefix = "$argon2id$"
+	argon2idFormat = "$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s"
+)
+
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// Argon2idHasher produces PHC strings like $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>.
+type Argon2idHasher struct {
+	params Argon2idParams
+}
+
+func NewArgon2idHasher(params Argon2idParams) (*Argon2idHasher, error) {
+	if params.Memory == 0 || params.Iterations == 0 || params.Parallelism == 0 ||
+		params.SaltLength == 0 || params.KeyLength == 0 {
+		return nil, fmt.Errorf("invalid argon2id parameters %+v", params)
+	}
+	return &Argon2idHasher{params: params}, nil
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("Failed to hash password")
+	}
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+
+	return fmt.Sprintf(argon2idFormat, argon2.Version, h.params.Memory, h.params.Iterations, h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h *Argon2idHasher) Verify(password, passwordHash string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(passwordHash)
+	if err != nil {
+		return false, err
+	}
+	actual := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	return subtle.ConstantTimeCompare(actual, key) == 1, nil
+}
+
+func (h *Argon2idHasher) NeedsRehash(passwordHash string) bool {
+	params, _, _, err := decodeArgon2id(passwordHash)
+	return err != nil || params != h.params
+}
+
+func decodeArgon2id(passwordHash string) (params Argon2idParams, salt, key []byte, err error) {
+	parts := strings.Split(passwordHash, "$")
+	if len(parts) != 6 || !strings.HasPrefix(passwordHash, argon2idPrefix) {
+		return params, nil, nil, ErrUnknownHashFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("%w: unsupported argon2id version %q", ErrUnknownHashFormat, parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("%w: invalid argon2id parameters %q", ErrUnknownHashFormat, parts[3])
+	}
+	// Zero iterations or parallelism make argon2 panic.
+	if params.Memory == 0 || params.Iterations == 0 || params.Parallelism == 0 {
+		return params, nil, nil, fmt.Errorf("%w: invalid argon2id parameters %q", ErrUnknownHashFormat, parts[3])
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return params, nil, nil, fmt.Errorf("%w: invalid argon2id salt", ErrUnknownHashFormat)
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return params, nil, nil, fmt.Errorf("%w: invalid argon2id key", ErrUnknownHashFormat)
+	}
+	// An empty key would match the empty key derived for any password.
+	if len(salt) == 0 || len(key) == 0 {
+		return params, nil, nil, fmt.Errorf("%w: empty argon2id salt or key", ErrUnknownHashFormat)
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
+
+// BcryptHasher produces modular crypt strings like $2a$12$<salt and key>, bcrypt's
+// own self-describing format.
+type BcryptHasher struct {
+	cost int
+}
+
+func NewBcryptHasher(cost int) (*BcryptHasher, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	return &BcryptHasher{cost: cost}, nil
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", fmt.Errorf("Failed to hash password")
+	}
+	return string(hash), nil
+}
+
+func (h *BcryptHasher) Verify(password, passwordHash string) (bool, error) {
+	if !isBcrypt(passwordHash) {
+		return false, ErrUnknownHashFormat
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (h *BcryptHasher) NeedsRehash(passwordHash string) bool {
+	cost, err := bcrypt.Cost([]byte(passwordHash))
+	return err != nil || cost != h.cost
+}
+
+func isBcrypt(passwordHash string) bool {
+	return strings.HasPrefix(passwordHash, "$2a$") || strings.HasPrefix(passwordHash, "$2b$") ||
+		strings.HasPrefix(passwordHash, "$2y$")
+}
+
+// MigratingHasher hashes passwords with the primary hasher and verifies hashes of every
+// supported format, including legacy SHA1 ones. Everything not made by the primary
+// hasher with its current parameters needs rehash.
+type MigratingHasher struct {
+	primary PasswordHasher
+	legacy  *SHA1Hasher
+}
+
+func NewMigratingHasher(primary PasswordHasher, legacy *SHA1Hasher) *MigratingHasher {
+	return &MigratingHasher{primary: primary, legacy: legacy}
+}
+
+func (h *MigratingHasher) Hash(password string) (string, error) {
+	return h.primary.Hash(password)
+}
+
+func (h *MigratingHasher) Verify(password, passwordHash string) (bool, error) {
+	switch {
+	case strings.HasPrefix(passwordHash, argon2idPrefix):
+		return (&Argon2idHasher{}).Verify(password, passwordHash)
+	case isBcrypt(passwordHash):
+		return (&BcryptHasher{}).Verify(password, passwordHash)
+	case strings.HasPrefix(passwordHash, "$"):
+		return false, ErrUnknownHashFormat
+	}
+	if h.legacy == nil {
+		return false, ErrUnknownHashFormat
+	}
+	return h.legacy.Verify(password, passwordHash)
+}
+
+func (h *MigratingHasher) NeedsRehash(passwordHash string) bool {
+	return h.primary.NeedsRehash(passwordHash)
+}
@@ -0,0 +1,132 @@
+package hash
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testArgon2idParams = Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestPasswordHasher_HashAndVerify(t *testing.T) {
+	argon2idHasher, err := NewArgon2idHasher(testArgon2idParams)
+	require.NoError(t, err)
+	bcryptHasher, err := NewBcryptHasher(bcrypt.MinCost)
+	require.NoError(t, err)
+
+	testTable := []struct {
+		name           string
+		hasher         PasswordHasher
+		expectedPrefix string
+	}{
+		{name: "Argon2id", hasher: argon2idHasher, expectedPrefix: "$argon2id$v=19$m=1024,t=1,p=1$"},
+		{name: "Bcrypt", hasher: bcryptHasher, expectedPrefix: "$2a$04$"},
+		{name: "SHA1", hasher: NewSHA1Hasher("salt")},
+		{name: "Migrating", hasher: NewMigratingHasher(argon2idHasher, NewSHA1Hasher("salt")), expectedPrefix: "$argon2id$"},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			passwordHash, err := testCase.hasher.Hash("qwerty123")
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(passwordHash, testCase.expectedPrefix), passwordHash)
+			assert.False(t, testCase.hasher.NeedsRehash(passwordHash))
+
+			ok, err := testCase.hasher.Verify("qwerty123", passwordHash)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+
+			ok, err = testCase.hasher.Verify("qwerty1234", passwordHash)
+			assert.NoError(t, err)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestArgon2idHasher_SaltIsRandom(t *testing.T) {
+	hasher, err := NewArgon2idHasher(testArgon2idParams)
+	require.NoError(t, err)
+
+	first, err := hasher.Hash("qwerty123")
+	require.NoError(t, err)
+	second, err := hasher.Hash("qwerty123")
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+}
+
+func TestMigratingHasher(t *testing.T) {
+	legacy := NewSHA1Hasher("salt")
+	oldArgon2idHasher, err := NewArgon2idHasher(testArgon2idParams)
+	require.NoError(t, err)
+	newParams := testArgon2idParams
+	newParams.Iterations = 2
+	argon2idHasher, err := NewArgon2idHasher(newParams)
+	require.NoError(t, err)
+	bcryptHasher, err := NewBcryptHasher(bcrypt.MinCost)
+	require.NoError(t, err)
+
+	legacyHash, _ := legacy.Hash("qwerty123")
+	oldArgon2idHash, _ := oldArgon2idHasher.Hash("qwerty123")
+	argon2idHash, _ := argon2idHasher.Hash("qwerty123")
+	bcryptHash, _ := bcryptHasher.Hash("qwerty123")
+
+	hasher := NewMigratingHasher(argon2idHasher, legacy)
+
+	testTable := []struct {
+		name                string
+		passwordHash        string
+		expectedOk          bool
+		expectedErr         error
+		expectedNeedsRehash bool
+	}{
+		{name: "Legacy SHA1", passwordHash: legacyHash, expectedOk: true, expectedNeedsRehash: true},
+		{name: "Argon2id with old parameters", passwordHash: oldArgon2idHash, expectedOk: true, expectedNeedsRehash: true},
+		{name: "Argon2id with current parameters", passwordHash: argon2idHash, expectedOk: true},
+		{name: "Bcrypt", passwordHash: bcryptHash, expectedOk: true, expectedNeedsRehash: true},
+		{name: "Unknown format", passwordHash: "$scrypt$ln=15$abc$def", expectedErr: ErrUnknownHashFormat, expectedNeedsRehash: true},
+		{name: "Corrupted argon2id", passwordHash: "$argon2id$v=19$m=1024$abc$def", expectedErr: ErrUnknownHashFormat, expectedNeedsRehash: true},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			ok, err := hasher.Verify("qwerty123", testCase.passwordHash)
+			assert.Equal(t, testCase.expectedOk, ok)
+			if testCase.expectedErr != nil {
+				assert.ErrorIs(t, err, testCase.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, testCase.expectedNeedsRehash, hasher.NeedsRehash(testCase.passwordHash))
+		})
+	}
+}
+
+func TestArgon2idHasher_InvalidStoredHash(t *testing.T) {
+	hasher, err := NewArgon2idHasher(testArgon2idParams)
+	require.NoError(t, err)
+
+	for name, passwordHash := range map[string]string{
+		"Empty key":        "$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHQ$",
+		"Empty salt":       "$argon2id$v=19$m=1024,t=1,p=1$$c2FsdHNhbHQ",
+		"Zero memory":      "$argon2id$v=19$m=0,t=1,p=1$c2FsdHNhbHQ$c2FsdHNhbHQ",
+		"Zero iterations":  "$argon2id$v=19$m=1024,t=0,p=1$c2FsdHNhbHQ$c2FsdHNhbHQ",
+		"Zero parallelism": "$argon2id$v=19$m=1024,t=1,p=0$c2FsdHNhbHQ$c2FsdHNhbHQ",
+	} {
+		t.Run(name, func(t *testing.T) {
+			ok, err := hasher.Verify("", passwordHash)
+			assert.False(t, ok)
+			assert.ErrorIs(t, err, ErrUnknownHashFormat)
+			assert.True(t, hasher.NeedsRehash(passwordHash))
+		})
+	}
+}
+
+func TestNewHasher_InvalidParameters(t *testing.T) {
+	_, err := NewArgon2idHasher(Argon2idParams{})
+	assert.Error(t, err)
+	_, err = NewBcryptHasher(bcrypt.MaxCost + 1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,13 @@
+package hash
+
+import (
+	"crypto/sha256"
+	"fmt"
+)
+
+// HashToken returns a hex encoded SHA256 digest of a random token.
+// Tokens are long random strings, so a fast unsalted digest is enough to keep
+// the stored value useless for an attacker reading the database.
+func HashToken(token string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(token)))
+}
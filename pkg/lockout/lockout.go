@@ -0,0 +1,157 @@
+// Package lockout slows down guessing of secrets. Failed attempts are counted per key,
+// like an account or an IP address, and the key is blocked for a while that grows with
+// every failure, or locked out after too many of them.
+package lockout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrTooManyAttempts = errors.New("too many failed attempts, try again later")
+	ErrLocked          = errors.New("locked after too many failed attempts")
+)
+
+// BlockedError tells when the key can be tried again, it wraps ErrTooManyAttempts or ErrLocked.
+type BlockedError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *BlockedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *BlockedError) Unwrap() error {
+	return e.Err
+}
+
+// Rule is how failures of a key are punished. The first FreeAttempts failures cost
+// nothing, each next one blocks the key for BaseDelay doubled on every failure up to
+// MaxDelay, which is required with BaseDelay. LockAfter failures lock the key for LockDuration, zero never locks it.
+// Failures are forgotten after Window without a new one.
+type Rule struct {
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	LockAfter    int
+	LockDuration time.Duration
+	Window       time.Duration
+}
+
+// blockedUntil returns when the key with the record can be tried again and whether
+// it is locked rather than delayed.
+func (r Rule) blockedUntil(record Record) (time.Time, bool) {
+	if r.LockAfter > 0 && record.Failures >= r.LockAfter {
+		return record.LastFailure.Add(r.LockDuration), true
+	}
+	if record.Failures <= r.FreeAttempts || r.BaseDelay <= 0 {
+		return time.Time{}, false
+	}
+
+	delay := r.BaseDelay
+	for i := r.FreeAttempts + 1; i < record.Failures && delay < r.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > r.MaxDelay {
+		delay = r.MaxDelay
+	}
+	return record.LastFailure.Add(delay), false
+}
+
+// ttl is how long the store keeps failures of a key, long enough to outlive the block.
+func (r Rule) ttl() time.Duration {
+	ttl := r.Window
+	if r.LockDuration > ttl {
+		ttl = r.LockDuration
+	}
+	if r.MaxDelay > ttl {
+		ttl = r.MaxDelay
+	}
+	return ttl
+}
+
+// Limiter applies the rule to failures kept in the store.
+type Limiter struct {
+	store Store
+	rule  Rule
+	now   func() time.Time
+}
+
+func NewLimiter(store Store, rule Rule) (*Limiter, error) {
+	if rule.ttl() <= 0 {
+		return nil, fmt.Errorf("lockout window must be positive")
+	}
+	if rule.BaseDelay > 0 && rule.MaxDelay < rule.BaseDelay {
+		return nil, fmt.Errorf("lockout max delay must not be less than base delay")
+	}
+	if rule.LockAfter > 0 && rule.LockDuration <= 0 {
+		return nil, fmt.Errorf("lockout duration must be positive when lock after is set")
+	}
+	return &Limiter{
+		store: store,
+		rule:  rule,
+		now:   time.Now,
+	}, nil
+}
+
+// Check returns *BlockedError when the key can't be tried now.
+func (l *Limiter) Check(ctx context.Context, key string) error {
+	record, err := l.store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	return l.blocked(record)
+}
+
+// Attempt counts an attempt of the key before it is made and returns *BlockedError when
+// the key can't be tried now. Attempts are counted atomically, so concurrent ones are
+// judged one after another and no more of them get through than the rule allows. An
+// attempt that succeeds is taken back with Forgive or Reset.
+func (l *Limiter) Attempt(ctx context.Context, key string) error {
+	// Blocked keys aren't counted, so retrying while blocked doesn't extend the block.
+	if err := l.Check(ctx, key); err != nil {
+		return err
+	}
+	previous, err := l.store.Fail(ctx, key, l.now(), l.rule.ttl())
+	if err != nil {
+		return err
+	}
+	return l.blocked(previous)
+}
+
+// Fail records a failed attempt of the key and returns *BlockedError when the key is
+// blocked after it.
+func (l *Limiter) Fail(ctx context.Context, key string) error {
+	now := l.now()
+	previous, err := l.store.Fail(ctx, key, now, l.rule.ttl())
+	if err != nil {
+		return err
+	}
+	return l.blocked(Record{Failures: previous.Failures + 1, LastFailure: now})
+}
+
+// Forgive takes back an attempt of the key counted by Attempt that succeeded.
+func (l *Limiter) Forgive(ctx context.Context, key string) error {
+	return l.store.Forgive(ctx, key)
+}
+
+// Reset forgets failures of the key, after a successful attempt or to unlock it.
+func (l *Limiter) Reset(ctx context.Context, key string) error {
+	return l.store.Reset(ctx, key)
+}
+
+func (l *Limiter) blocked(record Record) error {
+	until, locked := l.rule.blockedUntil(record)
+	retryAfter := until.Sub(l.now())
+	if retryAfter <= 0 {
+		return nil
+	}
+	if locked {
+		return &BlockedError{Err: ErrLocked, RetryAfter: retryAfter}
+	}
+	return &BlockedError{Err: ErrTooManyAttempts, RetryAfter: retryAfter}
+}
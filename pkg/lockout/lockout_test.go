@@ -0,0 +1,244 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleBlockedUntil(t *testing.T) {
+	last := time.Unix(1000, 0)
+	rule := Rule{
+		FreeAttempts: 2,
+		BaseDelay:    time.Second,
+		MaxDelay:     5 * time.Second,
+		LockAfter:    10,
+		LockDuration: time.Hour,
+		Window:       time.Hour,
+	}
+
+	testTable := []struct {
+		name           string
+		failures       int
+		expectedDelay  time.Duration
+		expectedLocked bool
+	}{
+		{
+			name:     "Free attempt",
+			failures: 2,
+		},
+		{
+			name:          "First delay",
+			failures:      3,
+			expectedDelay: time.Second,
+		},
+		{
+			name:          "Doubled delay",
+			failures:      5,
+			expectedDelay: 4 * time.Second,
+		},
+		{
+			name:          "Max delay",
+			failures:      9,
+			expectedDelay: 5 * time.Second,
+		},
+		{
+			name:           "Locked",
+			failures:       10,
+			expectedDelay:  time.Hour,
+			expectedLocked: true,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			until, locked := rule.blockedUntil(Record{Failures: testCase.failures, LastFailure: last})
+			assert.Equal(t, testCase.expectedLocked, locked)
+			if testCase.expectedDelay == 0 {
+				assert.True(t, until.IsZero())
+				return
+			}
+			assert.Equal(t, testCase.expectedDelay, until.Sub(last))
+		})
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	limiter, err := NewLimiter(NewMemoryStore(), Rule{
+		FreeAttempts: 1,
+		BaseDelay:    time.Minute,
+		MaxDelay:     time.Minute,
+		LockAfter:    3,
+		LockDuration: time.Hour,
+		Window:       time.Hour,
+	})
+	require.NoError(t, err)
+
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	require.NoError(t, limiter.Fail(ctx, "key"))
+	require.NoError(t, limiter.Check(ctx, "key"))
+
+	err = limiter.Fail(ctx, "key")
+	var blockedErr *BlockedError
+	require.True(t, errors.As(err, &blockedErr))
+	assert.True(t, errors.Is(err, ErrTooManyAttempts))
+	assert.Equal(t, time.Minute, blockedErr.RetryAfter)
+	assert.ErrorIs(t, limiter.Check(ctx, "key"), ErrTooManyAttempts)
+	assert.NoError(t, limiter.Check(ctx, "other"))
+
+	now = now.Add(time.Minute)
+	require.NoError(t, limiter.Check(ctx, "key"))
+
+	assert.ErrorIs(t, limiter.Fail(ctx, "key"), ErrLocked)
+	now = now.Add(30 * time.Minute)
+	assert.ErrorIs(t, limiter.Check(ctx, "key"), ErrLocked)
+
+	require.NoError(t, limiter.Reset(ctx, "key"))
+	assert.NoError(t, limiter.Check(ctx, "key"))
+}
+
+func TestLimiterAttempt(t *testing.T) {
+	ctx := context.Background()
+	limiter, err := NewLimiter(NewMemoryStore(), Rule{
+		FreeAttempts: 1,
+		BaseDelay:    time.Minute,
+		MaxDelay:     time.Minute,
+		LockAfter:    3,
+		LockDuration: time.Hour,
+		Window:       time.Hour,
+	})
+	require.NoError(t, err)
+
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	// A successful attempt is taken back.
+	require.NoError(t, limiter.Attempt(ctx, "key"))
+	require.NoError(t, limiter.Forgive(ctx, "key"))
+	require.NoError(t, limiter.Attempt(ctx, "key"))
+	require.NoError(t, limiter.Attempt(ctx, "key"))
+
+	err = limiter.Attempt(ctx, "key")
+	var blockedErr *BlockedError
+	require.True(t, errors.As(err, &blockedErr))
+	assert.True(t, errors.Is(err, ErrTooManyAttempts))
+	assert.Equal(t, time.Minute, blockedErr.RetryAfter)
+
+	// Blocked attempts aren't counted.
+	record, err := limiter.store.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, 2, record.Failures)
+
+	now = now.Add(time.Minute)
+	require.NoError(t, limiter.Attempt(ctx, "key"))
+	assert.ErrorIs(t, limiter.Attempt(ctx, "key"), ErrLocked)
+}
+
+func TestLimiterAttempt_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	limiter, err := NewLimiter(NewMemoryStore(), Rule{
+		LockAfter:    5,
+		LockDuration: time.Hour,
+		Window:       time.Hour,
+	})
+	require.NoError(t, err)
+
+	const attempts = 50
+	var allowed int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if limiter.Attempt(ctx, "key") == nil {
+				atomic.AddInt32(&allowed, 1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	assert.Equal(t, int32(5), allowed)
+	assert.ErrorIs(t, limiter.Check(ctx, "key"), ErrLocked)
+}
+
+func TestNewLimiter(t *testing.T) {
+	testTable := []struct {
+		name string
+		rule Rule
+	}{
+		{
+			name: "No window",
+			rule: Rule{FreeAttempts: 1},
+		},
+		{
+			name: "Max delay less than base delay",
+			rule: Rule{BaseDelay: time.Minute, MaxDelay: time.Second, Window: time.Hour},
+		},
+		{
+			name: "Lock without duration",
+			rule: Rule{LockAfter: 5, Window: time.Hour},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := NewLimiter(NewMemoryStore(), testCase.rule)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	now := time.Now()
+
+	// Fail returns the record before the failure.
+	record, err := store.Fail(ctx, "key", now, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, Record{}, record)
+
+	record, err = store.Fail(ctx, "key", now, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, Record{Failures: 1, LastFailure: now}, record)
+
+	record, err = store.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, 2, record.Failures)
+
+	require.NoError(t, store.Forgive(ctx, "key"))
+	record, err = store.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, 1, record.Failures)
+
+	// An expired record starts counting again.
+	record, err = store.Fail(ctx, "key", now.Add(2*time.Hour), time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, Record{}, record)
+	record, err = store.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, 1, record.Failures)
+
+	_, err = store.Fail(ctx, "expired", now.Add(-2*time.Hour), time.Hour)
+	require.NoError(t, err)
+	record, err = store.Get(ctx, "expired")
+	require.NoError(t, err)
+	assert.Equal(t, Record{}, record)
+
+	require.NoError(t, store.Reset(ctx, "key"))
+	record, err = store.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, Record{}, record)
+}
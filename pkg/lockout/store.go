@@ -0,0 +1,103 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+// Record is the count of failed attempts of a key and the time of the last one.
+type Record struct {
+	Failures    int
+	LastFailure time.Time
+}
+
+// Store keeps failures of keys, a missing or expired key has the zero Record.
+type Store interface {
+	Get(ctx context.Context, key string) (Record, error)
+	// Fail counts a failure at now atomically, keeps the record for ttl after it and
+	// returns the record as it was before the failure.
+	Fail(ctx context.Context, key string, now time.Time, ttl time.Duration) (Record, error)
+	// Forgive takes back one failure of the key, when an attempt counted up front succeeds.
+	Forgive(ctx context.Context, key string) error
+	Reset(ctx context.Context, key string) error
+}
+
+var _ Store = &MemoryStore{}
+
+type memoryRecord struct {
+	Record
+	expiresAt time.Time
+}
+
+// MemoryStore is a Store for a single instance of the app.
+type MemoryStore struct {
+	mu        sync.Mutex
+	records   map[string]memoryRecord
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records:   make(map[string]memoryRecord),
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[key]
+	if !ok || !time.Now().Before(record.expiresAt) {
+		return Record{}, nil
+	}
+	return record.Record, nil
+}
+
+func (s *MemoryStore) Fail(ctx context.Context, key string, now time.Time, ttl time.Duration) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > sweepInterval {
+		for k, record := range s.records {
+			if !now.Before(record.expiresAt) {
+				delete(s.records, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	record := s.records[key]
+	if !now.Before(record.expiresAt) {
+		record = memoryRecord{}
+	}
+	previous := record.Record
+	record.Failures++
+	record.LastFailure = now
+	record.expiresAt = now.Add(ttl)
+	s.records[key] = record
+	return previous, nil
+}
+
+func (s *MemoryStore) Forgive(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[key]
+	if ok && record.Failures > 0 {
+		record.Failures--
+		s.records[key] = record
+	}
+	return nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
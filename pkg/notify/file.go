@@ -0,0 +1,68 @@
+package notify
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileMailer drops every message into the directory as an .eml file, which mail
+// clients open, so mail can be read during development without an SMTP server.
+type FileMailer struct {
+	dir string
+}
+
+func NewFileMailer(dir string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory due to error: %v", err)
+	}
+	return &FileMailer{dir: dir}, nil
+}
+
+func (m *FileMailer) Send(_ context.Context, message Message) error {
+	body, err := message.Bytes()
+	if err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Errorf("failed to generate mail file name due to error: %v", err)
+	}
+	name := time.Now().UTC().Format("20060102T150405.000000000") + "-" + hex.EncodeToString(suffix) + ".eml"
+	if err := os.WriteFile(filepath.Join(m.dir, name), body, 0o600); err != nil {
+		return fmt.Errorf("failed to write mail file due to error: %v", err)
+	}
+	return nil
+}
+
+// WriterMailer writes messages one after another, like to os.Stdout.
+type WriterMailer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterMailer(w io.Writer) *WriterMailer {
+	return &WriterMailer{w: w}
+}
+
+func (m *WriterMailer) Send(_ context.Context, message Message) error {
+	body, err := message.Bytes()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.w.Write(append(body, "\r\n\r\n"...)); err != nil {
+		return fmt.Errorf("failed to write mail due to error: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMailer_Send(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	mailer, err := NewFileMailer(dir)
+	require.NoError(t, err)
+
+	require.NoError(t, mailer.Send(context.Background(), newTestMessage()))
+	require.NoError(t, mailer.Send(context.Background(), newTestMessage()))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	content, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "To: <test@test.ru>")
+}
+
+func TestWriterMailer_Send(t *testing.T) {
+	var buf bytes.Buffer
+	mailer := NewWriterMailer(&buf)
+
+	require.NoError(t, mailer.Send(context.Background(), newTestMessage()))
+	assert.Contains(t, buf.String(), "From: \"Users\" <no-reply@test.ru>")
+
+	err := mailer.Send(context.Background(), Message{From: "no-reply@test.ru", Text: "Hello"})
+	assert.ErrorIs(t, err, ErrRejected)
+}
@@ -0,0 +1,148 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueueFull   = errors.New("mail queue is full")
+	ErrQueueClosed = errors.New("mail queue is closed")
+)
+
+type QueueConfig struct {
+	// Size is how many messages wait for delivery before Send fails.
+	Size    int
+	Workers int
+	// MaxAttempts counts the first attempt, messages are dropped after the last one.
+	MaxAttempts int
+	// RetryBackoff is the wait before the second attempt, it doubles on every retry.
+	RetryBackoff time.Duration
+}
+
+// Queue is a Mailer that returns as soon as the message is queued, so requests don't
+// wait for the server. Workers deliver messages through the wrapped Mailer and retry
+// failures except ErrRejected.
+type Queue struct {
+	mailer Mailer
+	config QueueConfig
+
+	mu       sync.RWMutex
+	closed   bool
+	messages chan Message
+	// ctx is cancelled when Close gives up waiting, it stops deliveries and retries.
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewQueue starts the workers, Close stops them.
+func NewQueue(mailer Mailer, config QueueConfig) *Queue {
+	if config.Size <= 0 {
+		config.Size = 100
+	}
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		mailer:   mailer,
+		config:   config,
+		messages: make(chan Message, config.Size),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	q.wg.Add(config.Workers)
+	for i := 0; i < config.Workers; i++ {
+		go q.work()
+	}
+	return q
+}
+
+// Send queues the message. The context only bounds queueing, delivery outlives the request.
+func (q *Queue) Send(ctx context.Context, message Message) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case q.messages <- message:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting messages and waits until the queued ones are delivered or
+// the context is done, then the rest are dropped.
+func (q *Queue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.messages)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.cancel()
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for message := range q.messages {
+		if q.ctx.Err() != nil {
+			log.Printf("mail to %v dropped, queue is closed", message.To)
+			continue
+		}
+		q.deliver(message)
+	}
+}
+
+func (q *Queue) deliver(message Message) {
+	backoff := q.config.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := q.mailer.Send(q.ctx, message)
+		if err == nil {
+			return
+		}
+		if errors.Is(err, ErrRejected) || attempt >= q.config.MaxAttempts {
+			log.Printf("failed to send mail to %v after %d attempts due to error: %v", message.To, attempt, err)
+			return
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-q.ctx.Done():
+			timer.Stop()
+			log.Printf("failed to send mail to %v, queue closed before retry: %v", message.To, err)
+			return
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,103 @@
+package notify
+
+import (
+	"context"
+	"sync"
+	"test/pkg/notify/smtptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueue_Send(t *testing.T) {
+	testTable := []struct {
+		name             string
+		failures         []int
+		expectedMessages int
+	}{
+		{
+			name:             "OK",
+			expectedMessages: 1,
+		},
+		{
+			name:             "Retried after temporary failures",
+			failures:         []int{421, 451},
+			expectedMessages: 1,
+		},
+		{
+			name:             "Rejected isn't retried",
+			failures:         []int{550},
+			expectedMessages: 0,
+		},
+		{
+			name:             "Dropped after max attempts",
+			failures:         []int{451, 451, 451},
+			expectedMessages: 0,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			server := smtptest.NewServer(t)
+			server.Fail(testCase.failures...)
+			mailer, err := NewSMTPMailer(SMTPConfig{Host: server.Host(), Port: server.Port()})
+			require.NoError(t, err)
+
+			queue := NewQueue(mailer, QueueConfig{Size: 1, Workers: 1, MaxAttempts: 3, RetryBackoff: time.Millisecond})
+			require.NoError(t, queue.Send(context.Background(), newTestMessage()))
+			require.NoError(t, queue.Close(context.Background()))
+
+			assert.Len(t, server.Messages(), testCase.expectedMessages)
+		})
+	}
+}
+
+// blockingMailer holds deliveries until release is closed.
+type blockingMailer struct {
+	release chan struct{}
+	mu      sync.Mutex
+	sent    int
+}
+
+func (m *blockingMailer) Send(ctx context.Context, _ Message) error {
+	select {
+	case <-m.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent++
+	return nil
+}
+
+func TestQueue_SendFull(t *testing.T) {
+	mailer := &blockingMailer{release: make(chan struct{})}
+	queue := NewQueue(mailer, QueueConfig{Size: 1, Workers: 1, MaxAttempts: 1})
+
+	// The worker takes the first message and blocks, the second waits in the queue.
+	require.NoError(t, queue.Send(context.Background(), newTestMessage()))
+	require.Eventually(t, func() bool { return len(queue.messages) == 0 }, time.Second, time.Millisecond)
+	require.NoError(t, queue.Send(context.Background(), newTestMessage()))
+	assert.ErrorIs(t, queue.Send(context.Background(), newTestMessage()), ErrQueueFull)
+
+	close(mailer.release)
+	require.NoError(t, queue.Close(context.Background()))
+	assert.Equal(t, 2, mailer.sent)
+	assert.ErrorIs(t, queue.Send(context.Background(), newTestMessage()), ErrQueueClosed)
+}
+
+func TestQueue_CloseTimeout(t *testing.T) {
+	mailer := &blockingMailer{release: make(chan struct{})}
+	queue := NewQueue(mailer, QueueConfig{Size: 10, Workers: 1, MaxAttempts: 1})
+	for i := 0; i < 3; i++ {
+		require.NoError(t, queue.Send(context.Background(), newTestMessage()))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, queue.Close(ctx), context.DeadlineExceeded)
+	assert.Equal(t, 0, mailer.sent)
+}
@@ -0,0 +1,163 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+const (
+	// TLSStartTLS upgrades the connection when the server offers STARTTLS.
+	TLSStartTLS = "starttls"
+	// TLSImplicit connects over TLS, usually to port 465.
+	TLSImplicit = "tls"
+	// TLSNone never encrypts, meant for servers on localhost.
+	TLSNone = "none"
+
+	defaultSMTPTimeout = 30 * time.Second
+)
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// TLS is TLSStartTLS, TLSImplicit or TLSNone, empty means TLSStartTLS.
+	TLS string
+	// Timeout bounds the whole delivery when the context has no deadline.
+	Timeout time.Duration
+	// TLSConfig overrides the default one verifying the server certificate for Host.
+	TLSConfig *tls.Config
+}
+
+// SMTPMailer sends every message over a new connection to the server, authenticating
+// with PLAIN when a username is configured.
+type SMTPMailer struct {
+	config SMTPConfig
+}
+
+func NewSMTPMailer(config SMTPConfig) (*SMTPMailer, error) {
+	if config.Host == "" {
+		return nil, errors.New("smtp host is required")
+	}
+	switch config.TLS {
+	case "":
+		config.TLS = TLSStartTLS
+	case TLSStartTLS, TLSImplicit, TLSNone:
+	default:
+		return nil, fmt.Errorf("unknown smtp tls mode %q", config.TLS)
+	}
+	if config.Timeout == 0 {
+		config.Timeout = defaultSMTPTimeout
+	}
+	if config.TLSConfig == nil {
+		config.TLSConfig = &tls.Config{ServerName: config.Host, MinVersion: tls.VersionTLS12}
+	}
+	return &SMTPMailer{config: config}, nil
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, message Message) error {
+	body, err := message.Bytes()
+	if err != nil {
+		return err
+	}
+	from, err := mail.ParseAddress(message.From)
+	if err != nil {
+		return fmt.Errorf("%w: invalid sender: %v", ErrRejected, err)
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.config.Timeout)
+		defer cancel()
+	}
+
+	client, err := m.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.Mail(from.Address); err != nil {
+		return smtpError("MAIL FROM", err)
+	}
+	for _, recipient := range message.To {
+		to, err := mail.ParseAddress(recipient)
+		if err != nil {
+			return fmt.Errorf("%w: invalid recipient: %v", ErrRejected, err)
+		}
+		if err := client.Rcpt(to.Address); err != nil {
+			return smtpError("RCPT TO", err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return smtpError("DATA", err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return smtpError("DATA", err)
+	}
+	if err := w.Close(); err != nil {
+		return smtpError("DATA", err)
+	}
+	return client.Quit()
+}
+
+func (m *SMTPMailer) dial(ctx context.Context) (*smtp.Client, error) {
+	address := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to smtp server due to error: %v", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if m.config.TLS == TLSImplicit {
+		conn = tls.Client(conn, m.config.TLSConfig)
+	}
+
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, smtpError("greeting", err)
+	}
+
+	if m.config.TLS == TLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(m.config.TLSConfig); err != nil {
+				client.Close()
+				return nil, smtpError("STARTTLS", err)
+			}
+		}
+	}
+
+	if m.config.Username != "" {
+		// PlainAuth refuses to send the password over an unencrypted connection
+		// unless the server is on localhost.
+		auth := smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+		if err := client.Auth(auth); err != nil {
+			client.Close()
+			return nil, smtpError("AUTH", err)
+		}
+	}
+	return client, nil
+}
+
+// smtpError marks permanent 5xx replies as rejected, other errors may be retried.
+func smtpError(command string, err error) error {
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return fmt.Errorf("%w: %s: %v", ErrRejected, command, err)
+	}
+	return fmt.Errorf("smtp %s failed due to error: %v", command, err)
+}
@@ -0,0 +1,135 @@
+package notify
+
+import (
+	"context"
+	"test/pkg/notify/smtptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMessage() Message {
+	return Message{
+		From:    "Users <no-reply@test.ru>",
+		To:      []string{"test@test.ru"},
+		Subject: "Сброс пароля",
+		Text:    "Token: abc\n.line starting with a dot\n",
+		HTML:    "<p>Token: <b>abc</b></p>",
+	}
+}
+
+func TestSMTPMailer_Send(t *testing.T) {
+	testTable := []struct {
+		name        string
+		username    string
+		password    string
+		failures    []int
+		message     Message
+		expectedErr error
+		expectError bool
+	}{
+		{
+			name:    "OK",
+			message: newTestMessage(),
+		},
+		{
+			name:     "With auth",
+			username: "user",
+			password: "secret",
+			message:  newTestMessage(),
+		},
+		{
+			name:    "Text only",
+			message: Message{From: "no-reply@test.ru", To: []string{"test@test.ru"}, Subject: "Hi", Text: "Hello\n"},
+		},
+		{
+			name:        "Rejected",
+			failures:    []int{554},
+			message:     newTestMessage(),
+			expectedErr: ErrRejected,
+			expectError: true,
+		},
+		{
+			name:        "Temporary failure",
+			failures:    []int{451},
+			message:     newTestMessage(),
+			expectError: true,
+		},
+		{
+			name:        "Invalid recipient",
+			message:     Message{From: "no-reply@test.ru", To: []string{"not an address"}, Text: "Hello"},
+			expectedErr: ErrRejected,
+			expectError: true,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			server := smtptest.NewServer(t)
+			server.Username = testCase.username
+			server.Password = testCase.password
+			server.Fail(testCase.failures...)
+
+			mailer, err := NewSMTPMailer(SMTPConfig{
+				Host:     server.Host(),
+				Port:     server.Port(),
+				Username: testCase.username,
+				Password: testCase.password,
+			})
+			require.NoError(t, err)
+
+			err = mailer.Send(context.Background(), testCase.message)
+			if testCase.expectError {
+				assert.Error(t, err)
+				if testCase.expectedErr != nil {
+					assert.ErrorIs(t, err, testCase.expectedErr)
+				} else {
+					assert.NotErrorIs(t, err, ErrRejected)
+				}
+				assert.Empty(t, server.Messages())
+				return
+			}
+			require.NoError(t, err)
+
+			messages := server.Messages()
+			require.Len(t, messages, 1)
+			assert.Equal(t, "no-reply@test.ru", messages[0].From)
+			assert.Equal(t, []string{"test@test.ru"}, messages[0].To)
+
+			subject, err := messages[0].Header("Subject")
+			require.NoError(t, err)
+			assert.Equal(t, testCase.message.Subject, subject)
+
+			bodies, err := messages[0].Bodies()
+			require.NoError(t, err)
+			assert.Equal(t, testCase.message.Text, bodies["text/plain"])
+			if testCase.message.HTML != "" {
+				assert.Equal(t, testCase.message.HTML, bodies["text/html"])
+			} else {
+				assert.NotContains(t, bodies, "text/html")
+			}
+		})
+	}
+}
+
+func TestSMTPMailer_SendWrongPassword(t *testing.T) {
+	server := smtptest.NewServer(t)
+	server.Username = "user"
+	server.Password = "secret"
+
+	mailer, err := NewSMTPMailer(SMTPConfig{Host: server.Host(), Port: server.Port(), Username: "user", Password: "wrong"})
+	require.NoError(t, err)
+
+	err = mailer.Send(context.Background(), newTestMessage())
+	assert.ErrorIs(t, err, ErrRejected)
+	assert.Empty(t, server.Messages())
+}
+
+func TestNewSMTPMailer(t *testing.T) {
+	_, err := NewSMTPMailer(SMTPConfig{Host: "localhost", Port: 25, TLS: "ssl"})
+	assert.Error(t, err)
+
+	_, err = NewSMTPMailer(SMTPConfig{Port: 25})
+	assert.Error(t, err)
+}
@@ -0,0 +1,323 @@
+// Package smtptest provides an in-process SMTP server for tests.
+package smtptest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Server accepts mail on localhost without TLS. It requires AUTH PLAIN when Username
+// is set, and Fail makes it refuse the next messages.
+type Server struct {
+	Username string
+	Password string
+
+	listener net.Listener
+	mu       sync.Mutex
+	messages []Message
+	failures []int
+	conns    map[net.Conn]bool
+	received chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Message is the envelope and the raw data the client sent.
+type Message struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s := &Server{
+		listener: listener,
+		conns:    make(map[net.Conn]bool),
+		received: make(chan struct{}, 1),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *Server) Host() string {
+	return "127.0.0.1"
+}
+
+func (s *Server) Port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *Server) Close() {
+	s.listener.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// Fail replies with the codes to the next messages instead of accepting them,
+// 4xx codes are temporary failures and 5xx are permanent.
+func (s *Server) Fail(codes ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = append(s.failures, codes...)
+}
+
+// Messages returns accepted messages in the order they were received.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Message(nil), s.messages...)
+}
+
+// WaitForMessages waits until n messages are accepted, for mail sent in background.
+func (s *Server) WaitForMessages(t testing.TB, n int) []Message {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		if messages := s.Messages(); len(messages) >= n {
+			return messages
+		}
+		select {
+		case <-s.received:
+		case <-timeout:
+			t.Fatalf("got %d messages, want %d", len(s.Messages()), n)
+		}
+	}
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = true
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.session(conn)
+
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+			conn.Close()
+		}()
+	}
+}
+
+func (s *Server) session(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	reply := func(format string, args ...interface{}) {
+		fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+
+	reply("220 smtptest ready")
+	var message Message
+	authenticated := s.Username == ""
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(command) {
+		case "EHLO":
+			if s.Username != "" {
+				reply("250-smtptest")
+				reply("250 AUTH PLAIN")
+			} else {
+				reply("250 smtptest")
+			}
+		case "HELO":
+			reply("250 smtptest")
+		case "AUTH":
+			if s.authenticate(arg) {
+				authenticated = true
+				reply("235 authenticated")
+			} else {
+				reply("535 invalid credentials")
+			}
+		case "MAIL":
+			if !authenticated {
+				reply("530 authentication required")
+				continue
+			}
+			message = Message{From: address(arg)}
+			reply("250 ok")
+		case "RCPT":
+			message.To = append(message.To, address(arg))
+			reply("250 ok")
+		case "DATA":
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			data, err := readData(r)
+			if err != nil {
+				return
+			}
+			if code := s.nextFailure(); code != 0 {
+				reply("%d failed", code)
+				continue
+			}
+			message.Data = data
+			s.accept(message)
+			reply("250 ok")
+		case "RSET":
+			message = Message{}
+			reply("250 ok")
+		case "NOOP":
+			reply("250 ok")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 unknown command")
+		}
+	}
+}
+
+func (s *Server) authenticate(arg string) bool {
+	mechanism, initial, _ := strings.Cut(arg, " ")
+	if !strings.EqualFold(mechanism, "PLAIN") {
+		return false
+	}
+	credentials, err := base64.StdEncoding.DecodeString(initial)
+	if err != nil {
+		return false
+	}
+	parts := strings.Split(string(credentials), "\x00")
+	return len(parts) == 3 && parts[1] == s.Username && parts[2] == s.Password
+}
+
+func (s *Server) nextFailure() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.failures) == 0 {
+		return 0
+	}
+	code := s.failures[0]
+	s.failures = s.failures[1:]
+	return code
+}
+
+func (s *Server) accept(message Message) {
+	s.mu.Lock()
+	s.messages = append(s.messages, message)
+	s.mu.Unlock()
+
+	select {
+	case s.received <- struct{}{}:
+	default:
+	}
+}
+
+// address returns the address of "FROM:<a@b.c> SIZE=1" arguments.
+func address(arg string) string {
+	_, path, _ := strings.Cut(arg, ":")
+	path, _, _ = strings.Cut(strings.TrimSpace(path), " ")
+	return strings.Trim(path, "<>")
+}
+
+func readData(r *bufio.Reader) ([]byte, error) {
+	var data bytes.Buffer
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if line == ".\r\n" {
+			return data.Bytes(), nil
+		}
+		data.WriteString(strings.TrimPrefix(line, "."))
+	}
+}
+
+// Header returns the decoded header of the message.
+func (m Message) Header(key string) (string, error) {
+	message, err := mail.ReadMessage(bytes.NewReader(m.Data))
+	if err != nil {
+		return "", err
+	}
+	return new(mime.WordDecoder).DecodeHeader(message.Header.Get(key))
+}
+
+// Bodies returns decoded bodies with LF line endings by their media type, like "text/plain".
+func (m Message) Bodies() (map[string]string, error) {
+	message, err := mail.ReadMessage(bytes.NewReader(m.Data))
+	if err != nil {
+		return nil, err
+	}
+	mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+
+	bodies := make(map[string]string)
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		body, err := decode(message.Body, message.Header.Get("Content-Transfer-Encoding"))
+		if err != nil {
+			return nil, err
+		}
+		bodies[mediaType] = body
+		return bodies, nil
+	}
+
+	parts := multipart.NewReader(message.Body, params["boundary"])
+	for {
+		part, err := parts.NextRawPart()
+		if err == io.EOF {
+			return bodies, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		partType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if err != nil {
+			return nil, err
+		}
+		body, err := decode(part, part.Header.Get("Content-Transfer-Encoding"))
+		if err != nil {
+			return nil, err
+		}
+		bodies[partType] = body
+	}
+}
+
+func decode(r io.Reader, encoding string) (string, error) {
+	if strings.EqualFold(encoding, "quoted-printable") {
+		r = quotedprintable.NewReader(r)
+	}
+	body, err := io.ReadAll(r)
+	return strings.ReplaceAll(string(body), "\r\n", "\n"), err
+}
@@ -0,0 +1,105 @@
+package notify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+)
+
+const (
+	textExtension = ".txt"
+	htmlExtension = ".html"
+	// subjectTemplate is defined in the text file of every message.
+	subjectTemplate = "subject"
+)
+
+var (
+	ErrUnknownTemplate = errors.New("unknown template")
+)
+
+// Templates render messages from a directory where a message named "welcome" is
+// welcome.txt with the text body and {{define "subject"}}, and optional welcome.html.
+// Templates fail on data keys they don't get, so a typo doesn't send a blank link.
+type Templates struct {
+	messages map[string]messageTemplate
+}
+
+type messageTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+func LoadTemplates(dir string) (*Templates, error) {
+	textFiles, err := filepath.Glob(filepath.Join(dir, "*"+textExtension))
+	if err != nil {
+		return nil, err
+	}
+	if len(textFiles) == 0 {
+		return nil, fmt.Errorf("no mail templates in %s", dir)
+	}
+
+	templates := &Templates{messages: make(map[string]messageTemplate, len(textFiles))}
+	for _, textFile := range textFiles {
+		name := strings.TrimSuffix(filepath.Base(textFile), textExtension)
+
+		text, err := os.ReadFile(textFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read mail template due to error: %v", err)
+		}
+		var message messageTemplate
+		message.text, err = texttemplate.New(name).Option("missingkey=error").Parse(string(text))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse mail template %s due to error: %v", name, err)
+		}
+		if message.text.Lookup(subjectTemplate) == nil {
+			return nil, fmt.Errorf("mail template %s doesn't define the %s", name, subjectTemplate)
+		}
+
+		html, err := os.ReadFile(filepath.Join(dir, name+htmlExtension))
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return nil, fmt.Errorf("failed to read mail template due to error: %v", err)
+		default:
+			message.html, err = htmltemplate.New(name).Option("missingkey=error").Parse(string(html))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse mail template %s due to error: %v", name, err)
+			}
+		}
+
+		templates.messages[name] = message
+	}
+	return templates, nil
+}
+
+// Render returns the message with the subject and bodies, the caller sets From and To.
+func (t *Templates) Render(name string, data interface{}) (Message, error) {
+	message, ok := t.messages[name]
+	if !ok {
+		return Message{}, fmt.Errorf("%w %q", ErrUnknownTemplate, name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := message.text.ExecuteTemplate(&subject, subjectTemplate, data); err != nil {
+		return Message{}, err
+	}
+	if err := message.text.Execute(&text, data); err != nil {
+		return Message{}, err
+	}
+	if message.html != nil {
+		if err := message.html.Execute(&html, data); err != nil {
+			return Message{}, err
+		}
+	}
+
+	return Message{
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTemplates(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+	return dir
+}
+
+func TestTemplates_Render(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"reset.txt": `{{define "subject"}}Reset
+			password{{end}}
+Open {{.link}}
+`,
+		"reset.html":  `<a href="{{.link}}">Reset</a>`,
+		"welcome.txt": `{{define "subject"}}Welcome, {{.name}}{{end}}Hello`,
+	})
+	templates, err := LoadTemplates(dir)
+	require.NoError(t, err)
+
+	testTable := []struct {
+		name            string
+		template        string
+		data            map[string]string
+		expectedMessage Message
+		expectedErr     error
+		expectError     bool
+	}{
+		{
+			name:     "OK",
+			template: "reset",
+			data:     map[string]string{"link": "http://test.ru/?a=1&b=<2>"},
+			expectedMessage: Message{
+				Subject: "Reset password",
+				Text:    "Open http://test.ru/?a=1&b=<2>\n",
+				HTML:    `<a href="http://test.ru/?a=1&amp;b=%3c2%3e">Reset</a>`,
+			},
+		},
+		{
+			name:            "Text only",
+			template:        "welcome",
+			data:            map[string]string{"name": "Test"},
+			expectedMessage: Message{Subject: "Welcome, Test", Text: "Hello\n"},
+		},
+		{
+			name:        "Missing data",
+			template:    "reset",
+			data:        map[string]string{},
+			expectError: true,
+		},
+		{
+			name:        "Unknown template",
+			template:    "unknown",
+			expectedErr: ErrUnknownTemplate,
+			expectError: true,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			message, err := templates.Render(testCase.template, testCase.data)
+			if testCase.expectError {
+				assert.Error(t, err)
+				if testCase.expectedErr != nil {
+					assert.ErrorIs(t, err, testCase.expectedErr)
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedMessage, message)
+		})
+	}
+}
+
+func TestLoadTemplates(t *testing.T) {
+	_, err := LoadTemplates(writeTemplates(t, map[string]string{"reset.txt": "No subject"}))
+	assert.Error(t, err)
+
+	_, err = LoadTemplates(writeTemplates(t, map[string]string{"reset.txt": `{{define "subject"}}{{end}}{{.link`}))
+	assert.Error(t, err)
+
+	_, err = LoadTemplates(t.TempDir())
+	assert.Error(t, err)
+}
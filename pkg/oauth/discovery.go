@@ -0,0 +1,157 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"test/pkg/api/auth"
+	"time"
+)
+
+const (
+	discoveryPath = "/.well-known/openid-configuration"
+	// keysRequestInterval is how often keys may be requested again for unknown key ids.
+	keysRequestInterval = time.Minute
+)
+
+// Metadata is the part of the OpenID provider metadata we use, see OpenID Connect Discovery 1.0.
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// discover returns the provider endpoints, the metadata is requested once for OpenID
+// Connect providers and the configured urls are used as they are for others.
+func (p *Provider) discover(ctx context.Context) (Metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return *p.metadata, nil
+	}
+
+	var metadata Metadata
+	if p.isOIDC() {
+		url := strings.TrimSuffix(p.config.Issuer, "/") + discoveryPath
+		if err := getJSON(ctx, p.client, url, &metadata); err != nil {
+			return Metadata{}, fmt.Errorf("failed to discover %s provider due to error: %v", p.config.Name, err)
+		}
+		if metadata.Issuer != p.config.Issuer {
+			return Metadata{}, fmt.Errorf("%s provider issuer %q doesn't match configured %q",
+				p.config.Name, metadata.Issuer, p.config.Issuer)
+		}
+		if metadata.JWKSURI == "" {
+			return Metadata{}, fmt.Errorf("%s provider has no jwks_uri", p.config.Name)
+		}
+	}
+	if p.config.AuthURL != "" {
+		metadata.AuthorizationEndpoint = p.config.AuthURL
+	}
+	if p.config.TokenURL != "" {
+		metadata.TokenEndpoint = p.config.TokenURL
+	}
+	if p.config.UserInfoURL != "" {
+		metadata.UserInfoEndpoint = p.config.UserInfoURL
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" {
+		return Metadata{}, fmt.Errorf("%s provider has no authorization or token endpoint", p.config.Name)
+	}
+
+	p.metadata = &metadata
+	return metadata, nil
+}
+
+// key returns the provider key the ID token is signed with. Keys are requested again
+// when the key id is unknown, this is how rotated keys are picked up. Keys are requested
+// outside the lock and one request at a time, lookups during a request wait for it and
+// share its error. After keys are stored they aren't requested again for unknown key
+// ids within keysRequestInterval, so tokens with made up key ids can't make us flood
+// the provider.
+func (p *Provider) key(ctx context.Context, jwksURI, kid string) (interface{}, error) {
+	for {
+		p.mu.Lock()
+		if key, ok := p.keys[kid]; ok {
+			p.mu.Unlock()
+			return key, nil
+		}
+		if request := p.keysRequest; request != nil {
+			p.mu.Unlock()
+			select {
+			case <-request.done:
+				if request.err != nil {
+					return nil, request.err
+				}
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		if !p.keysRequestedAt.IsZero() && p.now().Sub(p.keysRequestedAt) < keysRequestInterval {
+			p.mu.Unlock()
+			return nil, fmt.Errorf("unknown key id %q of %s provider", kid, p.config.Name)
+		}
+		request := &keysRequest{done: make(chan struct{})}
+		p.keysRequest = request
+		p.mu.Unlock()
+
+		keys, err := p.requestKeys(ctx, jwksURI)
+
+		p.mu.Lock()
+		if err == nil {
+			p.keys = keys
+			p.keysRequestedAt = p.now()
+		}
+		request.err = err
+		p.keysRequest = nil
+		close(request.done)
+		p.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *Provider) requestKeys(ctx context.Context, jwksURI string) (map[string]interface{}, error) {
+	var jwks auth.JWKS
+	if err := getJSON(ctx, p.client, jwksURI, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to request %s provider keys due to error: %v", p.config.Name, err)
+	}
+	keys := make(map[string]interface{}, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
@@ -0,0 +1,40 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// idTokenMethods are the asymmetric algorithms ID tokens may be signed with. HMAC is
+// refused, the client secret isn't meant to be a signing key.
+var idTokenMethods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// verifyIDToken checks the signature, issuer, audience, expiration and nonce of the ID
+// token and returns its claims, see OpenID Connect Core 1.0 section 3.1.3.7.
+func (p *Provider) verifyIDToken(ctx context.Context, metadata Metadata, rawIDToken, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	parser := jwt.Parser{ValidMethods: idTokenMethods, UseJSONNumber: true}
+	_, err := parser.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, metadata.JWKSURI, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	if !claims.VerifyIssuer(p.config.Issuer, true) {
+		return nil, fmt.Errorf("%w: unexpected issuer", ErrInvalidIDToken)
+	}
+	if !claims.VerifyAudience(p.config.ClientId, true) {
+		return nil, fmt.Errorf("%w: unexpected audience", ErrInvalidIDToken)
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("%w: no expiration", ErrInvalidIDToken)
+	}
+	if tokenNonce, _ := claims["nonce"].(string); nonce == "" || tokenNonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	return claims, nil
+}
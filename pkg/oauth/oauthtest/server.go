@@ -0,0 +1,228 @@
+// Package oauthtest provides a fake OpenID Connect provider for tests.
+package oauthtest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"test/pkg/api/auth"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	ClientId     = "test-client"
+	ClientSecret = "test-secret"
+	keyId        = "test-key"
+)
+
+// Server is a provider with discovery, token, userinfo and jwks endpoints. The consent
+// page is skipped, Authorize turns the auth code url into a code right away.
+type Server struct {
+	*httptest.Server
+
+	// JWKSFailures is how many of the next key requests fail with 500.
+	JWKSFailures int
+	// Claims are returned by userinfo and put into ID tokens, where they override the
+	// standard claims, so tests can issue tokens with a wrong nonce or audience.
+	Claims map[string]interface{}
+
+	key   *rsa.PrivateKey
+	mu    sync.Mutex
+	codes map[string]authorization
+	// tokens are the issued access tokens.
+	tokens       map[string]bool
+	jwksRequests int
+}
+
+type authorization struct {
+	nonce         string
+	codeChallenge string
+}
+
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	s := &Server{
+		Claims: map[string]interface{}{"sub": "subject", "email": "test@test.ru", "email_verified": true},
+		key:    key,
+		codes:  make(map[string]authorization),
+		tokens: make(map[string]bool),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/token", s.token)
+	mux.HandleFunc("/userinfo", s.userInfo)
+	mux.HandleFunc("/jwks", s.jwks)
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *Server) Issuer() string {
+	return s.URL
+}
+
+// Authorize grants the auth code url as if the user consented and returns the code.
+func (s *Server) Authorize(t testing.TB, authCodeURL string) string {
+	t.Helper()
+
+	u, err := url.Parse(authCodeURL)
+	if err != nil {
+		t.Fatalf("failed to parse auth code url: %v", err)
+	}
+	query := u.Query()
+	if query.Get("client_id") != ClientId {
+		t.Fatalf("unexpected client id %q", query.Get("client_id"))
+	}
+	if method := query.Get("code_challenge_method"); method != "" && method != "S256" {
+		t.Fatalf("unexpected code challenge method %q", method)
+	}
+
+	code := randomString(t)
+	s.mu.Lock()
+	s.codes[code] = authorization{nonce: query.Get("nonce"), codeChallenge: query.Get("code_challenge")}
+	s.mu.Unlock()
+	return code
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 s.Issuer(),
+		"authorization_endpoint": s.URL + "/authorize",
+		"token_endpoint":         s.URL + "/token",
+		"userinfo_endpoint":      s.URL + "/userinfo",
+		"jwks_uri":               s.URL + "/jwks",
+	})
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	clientId, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientId, clientSecret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+	}
+	if clientId != ClientId || clientSecret != ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	s.mu.Lock()
+	code := r.PostFormValue("code")
+	authorization, ok := s.codes[code]
+	delete(s.codes, code)
+	s.mu.Unlock()
+	if !ok || r.PostFormValue("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	if authorization.codeChallenge != "" {
+		sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != authorization.codeChallenge {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+	}
+
+	claims := jwt.MapClaims{
+		"iss": s.Issuer(),
+		"aud": ClientId,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	if authorization.nonce != "" {
+		claims["nonce"] = authorization.nonce
+	}
+	s.mu.Lock()
+	for name, value := range s.Claims {
+		claims[name] = value
+	}
+	accessToken := base64.RawURLEncoding.EncodeToString([]byte(code))
+	s.tokens[accessToken] = true
+	s.mu.Unlock()
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = keyId
+	signed, err := idToken.SignedString(s.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     signed,
+	})
+}
+
+func (s *Server) userInfo(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")] {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.Claims)
+}
+
+// JWKSRequests returns how many times the keys were requested.
+func (s *Server) JWKSRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jwksRequests
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.jwksRequests++
+	fail := s.JWKSFailures > 0
+	if fail {
+		s.JWKSFailures--
+	}
+	s.mu.Unlock()
+	if fail {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, auth.JWKS{Keys: []auth.JWK{{
+		Kty: "RSA",
+		Kid: keyId,
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+	}}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v) //nolint:errcheck
+}
+
+func randomString(t testing.TB) string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,245 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown oauth provider")
+	ErrInvalidIDToken  = errors.New("invalid id token")
+)
+
+// Config describes a provider. OpenID Connect providers need the issuer only, their
+// endpoints and keys are discovered. Plain OAuth2 providers like GitHub set the auth,
+// token and userinfo urls instead, explicit urls win over discovered ones.
+type Config struct {
+	Name         string
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	PKCE         bool
+	Claims       ClaimMapping
+}
+
+// ClaimMapping names the ID token or userinfo claims the identity is read from.
+// Empty names default to the standard sub, email and email_verified claims.
+type ClaimMapping struct {
+	Subject       string
+	Email         string
+	EmailVerified string
+}
+
+// Identity is the account of the user at the provider.
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+// AuthRequest holds the secrets of one sign in, kept by the browser between the login
+// and the callback: the state against CSRF, the nonce bound to the ID token and
+// the PKCE code verifier.
+type AuthRequest struct {
+	State        string
+	Nonce        string
+	CodeVerifier string
+}
+
+func NewAuthRequest() (AuthRequest, error) {
+	var request AuthRequest
+	for _, value := range []*string{&request.State, &request.Nonce, &request.CodeVerifier} {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return AuthRequest{}, errors.New("failed to generate oauth auth request")
+		}
+		*value = base64.RawURLEncoding.EncodeToString(b)
+	}
+	return request, nil
+}
+
+func (r AuthRequest) codeChallenge() string {
+	sum := sha256.Sum256([]byte(r.CodeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+type Provider struct {
+	config Config
+	client *http.Client
+
+	mu       sync.Mutex
+	metadata *Metadata
+	keys     map[string]interface{}
+	// keysRequest is the request of keys in progress, keysRequestedAt is when keys
+	// were stored last.
+	keysRequest     *keysRequest
+	keysRequestedAt time.Time
+	now             func() time.Time
+}
+
+// keysRequest is done once the keys are stored or err is set.
+type keysRequest struct {
+	done chan struct{}
+	err  error
+}
+
+// NewProvider doesn't make requests, discovery happens on first use and is retried
+// until it succeeds, so a provider being down doesn't stop the server from starting.
+func NewProvider(config Config, client *http.Client) (*Provider, error) {
+	if config.Name == "" {
+		return nil, errors.New("oauth provider has no name")
+	}
+	if config.Issuer == "" && (config.AuthURL == "" || config.TokenURL == "" || config.UserInfoURL == "") {
+		return nil, fmt.Errorf("oauth provider %s needs either issuer or auth, token and userinfo urls", config.Name)
+	}
+	if config.Claims.Subject == "" {
+		config.Claims.Subject = "sub"
+	}
+	if config.Claims.Email == "" {
+		config.Claims.Email = "email"
+	}
+	if config.Claims.EmailVerified == "" {
+		config.Claims.EmailVerified = "email_verified"
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Provider{config: config, client: client, now: time.Now}, nil
+}
+
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+func (p *Provider) isOIDC() bool {
+	return p.config.Issuer != ""
+}
+
+// AuthCodeURL returns the consent page url the user is redirected to.
+func (p *Provider) AuthCodeURL(ctx context.Context, request AuthRequest) (string, error) {
+	config, _, err := p.oauth2Config(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var options []oauth2.AuthCodeOption
+	if p.isOIDC() {
+		options = append(options, oauth2.SetAuthURLParam("nonce", request.Nonce))
+	}
+	if p.config.PKCE {
+		options = append(options,
+			oauth2.SetAuthURLParam("code_challenge", request.codeChallenge()),
+			oauth2.SetAuthURLParam("code_challenge_method", "S256"))
+	}
+	return config.AuthCodeURL(request.State, options...), nil
+}
+
+// Exchange redeems the authorization code and returns the identity of the user. The ID
+// token of OpenID Connect providers is verified, userinfo is requested when the token
+// lacks the email or the provider isn't OpenID Connect one.
+func (p *Provider) Exchange(ctx context.Context, code string, request AuthRequest) (Identity, error) {
+	config, metadata, err := p.oauth2Config(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, p.client)
+	var options []oauth2.AuthCodeOption
+	if p.config.PKCE {
+		options = append(options, oauth2.SetAuthURLParam("code_verifier", request.CodeVerifier))
+	}
+	token, err := config.Exchange(ctx, code, options...)
+	if err != nil {
+		return Identity{}, fmt.Errorf("failed to exchange authorization code due to error: %v", err)
+	}
+
+	claims := map[string]interface{}{}
+	if p.isOIDC() {
+		rawIDToken, _ := token.Extra("id_token").(string)
+		if rawIDToken == "" {
+			return Identity{}, fmt.Errorf("%w: token response has no id token", ErrInvalidIDToken)
+		}
+		if claims, err = p.verifyIDToken(ctx, metadata, rawIDToken, request.Nonce); err != nil {
+			return Identity{}, err
+		}
+	}
+
+	if _, ok := claims[p.config.Claims.Email]; (!ok || !p.isOIDC()) && metadata.UserInfoEndpoint != "" {
+		var userInfo map[string]interface{}
+		if err := getJSON(ctx, config.Client(ctx, token), metadata.UserInfoEndpoint, &userInfo); err != nil {
+			return Identity{}, fmt.Errorf("failed to request userinfo due to error: %v", err)
+		}
+		if p.isOIDC() && userInfo["sub"] != claims["sub"] {
+			return Identity{}, errors.New("userinfo subject doesn't match id token")
+		}
+		for name, value := range userInfo {
+			if _, ok := claims[name]; !ok {
+				claims[name] = value
+			}
+		}
+	}
+
+	return p.identity(claims)
+}
+
+func (p *Provider) identity(claims map[string]interface{}) (Identity, error) {
+	identity := Identity{
+		Provider: p.config.Name,
+		Subject:  claimString(claims[p.config.Claims.Subject]),
+		Email:    claimString(claims[p.config.Claims.Email]),
+	}
+	if identity.Subject == "" {
+		return Identity{}, fmt.Errorf("%s identity has no %s claim", p.config.Name, p.config.Claims.Subject)
+	}
+	identity.EmailVerified, _ = strconv.ParseBool(claimString(claims[p.config.Claims.EmailVerified]))
+	return identity, nil
+}
+
+// claimString formats string, number and boolean claims, numeric ids like GitHub's included.
+func claimString(claim interface{}) string {
+	switch value := claim.(type) {
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	}
+	return ""
+}
+
+func (p *Provider) oauth2Config(ctx context.Context) (*oauth2.Config, Metadata, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	return &oauth2.Config{
+		ClientID:     p.config.ClientId,
+		ClientSecret: p.config.ClientSecret,
+		RedirectURL:  p.config.RedirectURL,
+		Scopes:       p.config.Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  metadata.AuthorizationEndpoint,
+			TokenURL: metadata.TokenEndpoint,
+		},
+	}, metadata, nil
+}
@@ -0,0 +1,245 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"test/pkg/oauth/oauthtest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newOIDCProvider(t *testing.T, server *oauthtest.Server, pkce bool) *Provider {
+	t.Helper()
+
+	provider, err := NewProvider(Config{
+		Name:         "test",
+		Issuer:       server.Issuer(),
+		ClientId:     oauthtest.ClientId,
+		ClientSecret: oauthtest.ClientSecret,
+		RedirectURL:  "http://localhost/auth/test/callback",
+		Scopes:       []string{"openid", "email"},
+		PKCE:         pkce,
+	}, server.Client())
+	require.NoError(t, err)
+	return provider
+}
+
+func signIn(t *testing.T, server *oauthtest.Server, provider *Provider, tamper func(request *AuthRequest)) (Identity, error) {
+	t.Helper()
+
+	request, err := NewAuthRequest()
+	require.NoError(t, err)
+	authCodeURL, err := provider.AuthCodeURL(context.Background(), request)
+	require.NoError(t, err)
+	code := server.Authorize(t, authCodeURL)
+
+	if tamper != nil {
+		tamper(&request)
+	}
+	return provider.Exchange(context.Background(), code, request)
+}
+
+func TestProvider_Exchange(t *testing.T) {
+	testTable := []struct {
+		name             string
+		pkce             bool
+		claims           map[string]interface{}
+		tamper           func(request *AuthRequest)
+		expectedIdentity Identity
+		expectedErr      error
+		expectError      bool
+	}{
+		{
+			name:             "OK",
+			pkce:             true,
+			expectedIdentity: Identity{Provider: "test", Subject: "subject", Email: "test@test.ru", EmailVerified: true},
+		},
+		{
+			name:             "Without PKCE",
+			expectedIdentity: Identity{Provider: "test", Subject: "subject", Email: "test@test.ru", EmailVerified: true},
+		},
+		{
+			name:             "Userinfo requested without email in ID token",
+			claims:           map[string]interface{}{"sub": "subject"},
+			expectedIdentity: Identity{Provider: "test", Subject: "subject"},
+		},
+		{
+			name:        "Wrong code verifier",
+			pkce:        true,
+			tamper:      func(request *AuthRequest) { request.CodeVerifier = "wrong" },
+			expectError: true,
+		},
+		{
+			name:        "Nonce mismatch",
+			tamper:      func(request *AuthRequest) { request.Nonce = "wrong" },
+			expectedErr: ErrInvalidIDToken,
+		},
+		{
+			name:        "Foreign audience",
+			claims:      map[string]interface{}{"sub": "subject", "aud": "another-client"},
+			expectedErr: ErrInvalidIDToken,
+		},
+		{
+			name:        "Foreign issuer",
+			claims:      map[string]interface{}{"sub": "subject", "iss": "https://issuer.example.com"},
+			expectedErr: ErrInvalidIDToken,
+		},
+		{
+			name:        "Expired",
+			claims:      map[string]interface{}{"sub": "subject", "exp": 1},
+			expectedErr: ErrInvalidIDToken,
+		},
+		{
+			name:        "Without subject",
+			claims:      map[string]interface{}{"email": "test@test.ru"},
+			expectError: true,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			server := oauthtest.NewServer(t)
+			if testCase.claims != nil {
+				server.Claims = testCase.claims
+			}
+			provider := newOIDCProvider(t, server, testCase.pkce)
+
+			identity, err := signIn(t, server, provider, testCase.tamper)
+
+			switch {
+			case testCase.expectedErr != nil:
+				assert.ErrorIs(t, err, testCase.expectedErr)
+			case testCase.expectError:
+				assert.Error(t, err)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, testCase.expectedIdentity, identity)
+			}
+		})
+	}
+}
+
+func TestProvider_KeyRequests(t *testing.T) {
+	server := oauthtest.NewServer(t)
+	provider := newOIDCProvider(t, server, false)
+	now := time.Now()
+	provider.now = func() time.Time { return now }
+
+	metadata, err := provider.discover(context.Background())
+	require.NoError(t, err)
+
+	// Concurrent lookups of unknown key ids wait for a single request of keys and
+	// don't request them again right away.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := provider.key(context.Background(), metadata.JWKSURI, "unknown")
+			assert.Error(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, server.JWKSRequests())
+
+	_, err = signIn(t, server, provider, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, server.JWKSRequests())
+
+	now = now.Add(keysRequestInterval)
+	_, err = provider.key(context.Background(), metadata.JWKSURI, "unknown")
+	assert.Error(t, err)
+	assert.Equal(t, 2, server.JWKSRequests())
+}
+
+func TestProvider_KeyRequestFailure(t *testing.T) {
+	server := oauthtest.NewServer(t)
+	server.JWKSFailures = 1
+	provider := newOIDCProvider(t, server, false)
+
+	_, err := signIn(t, server, provider, nil)
+	require.ErrorIs(t, err, ErrInvalidIDToken)
+	assert.Contains(t, err.Error(), "failed to request test provider keys")
+
+	// A failed request doesn't keep keys from being requested again.
+	_, err = signIn(t, server, provider, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, server.JWKSRequests())
+}
+
+func TestProvider_ExchangeClaimMapping(t *testing.T) {
+	server := oauthtest.NewServer(t)
+	server.Claims = map[string]interface{}{"id": json.Number("12345"), "login": "octocat", "email": "test@test.ru"}
+
+	// Plain OAuth2 provider like GitHub: no discovery, no ID token and a numeric id.
+	provider, err := NewProvider(Config{
+		Name:         "github",
+		ClientId:     oauthtest.ClientId,
+		ClientSecret: oauthtest.ClientSecret,
+		AuthURL:      server.URL + "/authorize",
+		TokenURL:     server.URL + "/token",
+		UserInfoURL:  server.URL + "/userinfo",
+		Claims:       ClaimMapping{Subject: "id"},
+	}, server.Client())
+	require.NoError(t, err)
+
+	identity, err := signIn(t, server, provider, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, Identity{Provider: "github", Subject: "12345", Email: "test@test.ru"}, identity)
+}
+
+func TestProvider_AuthCodeURL(t *testing.T) {
+	server := oauthtest.NewServer(t)
+	provider := newOIDCProvider(t, server, true)
+	request := AuthRequest{State: "state", Nonce: "nonce", CodeVerifier: "verifier"}
+
+	authCodeURL, err := provider.AuthCodeURL(context.Background(), request)
+
+	require.NoError(t, err)
+	assert.Contains(t, authCodeURL, server.URL+"/authorize?")
+	assert.Contains(t, authCodeURL, "state=state")
+	assert.Contains(t, authCodeURL, "nonce=nonce")
+	assert.Contains(t, authCodeURL, "code_challenge="+request.codeChallenge())
+	assert.Contains(t, authCodeURL, "code_challenge_method=S256")
+}
+
+func TestProvider_DiscoveryIssuerMismatch(t *testing.T) {
+	server := oauthtest.NewServer(t)
+	provider, err := NewProvider(Config{Name: "test", Issuer: server.Issuer() + "/"}, server.Client())
+	require.NoError(t, err)
+
+	_, err = provider.AuthCodeURL(context.Background(), AuthRequest{})
+
+	assert.Error(t, err)
+}
+
+func TestNewProvider_InvalidConfig(t *testing.T) {
+	_, err := NewProvider(Config{Issuer: "https://issuer.example.com"}, nil)
+	assert.Error(t, err)
+
+	_, err = NewProvider(Config{Name: "github", AuthURL: "https://github.com/login/oauth/authorize"}, nil)
+	assert.Error(t, err)
+}
+
+func TestRegistry_Provider(t *testing.T) {
+	provider, err := NewProvider(Config{Name: "test", Issuer: "https://issuer.example.com"}, nil)
+	require.NoError(t, err)
+
+	registry, err := NewRegistry(provider)
+	require.NoError(t, err)
+
+	actual, err := registry.Provider("test")
+	assert.NoError(t, err)
+	assert.Equal(t, provider, actual)
+
+	_, err = registry.Provider("unknown")
+	assert.ErrorIs(t, err, ErrUnknownProvider)
+
+	_, err = NewRegistry(provider, provider)
+	assert.Error(t, err)
+}
@@ -0,0 +1,27 @@
+package oauth
+
+import "fmt"
+
+// Registry holds configured providers by name, the name is the one used in routes.
+type Registry struct {
+	providers map[string]*Provider
+}
+
+func NewRegistry(providers ...*Provider) (*Registry, error) {
+	registry := &Registry{providers: make(map[string]*Provider, len(providers))}
+	for _, provider := range providers {
+		if _, ok := registry.providers[provider.Name()]; ok {
+			return nil, fmt.Errorf("duplicated oauth provider %q", provider.Name())
+		}
+		registry.providers[provider.Name()] = provider
+	}
+	return registry, nil
+}
+
+func (r *Registry) Provider(name string) (*Provider, error) {
+	provider, ok := r.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownProvider, name)
+	}
+	return provider, nil
+}
@@ -0,0 +1,122 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret is the SHA1 key of the RFC 6238 test vectors.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	// The last 6 digits of the 8 digit codes of RFC 6238 appendix B.
+	testTable := []struct {
+		unix         int64
+		expectedCode string
+	}{
+		{unix: 59, expectedCode: "287082"},
+		{unix: 1111111109, expectedCode: "081804"},
+		{unix: 1111111111, expectedCode: "050471"},
+		{unix: 1234567890, expectedCode: "005924"},
+		{unix: 2000000000, expectedCode: "279037"},
+		{unix: 20000000000, expectedCode: "353130"},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.expectedCode, func(t *testing.T) {
+			code, err := Code(rfcSecret, Step(time.Unix(testCase.unix, 0)))
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedCode, code)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+
+	codeAt := func(step int64) string {
+		code, err := Code(rfcSecret, step)
+		require.NoError(t, err)
+		return code
+	}
+
+	testTable := []struct {
+		name         string
+		code         string
+		skew         int
+		expectedStep int64
+		expectedOK   bool
+	}{
+		{
+			name:         "Current step",
+			code:         codeAt(current),
+			skew:         1,
+			expectedStep: current,
+			expectedOK:   true,
+		},
+		{
+			name:         "Previous step within skew",
+			code:         codeAt(current - 1),
+			skew:         1,
+			expectedStep: current - 1,
+			expectedOK:   true,
+		},
+		{
+			name:         "Next step within skew",
+			code:         codeAt(current + 1),
+			skew:         1,
+			expectedStep: current + 1,
+			expectedOK:   true,
+		},
+		{
+			name: "Outside skew",
+			code: codeAt(current - 2),
+			skew: 1,
+		},
+		{
+			name: "No skew",
+			code: codeAt(current - 1),
+		},
+		{
+			name: "Wrong length",
+			code: "12345",
+			skew: 1,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, testCase.code, now, testCase.skew)
+			assert.Equal(t, testCase.expectedOK, ok)
+			assert.Equal(t, testCase.expectedStep, step)
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	other, err := GenerateSecret()
+	require.NoError(t, err)
+	assert.NotEqual(t, secret, other)
+
+	_, err = Code(secret, 1)
+	assert.NoError(t, err)
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri, err := url.Parse(ProvisioningURI("Users", "test@test.ru", "SECRET"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/Users:test@test.ru", uri.Path)
+	assert.Equal(t, "SECRET", uri.Query().Get("secret"))
+	assert.Equal(t, "Users", uri.Query().Get("issuer"))
+}
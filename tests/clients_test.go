@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"test/internal/domain"
+	"test/internal/service/dto"
+	"test/pkg/api/auth"
+	"test/pkg/hash"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (s *ApiTestSuite) TestClientCredentials() {
+	router := s.handler.Init()
+	r := s.Require()
+
+	userId, err := s.repos.UserRepositiry.Create(context.Background(), domain.User{Email: "test@test.com", Roles: []string{auth.UserRole}})
+	s.NoError(err)
+	adminId, err := s.repos.UserRepositiry.Create(context.Background(), domain.User{Email: "admin@test.com", Roles: []string{auth.AdminRole}})
+	s.NoError(err)
+
+	req, _ := http.NewRequest("POST", "/api/v1/clients/", bytes.NewBufferString(`{"name":"reports","scopes":["users:read"]}`))
+	req.Header.Set("Content-type", "application/json")
+	req.Header.Set("Authorization", s.accessToken(adminId, auth.AdminRole))
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	r.Equal(http.StatusCreated, resp.Result().StatusCode)
+
+	var clientDTO dto.ClientDTO
+	r.NoError(json.Unmarshal(resp.Body.Bytes(), &clientDTO))
+
+	var client domain.Client
+	err = s.db.Collection("clients").FindOne(context.Background(), bson.M{"_id": clientDTO.Id}).Decode(&client)
+	s.NoError(err)
+	r.Equal(hash.HashToken(clientDTO.ClientSecret), client.SecretHash)
+
+	requestToken := func(secret, scope string) *httptest.ResponseRecorder {
+		form := url.Values{"grant_type": {"client_credentials"}, "scope": {scope}}
+		req, _ := http.NewRequest("POST", "/oauth/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(clientDTO.Id, secret)
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+	r.Equal(http.StatusUnauthorized, requestToken("wrong", "").Result().StatusCode)
+	r.Equal(http.StatusBadRequest, requestToken(clientDTO.ClientSecret, "users:delete").Result().StatusCode)
+
+	resp = requestToken(clientDTO.ClientSecret, "users:read")
+	r.Equal(http.StatusOK, resp.Result().StatusCode)
+
+	var tokenDTO dto.ClientTokenDTO
+	r.NoError(json.Unmarshal(resp.Body.Bytes(), &tokenDTO))
+	r.Equal("users:read", tokenDTO.Scope)
+
+	callWithToken := func(method string) int {
+		req, _ := http.NewRequest(method, "/api/v1/users/"+userId.Hex(), nil)
+		req.Header.Set("Authorization", auth.PrefixToken+tokenDTO.AccessToken)
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp.Result().StatusCode
+	}
+	r.Equal(http.StatusOK, callWithToken("GET"))
+	r.Equal(http.StatusForbidden, callWithToken("DELETE"))
+}
+
+func (s *ApiTestSuite) TestTokenIntrospection() {
+	router := s.handler.Init()
+	r := s.Require()
+
+	clientDTO, err := s.services.Clients.Create(context.Background(), dto.CreateClientDTO{
+		Name:   "gateway",
+		Scopes: []string{"tokens:introspect", "tokens:revoke"},
+	})
+	s.NoError(err)
+	otherClientDTO, err := s.services.Clients.Create(context.Background(), dto.CreateClientDTO{Name: "reports", Scopes: []string{"users:read"}})
+	s.NoError(err)
+
+	tokenDTO, err := s.services.Users.Create(context.Background(), dto.CreateUserDTO{Email: "test@test.com", Password: "qwerty123"}, dto.DeviceDTO{})
+	s.NoError(err)
+	accessToken := strings.TrimPrefix(tokenDTO.AccessToken, auth.PrefixToken)
+
+	call := func(path, token string) *httptest.ResponseRecorder {
+		form := url.Values{"token": {token}}
+		req, _ := http.NewRequest("POST", path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(clientDTO.Id, clientDTO.ClientSecret)
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+	introspect := func(token string) dto.IntrospectionDTO {
+		resp := call("/oauth/introspect", token)
+		r.Equal(http.StatusOK, resp.Result().StatusCode)
+
+		var introspection dto.IntrospectionDTO
+		r.NoError(json.Unmarshal(resp.Body.Bytes(), &introspection))
+		return introspection
+	}
+
+	req, _ := http.NewRequest("POST", "/oauth/revoke", strings.NewReader(url.Values{"token": {tokenDTO.RefreshToken}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(otherClientDTO.Id, otherClientDTO.ClientSecret)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	r.Equal(http.StatusForbidden, resp.Result().StatusCode, "clients without tokens:revoke can't revoke tokens of users")
+
+	r.True(introspect(accessToken).Active)
+	introspection := introspect(tokenDTO.RefreshToken)
+	r.True(introspection.Active)
+	r.Equal("refresh_token", introspection.TokenType)
+
+	r.Equal(http.StatusOK, call("/oauth/revoke", tokenDTO.RefreshToken).Result().StatusCode)
+	r.False(introspect(tokenDTO.RefreshToken).Active)
+	r.False(introspect(accessToken).Active, "the last access token of the session is revoked with it")
+
+	r.Equal(http.StatusOK, call("/oauth/revoke", "unknown").Result().StatusCode)
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	v1 "test/internal/delivery/http/v1"
+	"test/pkg/api/auth"
+	"time"
+)
+
+func (s *ApiTestSuite) TestCookies() {
+	handler := v1.NewHandler(s.services, s.tokenManager)
+	handler.UseCookies(v1.Cookies{
+		SameSite:        http.SameSiteStrictMode,
+		AccessTokenTTL:  time.Minute * 15,
+		RefreshTokenTTL: time.Minute * 15,
+	})
+	router := handler.Init()
+	r := s.Require()
+
+	req, _ := http.NewRequest("POST", "/api/v1/users/", bytes.NewBufferString(`{"email":"test@test.com","password":"password1"}`))
+	req.Header.Set("Content-type", "application/json")
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	r.Equal(http.StatusCreated, resp.Result().StatusCode)
+	r.Empty(resp.Header().Get("Access-Token"))
+
+	cookies := make(map[string]*http.Cookie)
+	for _, cookie := range resp.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	r.Len(cookies, 3)
+
+	send := func(method, path, csrfToken string, names ...string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		for _, name := range names {
+			req.AddCookie(cookies[name])
+		}
+		if csrfToken != "" {
+			req.Header.Set(auth.CSRFHeader, csrfToken)
+		}
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+	csrfToken := cookies[auth.CSRFCookie].Value
+
+	resp = send("POST", "/api/v1/auth/refresh", "", auth.RefreshTokenCookie, auth.CSRFCookie)
+	r.Equal(http.StatusForbidden, resp.Result().StatusCode, "refresh without csrf token")
+	resp = send("POST", "/api/v1/auth/refresh", csrfToken, auth.RefreshTokenCookie, auth.CSRFCookie)
+	r.Equal(http.StatusOK, resp.Result().StatusCode)
+	for _, cookie := range resp.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	csrfToken = cookies[auth.CSRFCookie].Value
+
+	resp = send("POST", "/api/v1/auth/logout", "", auth.AccessTokenCookie, auth.CSRFCookie)
+	r.Equal(http.StatusForbidden, resp.Result().StatusCode, "logout without csrf token")
+	resp = send("POST", "/api/v1/auth/logout", csrfToken, auth.AccessTokenCookie, auth.CSRFCookie)
+	r.Equal(http.StatusOK, resp.Result().StatusCode)
+	for _, cookie := range resp.Result().Cookies() {
+		r.Equal(-1, cookie.MaxAge, "cookies are removed on logout")
+	}
+}
@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"test/internal/domain"
+	"test/internal/service/dto"
+	"test/pkg/api/auth"
+)
+
+func (s *ApiTestSuite) TestImpersonation() {
+	router := s.handler.Init()
+	r := s.Require()
+
+	userId, err := s.repos.UserRepositiry.Create(context.Background(), domain.User{Email: "test@test.com", Roles: []string{auth.UserRole}})
+	s.NoError(err)
+	adminId, err := s.repos.UserRepositiry.Create(context.Background(), domain.User{Email: "admin@test.com", Roles: []string{auth.AdminRole}})
+	s.NoError(err)
+	adminToken := s.accessToken(adminId, auth.AdminRole)
+
+	req, _ := http.NewRequest("POST", "/api/v1/users/"+userId.Hex()+"/impersonations", bytes.NewBufferString(`{"reason":"ticket 42"}`))
+	req.Header.Set("Content-type", "application/json")
+	req.Header.Set("Authorization", adminToken)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	r.Equal(http.StatusCreated, resp.Result().StatusCode)
+
+	var impersonationDTO dto.ImpersonationDTO
+	r.NoError(json.Unmarshal(resp.Body.Bytes(), &impersonationDTO))
+	r.Equal(adminId, impersonationDTO.ActorId)
+
+	callWithToken := func(method, path, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", token)
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+	userURL := "/api/v1/users/" + userId.Hex()
+	r.Equal(http.StatusOK, callWithToken("GET", userURL, impersonationDTO.AccessToken).Result().StatusCode)
+	r.Equal(http.StatusForbidden, callWithToken("DELETE", userURL, impersonationDTO.AccessToken).Result().StatusCode,
+		"sensitive actions are denied to impersonation tokens")
+
+	resp = callWithToken("GET", userURL+"/audit", adminToken)
+	r.Equal(http.StatusOK, resp.Result().StatusCode)
+	var events []domain.AuditEvent
+	r.NoError(json.Unmarshal(resp.Body.Bytes(), &events))
+	actions := make([]string, 0, len(events))
+	for _, event := range events {
+		r.Equal(adminId, event.ActorId)
+		r.Equal(impersonationDTO.Id, event.ImpersonationId)
+		actions = append(actions, event.Action)
+	}
+	r.ElementsMatch([]string{domain.AuditImpersonationStarted, domain.AuditImpersonatedRequest, domain.AuditImpersonatedRequest}, actions)
+
+	resp = callWithToken("DELETE", userURL+"/impersonations/"+impersonationDTO.Id.Hex(), adminToken)
+	r.Equal(http.StatusOK, resp.Result().StatusCode)
+	r.Equal(http.StatusUnauthorized, callWithToken("GET", userURL, impersonationDTO.AccessToken).Result().StatusCode)
+
+	resp = callWithToken("GET", userURL+"/impersonations", adminToken)
+	r.Equal(http.StatusOK, resp.Result().StatusCode)
+	r.Equal(`[]`, resp.Body.String())
+}
@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"test/internal/domain"
+	"test/internal/service"
+	"test/pkg/api/auth"
+)
+
+func (s *ApiTestSuite) TestMagicLink() {
+	router := s.handler.Init()
+	r := s.Require()
+
+	email := "test@test.com"
+	_, err := s.repos.UserRepositiry.Create(context.Background(), domain.User{Email: email, Roles: []string{auth.UserRole}})
+	s.NoError(err)
+
+	req, _ := http.NewRequest("POST", "/api/v1/auth/magic-link", bytes.NewBufferString(`{"email":"`+email+`"}`))
+	req.Header.Set("Content-type", "application/json")
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	r.Equal(http.StatusAccepted, resp.Result().StatusCode)
+	r.Len(resp.Result().Cookies(), 1)
+	browser := resp.Result().Cookies()[0]
+
+	notification, ok := s.outbox.Last(service.NotificationMagicLink, email)
+	r.True(ok)
+	link, err := url.Parse(notification.Data["link"])
+	r.NoError(err)
+
+	consume := func(cookie *http.Cookie) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", link.RequestURI(), nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+	r.Equal(http.StatusBadRequest, consume(nil).Result().StatusCode, "another browser")
+
+	resp = consume(browser)
+	r.Equal(http.StatusOK, resp.Result().StatusCode)
+	r.NotEmpty(resp.Header().Get("Access-Token"))
+	r.NotEmpty(resp.Header().Get("Refresh-Token"))
+	r.Contains(resp.Body.String(), `"access_token"`)
+
+	r.Equal(http.StatusBadRequest, consume(browser).Result().StatusCode, "the link works once")
+}
@@ -10,11 +10,13 @@ import (
 	"test/pkg/api/auth"
 	"test/pkg/client/mongodb"
 	"test/pkg/hash"
+	"test/pkg/lockout"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/suite"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
@@ -33,6 +35,7 @@ type ApiTestSuite struct {
 
 	tokenManager auth.TokenManager
 	hasher       *hash.SHA1Hasher
+	outbox       *service.Outbox
 }
 
 func TestAPISuite(t *testing.T) {
@@ -62,17 +65,44 @@ func (s *ApiTestSuite) TearDownSuite() {
 
 func (s *ApiTestSuite) BeforeTest(suiteName, testName string) {
 	s.db.Collection("users").DeleteMany(context.Background(), bson.D{})
+	s.db.Collection("sessions").DeleteMany(context.Background(), bson.D{})
+	s.db.Collection("revoked_tokens").DeleteMany(context.Background(), bson.D{})
+	s.db.Collection("failed_attempts").DeleteMany(context.Background(), bson.D{})
+	s.db.Collection("api_keys").DeleteMany(context.Background(), bson.D{})
+	s.db.Collection("clients").DeleteMany(context.Background(), bson.D{})
+	s.db.Collection("impersonations").DeleteMany(context.Background(), bson.D{})
+	s.db.Collection("audit_log").DeleteMany(context.Background(), bson.D{})
 }
 
 func (s *ApiTestSuite) initDeps() {
 	repos := repository.NewRepository(s.db)
 	hasher := hash.NewSHA1Hasher("salt")
 
-	tokenManager, err := auth.NewManager("signing_key")
+	keys, err := auth.NewHMACKeySet("signing_key")
+	if err != nil {
+		s.FailNow("Failed to initialize signing keys", err)
+	}
+
+	tokenManager, err := auth.NewManager(keys, repos.Denylist, auth.DefaultPolicy(), auth.ManagerConfig{
+		Issuer:    "users-api",
+		Audiences: []string{"users-api"},
+		Cookies:   true,
+	})
 	if err != nil {
 		s.FailNow("Failed to initialize token manager", err)
 	}
 
+	accounts, err := lockout.NewLimiter(repos.Attempts, lockout.Rule{
+		FreeAttempts: 2,
+		LockAfter:    3,
+		LockDuration: time.Hour,
+		Window:       time.Hour,
+	})
+	if err != nil {
+		s.FailNow("Failed to initialize lockout", err)
+	}
+
+	outbox := service.NewOutbox()
 	services := service.NewServices(service.Deps{
 
 		Repos:        repos,
@@ -81,6 +111,23 @@ func (s *ApiTestSuite) initDeps() {
 
 		AccessTokenTTL:  time.Minute * 15,
 		RefreshTokenTTL: time.Minute * 15,
+
+		Notifier:         outbox,
+		PasswordResetTTL: time.Minute * 15,
+		EmailVerification: service.EmailVerification{
+			LinkURL:  "http://localhost:4000/api/v1/auth/verify-email",
+			TokenTTL: time.Minute * 15,
+		},
+		TOTP:           service.TOTP{Issuer: "Users", Skew: 1, RecoveryCodes: 10},
+		Lockout:        service.Lockout{Accounts: accounts},
+		ClientTokenTTL: time.Minute * 15,
+
+		ImpersonationTokenTTL: time.Minute * 15,
+		MagicLink: service.MagicLink{
+			Enabled:  true,
+			LinkURL:  "http://localhost:4000/api/v1/auth/magic-link/consume",
+			TokenTTL: time.Minute * 15,
+		},
 	})
 
 	s.repos = repos
@@ -88,6 +135,19 @@ func (s *ApiTestSuite) initDeps() {
 	s.handler = v1.NewHandler(services, tokenManager)
 	s.hasher = hasher
 	s.tokenManager = tokenManager
+	s.outbox = outbox
+}
+
+// accessToken signs an access token of the user with the roles.
+func (s *ApiTestSuite) accessToken(id primitive.ObjectID, roles ...string) string {
+	claims := auth.Claims{Roles: roles}
+	claims.Subject = id.Hex()
+
+	token, err := s.tokenManager.GenerateAccessToken(claims, time.Minute)
+	if err != nil {
+		s.FailNow("Failed to generate access token", err)
+	}
+	return token
 }
 
 func TestMain(m *testing.M) {
@@ -3,10 +3,18 @@ package tests
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"test/internal/domain"
+	"test/internal/service"
+	"test/internal/service/dto"
+	"test/pkg/api/auth"
+	"test/pkg/hash"
+	"test/pkg/totp"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -51,12 +59,12 @@ func (s *ApiTestSuite) TestUserFindOne() {
 		Id:           id,
 		PasswordHash: passwordHash,
 		Email:        email,
-		Session:      domain.Session{},
 	})
 	s.NoError(err)
 
 	req, _ := http.NewRequest("GET", "/api/v1/users/"+id.Hex(), &bytes.Reader{})
 	req.Header.Set("Content-type", "application/json")
+	req.Header.Set("Authorization", s.accessToken(id, auth.UserRole))
 
 	resp := httptest.NewRecorder()
 	router.ServeHTTP(resp, req)
@@ -109,6 +117,7 @@ func (s *ApiTestSuite) TestUserFindAll() {
 
 	req, _ := http.NewRequest("GET", "/api/v1/users/", &bytes.Reader{})
 	req.Header.Set("Content-type", "application/json")
+	req.Header.Set("Authorization", s.accessToken(primitive.NewObjectID(), auth.AdminRole))
 
 	resp := httptest.NewRecorder()
 	router.ServeHTTP(resp, req)
@@ -141,7 +150,6 @@ func (s *ApiTestSuite) TestUserUpdate() {
 		Id:           id,
 		PasswordHash: passwordHash,
 		Email:        email,
-		Session:      domain.Session{},
 	})
 	s.NoError(err)
 
@@ -150,6 +158,7 @@ func (s *ApiTestSuite) TestUserUpdate() {
 
 	req, _ := http.NewRequest("PUT", "/api/v1/users/"+id.Hex(), bytes.NewBuffer([]byte(usersData)))
 	req.Header.Set("Content-type", "application/json")
+	req.Header.Set("Authorization", s.accessToken(id, auth.UserRole))
 
 	resp := httptest.NewRecorder()
 	router.ServeHTTP(resp, req)
@@ -163,7 +172,8 @@ func (s *ApiTestSuite) TestUserUpdate() {
 	updatePasswordHash, err := s.hasher.Hash(updatePassword)
 	s.NoError(err)
 
-	r.Equal(updateEmail, user.Email)
+	r.Equal(email, user.Email, "new email takes effect once verified")
+	r.Equal(updateEmail, user.PendingEmail)
 	r.Equal(updatePasswordHash, user.PasswordHash)
 }
 
@@ -181,12 +191,12 @@ func (s *ApiTestSuite) TestUserDelete() {
 		Id:           id,
 		PasswordHash: passwordHash,
 		Email:        email,
-		Session:      domain.Session{},
 	})
 	s.NoError(err)
 
 	req, _ := http.NewRequest("DELETE", "/api/v1/users/"+id.Hex(), &bytes.Reader{})
 	req.Header.Set("Content-type", "application/json")
+	req.Header.Set("Authorization", s.accessToken(id, auth.UserRole))
 
 	resp := httptest.NewRecorder()
 	router.ServeHTTP(resp, req)
@@ -216,7 +226,11 @@ func (s *ApiTestSuite) TestUserCreateSetSession() {
 	err := s.db.Collection("users").FindOne(context.Background(), bson.M{"email": email}).Decode(&user)
 	s.NoError(err)
 
-	r.NotEmpty(user.Session.RefreshToken, user.Session.ExpiresAt)
+	var session domain.Session
+	err = s.db.Collection("sessions").FindOne(context.Background(), bson.M{"user_id": user.Id}).Decode(&session)
+	s.NoError(err)
+
+	r.NotEmpty(session.RefreshTokenHash, session.ExpiresAt)
 
 }
 
@@ -227,9 +241,18 @@ func (s *ApiTestSuite) TestUserGetUserByRefreshToken() {
 	email, password := "test@test.com", "qwerty123"
 	id := primitive.NewObjectID()
 
+	refreshToken := "Test token"
+	// The session already remembers as many exchanged tokens as it keeps.
+	rotated := make([]string, 50)
+	for i := range rotated {
+		rotated[i] = hash.HashToken(fmt.Sprintf("Rotated token %d", i))
+	}
 	session := domain.Session{
-		RefreshToken: "Test token",
-		ExpiresAt:    time.Now().Add(time.Minute * 2),
+		Id:                 primitive.NewObjectID(),
+		UserId:             id,
+		RefreshTokenHash:   hash.HashToken(refreshToken),
+		RotatedTokenHashes: rotated,
+		ExpiresAt:          time.Now().Add(time.Minute * 2),
 	}
 	passwordHash, err := s.hasher.Hash(password)
 	s.NoError(err)
@@ -238,11 +261,13 @@ func (s *ApiTestSuite) TestUserGetUserByRefreshToken() {
 		Id:           id,
 		PasswordHash: passwordHash,
 		Email:        email,
-		Session:      session,
 	})
 	s.NoError(err)
+	_, err = s.db.Collection("sessions").InsertOne(context.Background(), session)
+	s.NoError(err)
 
-	req, _ := http.NewRequest("GET", "/api/v1/users/"+id.Hex()+"/auth/refresh", &bytes.Reader{})
+	refreshData := fmt.Sprintf(`{"refresh_token":"%s"}`, refreshToken)
+	req, _ := http.NewRequest("POST", "/api/v1/auth/refresh", bytes.NewBuffer([]byte(refreshData)))
 	req.Header.Set("Content-type", "application/json")
 
 	resp := httptest.NewRecorder()
@@ -250,11 +275,423 @@ func (s *ApiTestSuite) TestUserGetUserByRefreshToken() {
 
 	r.Equal(http.StatusOK, resp.Result().StatusCode)
 
+	var refreshed domain.Session
+	err = s.db.Collection("sessions").FindOne(context.Background(), bson.M{"_id": session.Id}).Decode(&refreshed)
+	s.NoError(err)
+
+	r.NotEqual(refreshed.RefreshTokenHash, session.RefreshTokenHash)
+	r.NotEqual(refreshed.ExpiresAt, session.ExpiresAt)
+	r.Contains(refreshed.RotatedTokenHashes, session.RefreshTokenHash)
+	r.Len(refreshed.RotatedTokenHashes, len(rotated))
+	r.NotContains(refreshed.RotatedTokenHashes, rotated[0])
+
+	req, _ = http.NewRequest("POST", "/api/v1/auth/refresh", bytes.NewBuffer([]byte(refreshData)))
+	req.Header.Set("Content-type", "application/json")
+
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	r.Equal(http.StatusUnauthorized, resp.Result().StatusCode)
+
+	result := s.db.Collection("sessions").FindOne(context.Background(), bson.M{"_id": session.Id})
+	s.ErrorIs(result.Err(), mongo.ErrNoDocuments)
+
+}
+
+func (s *ApiTestSuite) TestUserSignIn() {
+	router := s.handler.Init()
+	r := s.Require()
+
+	email, password := "test@test.com", "qwerty123"
+
+	passwordHash, err := s.hasher.Hash(password)
+	s.NoError(err)
+
+	_, err = s.db.Collection("users").InsertOne(context.Background(), domain.User{
+		Id:           primitive.NewObjectID(),
+		PasswordHash: passwordHash,
+		Email:        email,
+	})
+	s.NoError(err)
+
+	signInData := fmt.Sprintf(`{"email":"%s","password":"%s"}`, email, password)
+	req, _ := http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer([]byte(signInData)))
+	req.Header.Set("Content-type", "application/json")
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	r.Equal(http.StatusOK, resp.Result().StatusCode)
+	r.NotEmpty(resp.Header().Get("Access-Token"))
+	r.NotEmpty(resp.Header().Get("Refresh-Token"))
+
+	wrongData := fmt.Sprintf(`{"email":"%s","password":"%s"}`, email, "wrong1234")
+	req, _ = http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer([]byte(wrongData)))
+	req.Header.Set("Content-type", "application/json")
+
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	r.Equal(http.StatusUnauthorized, resp.Result().StatusCode)
+}
+
+func (s *ApiTestSuite) TestUserLockout() {
+	router := s.handler.Init()
+	r := s.Require()
+
+	email, password := "test@test.com", "qwerty123"
+
+	passwordHash, err := s.hasher.Hash(password)
+	s.NoError(err)
+
+	id := primitive.NewObjectID()
+	_, err = s.db.Collection("users").InsertOne(context.Background(), domain.User{
+		Id:           id,
+		PasswordHash: passwordHash,
+		Email:        email,
+	})
+	s.NoError(err)
+
+	signIn := func(password string) *httptest.ResponseRecorder {
+		signInData := fmt.Sprintf(`{"email":"%s","password":"%s"}`, email, password)
+		req, _ := http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer([]byte(signInData)))
+		req.Header.Set("Content-type", "application/json")
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	for i := 0; i < 3; i++ {
+		r.Equal(http.StatusUnauthorized, signIn("wrong1234").Result().StatusCode)
+	}
+
+	resp := signIn(password)
+	r.Equal(http.StatusLocked, resp.Result().StatusCode)
+	r.NotEmpty(resp.Header().Get("Retry-After"))
+
+	req, _ := http.NewRequest("DELETE", "/api/v1/users/"+id.Hex()+"/lockout", nil)
+	req.Header.Set("Authorization", s.accessToken(primitive.NewObjectID(), auth.AdminRole))
+
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	r.Equal(http.StatusOK, resp.Result().StatusCode)
+
+	r.Equal(http.StatusOK, signIn(password).Result().StatusCode)
+}
+
+func (s *ApiTestSuite) TestUserLogout() {
+	router := s.handler.Init()
+	r := s.Require()
+	email, password := "test@test.com", "qwerty123"
+	usersData := fmt.Sprintf(`{"email":"%s","password":"%s"}`, email, password)
+
+	req, _ := http.NewRequest("POST", "/api/v1/users/", bytes.NewBuffer([]byte(usersData)))
+	req.Header.Set("Content-type", "application/json")
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	r.Equal(http.StatusCreated, resp.Result().StatusCode)
+	accessToken := resp.Header().Get("Access-Token")
+
+	req, _ = http.NewRequest("POST", "/api/v1/auth/logout", &bytes.Reader{})
+	req.Header.Set("Authorization", accessToken)
+
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	r.Equal(http.StatusOK, resp.Result().StatusCode)
+
+	count, err := s.db.Collection("sessions").CountDocuments(context.Background(), bson.D{})
+	s.NoError(err)
+	r.Zero(count)
+
+	req, _ = http.NewRequest("POST", "/api/v1/auth/logout", &bytes.Reader{})
+	req.Header.Set("Authorization", accessToken)
+
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	r.Equal(http.StatusUnauthorized, resp.Result().StatusCode)
+}
+
+func (s *ApiTestSuite) TestUserGrantRole() {
+	router := s.handler.Init()
+	r := s.Require()
+	adminEmail, adminPassword := "admin@test.com", "qwerty123"
+
+	err := s.services.Users.BootstrapAdmin(context.Background(), adminEmail, adminPassword)
+	s.NoError(err)
+
+	signInData := fmt.Sprintf(`{"email":"%s","password":"%s"}`, adminEmail, adminPassword)
+	req, _ := http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer([]byte(signInData)))
+	req.Header.Set("Content-type", "application/json")
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	r.Equal(http.StatusOK, resp.Result().StatusCode)
+	accessToken := resp.Header().Get("Access-Token")
+
+	id, err := s.repos.UserRepositiry.Create(context.Background(), domain.User{Email: "test@test.com"})
+	s.NoError(err)
+
+	req, _ = http.NewRequest("POST", "/api/v1/users/"+id.Hex()+"/roles", bytes.NewBuffer([]byte(`{"role":"admin"}`)))
+	req.Header.Set("Content-type", "application/json")
+	req.Header.Set("Authorization", accessToken)
+
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	r.Equal(http.StatusOK, resp.Result().StatusCode)
+
+	var user domain.User
+	err = s.db.Collection("users").FindOne(context.Background(), bson.M{"_id": id}).Decode(&user)
+	s.NoError(err)
+	r.True(user.HasRole(auth.AdminRole))
+
+	req, _ = http.NewRequest("DELETE", "/api/v1/users/"+id.Hex()+"/roles/admin", &bytes.Reader{})
+	req.Header.Set("Authorization", accessToken)
+
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	r.Equal(http.StatusOK, resp.Result().StatusCode)
+
+	err = s.db.Collection("users").FindOne(context.Background(), bson.M{"_id": id}).Decode(&user)
+	s.NoError(err)
+	r.False(user.HasRole(auth.AdminRole))
+}
+
+func (s *ApiTestSuite) TestUserUnlinkIdentity() {
+	router := s.handler.Init()
+	r := s.Require()
+
+	id, err := s.repos.UserRepositiry.Create(context.Background(), domain.User{
+		Email: "test@test.com",
+		Identities: []domain.Identity{
+			{Provider: "google", Subject: "google-subject"},
+			{Provider: "github", Subject: "github-subject"},
+		},
+	})
+	s.NoError(err)
+	accessToken := s.accessToken(id, auth.UserRole)
+
+	for _, step := range []struct {
+		provider   string
+		statusCode int
+	}{
+		{provider: "google", statusCode: http.StatusOK},
+		{provider: "google", statusCode: http.StatusNotFound},
+		{provider: "github", statusCode: http.StatusConflict},
+	} {
+		req, _ := http.NewRequest("DELETE", "/api/v1/users/"+id.Hex()+"/identities/"+step.provider, &bytes.Reader{})
+		req.Header.Set("Authorization", accessToken)
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		r.Equal(step.statusCode, resp.Result().StatusCode, step.provider)
+	}
+
+	var user domain.User
+	err = s.db.Collection("users").FindOne(context.Background(), bson.M{"_id": id}).Decode(&user)
+	s.NoError(err)
+	r.Len(user.Identities, 1)
+	r.Equal("github", user.Identities[0].Provider)
+}
+
+func (s *ApiTestSuite) TestUserResetPassword() {
+	router := s.handler.Init()
+	r := s.Require()
+	email, password := "test@test.com", "qwerty123"
+
+	passwordHash, err := s.hasher.Hash(password)
+	s.NoError(err)
+	id, err := s.repos.UserRepositiry.Create(context.Background(), domain.User{Email: email, PasswordHash: passwordHash})
+	s.NoError(err)
+	s.NoError(s.repos.UserRepositiry.CreateSession(context.Background(), domain.Session{
+		Id:        primitive.NewObjectID(),
+		UserId:    id,
+		ExpiresAt: time.Now().Add(time.Minute),
+	}))
+
+	req, _ := http.NewRequest("POST", "/api/v1/auth/password/forgot", bytes.NewBufferString(`{"email":"`+email+`"}`))
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	r.Equal(http.StatusAccepted, resp.Result().StatusCode)
+
+	notification, ok := s.outbox.Last(service.NotificationPasswordReset, email)
+	r.True(ok)
+	resetData := fmt.Sprintf(`{"token":"%s","password":"newPassword1"}`, notification.Data["token"])
+
+	req, _ = http.NewRequest("POST", "/api/v1/auth/password/reset", bytes.NewBufferString(resetData))
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	r.Equal(http.StatusOK, resp.Result().StatusCode)
+
+	req, _ = http.NewRequest("POST", "/api/v1/auth/password/reset", bytes.NewBufferString(resetData))
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	r.Equal(http.StatusBadRequest, resp.Result().StatusCode, "reset token is single-use")
+
+	sessions, err := s.repos.UserRepositiry.FindSessions(context.Background(), id)
+	s.NoError(err)
+	r.Empty(sessions)
+
+	req, _ = http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBufferString(`{"email":"`+email+`","password":"newPassword1"}`))
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	r.Equal(http.StatusOK, resp.Result().StatusCode)
+}
+
+func (s *ApiTestSuite) TestUserVerifyEmail() {
+	router := s.handler.Init()
+	r := s.Require()
+
+	verify := func(email string) int {
+		notification, ok := s.outbox.Last(service.NotificationEmailVerification, email)
+		r.True(ok)
+		link, err := url.Parse(notification.Data["link"])
+		r.NoError(err)
+
+		req, _ := http.NewRequest("GET", "/api/v1/auth/verify-email?"+link.RawQuery, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp.Result().StatusCode
+	}
+
+	req, _ := http.NewRequest("POST", "/api/v1/users/", bytes.NewBufferString(`{"email":"test@test.com","password":"qwerty123"}`))
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	r.Equal(http.StatusOK, resp.Result().StatusCode)
+
+	user, err := s.repos.UserRepositiry.FindByEmail(context.Background(), "test@test.com")
+	s.NoError(err)
+	r.False(user.EmailVerified)
+
+	r.Equal(http.StatusOK, verify("test@test.com"))
+	user, err = s.repos.UserRepositiry.FindOne(context.Background(), user.Id)
+	s.NoError(err)
+	r.True(user.EmailVerified)
+
+	err = s.services.Users.Update(context.Background(), dto.UpdateUserDTO{Id: user.Id.Hex(), Email: "new@test.com", Password: "qwerty123"})
+	s.NoError(err)
+	user, err = s.repos.UserRepositiry.FindOne(context.Background(), user.Id)
+	s.NoError(err)
+	r.Equal("test@test.com", user.Email, "email changes once verified")
+	r.Equal("new@test.com", user.PendingEmail)
+
+	r.Equal(http.StatusOK, verify("new@test.com"))
+	user, err = s.repos.UserRepositiry.FindOne(context.Background(), user.Id)
+	s.NoError(err)
+	r.Equal("new@test.com", user.Email)
+	r.Empty(user.PendingEmail)
+	r.True(user.EmailVerified)
+
+	r.Equal(http.StatusBadRequest, verify("test@test.com"), "old email can't be verified any more")
+}
+
+func (s *ApiTestSuite) TestUserMFA() {
+	router := s.handler.Init()
+	r := s.Require()
+	email, password := "test@test.com", "qwerty123"
+
+	passwordHash, err := s.hasher.Hash(password)
+	s.NoError(err)
+	id, err := s.repos.UserRepositiry.Create(context.Background(), domain.User{Email: email, PasswordHash: passwordHash})
+	s.NoError(err)
+	accessToken := s.accessToken(id, auth.UserRole)
+
+	send := func(method, path, body, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := send("POST", "/api/v1/users/"+id.Hex()+"/mfa", "", accessToken)
+	r.Equal(http.StatusOK, resp.Result().StatusCode)
+	var enrollmentDTO dto.MFAEnrollmentDTO
+	r.NoError(json.Unmarshal(resp.Body.Bytes(), &enrollmentDTO))
+
+	code, err := totp.Code(enrollmentDTO.Secret, totp.Step(time.Now()))
+	s.NoError(err)
+	resp = send("POST", "/api/v1/users/"+id.Hex()+"/mfa/confirm", `{"code":"`+code+`"}`, accessToken)
+	r.Equal(http.StatusOK, resp.Result().StatusCode)
+	var recoveryCodesDTO dto.RecoveryCodesDTO
+	r.NoError(json.Unmarshal(resp.Body.Bytes(), &recoveryCodesDTO))
+	r.Len(recoveryCodesDTO.RecoveryCodes, 10)
+
+	resp = send("POST", "/api/v1/auth/login", `{"email":"`+email+`","password":"`+password+`"}`, "")
+	r.Equal(http.StatusAccepted, resp.Result().StatusCode)
+	r.Empty(resp.Header().Get("Access-Token"))
+	var challengeDTO dto.MFAChallengeDTO
+	r.NoError(json.Unmarshal(resp.Body.Bytes(), &challengeDTO))
+
+	verify := func(code string) *httptest.ResponseRecorder {
+		return send("POST", "/api/v1/auth/mfa/verify", `{"mfa_token":"`+challengeDTO.MFAToken+`","code":"`+code+`"}`, "")
+	}
+	r.Equal(http.StatusUnauthorized, verify(code).Result().StatusCode, "the confirming code can't be replayed")
+
+	recoveryCode := recoveryCodesDTO.RecoveryCodes[0]
+	resp = verify(recoveryCode)
+	r.Equal(http.StatusOK, resp.Result().StatusCode)
+	r.NotEmpty(resp.Header().Get("Access-Token"))
+	r.Equal(http.StatusUnauthorized, verify(recoveryCode).Result().StatusCode, "recovery codes are single-use")
+
 	var user domain.User
-	err = s.db.Collection("users").FindOne(context.Background(), bson.M{"email": email}).Decode(&user)
+	err = s.db.Collection("users").FindOne(context.Background(), bson.M{"_id": id}).Decode(&user)
+	s.NoError(err)
+	r.True(user.MFAEnabled())
+	r.Len(user.MFA.RecoveryCodes, 9)
+}
+
+func (s *ApiTestSuite) TestUserAPIKey() {
+	router := s.handler.Init()
+	r := s.Require()
+
+	id, err := s.repos.UserRepositiry.Create(context.Background(), domain.User{Email: "test@test.com", Roles: []string{auth.UserRole}})
+	s.NoError(err)
+
+	req, _ := http.NewRequest("POST", "/api/v1/users/"+id.Hex()+"/api-keys", bytes.NewBufferString(`{"name":"ci","scopes":["users:read"]}`))
+	req.Header.Set("Content-type", "application/json")
+	req.Header.Set("Authorization", s.accessToken(id, auth.UserRole))
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	r.Equal(http.StatusCreated, resp.Result().StatusCode)
+
+	var apiKeyDTO dto.APIKeyDTO
+	r.NoError(json.Unmarshal(resp.Body.Bytes(), &apiKeyDTO))
+	r.True(strings.HasPrefix(apiKeyDTO.Key, service.APIKeyPrefix))
+
+	var apiKey domain.APIKey
+	err = s.db.Collection("api_keys").FindOne(context.Background(), bson.M{"_id": apiKeyDTO.Id}).Decode(&apiKey)
 	s.NoError(err)
+	r.Equal(hash.HashToken(apiKeyDTO.Key), apiKey.KeyHash)
+
+	callWithKey := func(method string) int {
+		req, _ := http.NewRequest(method, "/api/v1/users/"+id.Hex(), bytes.NewBufferString(`{"email":"test@test.com","password":"qwerty123"}`))
+		req.Header.Set("X-API-Key", apiKeyDTO.Key)
+
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp.Result().StatusCode
+	}
+	r.Equal(http.StatusOK, callWithKey("GET"))
+	r.Equal(http.StatusForbidden, callWithKey("PUT"))
 
-	r.NotEqual(user.Session.RefreshToken, session.RefreshToken)
-	r.NotEqual(user.Session.ExpiresAt, session.ExpiresAt)
+	req, _ = http.NewRequest("DELETE", "/api/v1/users/"+id.Hex()+"/api-keys/"+apiKeyDTO.Id.Hex(), nil)
+	req.Header.Set("Authorization", s.accessToken(id, auth.UserRole))
+
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	r.Equal(http.StatusOK, resp.Result().StatusCode)
 
+	r.Equal(http.StatusUnauthorized, callWithKey("GET"))
 }